@@ -12,7 +12,6 @@ require (
 )
 
 require (
-	github.com/codingsince1985/geo-golang v1.8.5 // indirect
 	github.com/deckarep/golang-set/v2 v2.8.0 // indirect
 	github.com/doug-martin/goqu/v9 v9.19.0 // indirect
 	github.com/golang-jwt/jwt/v5 v5.2.2 // indirect
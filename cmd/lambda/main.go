@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/aws/aws-lambda-go/lambda"
 
@@ -13,7 +14,9 @@ import (
 
 // //////////////////////////////////////////////////////////////////////////
 type LambdaRequestBody struct {
-	Query string `json:"query"`
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+	OperationName string                 `json:"operationName,omitempty"`
 }
 
 // //////////////////////////////////////////////////////////////////////////
@@ -36,6 +39,7 @@ func generateResp(body string, statusCode int) LambdaResponse {
 		Body:       body,
 		Headers: map[string]string{
 			"Access-Control-Allow-Origin": "*",
+			"Content-Type":                "application/json",
 		},
 	}
 }
@@ -45,15 +49,44 @@ func generateOkResp(body string) LambdaResponse {
 	return generateResp(body, http.StatusOK)
 }
 
+// How long before the invocation's deadline waiting for order geocodes stops
+const geocodeWaitMargin = time.Second
+
+// //////////////////////////////////////////////////////////////////////////
+// The Lambda is frozen as soon as the handler returns so the geocoding
+// started for the orders it saved is finished first.  Orders it doesn't get
+// to before the deadline are linked later by geocodeOrderAddrs.
+func waitForOrderGeocodes(ctx context.Context) {
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline.Add(-geocodeWaitMargin))
+		defer cancel()
+	}
+	if err := frgql.WaitForOrderGeocodes(ctx); err != nil {
+		log.Println("Stopped waiting for order geocodes: ", err)
+	}
+}
+
 // //////////////////////////////////////////////////////////////////////////
+// Failures are sent back as a GraphQL response with the errors in it and a
+// status for the first error's code.  The handler itself doesn't fail so API
+// Gateway passes that status along.
 func HandleLambdaEvent(ctx context.Context, event LambdaRequest) (LambdaResponse, error) {
 	// if dbconn not already established then RwLock to go ahead and try once sync.Once
 	// check authorization
 	// run query
 	// return results
+	defer waitForOrderGeocodes(ctx)
+
 	if err := frgql.OpenDb(); err != nil {
 		log.Println("Failed to initialize db:", err)
-		return generateResp("", http.StatusInternalServerError), err
+		return generateResp(string(frgql.MakeGqlErrorResp(err)), http.StatusInternalServerError), nil
+	}
+
+	// Don't serve against a schema this build doesn't know about
+	if err := frgql.VerifySchemaVersion(ctx); err != nil {
+		log.Println("Schema check failed: ", err)
+		return generateResp(string(frgql.MakeGqlErrorResp(err)), http.StatusInternalServerError), nil
 	}
 
 	if bearerToken, prs := event.Headers["Authorization"]; prs {
@@ -65,12 +98,19 @@ func HandleLambdaEvent(ctx context.Context, event LambdaRequest) (LambdaResponse
 	log.Println("Rxed GraphQL Query: ", event)
 
 	body := LambdaRequestBody{}
-	json.Unmarshal([]byte(event.Body), &body)
+	if err := json.Unmarshal([]byte(event.Body), &body); err != nil {
+		log.Println("Failed to decode request body: ", err)
+		badReqErr := &frgql.BadRequestError{Message: "request body is not valid json: " + err.Error()}
+		return generateResp(string(frgql.MakeGqlErrorResp(badReqErr)), http.StatusBadRequest), nil
+	}
 
-	respBody, err := frgql.MakeGqlQuery(ctx, body.Query)
+	respBody, err := frgql.MakeGqlQueryWithVars(ctx, body.Query, body.Variables, body.OperationName)
 	if err != nil {
 		log.Println("GraphQL Query Failed: ", err)
-		return generateResp("", http.StatusBadRequest), err
+		if len(respBody) == 0 {
+			respBody = frgql.MakeGqlErrorResp(err)
+		}
+		return generateResp(string(respBody), frgql.GetHttpStatusForError(err)), nil
 	}
 
 	return generateOkResp(string(respBody)), nil
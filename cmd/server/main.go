@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/cch71/T27FundraisingLambda/frgql"
+)
+
+// //////////////////////////////////////////////////////////////////////////
+type GqlRequestBody struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+	OperationName string                 `json:"operationName,omitempty"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Same CORS headers the Lambda hands back plus what browsers need for the
+// preflight request since there is no API Gateway in front of us.
+func setCorsHeaders(w http.ResponseWriter) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func writeResp(w http.ResponseWriter, body string, statusCode int) {
+	setCorsHeaders(w)
+	if len(body) != 0 {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(statusCode)
+	io.WriteString(w, body)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func handleGql(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	switch r.Method {
+	case http.MethodOptions:
+		writeResp(w, "", http.StatusNoContent)
+		return
+	case http.MethodGet, http.MethodPost:
+	default:
+		writeResp(w, "", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if bearerToken, prs := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); prs {
+		ctx = context.WithValue(ctx, "T27FrAuthorization", bearerToken)
+	}
+
+	body := GqlRequestBody{}
+	if r.Method == http.MethodGet {
+		body.Query = r.URL.Query().Get("query")
+		body.OperationName = r.URL.Query().Get("operationName")
+		if vars := r.URL.Query().Get("variables"); len(vars) != 0 {
+			if err := json.Unmarshal([]byte(vars), &body.Variables); err != nil {
+				log.Println("Failed to decode variables: ", err)
+				badReqErr := &frgql.BadRequestError{Message: "variables are not valid json: " + err.Error()}
+				writeResp(w, string(frgql.MakeGqlErrorResp(badReqErr)), http.StatusBadRequest)
+				return
+			}
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		log.Println("Failed to decode request body: ", err)
+		badReqErr := &frgql.BadRequestError{Message: "request body is not valid json: " + err.Error()}
+		writeResp(w, string(frgql.MakeGqlErrorResp(badReqErr)), http.StatusBadRequest)
+		return
+	}
+
+	// GET must not change anything (links, prefetches) so mutations have to
+	// be sent with POST
+	if r.Method == http.MethodGet && frgql.GetGqlOperationType(body.Query, body.OperationName) == "mutation" {
+		log.Println("Rejecting mutation sent with GET")
+		badReqErr := &frgql.BadRequestError{Message: "mutations must be sent with POST"}
+		w.Header().Set("Allow", "POST")
+		writeResp(w, string(frgql.MakeGqlErrorResp(badReqErr)), http.StatusMethodNotAllowed)
+		return
+	}
+
+	log.Println("Rxed GraphQL Query: ", body.Query)
+
+	respBody, err := frgql.MakeGqlQueryWithVars(ctx, body.Query, body.Variables, body.OperationName)
+	if err != nil {
+		log.Println("GraphQL Query Failed: ", err)
+		if len(respBody) == 0 {
+			respBody = frgql.MakeGqlErrorResp(err)
+		}
+		writeResp(w, string(respBody), frgql.GetHttpStatusForError(err))
+		return
+	}
+
+	writeResp(w, string(respBody), http.StatusOK)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// GraphiQL page that talks back to this server
+const graphiqlPage = `<!DOCTYPE html>
+<html>
+  <head>
+    <title>T27 Fundraiser GraphiQL</title>
+    <link rel="stylesheet" href="https://unpkg.com/graphiql@3/graphiql.min.css" />
+  </head>
+  <body style="margin: 0;">
+    <div id="graphiql" style="height: 100vh;"></div>
+    <script crossorigin src="https://unpkg.com/react@18/umd/react.production.min.js"></script>
+    <script crossorigin src="https://unpkg.com/react-dom@18/umd/react-dom.production.min.js"></script>
+    <script crossorigin src="https://unpkg.com/graphiql@3/graphiql.min.js"></script>
+    <script>
+      const fetcher = GraphiQL.createFetcher({ url: '/graphql' });
+      ReactDOM.createRoot(document.getElementById('graphiql')).render(
+        React.createElement(GraphiQL, { fetcher: fetcher, defaultEditorToolsVisibility: true }),
+      );
+    </script>
+  </body>
+</html>
+`
+
+// //////////////////////////////////////////////////////////////////////////
+func handleGraphiql(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" && r.URL.Path != "/graphiql" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	io.WriteString(w, graphiqlPage)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// usage:
+//
+//	go run . [--addr :8080] [--graphiql] [--mem-store]
+func main() {
+	defaultAddr := ":8080"
+	if port := os.Getenv("PORT"); len(port) != 0 {
+		defaultAddr = ":" + port
+	}
+	addr := flag.String("addr", defaultAddr, "Address to listen on")
+	doServeGraphiql := flag.Bool("graphiql", false, "Serve the GraphiQL page at /")
+	doUseMemStore := flag.Bool("mem-store", false, "Use an in-memory store instead of the database")
+	flag.Parse()
+
+	if *doUseMemStore {
+		log.Println("Using in-memory store. Nothing will be persisted")
+		frgql.SetStore(frgql.NewMemStore())
+	}
+
+	if err := frgql.OpenDb(); err != nil {
+		log.Fatal("Failed to initialize db:", err)
+	}
+	defer frgql.CloseDb()
+
+	if err := frgql.VerifySchemaVersion(context.Background()); err != nil {
+		log.Fatal("Schema check failed: ", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", handleGql)
+	if *doServeGraphiql {
+		mux.HandleFunc("/", handleGraphiql)
+	}
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		log.Println("Listening on: ", *addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Server failed: ", err)
+		}
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+
+	log.Println("Shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Println("Shutdown failed: ", err)
+	}
+	// Addresses of orders saved just before the shutdown
+	if err := frgql.WaitForOrderGeocodes(ctx); err != nil {
+		log.Println("Stopped waiting for order geocodes: ", err)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// //////////////////////////////////////////////////////////////////////////
+// Requests that are answered before any GraphQL is run
+func TestHandleGqlRejects(t *testing.T) {
+	mutation := "mutation { deleteMulchOrder(orderId: \"1\") }"
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		statusCode int
+	}{
+		{"preflight", http.MethodOptions, "/graphql", "", http.StatusNoContent},
+		{"unsupported method", http.MethodPut, "/graphql", "", http.StatusMethodNotAllowed},
+		{"mutation sent with GET", http.MethodGet, "/graphql?query=" + url.QueryEscape(mutation), "", http.StatusMethodNotAllowed},
+		{"named mutation sent with GET", http.MethodGet, "/graphql?operationName=Del&query=" +
+			url.QueryEscape("query Q { __typename } mutation Del { deleteMulchOrder(orderId: \"1\") }"), "",
+			http.StatusMethodNotAllowed},
+		{"variables that aren't json", http.MethodGet, "/graphql?query=%7B__typename%7D&variables=%7B", "", http.StatusBadRequest},
+		{"body that isn't json", http.MethodPost, "/graphql", "{query", http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handleGql(w, httptest.NewRequest(test.method, test.target, strings.NewReader(test.body)))
+			if w.Code != test.statusCode {
+				t.Errorf("status: %d expected: %d body: %s", w.Code, test.statusCode, w.Body.String())
+			}
+			if w.Header().Get("Access-Control-Allow-Origin") != "*" {
+				t.Errorf("no CORS headers: %v", w.Header())
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/cch71/T27FundraisingLambda/frgql"
+)
+
+const geocodeOrderAddrsGql = `mutation GeocodeOrderAddrs($limit: Int) {
+  geocodeOrderAddrs(limit: $limit)
+}`
+
+// //////////////////////////////////////////////////////////////////////////
+// Geocodes the addresses of the orders that were saved without a known
+// address.  A limit of 0 geocodes all of them.
+func GeocodeOrderAddrs(ctx context.Context, limit int) {
+	if err := frgql.OpenDb(); err != nil {
+		log.Panic("Failed to initialize db:", err)
+	}
+	defer frgql.CloseDb()
+
+	_, token := LoginKcAdmin(ctx)
+	ctx = context.WithValue(ctx, "T27FrAuthorization", token)
+
+	variables := map[string]interface{}{"limit": limit}
+	rJSON, err := frgql.MakeGqlQueryWithVars(ctx, geocodeOrderAddrsGql, variables, "GeocodeOrderAddrs")
+	if err != nil {
+		log.Panic("Geocoding order addresses failed with code: ", frgql.GetErrorCode(err), " Err: ", err)
+	}
+	resp := struct {
+		Data struct {
+			GeocodeOrderAddrs int `json:"geocodeOrderAddrs"`
+		} `json:"data"`
+	}{}
+	if err := json.Unmarshal(rJSON, &resp); err != nil {
+		log.Panic("Parsing geocode response failed: ", err)
+	}
+	log.Printf("Linked: %d orders to their addresses", resp.Data.GeocodeOrderAddrs)
+}
@@ -9,11 +9,11 @@ require (
 	github.com/cch71/T27FundraisingLambda/frgql v0.0.0
 	github.com/google/uuid v1.6.0
 	github.com/joho/godotenv v1.5.1
+	github.com/jung-kurt/gofpdf v1.16.2
 	github.com/sethvargo/go-password v0.3.1
 )
 
 require (
-	github.com/codingsince1985/geo-golang v1.8.5 // indirect
 	github.com/deckarep/golang-set/v2 v2.8.0 // indirect
 	github.com/doug-martin/goqu/v9 v9.19.0 // indirect
 	github.com/go-resty/resty/v2 v2.16.5 // indirect
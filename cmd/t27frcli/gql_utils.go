@@ -10,7 +10,7 @@ import (
 )
 
 // //////////////////////////////////////////////////////////////////////////
-func MakeGqlReq(ctx context.Context, gqlFn *string) {
+func MakeGqlReq(ctx context.Context, gqlFn *string, varsFn *string, operationName *string) {
 
 	// Open File
 	query, err := os.ReadFile(*gqlFn)
@@ -18,6 +18,17 @@ func MakeGqlReq(ctx context.Context, gqlFn *string) {
 		log.Panic("Failed opening file: ", *gqlFn, " Err: ", err)
 	}
 
+	var variables map[string]interface{}
+	if len(*varsFn) != 0 {
+		varsJson, err := os.ReadFile(*varsFn)
+		if err != nil {
+			log.Panic("Failed opening file: ", *varsFn, " Err: ", err)
+		}
+		if err := json.Unmarshal(varsJson, &variables); err != nil {
+			log.Panic("Parsing variables file: ", *varsFn, " failed: ", err)
+		}
+	}
+
 	// Initialize Database Connection and Keycloak token
 	if err := frgql.OpenDb(); err != nil {
 		log.Panic("Failed to initialize db:", err)
@@ -27,10 +38,10 @@ func MakeGqlReq(ctx context.Context, gqlFn *string) {
 	_, token := LoginKcAdmin(ctx)
 	ctx = context.WithValue(ctx, "T27FrAuthorization", token)
 
-	// Make GQL Query
-	rJSON, err := frgql.MakeGqlQuery(ctx, string(query))
-	if err != nil {
-		log.Panic("GraphQL Query Failed: ", err)
+	// Make GQL Query.  The response still has the errors in it when it fails
+	rJSON, queryErr := frgql.MakeGqlQueryWithVars(ctx, string(query), variables, *operationName)
+	if queryErr != nil && len(rJSON) == 0 {
+		log.Panic("GraphQL Query Failed: ", queryErr)
 	}
 
 	var unmarshalledJson interface{}
@@ -44,4 +55,8 @@ func MakeGqlReq(ctx context.Context, gqlFn *string) {
 	}
 
 	log.Printf("JSON Resp:\n%s", rJSON)
+
+	if queryErr != nil {
+		log.Panic("GraphQL Query Failed with code: ", frgql.GetErrorCode(queryErr), " Err: ", queryErr)
+	}
 }
@@ -17,17 +17,40 @@ var (
 // //////////////////////////////////////////////////////////////////////////
 // usage:
 //
-//	go run main.go gql --in <gql filename>
+//	go run main.go gql --in <gql filename> [--vars <json variables filename>] [--op <operation name>]
+//	go run main.go migrate up|down|status [--to <version>]
+//	go run main.go importseason --season <year> --in <json filename>
+//	go run main.go manifest --delivery <delivery id> [--format csv|pdf] [--out <filename>]
+//	go run main.go geocode [--limit <max orders>]
 func main() {
 	ctx := context.Background()
 
 	gqlCmd := flag.NewFlagSet("gql", flag.ExitOnError)
 	gqlCmdFilenameInPtr := gqlCmd.String("in", "", "GraphGQ File")
+	gqlCmdVarsFilenamePtr := gqlCmd.String("vars", "", "JSON file with the GraphQL variables")
+	gqlCmdOperationNamePtr := gqlCmd.String("op", "", "GraphQL operation name to run")
 
 	syncKcUsersCmd := flag.NewFlagSet("syncusers", flag.ExitOnError)
 	syncKcUsersBackupDbDir := syncKcUsersCmd.String("dbdir", "", "Local DB dir for backup data")
+
+	migrateCmd := flag.NewFlagSet("migrate", flag.ExitOnError)
+	migrateCmdToVersion := migrateCmd.Int("to", -1,
+		"Version to migrate to. Default for up is the latest and for down is one before the current")
+
+	importSeasonCmd := flag.NewFlagSet("importseason", flag.ExitOnError)
+	importSeasonCmdSeason := importSeasonCmd.Int("season", 0, "Year of the season being imported")
+	importSeasonCmdFilenameInPtr := importSeasonCmd.String("in", "", "JSON file with the season's records")
+
+	manifestCmd := flag.NewFlagSet("manifest", flag.ExitOnError)
+	manifestCmdDeliveryId := manifestCmd.Int("delivery", 0, "Id of the delivery")
+	manifestCmdFormat := manifestCmd.String("format", "csv", "csv or pdf")
+	manifestCmdFilenameOutPtr := manifestCmd.String("out", "",
+		"File to write. Default for csv is stdout and for pdf is manifest-delivery-<id>.pdf")
+
+	geocodeCmd := flag.NewFlagSet("geocode", flag.ExitOnError)
+	geocodeCmdLimit := geocodeCmd.Int("limit", 0, "Most orders to geocode. Default is all of them")
 	if len(os.Args) < 2 {
-		fmt.Println("expected 'gql' or 'synckcusers' subcommands")
+		fmt.Println("expected 'gql', 'migrate', 'importseason', 'manifest', 'geocode' or 'synckcusers' subcommands")
 		os.Exit(1)
 	}
 
@@ -46,7 +69,32 @@ func main() {
 		if 0 >= len(*gqlCmdFilenameInPtr) {
 			log.Panic("in param required for gql request")
 		}
-		MakeGqlReq(ctx, gqlCmdFilenameInPtr)
+		MakeGqlReq(ctx, gqlCmdFilenameInPtr, gqlCmdVarsFilenamePtr, gqlCmdOperationNamePtr)
+	case "migrate":
+		if len(os.Args) < 3 {
+			log.Panic("migrate expects up, down or status")
+		}
+		migrateCmd.Parse(os.Args[3:])
+		toVersion := *migrateCmdToVersion
+		if os.Args[2] == "up" && toVersion < 0 {
+			toVersion = 0
+		}
+		RunMigrations(ctx, os.Args[2], toVersion)
+	case "importseason":
+		importSeasonCmd.Parse(os.Args[2:])
+		if *importSeasonCmdSeason == 0 || len(*importSeasonCmdFilenameInPtr) == 0 {
+			log.Panic("season and in params required for importseason")
+		}
+		ImportSeason(ctx, *importSeasonCmdSeason, importSeasonCmdFilenameInPtr)
+	case "manifest":
+		manifestCmd.Parse(os.Args[2:])
+		if *manifestCmdDeliveryId == 0 {
+			log.Panic("delivery param required for manifest")
+		}
+		PrintDeliveryManifest(ctx, *manifestCmdDeliveryId, *manifestCmdFormat, *manifestCmdFilenameOutPtr)
+	case "geocode":
+		geocodeCmd.Parse(os.Args[2:])
+		GeocodeOrderAddrs(ctx, *geocodeCmdLimit)
 	case "gentoken":
 		_, token := LoginKcAdmin(ctx)
 		log.Printf("Bearer %s", token)
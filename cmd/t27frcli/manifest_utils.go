@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/cch71/T27FundraisingLambda/frgql"
+	"github.com/jung-kurt/gofpdf"
+)
+
+const deliveryManifestGql = `query DeliveryManifest($deliveryId: Int!) {
+  deliveryManifest(deliveryId: $deliveryId) {
+    deliveryId deliveryDate numOrders numBags numBagsToSpread
+    distributionPoints {
+      distributionPoint numBags numBagsToSpread
+      neighborhoods {
+        neighborhood numBags numBagsToSpread
+        orders {
+          orderId customerName addr1 addr2 city phone specialInstructions numBags numBagsToSpread
+          purchases { productId numSold }
+        }
+      }
+    }
+  }
+}`
+
+// //////////////////////////////////////////////////////////////////////////
+// Writes the manifest of a delivery as csv or pdf to outFn.  The csv goes
+// to stdout when there is no outFn.
+func PrintDeliveryManifest(ctx context.Context, deliveryId int, format string, outFn string) {
+	if format != "csv" && format != "pdf" {
+		log.Panic("manifest format must be csv or pdf not: ", format)
+	}
+	if format == "pdf" && len(outFn) == 0 {
+		outFn = fmt.Sprintf("manifest-delivery-%d.pdf", deliveryId)
+	}
+
+	if err := frgql.OpenDb(); err != nil {
+		log.Panic("Failed to initialize db:", err)
+	}
+	defer frgql.CloseDb()
+
+	_, token := LoginKcAdmin(ctx)
+	ctx = context.WithValue(ctx, "T27FrAuthorization", token)
+
+	variables := map[string]interface{}{"deliveryId": deliveryId}
+	rJSON, err := frgql.MakeGqlQueryWithVars(ctx, deliveryManifestGql, variables, "DeliveryManifest")
+	if err != nil {
+		log.Panic("Delivery manifest query failed with code: ", frgql.GetErrorCode(err), " Err: ", err)
+	}
+	resp := struct {
+		Data struct {
+			DeliveryManifest frgql.DeliveryManifestType `json:"deliveryManifest"`
+		} `json:"data"`
+	}{}
+	if err := json.Unmarshal(rJSON, &resp); err != nil {
+		log.Panic("Parsing delivery manifest failed: ", err)
+	}
+	manifest := resp.Data.DeliveryManifest
+
+	if format == "pdf" {
+		if err := writeManifestPdf(manifest, outFn); err != nil {
+			log.Panic("Writing manifest pdf: ", outFn, " failed: ", err)
+		}
+	} else {
+		out := os.Stdout
+		if len(outFn) != 0 {
+			if out, err = os.Create(outFn); err != nil {
+				log.Panic("Failed creating file: ", outFn, " Err: ", err)
+			}
+			defer out.Close()
+		}
+		if err := writeManifestCsv(manifest, out); err != nil {
+			log.Panic("Writing manifest csv failed: ", err)
+		}
+	}
+	if len(outFn) != 0 {
+		log.Printf("Wrote delivery: %d manifest with %d orders to: %s", deliveryId, manifest.NumOrders, outFn)
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func manifestDistPtName(distPt frgql.ManifestDistributionPointType) string {
+	if len(distPt.DistributionPoint) == 0 {
+		return "No distribution point"
+	}
+	return distPt.DistributionPoint
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Line items like "bags: 10, spreading: 5"
+func manifestLineItems(order frgql.ManifestOrderType) string {
+	items := []string{}
+	for _, purchase := range order.Purchases {
+		items = append(items, fmt.Sprintf("%s: %d", purchase.ProductId, purchase.NumSold))
+	}
+	return strings.Join(items, ", ")
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func manifestOrderAddr(order frgql.ManifestOrderType) string {
+	addr := order.Addr1
+	if order.Addr2 != nil && len(*order.Addr2) != 0 {
+		addr += " " + *order.Addr2
+	}
+	if order.City != nil && len(*order.City) != 0 {
+		addr += ", " + *order.City
+	}
+	return addr
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// One row per order so it can be sorted and filtered in a spreadsheet
+func writeManifestCsv(manifest frgql.DeliveryManifestType, out io.Writer) error {
+	w := csv.NewWriter(out)
+	err := w.Write([]string{"delivery_id", "distribution_point", "neighborhood", "order_id", "customer_name",
+		"address", "phone", "bags", "bags_to_spread", "line_items", "special_instructions"})
+	if err != nil {
+		return err
+	}
+	for _, distPt := range manifest.DistributionPoints {
+		for _, hood := range distPt.Neighborhoods {
+			for _, order := range hood.Orders {
+				instructions := ""
+				if order.SpecialInstructions != nil {
+					instructions = *order.SpecialInstructions
+				}
+				err := w.Write([]string{
+					fmt.Sprint(manifest.DeliveryId), distPt.DistributionPoint, hood.Neighborhood, order.OrderId,
+					order.CustomerName, manifestOrderAddr(order), order.Phone, fmt.Sprint(order.NumBags),
+					fmt.Sprint(order.NumBagsToSpread), manifestLineItems(order), instructions,
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// A page (or more) per distribution point with its totals and then each
+// neighborhood's orders
+func writeManifestPdf(manifest frgql.DeliveryManifestType, outFn string) error {
+	const lineHt = 5.0
+	headers := []string{"Customer", "Address", "Phone", "Bags", "Spread", "Special Instructions"}
+	widths := []float64{45, 70, 30, 15, 15, 84}
+
+	deliveryTitle := fmt.Sprintf("Delivery %d", manifest.DeliveryId)
+	if manifest.DeliveryDate != nil && len(*manifest.DeliveryDate) != 0 {
+		deliveryTitle += " (" + *manifest.DeliveryDate + ")"
+	}
+
+	pdf := gofpdf.New("L", "mm", "Letter", "")
+	pdf.SetMargins(10, 10, 10)
+	pdf.SetAutoPageBreak(true, 10)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-10)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.CellFormat(0, lineHt, fmt.Sprintf("%s - page %d", deliveryTitle, pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+
+	tableHeader := func() {
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.SetFillColor(220, 220, 220)
+		for idx, header := range headers {
+			pdf.CellFormat(widths[idx], lineHt+1, header, "1", 0, "L", true, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetFont("Helvetica", "", 9)
+	}
+
+	// The cells of a row wrap so the row is as tall as its tallest cell
+	tableRow := func(cells []string) {
+		numLines := 1
+		for idx, cell := range cells {
+			numLines = max(numLines, len(pdf.SplitLines([]byte(tr(cell)), widths[idx]-2)))
+		}
+		rowHt := float64(numLines) * lineHt
+		_, pageHt := pdf.GetPageSize()
+		_, _, _, bottomMargin := pdf.GetMargins()
+		if pdf.GetY()+rowHt > pageHt-bottomMargin {
+			pdf.AddPage()
+			tableHeader()
+		}
+		x, y := pdf.GetXY()
+		for idx, cell := range cells {
+			pdf.Rect(x, y, widths[idx], rowHt, "D")
+			pdf.SetXY(x, y)
+			pdf.MultiCell(widths[idx], lineHt, tr(cell), "", "L", false)
+			x += widths[idx]
+		}
+		pdf.SetXY(10, y+rowHt)
+	}
+
+	for _, distPt := range manifest.DistributionPoints {
+		pdf.AddPage()
+		pdf.SetFont("Helvetica", "B", 14)
+		pdf.CellFormat(0, 8, tr(fmt.Sprintf("%s - %s", deliveryTitle, manifestDistPtName(distPt))), "", 1, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 11)
+		pdf.CellFormat(0, 6, fmt.Sprintf("Bags: %d   To spread: %d", distPt.NumBags, distPt.NumBagsToSpread),
+			"", 1, "L", false, 0, "")
+
+		for _, hood := range distPt.Neighborhoods {
+			pdf.Ln(3)
+			pdf.SetFont("Helvetica", "B", 11)
+			pdf.CellFormat(0, 7, tr(fmt.Sprintf("%s  (%d orders, %d bags, %d to spread)",
+				hood.Neighborhood, len(hood.Orders), hood.NumBags, hood.NumBagsToSpread)), "", 1, "L", false, 0, "")
+			tableHeader()
+			for _, order := range hood.Orders {
+				instructions := ""
+				if order.SpecialInstructions != nil {
+					instructions = *order.SpecialInstructions
+				}
+				tableRow([]string{order.CustomerName, manifestOrderAddr(order), order.Phone,
+					fmt.Sprint(order.NumBags), fmt.Sprint(order.NumBagsToSpread), instructions})
+			}
+		}
+	}
+	if len(manifest.DistributionPoints) == 0 {
+		pdf.AddPage()
+		pdf.SetFont("Helvetica", "B", 14)
+		pdf.CellFormat(0, 8, tr(deliveryTitle+" has no orders to deliver"), "", 1, "L", false, 0, "")
+	}
+	return pdf.OutputFileAndClose(outFn)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/cch71/T27FundraisingLambda/frgql"
+)
+
+// //////////////////////////////////////////////////////////////////////////
+func printMigrationStatus(ctx context.Context) {
+	statuses, err := frgql.GetMigrationStatus(ctx)
+	if err != nil {
+		log.Panic("Getting migration status failed: ", err)
+	}
+
+	for _, status := range statuses {
+		appliedTime := "pending"
+		if status.IsApplied {
+			appliedTime = "applied " + status.AppliedTime
+		}
+		fmt.Printf("%04d  %-30s %s\n", status.Version, status.Name, appliedTime)
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// direction is up, down or status.  For up toVersion of 0 applies all of
+// them.  For down toVersion of -1 reverts just the newest applied one.
+func RunMigrations(ctx context.Context, direction string, toVersion int) {
+	if err := frgql.OpenDb(); err != nil {
+		log.Panic("Failed to initialize db:", err)
+	}
+	defer frgql.CloseDb()
+
+	switch direction {
+	case "up":
+		if err := frgql.MigrateUp(ctx, toVersion); err != nil {
+			log.Panic("Migrating up failed: ", err)
+		}
+	case "down":
+		if toVersion < 0 {
+			currentVersion, err := frgql.GetSchemaVersion(ctx)
+			if err != nil {
+				log.Panic("Getting schema version failed: ", err)
+			}
+			toVersion = max(currentVersion-1, 0)
+		}
+		if err := frgql.MigrateDown(ctx, toVersion); err != nil {
+			log.Panic("Migrating down failed: ", err)
+		}
+	case "status":
+	default:
+		log.Panic("migrate expects up, down or status not: ", direction)
+	}
+
+	printMigrationStatus(ctx)
+}
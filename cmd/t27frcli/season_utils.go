@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+
+	"github.com/cch71/T27FundraisingLambda/frgql"
+)
+
+const importSeasonGql = `mutation ImportSeason($season: Int!, $config: ConfigInputType,
+	$mulchOrders: [MulchOrderInputType], $mulchTimecards: [MulchTimecardInputType],
+	$allocations: [AllocationsInputType], $users: [UserInfoInputType]) {
+  importSeason(season: $season, config: $config, mulchOrders: $mulchOrders,
+    mulchTimecards: $mulchTimecards, allocations: $allocations, users: $users)
+}`
+
+// //////////////////////////////////////////////////////////////////////////
+// The import file is a JSON object with any of config, mulchOrders,
+// mulchTimecards, allocations and users in the same form as the GraphQL
+// input types (i.e. what the queries of the prior season returned).
+func ImportSeason(ctx context.Context, season int, importFn *string) {
+	importJson, err := os.ReadFile(*importFn)
+	if err != nil {
+		log.Panic("Failed opening file: ", *importFn, " Err: ", err)
+	}
+
+	variables := map[string]interface{}{}
+	if err := json.Unmarshal(importJson, &variables); err != nil {
+		log.Panic("Parsing import file: ", *importFn, " failed: ", err)
+	}
+	for name := range variables {
+		switch name {
+		case "config", "mulchOrders", "mulchTimecards", "allocations", "users":
+		default:
+			log.Panic("Import file has unknown section: ", name)
+		}
+	}
+	variables["season"] = season
+
+	if err := frgql.OpenDb(); err != nil {
+		log.Panic("Failed to initialize db:", err)
+	}
+	defer frgql.CloseDb()
+
+	_, token := LoginKcAdmin(ctx)
+	ctx = context.WithValue(ctx, "T27FrAuthorization", token)
+
+	rJSON, err := frgql.MakeGqlQueryWithVars(ctx, importSeasonGql, variables, "ImportSeason")
+	if err != nil {
+		log.Panic("Importing season failed with code: ", frgql.GetErrorCode(err), " Err: ", err, "\n", string(rJSON))
+	}
+	log.Printf("Imported season: %d", season)
+}
@@ -353,26 +353,25 @@ func saveFullUsersTo1Password(userMap map[string]UserInfo) {
 }
 
 var usersGql = `
-mutation {
-  addOrUpdateUsers(users: [{
-***USERS***
-  }])
+mutation AddOrUpdateUsers($users: [UserInfoInputType]) {
+  addOrUpdateUsers(users: $users)
 }`
 
 // //////////////////////////////////////////////////////////////////////////
 func updateFrDbUsersWithAuthCreds(ctx context.Context, users *[]UserInfo) {
-	gqlUserEntries := []string{}
+	gqlUserEntries := []interface{}{}
 
 	for _, user := range *users {
-		entry := fmt.Sprintf("        id: \"%s\"\n", user.Id)
-		entry = entry + "        hasAuthCreds: true"
-		gqlUserEntries = append(gqlUserEntries, entry)
+		gqlUserEntries = append(gqlUserEntries, map[string]interface{}{
+			"id":           user.Id,
+			"hasAuthCreds": true,
+		})
 	}
-	gqlOut := strings.ReplaceAll(usersGql, "***USERS***", strings.Join(gqlUserEntries, "\n    },{\n"))
-	log.Println(gqlOut)
+	variables := map[string]interface{}{"users": gqlUserEntries}
+	log.Printf("%s\nVariables: %v", usersGql, variables)
 
 	// Make GQL Query
-	rJSON, err := frgql.MakeGqlQuery(ctx, gqlOut)
+	rJSON, err := frgql.MakeGqlQueryWithVars(ctx, usersGql, variables, "")
 	if err != nil {
 		log.Panic("GraphQL Query Failed: ", err)
 	}
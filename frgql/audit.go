@@ -0,0 +1,234 @@
+package frgql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"reflect"
+	"slices"
+	"time"
+)
+
+// //////////////////////////////////////////////////////////////////////////
+// A single change made by a mutation.  Diff is a JSON object keyed by the
+// dotted field path with the before and after values of every field that
+// changed, e.g. {"customer.name": {"before": "A", "after": "B"}}
+type AuditLogEntryType struct {
+	Id          string `json:"id"`
+	CreatedTime string `json:"createdTime"`
+	ActorId     string `json:"actorId"`
+	Operation   string `json:"operation"`
+	TargetKind  string `json:"targetKind"`
+	TargetKey   string `json:"targetKey"`
+	Diff        string `json:"diff"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+type GetAuditLogParams struct {
+	ActorId    string
+	Operation  string
+	TargetKind string
+	TargetKey  string
+	After      *time.Time
+	Before     *time.Time
+	// 0 means no limit
+	Limit int
+}
+
+// Fields read to capture the before/after values of each record kind
+var (
+	allFrConfigGqlFields = []string{
+		"kind", "description", "lastModifiedTime", "isLocked", "mulchDeliveryConfigs", "products", "finalizationData",
+		"allocationPolicy", "inventoryPolicy",
+	}
+	allNeighborhoodGqlFields  = []string{"name", "zipcode", "city", "isVisible", "distributionPoint"}
+	allUserGqlFields          = []string{"id", "firstName", "lastName", "group", "hasAuthCreds"}
+	allMulchTimecardGqlFields = []string{"id", "deliveryId", "lastModifiedTime", "timeIn", "timeOut", "timeTotal"}
+)
+
+// //////////////////////////////////////////////////////////////////////////
+type auditFieldDiff struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Flattens the JSON form of v into dotted field paths.  Lists are treated as
+// a single value.
+func flattenForAudit(v any) (map[string]any, error) {
+	flattened := make(map[string]any)
+	if v == nil {
+		return flattened, nil
+	}
+
+	jsonBytes, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var decoded any
+	if err := json.Unmarshal(jsonBytes, &decoded); err != nil {
+		return nil, err
+	}
+	if decoded == nil {
+		return flattened, nil
+	}
+
+	var flatten func(prefix string, val any)
+	flatten = func(prefix string, val any) {
+		obj, isObj := val.(map[string]any)
+		if !isObj {
+			flattened[prefix] = val
+			return
+		}
+		for k, fieldVal := range obj {
+			if len(prefix) != 0 {
+				k = prefix + "." + k
+			}
+			flatten(k, fieldVal)
+		}
+	}
+	flatten("", decoded)
+	return flattened, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the JSON diff of before and after.  nil for before means it is
+// new and nil for after means it was deleted.
+func makeAuditDiff(before any, after any) (string, error) {
+	beforeFields, err := flattenForAudit(before)
+	if err != nil {
+		return "", err
+	}
+	afterFields, err := flattenForAudit(after)
+	if err != nil {
+		return "", err
+	}
+
+	diff := make(map[string]auditFieldDiff)
+	for k, beforeVal := range beforeFields {
+		// A missing field is the same as a null one
+		if afterVal := afterFields[k]; !reflect.DeepEqual(beforeVal, afterVal) {
+			diff[k] = auditFieldDiff{Before: beforeVal, After: afterVal}
+		}
+	}
+	for k, afterVal := range afterFields {
+		if _, ok := beforeFields[k]; !ok && afterVal != nil {
+			diff[k] = auditFieldDiff{After: afterVal}
+		}
+	}
+
+	diffBytes, err := json.Marshal(diff)
+	if err != nil {
+		return "", err
+	}
+	return string(diffBytes), nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Writes an audit log entry for a mutation.  This needs to be given the tx
+// store the mutation is using so the entry is only kept if the change is.
+func recordAuditEntry(ctx context.Context, tx FrStore, operation string, targetKind string, targetKey string, before any, after any) error {
+	claims, err := parseTokenClaimsFromCtx(ctx)
+	if err != nil {
+		return err
+	}
+
+	diff, err := makeAuditDiff(before, after)
+	if err != nil {
+		log.Println("Failed to make audit diff: ", err)
+		return err
+	}
+
+	entry := AuditLogEntryType{
+		CreatedTime: time.Now().UTC().Format(time.RFC3339),
+		ActorId:     claims.userId(),
+		Operation:   operation,
+		TargetKind:  targetKind,
+		TargetKey:   targetKey,
+		Diff:        diff,
+	}
+	log.Printf("AUDIT: %s %s %s:%s %s", entry.ActorId, operation, targetKind, targetKey, diff)
+	return tx.InsertAuditLogEntry(ctx, entry)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Records an entry for each of keys (once even if it is given more than once)
+// from the records before and after a bulk add/update
+func recordBulkAuditEntries[T any](ctx context.Context, tx FrStore, operation string, targetKind string, keys []string,
+	before []T, after []T, keyFn func(T) string) error {
+
+	findRecord := func(records []T, key string) *T {
+		for idx := range records {
+			if keyFn(records[idx]) == key {
+				return &records[idx]
+			}
+		}
+		return nil
+	}
+
+	for idx, key := range keys {
+		if slices.Contains(keys[:idx], key) {
+			continue
+		}
+		err := recordAuditEntry(ctx, tx, operation, targetKind, key, findRecord(before, key), findRecord(after, key))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Records the order as it is now in tx (after it has been written) against
+// what it was before.  Re-reading it means the diff is in the stored form and
+// doesn't show changes like "20" vs "20.0000".
+func recordMulchOrderAuditEntry(ctx context.Context, tx FrStore, operation string, before *MulchOrderType, orderId string) error {
+	after, err := tx.GetMulchOrder(ctx, GetMulchOrderParams{OrderId: orderId, GqlFields: allMulchOrderGqlFields})
+	if err != nil {
+		return err
+	}
+	return recordAuditEntry(ctx, tx, operation, "mulchOrder", orderId, before, after)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Runs the config change in a transaction and records the config before and
+// after it
+func recordFundraiserConfigChange(ctx context.Context, operation string, fn func(tx FrStore) error) error {
+	return frStore.InTx(ctx, func(tx FrStore) error {
+		var before *FrConfigType
+		frConfig, err := tx.GetFundraiserConfig(ctx, allFrConfigGqlFields)
+		if err == nil {
+			before = &frConfig
+		} else if !errors.Is(err, ErrNotFound) {
+			return err
+		}
+
+		if err := fn(tx); err != nil {
+			return err
+		}
+
+		after, err := tx.GetFundraiserConfig(ctx, allFrConfigGqlFields)
+		if err != nil {
+			return err
+		}
+		return recordAuditEntry(ctx, tx, operation, "fundraiserConfig", after.Kind, before, after)
+	})
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the audit log newest first.  Admin only
+func GetAuditLog(ctx context.Context, params GetAuditLogParams) ([]AuditLogEntryType, error) {
+	log.Println("Retrieving audit log: ", params)
+
+	if err := VerifyAdminTokenFromCtx(ctx); err != nil {
+		return nil, err
+	}
+
+	entries, err := frStore.GetAuditLog(ctx, params)
+	if err != nil {
+		log.Println("Audit log query failed: ", err)
+		return nil, err
+	}
+	return entries, nil
+}
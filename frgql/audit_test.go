@@ -0,0 +1,136 @@
+package frgql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// //////////////////////////////////////////////////////////////////////////
+// MemStore that runs beforeTx right before its next transaction as if
+// someone else changed something in between
+type interleavingStore struct {
+	*MemStore
+	beforeTx func()
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *interleavingStore) InTx(ctx context.Context, fn func(tx FrStore) error) error {
+	if beforeTx := s.beforeTx; beforeTx != nil {
+		s.beforeTx = nil
+		beforeTx()
+	}
+	return s.MemStore.InTx(ctx, fn)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// MemStore whose transactions can't write audit entries
+type failingAuditStore struct {
+	*MemStore
+}
+
+// //////////////////////////////////////////////////////////////////////////
+type failingAuditTx struct {
+	FrStore
+}
+
+var errTestAuditFailed = errors.New("audit entry not written")
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *failingAuditStore) InTx(ctx context.Context, fn func(tx FrStore) error) error {
+	return s.MemStore.InTx(ctx, func(tx FrStore) error {
+		return fn(&failingAuditTx{FrStore: tx})
+	})
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (tx *failingAuditTx) InsertAuditLogEntry(ctx context.Context, entry AuditLogEntryType) error {
+	return errTestAuditFailed
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the audit diff of the newest entry for operation
+func getTestAuditDiff(t *testing.T, store FrStore, operation string) map[string]auditFieldDiff {
+	t.Helper()
+	entries, err := store.GetAuditLog(context.Background(), GetAuditLogParams{Operation: operation, Limit: 1})
+	mustNotFail(t, err)
+	if len(entries) != 1 {
+		t.Fatalf("no audit entry for: %s", operation)
+	}
+	diff := map[string]auditFieldDiff{}
+	mustNotFail(t, json.Unmarshal([]byte(entries[0].Diff), &diff))
+	return diff
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// An order changed after updateMulchOrder read it is audited and saved in
+// its history as it was when the update was made
+func TestUpdateMulchOrderAuditsOrderInTx(t *testing.T) {
+	store := &interleavingStore{MemStore: newTestMemStore(t)}
+	SetStore(store)
+	ctx := context.Background()
+
+	order := makeTestOrder(testOrderId1, "scout1")
+	comments := "ring the bell"
+	order.Comments = &comments
+	mustNotFail(t, store.InsertMulchOrder(ctx, order))
+	store.beforeTx = func() {
+		concurrent := "knock"
+		mustNotFail(t, store.UpdateMulchOrder(ctx, MulchOrderType{OrderId: testOrderId1, Comments: &concurrent,
+			LastModifiedTime: makeLastModifiedTime()}, []string{"comments"}, ""))
+	}
+
+	updated := "leave by the garage"
+	_, err := UpdateMulchOrder(newTestCtx(t, "scout1", false), MulchOrderType{OrderId: testOrderId1, Comments: &updated},
+		[]string{"comments"}, "", false)
+	mustNotFail(t, err)
+
+	diff := getTestAuditDiff(t, store, "updateMulchOrder")
+	if diff["comments"].Before != "knock" || diff["comments"].After != updated {
+		t.Errorf("audited comments: %+v", diff["comments"])
+	}
+	history, err := store.GetMulchOrderHistory(ctx, testOrderId1)
+	mustNotFail(t, err)
+	if len(history) != 1 || *history[0].Order.Comments != "knock" {
+		t.Errorf("history: %+v", history)
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// The audit entry is written in the transaction of the change so a change
+// that can't be audited isn't kept
+func TestMutationNotKeptWithoutAuditEntry(t *testing.T) {
+	store := &failingAuditStore{MemStore: newTestMemStore(t)}
+	SetStore(store)
+	ctx := context.Background()
+	ownerCtx := newTestCtx(t, "scout1", false)
+	mustNotFail(t, store.InsertMulchOrder(ctx, makeTestOrder(testOrderId1, "scout1")))
+
+	comments := "leave by the garage"
+	_, err := UpdateMulchOrder(ownerCtx, MulchOrderType{OrderId: testOrderId1, Comments: &comments},
+		[]string{"comments"}, "", false)
+	if !errors.Is(err, errTestAuditFailed) {
+		t.Errorf("update returned: %v", err)
+	}
+	if _, err := DeleteMulchOrder(ownerCtx, testOrderId1, false); !errors.Is(err, errTestAuditFailed) {
+		t.Errorf("delete returned: %v", err)
+	}
+	if _, err := CreateMulchOrder(ownerCtx, makeTestOrder(testOrderId2, "scout1"), false); !errors.Is(err, errTestAuditFailed) {
+		t.Errorf("create returned: %v", err)
+	}
+
+	order, err := store.GetMulchOrder(ctx, GetMulchOrderParams{OrderId: testOrderId1, GqlFields: allMulchOrderGqlFields})
+	mustNotFail(t, err)
+	if order.Comments != nil {
+		t.Errorf("comments of the unaudited update were kept: %s", *order.Comments)
+	}
+	history, err := store.GetMulchOrderHistory(ctx, testOrderId1)
+	mustNotFail(t, err)
+	if len(history) != 0 {
+		t.Errorf("history of the unaudited changes was kept: %+v", history)
+	}
+	if _, err := store.GetMulchOrder(ctx, GetMulchOrderParams{OrderId: testOrderId2, GqlFields: allMulchOrderGqlFields}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unaudited new order returned: %v not ErrNotFound", err)
+	}
+}
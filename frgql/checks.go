@@ -0,0 +1,228 @@
+package frgql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Deposit status of a check.  It comes from the bank deposit ledger
+const (
+	CHECK_DEPOSITED   = "DEPOSITED"
+	CHECK_UNDEPOSITED = "UNDEPOSITED"
+)
+
+// //////////////////////////////////////////////////////////////////////////
+// A check an order was paid with.  IsBounced and BouncedTime are only set
+// by markCheckBounced.  DepositStatus and DepositId aren't stored, they are
+// filled in from the deposit ledger when the order is read.
+type OrderCheckType struct {
+	CheckNumber   string  `json:"checkNumber"`
+	Amount        string  `json:"amount"`
+	Payer         *string `json:"payer"`
+	IsBounced     bool    `json:"isBounced"`
+	BouncedTime   *string `json:"bouncedTime"`
+	DepositStatus string  `json:"depositStatus,omitempty"`
+	DepositId     *string `json:"depositId,omitempty"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Checks that every check has a unique number and an amount and that the
+// checks that haven't bounced add up to amountFromChecksCollected.  Orders
+// without checks (older clients) aren't checked.
+func validateOrderChecks(order MulchOrderType) error {
+	if len(order.Checks) == 0 {
+		return nil
+	}
+
+	total := decimal.Zero
+	checkNumbers := make(map[string]bool)
+	for idx, check := range order.Checks {
+		field := fmt.Sprintf("checks.%d", idx)
+		if len(strings.TrimSpace(check.CheckNumber)) == 0 {
+			return newValidationError(field+".checkNumber", "checks[%d] needs a checkNumber", idx)
+		}
+		if checkNumbers[check.CheckNumber] {
+			return newValidationError(field+".checkNumber", "check: %s is on the order more than once", check.CheckNumber)
+		}
+		checkNumbers[check.CheckNumber] = true
+
+		amount, err := parseAmount(&check.Amount)
+		if err != nil || !amount.IsPositive() {
+			return newValidationError(field+".amount", "check: %s amount is not an amount: %s", check.CheckNumber, check.Amount)
+		}
+		if !check.IsBounced {
+			total = total.Add(amount)
+		}
+	}
+
+	checksCollected, err := parseAmount(order.AmountFromChecksCollected)
+	if err != nil {
+		return newValidationError("amountFromChecksCollected", "amountFromChecksCollected is not a valid amount")
+	}
+	if !total.Equal(checksCollected) {
+		return &ValidationError{
+			Message: fmt.Sprintf("checks add up to %s but amountFromChecksCollected is %s",
+				total.StringFixedBank(4), checksCollected.StringFixedBank(4)),
+			Fields: []string{"checks", "amountFromChecksCollected"},
+		}
+	}
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the check numbers joined the way checkNumbers has always been
+// for older clients
+func joinCheckNumbers(checks []OrderCheckType) string {
+	checkNumbers := []string{}
+	for _, check := range checks {
+		checkNumbers = append(checkNumbers, check.CheckNumber)
+	}
+	return strings.Join(checkNumbers, ",")
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Checks given by a client can't change whether a check bounced so it is
+// carried over from the existing checks with the same number
+func keepBouncedChecks(existing []OrderCheckType, updated []OrderCheckType) {
+	for idx := range updated {
+		updated[idx].IsBounced, updated[idx].BouncedTime = false, nil
+		for _, check := range existing {
+			if check.CheckNumber == updated[idx].CheckNumber {
+				updated[idx].IsBounced, updated[idx].BouncedTime = check.IsBounced, check.BouncedTime
+			}
+		}
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns a copy of checks without the deposit status which isn't stored
+func storedOrderChecks(checks []OrderCheckType) []OrderCheckType {
+	if checks == nil {
+		return nil
+	}
+	stored := slices.Clone(checks)
+	for idx := range stored {
+		stored[idx].DepositStatus, stored[idx].DepositId = "", nil
+	}
+	return stored
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Fills in the deposit status of the checks of orders from the ledger.  All
+// of an order's checks are deposited when the whole order was.
+func fillCheckDepositStatus(ctx context.Context, store FrStore, orders []MulchOrderType) error {
+	hasChecks := false
+	for _, order := range orders {
+		hasChecks = hasChecks || len(order.Checks) != 0
+	}
+	if !hasChecks {
+		return nil
+	}
+
+	deposits, err := store.GetBankDeposits(ctx)
+	if err != nil {
+		return err
+	}
+	depositIds := make(map[string]string)
+	for _, deposit := range deposits {
+		for _, item := range deposit.Items {
+			depositIds[item.OrderId+"/"+item.CheckNumber] = deposit.Id
+		}
+	}
+
+	for _, order := range orders {
+		for idx := range order.Checks {
+			check := &order.Checks[idx]
+			depositId, ok := depositIds[order.OrderId+"/"+check.CheckNumber]
+			if !ok {
+				depositId, ok = depositIds[order.OrderId+"/"]
+			}
+			check.DepositStatus, check.DepositId = CHECK_UNDEPOSITED, nil
+			if ok {
+				check.DepositStatus, check.DepositId = CHECK_DEPOSITED, &depositId
+			}
+		}
+	}
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Marks a check of an order as bounced.  Its amount comes off
+// amountFromChecksCollected and the order is set to willCollectMoneyLater
+// so it shows as money owed.  The order's owner is notified.  Admin only
+func MarkCheckBounced(ctx context.Context, orderId string, checkNumber string) (bool, error) {
+	log.Printf("Marking check: %s of order: %s bounced", checkNumber, orderId)
+
+	if err := VerifyAdminTokenFromCtx(ctx); err != nil {
+		return false, err
+	}
+
+	err := frStore.InTx(ctx, func(tx FrStore) error {
+		order, err := tx.GetMulchOrder(ctx, GetMulchOrderParams{OrderId: orderId, GqlFields: allMulchOrderGqlFields})
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return &NotFoundError{Message: fmt.Sprintf("order %s was not found", orderId)}
+			}
+			return err
+		}
+		existingOrder := order
+
+		checkIdx := -1
+		for idx, check := range order.Checks {
+			if check.CheckNumber == checkNumber {
+				checkIdx = idx
+			}
+		}
+		if checkIdx < 0 {
+			return newValidationError("checkNumber", "order: %s doesn't have check: %s", orderId, checkNumber)
+		}
+		if order.Checks[checkIdx].IsBounced {
+			return newValidationError("checkNumber", "check: %s of order: %s has already bounced", checkNumber, orderId)
+		}
+
+		amount, err := parseAmount(&order.Checks[checkIdx].Amount)
+		if err != nil {
+			return fmt.Errorf("check: %s amount: %w", checkNumber, err)
+		}
+		checksCollected, err := parseAmount(order.AmountFromChecksCollected)
+		if err != nil {
+			return fmt.Errorf("order: %s amountFromChecksCollected: %w", orderId, err)
+		}
+		bouncedTime := time.Now().UTC().Format(time.RFC3339)
+		checksCollectedStr := checksCollected.Sub(amount).StringFixedBank(4)
+		willCollectMoneyLater := true
+
+		order.Checks = append([]OrderCheckType{}, order.Checks...)
+		order.Checks[checkIdx].IsBounced = true
+		order.Checks[checkIdx].BouncedTime = &bouncedTime
+		order.AmountFromChecksCollected = &checksCollectedStr
+		order.WillCollectMoneyLater = &willCollectMoneyLater
+		order.LastModifiedTime = makeLastModifiedTime()
+
+		if err := snapshotMulchOrder(ctx, tx, orderId, "markCheckBounced"); err != nil {
+			return err
+		}
+		err = tx.UpdateMulchOrder(ctx, order, []string{"checks", "amountFromChecksCollected", "willCollectMoneyLater"}, "")
+		if err != nil {
+			return err
+		}
+		if err := recordMulchOrderAuditEntry(ctx, tx, "markCheckBounced", &existingOrder, orderId); err != nil {
+			return err
+		}
+		return notifyUser(ctx, tx, order.OwnerId, "checkBounced", &orderId,
+			fmt.Sprintf("Check %s for %s from %s bounced.  The order needs to be paid again.",
+				checkNumber, amount.StringFixed(2), order.Customer.Name))
+	})
+	if err != nil {
+		log.Println("Marking check bounced failed: ", err)
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,103 @@
+package frgql
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// //////////////////////////////////////////////////////////////////////////
+// Order paid for with checks 101 (10) and 102 (14)
+func makeTestCheckOrder(orderId string, ownerId string) MulchOrderType {
+	order := makeTestOrder(orderId, ownerId)
+	checksCollected := "24"
+	order.AmountFromCashCollected = nil
+	order.AmountFromChecksCollected = &checksCollected
+	order.Checks = []OrderCheckType{{CheckNumber: "101", Amount: "10"}, {CheckNumber: "102", Amount: "14"}}
+	return order
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func TestValidateOrderChecks(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(order *MulchOrderType)
+		fields []string
+	}{
+		{"checks add up", nil, nil},
+		{"no checks", func(order *MulchOrderType) { order.Checks = nil }, nil},
+		{"bounced check isn't counted", func(order *MulchOrderType) {
+			order.Checks = append(order.Checks, OrderCheckType{CheckNumber: "103", Amount: "5", IsBounced: true})
+		}, nil},
+		{"no check number", func(order *MulchOrderType) { order.Checks[1].CheckNumber = " " }, []string{"checks.1.checkNumber"}},
+		{"same check twice", func(order *MulchOrderType) { order.Checks[1].CheckNumber = "101" }, []string{"checks.1.checkNumber"}},
+		{"no amount", func(order *MulchOrderType) { order.Checks[0].Amount = "0" }, []string{"checks.0.amount"}},
+		{"don't add up", func(order *MulchOrderType) { order.Checks[0].Amount = "9" },
+			[]string{"checks", "amountFromChecksCollected"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			order := makeTestCheckOrder(testOrderId1, "scout1")
+			if test.modify != nil {
+				test.modify(&order)
+			}
+			err := validateOrderChecks(order)
+			if fields := getValidationFields(err); !slices.Equal(fields, test.fields) || (test.fields == nil && err != nil) {
+				t.Errorf("got error: %v with fields: %v not: %v", err, fields, test.fields)
+			}
+		})
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// A bounced check comes off the money collected so the order owes it again
+// and its owner is told
+func TestMarkCheckBounced(t *testing.T) {
+	store := newTestMemStore(t)
+	ctx := context.Background()
+	adminCtx := newTestCtx(t, "admin1", true)
+	mustNotFail(t, store.InsertMulchOrder(ctx, makeTestCheckOrder(testOrderId1, "scout1")))
+
+	balances, err := GetOutstandingBalances(adminCtx, "")
+	mustNotFail(t, err)
+	if len(balances.Orders) != 0 {
+		t.Fatalf("paid order owes: %+v", balances.Orders)
+	}
+
+	_, err = MarkCheckBounced(adminCtx, testOrderId1, "102")
+	mustNotFail(t, err)
+	order, err := store.GetMulchOrder(ctx, GetMulchOrderParams{OrderId: testOrderId1, GqlFields: allMulchOrderGqlFields})
+	mustNotFail(t, err)
+	if *order.AmountFromChecksCollected != "10.0000" || !*order.WillCollectMoneyLater ||
+		order.Checks[0].IsBounced || !order.Checks[1].IsBounced || order.Checks[1].BouncedTime == nil {
+		t.Errorf("order after the check bounced: %+v", order)
+	}
+
+	balances, err = GetOutstandingBalances(adminCtx, "")
+	mustNotFail(t, err)
+	if len(balances.Orders) != 1 || balances.Orders[0].AmountOwed != "14.0000" || !balances.Orders[0].WillCollectMoneyLater {
+		t.Errorf("balances after the check bounced: %+v", balances.Orders)
+	}
+	notifications, err := store.GetNotifications(ctx, "scout1", true)
+	mustNotFail(t, err)
+	if len(notifications) != 1 || notifications[0].Kind != "checkBounced" || *notifications[0].OrderId != testOrderId1 {
+		t.Errorf("notifications: %+v", notifications)
+	}
+	history, err := store.GetMulchOrderHistory(ctx, testOrderId1)
+	mustNotFail(t, err)
+	if len(history) != 1 || history[0].Operation != "markCheckBounced" || history[0].Order.Checks[1].IsBounced {
+		t.Errorf("history: %+v", history)
+	}
+
+	if _, err := MarkCheckBounced(adminCtx, testOrderId1, "102"); !slices.Equal(getValidationFields(err), []string{"checkNumber"}) {
+		t.Errorf("bouncing the check again returned: %v", err)
+	}
+	if _, err := MarkCheckBounced(adminCtx, testOrderId1, "999"); !slices.Equal(getValidationFields(err), []string{"checkNumber"}) {
+		t.Errorf("bouncing a check the order doesn't have returned: %v", err)
+	}
+	var forbiddenErr *ForbiddenError
+	if _, err := MarkCheckBounced(newTestCtx(t, "scout1", false), testOrderId1, "101"); !errors.As(err, &forbiddenErr) {
+		t.Errorf("a scout bouncing a check returned: %v not a ForbiddenError", err)
+	}
+}
@@ -0,0 +1,658 @@
+package frgql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// //////////////////////////////////////////////////////////////////////////
+// Allocation policy stored in the fundraiser config.  Amounts and
+// percentages are strings like the rest of the config.  The troop decides
+// the percentages so they have to be set before closing out.  The rest are
+// optional.
+type AllocationPolicyType struct {
+	// Percentage of the profit kept by the troop
+	TroopPercentage *string `json:"troopPercentage"`
+	// Percentage of the scouts' pool (after spreading credits) paid for
+	// delivery time.  The rest is paid per bag sold.
+	DeliveryPercentage *string `json:"deliveryPercentage"`
+	// Credited for every bag spread.  Defaults to the spreading unit price
+	SpreadingCreditPerBag *string `json:"spreadingCreditPerBag"`
+	// Scouts with fewer timecard minutes than this get no delivery credit
+	MinDeliveryMinutes *int `json:"minDeliveryMinutes"`
+	// Most a scout can be allocated.  What is over it goes to the troop
+	MaxAllocationPerScout *string `json:"maxAllocationPerScout"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// AllocationPolicyType parsed for calcCloseout
+type closeoutPolicy struct {
+	TroopPercentage    decimal.Decimal
+	DeliveryPercentage decimal.Decimal
+	// nil means the spreading unit price
+	SpreadingCreditPerBag *decimal.Decimal
+	MinDeliveryMinutes    int
+	// nil means no cap
+	MaxAllocationPerScout *decimal.Decimal
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns policy with the fields overrides sets replaced
+func mergeAllocationPolicy(policy *AllocationPolicyType, overrides *AllocationPolicyType) *AllocationPolicyType {
+	if overrides == nil {
+		return policy
+	}
+	merged := AllocationPolicyType{}
+	if policy != nil {
+		merged = *policy
+	}
+	if overrides.TroopPercentage != nil {
+		merged.TroopPercentage = overrides.TroopPercentage
+	}
+	if overrides.DeliveryPercentage != nil {
+		merged.DeliveryPercentage = overrides.DeliveryPercentage
+	}
+	if overrides.SpreadingCreditPerBag != nil {
+		merged.SpreadingCreditPerBag = overrides.SpreadingCreditPerBag
+	}
+	if overrides.MinDeliveryMinutes != nil {
+		merged.MinDeliveryMinutes = overrides.MinDeliveryMinutes
+	}
+	if overrides.MaxAllocationPerScout != nil {
+		merged.MaxAllocationPerScout = overrides.MaxAllocationPerScout
+	}
+	return &merged
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Parses and checks the fields policy sets.  The ones that aren't set are
+// left zero so this is only good for checking a config's policy.  Closeouts
+// use parseCloseoutPolicy.
+func parseAllocationPolicy(policy *AllocationPolicyType) (closeoutPolicy, error) {
+	parsed := closeoutPolicy{}
+	if policy == nil {
+		return parsed, nil
+	}
+
+	parsePercentage := func(field string, val *string, percentage *decimal.Decimal) error {
+		if val == nil {
+			return nil
+		}
+		amt, err := decimal.NewFromString(*val)
+		if err != nil || amt.IsNegative() || amt.GreaterThan(decimal.NewFromInt(100)) {
+			return newValidationError("allocationPolicy."+field, "%s must be a percentage from 0 to 100 not: %s", field, *val)
+		}
+		*percentage = amt
+		return nil
+	}
+	if err := parsePercentage("troopPercentage", policy.TroopPercentage, &parsed.TroopPercentage); err != nil {
+		return parsed, err
+	}
+	if err := parsePercentage("deliveryPercentage", policy.DeliveryPercentage, &parsed.DeliveryPercentage); err != nil {
+		return parsed, err
+	}
+
+	parseOptionalAmount := func(field string, val *string) (*decimal.Decimal, error) {
+		if val == nil || len(*val) == 0 {
+			return nil, nil
+		}
+		amt, err := parseAmount(val)
+		if err != nil || amt.IsNegative() {
+			return nil, newValidationError("allocationPolicy."+field, "%s must be an amount of 0 or more not: %s", field, *val)
+		}
+		return &amt, nil
+	}
+	var err error
+	if parsed.SpreadingCreditPerBag, err = parseOptionalAmount("spreadingCreditPerBag", policy.SpreadingCreditPerBag); err != nil {
+		return parsed, err
+	}
+	if parsed.MaxAllocationPerScout, err = parseOptionalAmount("maxAllocationPerScout", policy.MaxAllocationPerScout); err != nil {
+		return parsed, err
+	}
+
+	if policy.MinDeliveryMinutes != nil {
+		if *policy.MinDeliveryMinutes < 0 {
+			return parsed, newValidationError("allocationPolicy.minDeliveryMinutes",
+				"minDeliveryMinutes can't be negative: %d", *policy.MinDeliveryMinutes)
+		}
+		parsed.MinDeliveryMinutes = *policy.MinDeliveryMinutes
+	}
+	return parsed, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Parses policy for a closeout.  There is no default split of the money so
+// it is a ValidationError if troopPercentage or deliveryPercentage isn't set.
+func parseCloseoutPolicy(policy *AllocationPolicyType) (closeoutPolicy, error) {
+	if policy == nil || policy.TroopPercentage == nil {
+		return closeoutPolicy{}, newValidationError("allocationPolicy.troopPercentage",
+			"the allocationPolicy needs a troopPercentage to close out")
+	}
+	if policy.DeliveryPercentage == nil {
+		return closeoutPolicy{}, newValidationError("allocationPolicy.deliveryPercentage",
+			"the allocationPolicy needs a deliveryPercentage to close out")
+	}
+	return parseAllocationPolicy(policy)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the policy in the config (nil if there isn't one)
+func getAllocationPolicy(ctx context.Context, store FrStore) (*AllocationPolicyType, error) {
+	frConfig, err := store.GetFundraiserConfig(ctx, []string{"allocationPolicy"})
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	return frConfig.AllocationPolicy, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the config's policy parsed for a closeout
+func getCloseoutPolicy(ctx context.Context, store FrStore) (closeoutPolicy, error) {
+	configPolicy, err := getAllocationPolicy(ctx, store)
+	if err != nil {
+		return closeoutPolicy{}, err
+	}
+	return parseCloseoutPolicy(configPolicy)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Results of a closeout.  FinalizationData is what is saved to the config
+// and Allocations replace the allocation summary.
+type CloseoutType struct {
+	FinalizationData FinalizationDataType `json:"finalizationData"`
+	Allocations      []AllocationItemType `json:"allocations"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+type closeoutScoutTotals struct {
+	bagsSold     int
+	bagsSpread   decimal.Decimal
+	deliveryTime time.Duration
+	donations    decimal.Decimal
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the bags each of the spreaders of an order are credited with.
+// They are split evenly between all of the spreaders like
+// getAssistedSpreadingOrderCountByOwnerId does.  Unlike that summary the
+// seller isn't left out when they spread their own order.  It only counts
+// the orders someone helped another seller with while the spreading credit
+// pays for the bags spread whoever sold them.
+func calcBagsSpreadPerSpreader(order MulchOrderType) decimal.Decimal {
+	if len(order.Spreaders) == 0 {
+		return decimal.Zero
+	}
+	numBags := 0
+	for _, item := range order.Purchases {
+		if item.ProductId == "spreading" {
+			numBags += item.NumSold
+		}
+	}
+	return decimal.NewFromInt(int64(numBags)).Div(decimal.NewFromInt(int64(len(order.Spreaders))))
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the percentage of amount
+func calcPercentageOf(amount decimal.Decimal, percentage decimal.Decimal) decimal.Decimal {
+	return amount.Mul(percentage).Div(decimal.NewFromInt(100))
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Works out the closeout from what is in store:
+//
+//   - mulchSalesGross is what was charged for bags and perBagCost is
+//     mulchCost over the bags sold.  profitsFromBags is the difference.
+//   - The profit is bankDeposited less mulchCost.  The troop keeps
+//     TroopPercentage of it (moneyPoolForTroop) and the rest is for the scouts
+//     (moneyPoolForScoutsSubPools).
+//   - Spreaders are credited SpreadingCreditPerBag (or the spreading unit
+//     price) for every bag they spread.  What is left of the scouts' pool is
+//     split DeliveryPercentage to delivery time (moneyPoolForScoutsDelivery)
+//     and the rest to bags sold (moneyPoolForScoutsSales).
+//   - perBagAvgEarnings and deliveryEarningsPerMinute are those pools over
+//     the bags sold and the delivery minutes.  Only the minutes of scouts
+//     with at least MinDeliveryMinutes are counted.
+//
+// A scout's allocation is their bags sold, bags spread and delivery minutes
+// times those rates.  Every owner, spreader and timecard gets one.  An
+// allocation over MaxAllocationPerScout is cut down to it and the difference
+// is moved from moneyPoolForScoutsSubPools to moneyPoolForTroop.
+//
+// It is a ValidationError if the spreading credits are more than the scouts'
+// pool.
+func calcCloseout(ctx context.Context, store FrStore, bankDeposited string, mulchCost string, policy closeoutPolicy) (CloseoutType, error) {
+	if len(bankDeposited) == 0 || len(mulchCost) == 0 {
+		return CloseoutType{}, newValidationError("", "bankDeposited and mulchCost must be given when the config doesn't have them")
+	}
+	bankDepositedAmt, err := parseAmount(&bankDeposited)
+	if err != nil {
+		return CloseoutType{}, newValidationError("bankDeposited", "bankDeposited is not an amount: %s", bankDeposited)
+	}
+	mulchCostAmt, err := parseAmount(&mulchCost)
+	if err != nil {
+		return CloseoutType{}, newValidationError("mulchCost", "mulchCost is not an amount: %s", mulchCost)
+	}
+
+	frConfig, err := store.GetFundraiserConfig(ctx, []string{"products"})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return CloseoutType{}, newValidationError("", "the fundraiser config has to be set before closing out")
+		}
+		return CloseoutType{}, err
+	}
+	spreadingPerBag := decimal.Zero
+	for _, product := range frConfig.Products {
+		if product.Id == "spreading" {
+			spreadingPerBag, err = parseAmount(&product.UnitPrice)
+			if err != nil {
+				return CloseoutType{}, fmt.Errorf("spreading unit price: %s is not an amount: %w", product.UnitPrice, err)
+			}
+		}
+	}
+	if policy.SpreadingCreditPerBag != nil {
+		spreadingPerBag = *policy.SpreadingCreditPerBag
+	}
+
+	orders, err := store.GetMulchOrders(ctx, GetMulchOrdersParams{
+		GqlFields: []string{"orderId", "ownerId", "purchases", "amountFromDonations", "spreaders"},
+	})
+	if err != nil {
+		// Can't split the money with some of the orders missing
+		return CloseoutType{}, err
+	}
+	timecards, err := store.GetMulchTimecards(ctx, "", -1, []string{"id", "timeTotal"})
+	if err != nil {
+		return CloseoutType{}, err
+	}
+
+	uids := []string{}
+	scouts := make(map[string]*closeoutScoutTotals)
+	getScout := func(uid string) *closeoutScoutTotals {
+		scout, ok := scouts[uid]
+		if !ok {
+			scout = &closeoutScoutTotals{}
+			scouts[uid] = scout
+			uids = append(uids, uid)
+		}
+		return scout
+	}
+
+	totalBagsSold := 0
+	totalBagsSpread := decimal.Zero
+	mulchSalesGross := decimal.Zero
+	for _, order := range orders {
+		owner := getScout(order.OwnerId)
+		donations, err := parseAmount(order.AmountFromDonations)
+		if err != nil {
+			return CloseoutType{}, fmt.Errorf("order: %s amountFromDonations: %w", order.OrderId, err)
+		}
+		owner.donations = owner.donations.Add(donations)
+
+		for _, item := range order.Purchases {
+			if item.ProductId != "bags" {
+				continue
+			}
+			amountCharged, err := parseAmount(&item.AmountCharged)
+			if err != nil {
+				return CloseoutType{}, fmt.Errorf("order: %s bags amountCharged: %w", order.OrderId, err)
+			}
+			owner.bagsSold += item.NumSold
+			totalBagsSold += item.NumSold
+			mulchSalesGross = mulchSalesGross.Add(amountCharged)
+		}
+
+		perSpreader := calcBagsSpreadPerSpreader(order)
+		for _, spreader := range order.Spreaders {
+			scout := getScout(spreader)
+			scout.bagsSpread = scout.bagsSpread.Add(perSpreader)
+			totalBagsSpread = totalBagsSpread.Add(perSpreader)
+		}
+	}
+
+	for _, timecard := range timecards {
+		duration, err := parseTimecardDuration(timecard.TimeTotal)
+		if err != nil {
+			return CloseoutType{}, fmt.Errorf("timecard of: %s: %w", timecard.Id, err)
+		}
+		getScout(timecard.Id).deliveryTime += duration
+	}
+	creditedDeliveryMinutes := func(scout *closeoutScoutTotals) decimal.Decimal {
+		minutes := math.Floor(scout.deliveryTime.Minutes())
+		if minutes < float64(policy.MinDeliveryMinutes) {
+			return decimal.Zero
+		}
+		return decimal.NewFromFloat(minutes)
+	}
+	totalDeliveryMinutes := decimal.Zero
+	for _, scout := range scouts {
+		totalDeliveryMinutes = totalDeliveryMinutes.Add(creditedDeliveryMinutes(scout))
+	}
+
+	perBagCost := decimal.Zero
+	if totalBagsSold != 0 {
+		perBagCost = mulchCostAmt.Div(decimal.NewFromInt(int64(totalBagsSold)))
+	}
+	profit := bankDepositedAmt.Sub(mulchCostAmt)
+	troopPool := calcPercentageOf(profit, policy.TroopPercentage)
+	scoutsPool := profit.Sub(troopPool)
+	spreadingCredits := totalBagsSpread.Mul(spreadingPerBag)
+	scoutsPoolAfterSpreading := scoutsPool.Sub(spreadingCredits)
+	if scoutsPoolAfterSpreading.IsNegative() {
+		// Paying the spreading credits would take money from the troop's pool
+		return CloseoutType{}, &ValidationError{
+			Message: fmt.Sprintf("the scouts' pool of %s doesn't cover the spreading credits of %s",
+				scoutsPool.StringFixed(2), spreadingCredits.StringFixed(2)),
+			Fields: []string{"bankDeposited", "mulchCost", "allocationPolicy.spreadingCreditPerBag"},
+		}
+	}
+	deliveryPool := calcPercentageOf(scoutsPoolAfterSpreading, policy.DeliveryPercentage)
+	salesPool := scoutsPoolAfterSpreading.Sub(deliveryPool)
+
+	perBagAvgEarnings := decimal.Zero
+	if totalBagsSold != 0 {
+		perBagAvgEarnings = salesPool.Div(decimal.NewFromInt(int64(totalBagsSold)))
+	}
+	deliveryEarningsPerMinute := decimal.Zero
+	if !totalDeliveryMinutes.IsZero() {
+		deliveryEarningsPerMinute = deliveryPool.Div(totalDeliveryMinutes)
+	}
+
+	closeout := CloseoutType{
+		FinalizationData: FinalizationDataType{
+			BankDeposited:              bankDepositedAmt.StringFixedBank(4),
+			MulchCost:                  mulchCostAmt.StringFixedBank(4),
+			PerBagCost:                 perBagCost.StringFixedBank(4),
+			ProfitsFromBags:            mulchSalesGross.Sub(mulchCostAmt).StringFixedBank(4),
+			MulchSalesGross:            mulchSalesGross.StringFixedBank(4),
+			MoneyPoolForTroop:          troopPool.StringFixedBank(4),
+			MoneyPoolForScoutsSubPools: scoutsPool.StringFixedBank(4),
+			MoneyPoolForScoutsSales:    salesPool.StringFixedBank(4),
+			MoneyPoolForScoutsDelivery: deliveryPool.StringFixedBank(4),
+			PerBagAvgEarnings:          perBagAvgEarnings.StringFixedBank(4),
+			DeliveryEarningsPerMinute:  deliveryEarningsPerMinute.StringFixedBank(4),
+		},
+		Allocations: []AllocationItemType{},
+	}
+
+	overCaps := decimal.Zero
+	slices.Sort(uids)
+	for _, uid := range uids {
+		scout := scouts[uid]
+		deliveryMinutes := decimal.NewFromFloat(math.Floor(scout.deliveryTime.Minutes()))
+		fromBagsSold := perBagAvgEarnings.Mul(decimal.NewFromInt(int64(scout.bagsSold))).RoundBank(4)
+		fromBagsSpread := spreadingPerBag.Mul(scout.bagsSpread).RoundBank(4)
+		fromDelivery := deliveryEarningsPerMinute.Mul(creditedDeliveryMinutes(scout)).RoundBank(4)
+
+		uncapped := fromBagsSold.Add(fromBagsSpread).Add(fromDelivery)
+		if policy.MaxAllocationPerScout != nil && uncapped.GreaterThan(*policy.MaxAllocationPerScout) {
+			// Each part is cut by the same ratio with delivery taking the rounding
+			maxAllocation := *policy.MaxAllocationPerScout
+			fromBagsSold = fromBagsSold.Mul(maxAllocation).Div(uncapped).RoundBank(4)
+			fromBagsSpread = fromBagsSpread.Mul(maxAllocation).Div(uncapped).RoundBank(4)
+			fromDelivery = maxAllocation.Sub(fromBagsSold).Sub(fromBagsSpread)
+			overCaps = overCaps.Add(uncapped.Sub(maxAllocation))
+		}
+
+		bagsSold := scout.bagsSold
+		bagsSpread := scout.bagsSpread.StringFixedBank(4)
+		deliveryMinutesStr := deliveryMinutes.StringFixedBank(4)
+		donations := scout.donations.StringFixedBank(4)
+		fromBagsSoldStr := fromBagsSold.StringFixed(4)
+		fromBagsSpreadStr := fromBagsSpread.StringFixed(4)
+		fromDeliveryStr := fromDelivery.StringFixed(4)
+		closeout.Allocations = append(closeout.Allocations, AllocationItemType{
+			Uid:                       uid,
+			BagsSold:                  &bagsSold,
+			BagsSpread:                &bagsSpread,
+			DeliveryMinutes:           &deliveryMinutesStr,
+			TotalDonations:            &donations,
+			AllocationsFromBagsSold:   &fromBagsSoldStr,
+			AllocationsFromBagsSpread: &fromBagsSpreadStr,
+			AllocationsFromDelivery:   &fromDeliveryStr,
+			AllocationsTotal:          fromBagsSold.Add(fromBagsSpread).Add(fromDelivery).StringFixed(4),
+		})
+	}
+	if !overCaps.IsZero() {
+		closeout.FinalizationData.MoneyPoolForTroop = troopPool.Add(overCaps).StringFixedBank(4)
+		closeout.FinalizationData.MoneyPoolForScoutsSubPools = scoutsPool.Sub(overCaps).StringFixedBank(4)
+	}
+	return closeout, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the values for any not given.  bankDeposited is the total of the
+// bank deposit ledger and mulchCost the total of the mulch inventory.
+// Without any deposits (or inventory costs) they are what the current config
+// has.
+func getCloseoutInputs(ctx context.Context, store FrStore, bankDeposited *string, mulchCost *string) (string, string, error) {
+	if bankDeposited == nil {
+		ledgerTotal, hasDeposits, err := getBankDepositedTotal(ctx, store)
+		if err != nil {
+			return "", "", err
+		}
+		if hasDeposits {
+			bankDeposited = &ledgerTotal
+		}
+	}
+	if mulchCost == nil {
+		inventoryTotal, hasCosts, err := getInventoryMulchCostTotal(ctx, store)
+		if err != nil {
+			return "", "", err
+		}
+		if hasCosts {
+			mulchCost = &inventoryTotal
+		}
+	}
+	if bankDeposited != nil && mulchCost != nil {
+		return *bankDeposited, *mulchCost, nil
+	}
+	frConfig, err := store.GetFundraiserConfig(ctx, []string{"finalizationData"})
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return "", "", err
+	}
+	finalizationData := FinalizationDataType{}
+	if frConfig.FinalizationData != nil {
+		finalizationData = *frConfig.FinalizationData
+	}
+	if bankDeposited == nil {
+		bankDeposited = &finalizationData.BankDeposited
+	}
+	if mulchCost == nil {
+		mulchCost = &finalizationData.MulchCost
+	}
+	return *bankDeposited, *mulchCost, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns what commitCloseout would save without saving it.  bankDeposited
+// defaults to the deposit ledger and mulchCost to the mulch inventory.  The
+// config's allocation policy is used.  Admin only
+func PreviewCloseout(ctx context.Context, bankDeposited *string, mulchCost *string) (CloseoutType, error) {
+	log.Println("Previewing closeout")
+
+	if err := VerifyAdminTokenFromCtx(ctx); err != nil {
+		return CloseoutType{}, err
+	}
+
+	bankDepositedStr, mulchCostStr, err := getCloseoutInputs(ctx, frStore, bankDeposited, mulchCost)
+	if err != nil {
+		return CloseoutType{}, err
+	}
+	policy, err := getCloseoutPolicy(ctx, frStore)
+	if err != nil {
+		return CloseoutType{}, err
+	}
+	closeout, err := calcCloseout(ctx, frStore, bankDepositedStr, mulchCostStr, policy)
+	if err != nil {
+		log.Println("Closeout preview failed: ", err)
+		return CloseoutType{}, err
+	}
+	return closeout, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Works out the closeout and saves the finalization data to the config and
+// the allocations (replacing all of them) in one transaction.  Admin only
+func CommitCloseout(ctx context.Context, bankDeposited *string, mulchCost *string) (CloseoutType, error) {
+	log.Println("Committing closeout")
+
+	if err := VerifyAdminTokenFromCtx(ctx); err != nil {
+		return CloseoutType{}, err
+	}
+
+	closeout := CloseoutType{}
+	err := recordFundraiserConfigChange(ctx, "commitCloseout", func(tx FrStore) error {
+		bankDepositedStr, mulchCostStr, err := getCloseoutInputs(ctx, tx, bankDeposited, mulchCost)
+		if err != nil {
+			return err
+		}
+		policy, err := getCloseoutPolicy(ctx, tx)
+		if err != nil {
+			return err
+		}
+		closeout, err = calcCloseout(ctx, tx, bankDepositedStr, mulchCostStr, policy)
+		if err != nil {
+			return err
+		}
+
+		existingAllocations, err := tx.GetAllocations(ctx)
+		if err != nil {
+			return err
+		}
+		existingByUid := make(map[string]*AllocationItemType)
+		for _, item := range existingAllocations {
+			existingByUid[item.Uid] = &item
+		}
+
+		finalizationData := closeout.FinalizationData
+		if err := updateFundraiserConfigWithTrxn(ctx, tx, FrConfigType{FinalizationData: &finalizationData}); err != nil {
+			return err
+		}
+		if err := tx.SetAllocations(ctx, closeout.Allocations); err != nil {
+			return err
+		}
+		for _, item := range closeout.Allocations {
+			if err := recordAuditEntry(ctx, tx, "commitCloseout", "allocation", item.Uid, existingByUid[item.Uid], item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Println("Committing closeout failed: ", err)
+		return CloseoutType{}, err
+	}
+	return closeout, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// A scout's simulated allocation next to the committed one.  Committed is
+// nil for scouts without a committed allocation.
+type AllocationSimulationItemType struct {
+	Uid        string              `json:"uid"`
+	Simulated  *AllocationItemType `json:"simulated"`
+	Committed  *AllocationItemType `json:"committed"`
+	TotalDelta string              `json:"totalDelta"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Policy is the one that was simulated with the config's filled in
+type AllocationSimulationType struct {
+	Policy           AllocationPolicyType           `json:"policy"`
+	FinalizationData FinalizationDataType           `json:"finalizationData"`
+	Allocations      []AllocationSimulationItemType `json:"allocations"`
+	TotalDelta       string                         `json:"totalDelta"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Works out the closeout with the config's allocation policy overridden by
+// what is set in policy and compares each scout's allocation with the
+// committed one in the allocation summary.  TotalDelta is simulated less
+// committed.  Nothing is saved so several policies can be compared.
+// Admin only
+func SimulateAllocations(ctx context.Context, policy *AllocationPolicyType, bankDeposited *string, mulchCost *string) (AllocationSimulationType, error) {
+	log.Println("Simulating allocations")
+
+	if err := VerifyAdminTokenFromCtx(ctx); err != nil {
+		return AllocationSimulationType{}, err
+	}
+
+	bankDepositedStr, mulchCostStr, err := getCloseoutInputs(ctx, frStore, bankDeposited, mulchCost)
+	if err != nil {
+		return AllocationSimulationType{}, err
+	}
+	configPolicy, err := getAllocationPolicy(ctx, frStore)
+	if err != nil {
+		return AllocationSimulationType{}, err
+	}
+	simulatedPolicy := mergeAllocationPolicy(configPolicy, policy)
+	parsedPolicy, err := parseCloseoutPolicy(simulatedPolicy)
+	if err != nil {
+		return AllocationSimulationType{}, err
+	}
+
+	closeout, err := calcCloseout(ctx, frStore, bankDepositedStr, mulchCostStr, parsedPolicy)
+	if err != nil {
+		log.Println("Allocation simulation failed: ", err)
+		return AllocationSimulationType{}, err
+	}
+	committedAllocations, err := frStore.GetAllocations(ctx)
+	if err != nil {
+		return AllocationSimulationType{}, err
+	}
+
+	simulation := AllocationSimulationType{
+		FinalizationData: closeout.FinalizationData,
+		Allocations:      []AllocationSimulationItemType{},
+	}
+	if simulatedPolicy != nil {
+		simulation.Policy = *simulatedPolicy
+	}
+
+	items := make(map[string]*AllocationSimulationItemType)
+	uids := []string{}
+	getItem := func(uid string) *AllocationSimulationItemType {
+		item, ok := items[uid]
+		if !ok {
+			item = &AllocationSimulationItemType{Uid: uid}
+			items[uid] = item
+			uids = append(uids, uid)
+		}
+		return item
+	}
+	for _, allocation := range closeout.Allocations {
+		getItem(allocation.Uid).Simulated = &allocation
+	}
+	for _, allocation := range committedAllocations {
+		getItem(allocation.Uid).Committed = &allocation
+	}
+
+	totalDelta := decimal.Zero
+	slices.Sort(uids)
+	for _, uid := range uids {
+		item := items[uid]
+		delta := decimal.Zero
+		if item.Simulated != nil {
+			delta = delta.Add(decimal.RequireFromString(item.Simulated.AllocationsTotal))
+		}
+		if item.Committed != nil {
+			committedTotal, err := parseAmount(&item.Committed.AllocationsTotal)
+			if err != nil {
+				return AllocationSimulationType{}, fmt.Errorf("committed allocation of: %s: %w", uid, err)
+			}
+			delta = delta.Sub(committedTotal)
+		}
+		totalDelta = totalDelta.Add(delta)
+		item.TotalDelta = delta.StringFixedBank(4)
+		simulation.Allocations = append(simulation.Allocations, *item)
+	}
+	simulation.TotalDelta = totalDelta.StringFixedBank(4)
+	return simulation, nil
+}
@@ -0,0 +1,273 @@
+package frgql
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// //////////////////////////////////////////////////////////////////////////
+func TestCalcBagsSpreadPerSpreader(t *testing.T) {
+	tests := []struct {
+		name      string
+		purchases []ProductsType
+		spreaders []string
+		expected  string
+	}{
+		{"no spreaders", []ProductsType{{ProductId: "spreading", NumSold: 10}}, nil, "0"},
+		{"no spreading", []ProductsType{{ProductId: "bags", NumSold: 10}}, []string{"scout1"}, "0"},
+		{"one spreader", []ProductsType{{ProductId: "spreading", NumSold: 10}}, []string{"scout1"}, "10"},
+		// The seller is one of the spreaders
+		{"split evenly", []ProductsType{{ProductId: "bags", NumSold: 12}, {ProductId: "spreading", NumSold: 10}},
+			[]string{"seller", "scout1", "scout2"}, "3.3333"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			order := MulchOrderType{OwnerId: "seller", Purchases: test.purchases, Spreaders: test.spreaders}
+			if perSpreader := calcBagsSpreadPerSpreader(order).StringFixed(4); perSpreader != decimal.RequireFromString(test.expected).StringFixed(4) {
+				t.Errorf("%s bags per spreader not: %s", perSpreader, test.expected)
+			}
+		})
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func TestParseCloseoutPolicy(t *testing.T) {
+	str := func(val string) *string { return &val }
+
+	tests := []struct {
+		name        string
+		policy      *AllocationPolicyType
+		errorFields []string
+	}{
+		{"no policy", nil, []string{"allocationPolicy.troopPercentage"}},
+		{"no troopPercentage", &AllocationPolicyType{DeliveryPercentage: str("25")}, []string{"allocationPolicy.troopPercentage"}},
+		{"no deliveryPercentage", &AllocationPolicyType{TroopPercentage: str("20")}, []string{"allocationPolicy.deliveryPercentage"}},
+		{"over 100", &AllocationPolicyType{TroopPercentage: str("120"), DeliveryPercentage: str("25")},
+			[]string{"allocationPolicy.troopPercentage"}},
+		{"negative credit", &AllocationPolicyType{TroopPercentage: str("20"), DeliveryPercentage: str("25"),
+			SpreadingCreditPerBag: str("-1")}, []string{"allocationPolicy.spreadingCreditPerBag"}},
+		{"percentages only", &AllocationPolicyType{TroopPercentage: str("20"), DeliveryPercentage: str("25")}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseCloseoutPolicy(test.policy)
+			if test.errorFields == nil {
+				if err != nil {
+					t.Errorf("valid policy: %v", err)
+				}
+				return
+			}
+			if fields := getValidationFields(err); !slices.Equal(fields, test.errorFields) {
+				t.Errorf("got error: %v with fields: %v not: %v", err, fields, test.errorFields)
+			}
+		})
+	}
+
+	// Checking a config's policy doesn't need the percentages
+	if _, err := parseAllocationPolicy(&AllocationPolicyType{MinDeliveryMinutes: new(int)}); err != nil {
+		t.Errorf("config policy without percentages: %v", err)
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// scout1 sold 10 bags and spread half of its 10 bags of spreading with
+// scout2.  scout2 sold 30 bags and has 30 delivery minutes and scout3 only
+// has 60 delivery minutes.
+func insertTestCloseoutRecords(t *testing.T, ctx context.Context, store FrStore) {
+	t.Helper()
+	order1 := makeTestOrder(testOrderId1, "scout1")
+	donations := "5"
+	order1.AmountFromDonations = &donations
+	order1.Purchases = []ProductsType{
+		{ProductId: "bags", NumSold: 10, AmountCharged: "45"},
+		{ProductId: "spreading", NumSold: 10, AmountCharged: "20"},
+	}
+	order2 := makeTestOrder(testOrderId2, "scout2")
+	order2.Purchases = []ProductsType{{ProductId: "bags", NumSold: 30, AmountCharged: "120"}}
+	mustNotFail(t, store.InsertMulchOrder(ctx, order1))
+	mustNotFail(t, store.InsertMulchOrder(ctx, order2))
+	mustNotFail(t, store.SetSpreaders(ctx, testOrderId1, []string{"scout1", "scout2"}))
+	mustNotFail(t, store.InsertMulchTimecard(ctx, MulchTimecardType{Id: "scout2", DeliveryId: 1, TimeTotal: "00:30:00"}))
+	mustNotFail(t, store.InsertMulchTimecard(ctx, MulchTimecardType{Id: "scout3", DeliveryId: 1, TimeTotal: "01:00:00"}))
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// With the records of insertTestCloseoutRecords, 500 deposited and a mulch
+// cost of 100 the profit is 400.  The troop keeps 20% (80) leaving 320.  The
+// 10 bags spread at 2 are 20 which leaves 300 split 25% (75) for the 90
+// delivery minutes and 75% (225) for the 40 bags sold.
+func TestCalcCloseout(t *testing.T) {
+	store := newTestMemStore(t)
+	ctx := context.Background()
+
+	mustNotFail(t, store.SetFundraiserConfig(ctx, FrConfigType{
+		Products: testProducts, LastModifiedTime: makeLastModifiedTime(),
+	}))
+	insertTestCloseoutRecords(t, ctx, store)
+
+	str := func(val string) *string { return &val }
+	basePolicy := AllocationPolicyType{TroopPercentage: str("20"), DeliveryPercentage: str("25")}
+	withPolicy := func(modify func(policy *AllocationPolicyType)) closeoutPolicy {
+		policy := basePolicy
+		if modify != nil {
+			modify(&policy)
+		}
+		parsed, err := parseCloseoutPolicy(&policy)
+		mustNotFail(t, err)
+		return parsed
+	}
+	getTotals := func(closeout CloseoutType) map[string]string {
+		totals := map[string]string{}
+		for _, allocation := range closeout.Allocations {
+			totals[allocation.Uid] = allocation.AllocationsTotal
+		}
+		return totals
+	}
+
+	t.Run("policy percentages", func(t *testing.T) {
+		closeout, err := calcCloseout(ctx, store, "500", "100", withPolicy(nil))
+		mustNotFail(t, err)
+
+		data := closeout.FinalizationData
+		expectedData := map[string][2]string{
+			"perBagCost":                 {data.PerBagCost, "2.5000"},
+			"mulchSalesGross":            {data.MulchSalesGross, "165.0000"},
+			"profitsFromBags":            {data.ProfitsFromBags, "65.0000"},
+			"moneyPoolForTroop":          {data.MoneyPoolForTroop, "80.0000"},
+			"moneyPoolForScoutsSubPools": {data.MoneyPoolForScoutsSubPools, "320.0000"},
+			"moneyPoolForScoutsDelivery": {data.MoneyPoolForScoutsDelivery, "75.0000"},
+			"moneyPoolForScoutsSales":    {data.MoneyPoolForScoutsSales, "225.0000"},
+			"perBagAvgEarnings":          {data.PerBagAvgEarnings, "5.6250"},
+		}
+		for field, values := range expectedData {
+			if values[0] != values[1] {
+				t.Errorf("%s: %s not: %s", field, values[0], values[1])
+			}
+		}
+
+		// All of the scouts' pool is allocated
+		expectedTotals := map[string]string{"scout1": "66.2500", "scout2": "203.7500", "scout3": "50.0000"}
+		if totals := getTotals(closeout); !maps.Equal(totals, expectedTotals) {
+			t.Errorf("allocations: %v not: %v", totals, expectedTotals)
+		}
+		if donations := *closeout.Allocations[0].TotalDonations; donations != "5.0000" {
+			t.Errorf("scout1 donations: %s", donations)
+		}
+	})
+
+	t.Run("min delivery minutes", func(t *testing.T) {
+		minutes := 45
+		closeout, err := calcCloseout(ctx, store, "500", "100", withPolicy(func(policy *AllocationPolicyType) {
+			policy.MinDeliveryMinutes = &minutes
+		}))
+		mustNotFail(t, err)
+		// scout3 gets all of the delivery pool
+		expectedTotals := map[string]string{"scout1": "66.2500", "scout2": "178.7500", "scout3": "75.0000"}
+		if totals := getTotals(closeout); !maps.Equal(totals, expectedTotals) {
+			t.Errorf("allocations: %v not: %v", totals, expectedTotals)
+		}
+	})
+
+	t.Run("max allocation", func(t *testing.T) {
+		closeout, err := calcCloseout(ctx, store, "500", "100", withPolicy(func(policy *AllocationPolicyType) {
+			policy.MaxAllocationPerScout = str("100")
+		}))
+		mustNotFail(t, err)
+		expectedTotals := map[string]string{"scout1": "66.2500", "scout2": "100.0000", "scout3": "50.0000"}
+		if totals := getTotals(closeout); !maps.Equal(totals, expectedTotals) {
+			t.Errorf("allocations: %v not: %v", totals, expectedTotals)
+		}
+		// What is over the cap goes to the troop
+		if troopPool := closeout.FinalizationData.MoneyPoolForTroop; troopPool != "183.7500" {
+			t.Errorf("moneyPoolForTroop: %s", troopPool)
+		}
+	})
+
+	t.Run("spreading credits over the scouts' pool", func(t *testing.T) {
+		_, err := calcCloseout(ctx, store, "500", "100", withPolicy(func(policy *AllocationPolicyType) {
+			policy.SpreadingCreditPerBag = str("40")
+		}))
+		if fields := getValidationFields(err); !slices.Contains(fields, "allocationPolicy.spreadingCreditPerBag") {
+			t.Errorf("got error: %v with fields: %v", err, fields)
+		}
+	})
+
+	t.Run("missing amounts", func(t *testing.T) {
+		var validationErr *ValidationError
+		if _, err := calcCloseout(ctx, store, "", "100", withPolicy(nil)); !errors.As(err, &validationErr) {
+			t.Errorf("closed out without bankDeposited: %v", err)
+		}
+	})
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// The config's policy with the simulated fields overridden is compared with
+// the committed allocations without saving anything
+func TestSimulateAllocations(t *testing.T) {
+	store := newTestMemStore(t)
+	ctx := context.Background()
+	adminCtx := newTestCtx(t, "admin1", true)
+
+	str := func(val string) *string { return &val }
+	mustNotFail(t, store.SetFundraiserConfig(ctx, FrConfigType{
+		Products:         testProducts,
+		AllocationPolicy: &AllocationPolicyType{TroopPercentage: str("20"), DeliveryPercentage: str("50")},
+		LastModifiedTime: makeLastModifiedTime(),
+	}))
+	insertTestCloseoutRecords(t, ctx, store)
+	committed := []AllocationItemType{{Uid: "scout2", AllocationsTotal: "200.0000"}, {Uid: "scout4", AllocationsTotal: "10.0000"}}
+	mustNotFail(t, store.SetAllocations(ctx, committed))
+
+	// Same numbers as TestCalcCloseout
+	simulation, err := SimulateAllocations(adminCtx, &AllocationPolicyType{DeliveryPercentage: str("25")}, str("500"), str("100"))
+	mustNotFail(t, err)
+	if *simulation.Policy.TroopPercentage != "20" || *simulation.Policy.DeliveryPercentage != "25" {
+		t.Errorf("simulated policy: %+v", simulation.Policy)
+	}
+	deltas := map[string]string{}
+	for _, item := range simulation.Allocations {
+		deltas[item.Uid] = item.TotalDelta
+	}
+	expectedDeltas := map[string]string{"scout1": "66.2500", "scout2": "3.7500", "scout3": "50.0000", "scout4": "-10.0000"}
+	if !maps.Equal(deltas, expectedDeltas) || simulation.TotalDelta != "110.0000" {
+		t.Errorf("deltas: %v total: %s not: %v", deltas, simulation.TotalDelta, expectedDeltas)
+	}
+	if allocations, err := store.GetAllocations(ctx); err != nil || !slices.Equal(allocations, committed) {
+		t.Errorf("simulation changed the allocations to: %+v %v", allocations, err)
+	}
+
+	t.Run("invalid override", func(t *testing.T) {
+		_, err := SimulateAllocations(adminCtx, &AllocationPolicyType{TroopPercentage: str("120")}, str("500"), str("100"))
+		if fields := getValidationFields(err); !slices.Equal(fields, []string{"allocationPolicy.troopPercentage"}) {
+			t.Errorf("got error: %v with fields: %v", err, fields)
+		}
+	})
+
+	t.Run("spreading credits over the scouts' pool", func(t *testing.T) {
+		_, err := SimulateAllocations(adminCtx, &AllocationPolicyType{SpreadingCreditPerBag: str("40")}, str("500"), str("100"))
+		if fields := getValidationFields(err); !slices.Contains(fields, "allocationPolicy.spreadingCreditPerBag") {
+			t.Errorf("got error: %v with fields: %v", err, fields)
+		}
+	})
+
+	t.Run("invalid config policy", func(t *testing.T) {
+		_, err := UpdateFundraiserConfig(adminCtx, FrConfigType{
+			AllocationPolicy: &AllocationPolicyType{MaxAllocationPerScout: str("-5")},
+		}, "")
+		if fields := getValidationFields(err); !slices.Equal(fields, []string{"allocationPolicy.maxAllocationPerScout"}) {
+			t.Errorf("got error: %v with fields: %v", err, fields)
+		}
+	})
+
+	t.Run("admin only", func(t *testing.T) {
+		var forbiddenErr *ForbiddenError
+		if _, err := SimulateAllocations(newTestCtx(t, "scout1", false), nil, str("500"), str("100")); !errors.As(err, &forbiddenErr) {
+			t.Errorf("a scout simulating returned: %v not a ForbiddenError", err)
+		}
+	})
+}
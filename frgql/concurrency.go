@@ -0,0 +1,58 @@
+package frgql
+
+import (
+	"time"
+)
+
+// Microseconds so two changes made within the same second still get
+// different versions.  This is also the most the db timestamps keep.
+const lastModifiedTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+// Formats lastModifiedTime can come back in. The db returns timestamps as
+// strings without the T and the client may send them back as RFC3339.
+var lastModifiedTimeParseFormats = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999-07",
+	"2006-01-02 15:04:05.999999999Z07:00",
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the lastModifiedTime to save with a change made now
+func makeLastModifiedTime() string {
+	return time.Now().UTC().Format(lastModifiedTimeFormat)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func parseLastModifiedTime(lastModifiedTime string) (time.Time, bool) {
+	for _, format := range lastModifiedTimeParseFormats {
+		if t, err := time.Parse(format, lastModifiedTime); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Compares two lastModifiedTimes that may be in different formats
+func isSameLastModifiedTime(a string, b string) bool {
+	aTime, isAOk := parseLastModifiedTime(a)
+	bTime, isBOk := parseLastModifiedTime(b)
+	if !isAOk || !isBOk {
+		return a == b
+	}
+	return aTime.Equal(bTime)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the error for an update that found the record at a different
+// version than the client expected.  current is the record as it is now.
+func newConflictError(targetKind string, targetKey string, currentLastModifiedTime string, current any) error {
+	return &ConflictError{
+		TargetKind:              targetKind,
+		TargetKey:               targetKey,
+		CurrentLastModifiedTime: currentLastModifiedTime,
+		Current:                 current,
+	}
+}
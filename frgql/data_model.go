@@ -19,48 +19,37 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/shopspring/decimal"
-
-	"github.com/codingsince1985/geo-golang"
-	"github.com/codingsince1985/geo-golang/openstreetmap"
-
-	"github.com/doug-martin/goqu/v9"
-	_ "github.com/doug-martin/goqu/v9/dialect/postgres"
 )
 
 // //////////////////////////////////////////////////////////////////////////
 // //////////////////////////////////////////////////////////////////////////
 var (
-	dbMutex  sync.Mutex
-	Db       *pgxpool.Pool
-	GEOCODER = get_geocoder()
+	dbMutex sync.Mutex
+	Db      *pgxpool.Pool
 )
 
 ////////////////////////////////////////////////////////////////////////////
 ////////////////////////////////////////////////////////////////////////////
 
 // //////////////////////////////////////////////////////////////////////////
-func get_geocoder() geo.Geocoder {
-	geocoder := openstreetmap.Geocoder()
+func OpenDb() error {
+	dbMutex.Lock()
+	defer dbMutex.Unlock()
 
-	return geocoder
-}
+	// A store may have already been set (in-memory store for example) in
+	// which case there isn't a database to connect to.
+	if frStore != nil {
+		return nil
+	}
 
-// //////////////////////////////////////////////////////////////////////////
-func OpenDb() error {
-	if Db == nil {
-		dbMutex.Lock()
-		defer dbMutex.Unlock()
-		if Db == nil {
-			cnxn, err := makeDbConnection()
-			if err != nil {
-				return err
-			}
-			Db = cnxn
-		}
+	cnxn, err := makeDbConnection()
+	if err != nil {
+		return err
 	}
+	Db = cnxn
+	frStore = NewPgStore(cnxn)
 
 	return nil
 }
@@ -131,21 +120,26 @@ func (claims *T27FrClaims) doesUidMatch(uid string) bool {
 }
 
 // //////////////////////////////////////////////////////////////////////////
+// Only returns claims from a token whose signature, issuer, audience and
+// expiry have been verified against the configured JWKS.
 func parseTokenClaimsFromCtx(ctx context.Context) (*T27FrClaims, error) {
-	if v := ctx.Value("T27FrAuthorization"); v != nil {
-
-		// Parse the token
-		token, _, _ := new(jwt.Parser).ParseUnverified(v.(string), &T27FrClaims{})
+	v, ok := ctx.Value("T27FrAuthorization").(string)
+	if !ok || len(v) == 0 {
+		return nil, &UnauthenticatedError{Message: "not authorized: Required token not found"}
+	}
 
-		if claims, ok := token.Claims.(*T27FrClaims); ok {
-			// log.Println(claims)
-			return claims, nil
-		}
-	} else {
-		return nil, errors.New("not authorized: Required token not found")
+	verifier, err := getTokenVerifier()
+	if err != nil {
+		log.Println("Token verifier unavailable: ", err)
+		return nil, &UnauthenticatedError{Message: "not authorized: Unable to verify token"}
 	}
 
-	return nil, errors.New("not authorized: Invalid token")
+	claims, err := verifier.verify(v)
+	if err != nil {
+		log.Println("Token verification failed: ", err)
+		return nil, &UnauthenticatedError{Message: "not authorized: Invalid token"}
+	}
+	return claims, nil
 }
 
 // //////////////////////////////////////////////////////////////////////////
@@ -156,7 +150,7 @@ func VerifyAdminTokenFromCtx(ctx context.Context) error {
 	}
 
 	if !claims.isAdmin() {
-		return errors.New("not authorized: Not an admin user")
+		return &ForbiddenError{Message: "not authorized: Not an admin user"}
 	}
 	return nil
 }
@@ -174,9 +168,9 @@ func verifyUidAllowedFromCtx(ctx context.Context, uid string) error {
 	}
 
 	if !claims.doesUidMatch(uid) {
-		return fmt.Errorf(
+		return &ForbiddenError{Message: fmt.Sprintf(
 			"not authorized: User is not admin and id does not match. Asking: %s Found: %s",
-			uid, claims.userId())
+			uid, claims.userId())}
 	}
 	return nil
 }
@@ -199,16 +193,15 @@ type OwnerIdSummaryType struct {
 }
 
 // //////////////////////////////////////////////////////////////////////////
-func getOrderSummaryByOwnerId(ownerId string, summary *OwnerIdSummaryType) error {
-	sqlCmd := "select purchases::jsonb, amount_from_donations::string, total_amount_collected::string" +
-		" from mulch_orders where order_owner_id = $1"
-
-	rows, err := Db.Query(context.Background(), sqlCmd, ownerId)
+func getOrderSummaryByOwnerId(ctx context.Context, ownerId string, summary *OwnerIdSummaryType) error {
+	orders, err := frStore.GetMulchOrders(ctx, GetMulchOrdersParams{
+		OwnerId:   ownerId,
+		GqlFields: []string{"purchases", "amountFromDonations", "amountTotalCollected"},
+	})
 	if err != nil {
 		log.Println("User summary query failed", err)
 		return err
 	}
-	defer rows.Close()
 
 	totalCollected := decimal.NewFromInt(0)
 	totalCollectedForDonations := decimal.NewFromInt(0)
@@ -217,36 +210,27 @@ func getOrderSummaryByOwnerId(ownerId string, summary *OwnerIdSummaryType) error
 	numBagsSold := 0
 	numBagsToSpreadSold := 0
 
-	for rows.Next() {
-		var purchases []ProductsType
-		var donationsAsStr *string
-		var totalCollectedAsStr *string
-
-		err = rows.Scan(&purchases, &donationsAsStr, &totalCollectedAsStr)
-		if err != nil {
-			// log.Println("Reading User Summary row failed: ", err)
-			return err
-		}
-		if totalCollectedAsStr == nil {
+	for _, order := range orders {
+		if order.AmountTotalCollected == nil {
 			continue
 		}
-		// log.Println("TotalCollectedAsStr: ", *totalCollectedAsStr)
-		total, err := decimal.NewFromString(*totalCollectedAsStr)
+		// log.Println("TotalCollectedAsStr: ", *order.AmountTotalCollected)
+		total, err := decimal.NewFromString(*order.AmountTotalCollected)
 		if err != nil {
 			return err
 		}
 		totalCollected = totalCollected.Add(total)
 
-		if donationsAsStr != nil {
-			// log.Println("DonationsStr: ", *donationsAsStr)
-			donationAmt, err := decimal.NewFromString(*donationsAsStr)
+		if order.AmountFromDonations != nil {
+			// log.Println("DonationsStr: ", *order.AmountFromDonations)
+			donationAmt, err := decimal.NewFromString(*order.AmountFromDonations)
 			if err != nil {
 				return err
 			}
 			totalCollectedForDonations = totalCollectedForDonations.Add(donationAmt)
 		}
 
-		for _, item := range purchases {
+		for _, item := range order.Purchases {
 			// log.Println("ItemAmountChargedStr: ", item.AmountCharged)a
 			// ISSUE #108
 			item.AmountCharged = strings.ReplaceAll(item.AmountCharged, ",", "")
@@ -265,10 +249,6 @@ func getOrderSummaryByOwnerId(ownerId string, summary *OwnerIdSummaryType) error
 		}
 	}
 
-	if err := rows.Err(); err != nil {
-		return err
-	}
-
 	summary.TotalNumBagsSold = numBagsSold
 	summary.TotalNumBagsSoldToSpread = numBagsToSpreadSold
 	summary.TotalAmountCollectedForDonations = totalCollectedForDonations.StringFixedBank(4)
@@ -279,38 +259,60 @@ func getOrderSummaryByOwnerId(ownerId string, summary *OwnerIdSummaryType) error
 }
 
 // //////////////////////////////////////////////////////////////////////////
-func getDeliveryTimecardSummaryByOwnerId(ownerId string, summary *OwnerIdSummaryType) error {
-	timecards, err := GetMulchTimecards(ownerId, -1, []string{"timeTotal"})
+// Parses a timecard timeTotal (hh:mm:ss).  An empty one is 0.
+func parseTimecardDuration(timeTotal string) (time.Duration, error) {
+	if len(timeTotal) == 0 {
+		return 0, nil
+	}
+	durarr := strings.Split(timeTotal, ":")
+	if len(durarr) != 3 {
+		return 0, fmt.Errorf("timecard time: %s is not hh:mm:ss", timeTotal)
+	}
+	hours, err := time.ParseDuration(durarr[0] + "h")
+	if err != nil {
+		return 0, err
+	}
+	mins, err := time.ParseDuration(durarr[1] + "m")
+	if err != nil {
+		return 0, err
+	}
+	secs, err := time.ParseDuration(durarr[2] + "s")
+	if err != nil {
+		return 0, err
+	}
+	return hours + mins + secs, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func getDeliveryTimecardSummaryByOwnerId(ctx context.Context, ownerId string, summary *OwnerIdSummaryType) error {
+	timecards, err := GetMulchTimecards(ctx, ownerId, -1, []string{"timeTotal"})
 	if err != nil {
 		return err
 	}
 	deliveryMinutes, _ := time.ParseDuration("0s")
 	for _, tc := range timecards {
 		log.Println("Timecard: ", tc.TimeTotal)
-		durarr := strings.Split(tc.TimeTotal, ":")
-		hours, _ := time.ParseDuration(durarr[0] + "h")
-		mins, _ := time.ParseDuration(durarr[1] + "m")
-		secs, _ := time.ParseDuration(durarr[2] + "s")
-		deliveryMinutes = deliveryMinutes + hours + mins + secs
+		duration, err := parseTimecardDuration(tc.TimeTotal)
+		if err != nil {
+			log.Println("Skipping timecard: ", err)
+			continue
+		}
+		deliveryMinutes = deliveryMinutes + duration
 	}
 	summary.TotalDeliveryMinutes = int(math.Floor(deliveryMinutes.Minutes()))
 	return nil
 }
 
 // //////////////////////////////////////////////////////////////////////////
-func getAllocationSummaryByOwnerId(ownerId string, summary *OwnerIdSummaryType) error {
+func getAllocationSummaryByOwnerId(ctx context.Context, ownerId string, summary *OwnerIdSummaryType) error {
 	allocationsFromDelivery := decimal.NewFromInt(0)
 	allocationsFromBagsSold := decimal.NewFromInt(0)
 	allocationsFromBagsSpread := decimal.NewFromInt(0)
 	allocationsTotal := decimal.NewFromInt(0)
-	var allocFromBagsSoldStr, allocFromBagsSpreadStr, allocFromDeliveryStr, allocTotalStr *string
 
-	sqlCmd := "select allocation_from_bags_sold::string, allocation_from_bags_spread::string, " +
-		"allocation_from_delivery::string, allocation_total::string from allocation_summary where allocation_summary.uid=$1"
-	log.Println("SqlCmd: ", sqlCmd)
-	err := Db.QueryRow(context.Background(), sqlCmd, ownerId).Scan(&allocFromBagsSoldStr, &allocFromBagsSpreadStr, &allocFromDeliveryStr, &allocTotalStr)
+	item, err := frStore.GetAllocation(ctx, ownerId)
 	if err != nil {
-		if err == pgx.ErrNoRows {
+		if errors.Is(err, ErrNotFound) {
 			// Need to make sure these values aren't just empty strings
 			summary.AllocationsFromDelivery = "0"
 			summary.AllocationsFromBagsSold = "0"
@@ -321,29 +323,29 @@ func getAllocationSummaryByOwnerId(ownerId string, summary *OwnerIdSummaryType)
 		return err
 	}
 
-	log.Println("Allocation summary query for: ", ownerId, "alloc: ", allocFromBagsSoldStr)
-	if nil != allocFromBagsSoldStr {
-		allocationsFromBagsSold, err = decimal.NewFromString(*allocFromBagsSoldStr)
+	log.Println("Allocation summary query for: ", ownerId, "alloc: ", item.AllocationsFromBagsSold)
+	if nil != item.AllocationsFromBagsSold {
+		allocationsFromBagsSold, err = decimal.NewFromString(*item.AllocationsFromBagsSold)
 		if err != nil {
 			return err
 		}
 	}
 
-	if nil != allocFromBagsSpreadStr {
-		allocationsFromBagsSpread, err = decimal.NewFromString(*allocFromBagsSpreadStr)
+	if nil != item.AllocationsFromBagsSpread {
+		allocationsFromBagsSpread, err = decimal.NewFromString(*item.AllocationsFromBagsSpread)
 		if err != nil {
 			return err
 		}
 	}
-	if nil != allocFromDeliveryStr {
-		allocationsFromDelivery, err = decimal.NewFromString(*allocFromDeliveryStr)
+	if nil != item.AllocationsFromDelivery {
+		allocationsFromDelivery, err = decimal.NewFromString(*item.AllocationsFromDelivery)
 		if err != nil {
 			return err
 		}
 	}
 
-	if nil != allocFromDeliveryStr {
-		allocationsTotal, err = decimal.NewFromString(*allocTotalStr)
+	if len(item.AllocationsTotal) != 0 {
+		allocationsTotal, err = decimal.NewFromString(item.AllocationsTotal)
 		if err != nil {
 			return err
 		}
@@ -355,44 +357,28 @@ func getAllocationSummaryByOwnerId(ownerId string, summary *OwnerIdSummaryType)
 	return nil
 }
 
-func getAssistedSpreadingOrderCountByOwnerId(ownerId string, summary *OwnerIdSummaryType) error {
-	sqlCmd, args, err := goqu.Dialect("postgres").
-		Select("purchases", "spreaders").
-		From("mulch_orders").
-		LeftJoin(goqu.T("mulch_spreaders"), goqu.On(goqu.Ex{"mulch_orders.order_id": goqu.I("mulch_spreaders.order_id")})).
-		Where(goqu.And(
-			goqu.V(ownerId).Eq(goqu.Any(goqu.L("spreaders"))),
-			goqu.V(ownerId).Neq(goqu.L("order_owner_id")),
-		)).ToSQL()
-	if err != nil {
-		return err
-	}
-	log.Println("SqlCmd: ", sqlCmd)
-	rows, err := Db.Query(context.Background(), sqlCmd, args...)
+func getAssistedSpreadingOrderCountByOwnerId(ctx context.Context, ownerId string, summary *OwnerIdSummaryType) error {
+	// Orders that someone else sold but this owner helped spread
+	orders, err := frStore.GetMulchOrders(ctx, GetMulchOrdersParams{
+		SpreaderId:     ownerId,
+		ExcludeOwnerId: ownerId,
+		GqlFields:      []string{"purchases", "spreaders"},
+	})
 	if err != nil {
 		log.Println("Getting assisted spreading order summary query failed", err)
 		return err
 	}
-	defer rows.Close()
 
 	numAssistedOrders := 0
 	numPersonSpread := decimal.NewFromFloat(0.0)
-	for rows.Next() {
-		var purchases []ProductsType
-		var spreaders []string
-
-		err = rows.Scan(&purchases, &spreaders)
-		if err != nil {
-			log.Println("Reading assisted spreading row failed: ", err)
-			return err
-		}
-		num_spreaders := int64(len(spreaders))
+	for _, order := range orders {
+		num_spreaders := int64(len(order.Spreaders))
 		if num_spreaders == 0 {
 			continue
 		}
 
 		numBags := int64(0)
-		for _, item := range purchases {
+		for _, item := range order.Purchases {
 			if item.ProductId == "spreading" {
 				numBags = int64(item.NumSold)
 				break
@@ -405,37 +391,32 @@ func getAssistedSpreadingOrderCountByOwnerId(ownerId string, summary *OwnerIdSum
 		numAssistedOrders = numAssistedOrders + 1
 	}
 
-	if err := rows.Err(); err != nil {
-		log.Println("Reading Summary rows had an issue: ", err)
-		return err
-	}
-
 	summary.TotalAssistedSpreadingOrders = numAssistedOrders
 	summary.TotalAssistedSpreadingBags = numPersonSpread.RoundBank(2).String()
 	return nil
 }
 
 // //////////////////////////////////////////////////////////////////////////
-func GetSummaryByOwnerId(ownerId string) (OwnerIdSummaryType, error) {
+func GetSummaryByOwnerId(ctx context.Context, ownerId string) (OwnerIdSummaryType, error) {
 	log.Println("Getting Summary for onwerId: ", ownerId)
 
 	summary := OwnerIdSummaryType{}
-	if err := getOrderSummaryByOwnerId(ownerId, &summary); err != nil {
+	if err := getOrderSummaryByOwnerId(ctx, ownerId, &summary); err != nil {
 		log.Println("Get order summary failed", err)
 		return OwnerIdSummaryType{}, err
 	}
 
-	if err := getDeliveryTimecardSummaryByOwnerId(ownerId, &summary); err != nil {
+	if err := getDeliveryTimecardSummaryByOwnerId(ctx, ownerId, &summary); err != nil {
 		log.Println("Summary timecard query failed", err)
 		return OwnerIdSummaryType{}, err
 	}
 
-	if err := getAssistedSpreadingOrderCountByOwnerId(ownerId, &summary); err != nil {
+	if err := getAssistedSpreadingOrderCountByOwnerId(ctx, ownerId, &summary); err != nil {
 		log.Println("Summary of assisted spreading orders query failed", err)
 		return OwnerIdSummaryType{}, err
 	}
 
-	if err := getAllocationSummaryByOwnerId(ownerId, &summary); err != nil {
+	if err := getAllocationSummaryByOwnerId(ctx, ownerId, &summary); err != nil {
 		log.Println("Allocation summary query failed: ", err)
 		return OwnerIdSummaryType{}, err
 	}
@@ -463,54 +444,68 @@ type TroopSummaryType struct {
 }
 
 // //////////////////////////////////////////////////////////////////////////
-func GetTroopSummary(numTopSellers int) (TroopSummaryType, error) {
+func GetTroopSummary(ctx context.Context, numTopSellers int) (TroopSummaryType, error) {
 	log.Println("Getting Troop Summary with this many top sellers: ", numTopSellers)
 
-	sqlCmd := "select users.first_name, users.last_name, users.group_id, sum(total_amount_collected)::string from mulch_orders" +
-		" inner join users on (mulch_orders.order_owner_id = users.id) where" +
-		" total_amount_collected is not null group by order_owner_id, users.first_name, users.last_name, users.group_id"
+	users, err := frStore.GetUsers(ctx, GetUsersParams{GqlFields: []string{"id", "firstName", "lastName", "group"}})
+	if err != nil {
+		log.Println("Troop summary users query failed", err)
+		return TroopSummaryType{}, err
+	}
+	usersById := make(map[string]UserInfo)
+	for _, user := range users {
+		usersById[user.Id] = user
+	}
 
-	rows, err := Db.Query(context.Background(), sqlCmd)
+	orders, err := frStore.GetMulchOrders(ctx, GetMulchOrdersParams{GqlFields: []string{"ownerId", "amountTotalCollected"}})
 	if err != nil {
 		log.Println("Troop summary query failed", err)
 		return TroopSummaryType{}, err
 	}
-	defer rows.Close()
+
+	// Only orders owned by known users count towards the troop
+	ownerIds := []string{}
+	ownerTotals := make(map[string]decimal.Decimal)
+	for _, order := range orders {
+		if order.AmountTotalCollected == nil {
+			continue
+		}
+		if _, ok := usersById[order.OwnerId]; !ok {
+			continue
+		}
+		total, err := decimal.NewFromString(*order.AmountTotalCollected)
+		if err != nil {
+			return TroopSummaryType{}, err
+		}
+		ownerTotal, is_present := ownerTotals[order.OwnerId]
+		if !is_present {
+			ownerIds = append(ownerIds, order.OwnerId)
+		}
+		ownerTotals[order.OwnerId] = ownerTotal.Add(total)
+	}
 
 	troopTotal := decimal.NewFromInt(0)
 	groupTotals := make(map[string]decimal.Decimal)
 	topSellers := []TopSellerType{}
 
-	for rows.Next() {
-		var firstName string
-		var lastName string
-		var group string
-		var totalAsStr string
+	for _, ownerId := range ownerIds {
+		user := usersById[ownerId]
+		total := ownerTotals[ownerId]
 
-		err = rows.Scan(&firstName, &lastName, &group, &totalAsStr)
-		if err != nil {
-			log.Println("Reading Summary row failed: ", err)
-			return TroopSummaryType{}, err
-		}
-		total, err := decimal.NewFromString(totalAsStr)
-		if err != nil {
-			return TroopSummaryType{}, err
-		}
 		troopTotal = troopTotal.Add(total)
-		group_val, is_present := groupTotals[group]
+		group_val, is_present := groupTotals[user.Group]
 		if is_present {
-			groupTotals[group] = group_val.Add(total)
+			groupTotals[user.Group] = group_val.Add(total)
 		} else {
-			groupTotals[group] = total
+			groupTotals[user.Group] = total
 		}
 
-		topSellers = append(topSellers, TopSellerType{Name: fmt.Sprintf("%s %s", firstName, lastName), TotalAmountCollected: totalAsStr})
+		topSellers = append(topSellers, TopSellerType{
+			Name:                 fmt.Sprintf("%s %s", user.FirstName, user.LastName),
+			TotalAmountCollected: total.StringFixedBank(4),
+		})
 	}
 
-	if err := rows.Err(); err != nil {
-		log.Println("Reading Summary rows had an issue: ", err)
-		return TroopSummaryType{}, err
-	}
 	groupSummary := []GroupSummaryType{}
 	for k, v := range groupTotals {
 		groupSummary = append(groupSummary, GroupSummaryType{GroupId: k, TotalAmountCollected: v.String()})
@@ -547,49 +542,39 @@ type NeighborhoodSummaryType struct {
 }
 
 // //////////////////////////////////////////////////////////////////////////
-func GetNeighborhoodSummary() ([]NeighborhoodSummaryType, error) {
+func GetNeighborhoodSummary(ctx context.Context) ([]NeighborhoodSummaryType, error) {
 	log.Println("Getting Neighborhood Summary")
 
-	sqlCmd := "select customer_neighborhood, count(*) from mulch_orders group by customer_neighborhood"
-
-	rows, err := Db.Query(context.Background(), sqlCmd)
+	orders, err := frStore.GetMulchOrders(ctx, GetMulchOrdersParams{GqlFields: []string{"customer"}})
 	if err != nil {
 		log.Println("Neighborhood summary query failed", err)
 		return nil, err
 	}
-	defer rows.Close()
 
 	results := []NeighborhoodSummaryType{}
-
-	for rows.Next() {
-		result := NeighborhoodSummaryType{}
-
-		err = rows.Scan(&result.Neighborhood, &result.NumOrders)
-		if err != nil {
-			log.Println("Reading Summary row failed: ", err)
-			return nil, err
+	resultIdxs := make(map[string]int)
+	for _, order := range orders {
+		idx, ok := resultIdxs[order.Customer.Neighborhood]
+		if !ok {
+			idx = len(results)
+			resultIdxs[order.Customer.Neighborhood] = idx
+			results = append(results, NeighborhoodSummaryType{Neighborhood: order.Customer.Neighborhood})
 		}
-
-		results = append(results, result)
-	}
-
-	if err := rows.Err(); err != nil {
-		log.Println("Reading Summary rows had an issue: ", err)
-		return nil, err
+		results[idx].NumOrders++
 	}
 	return results, nil
 }
 
 // //////////////////////////////////////////////////////////////////////////
 type CustomerType struct {
-	Name         string
-	Addr1        string
-	Addr2        *string
-	City         *string
-	Zipcode      *int
-	Phone        string
-	Email        *string
-	Neighborhood string
+	Name         string  `json:"name"`
+	Addr1        string  `json:"addr1"`
+	Addr2        *string `json:"addr2"`
+	City         *string `json:"city"`
+	Zipcode      *int    `json:"zipcode"`
+	Phone        string  `json:"phone"`
+	Email        *string `json:"email"`
+	Neighborhood string  `json:"neighborhood"`
 }
 
 // //////////////////////////////////////////////////////////////////////////
@@ -601,23 +586,25 @@ type ProductsType struct {
 
 // //////////////////////////////////////////////////////////////////////////
 type MulchOrderType struct {
-	OrderId                   string
-	OwnerId                   string
-	LastModifiedTime          string
-	SpecialInstructions       *string
-	AmountFromDonations       *string
-	AmountFromPurchases       *string
-	AmountFromCashCollected   *string
-	AmountFromChecksCollected *string
-	AmountTotalCollected      *string
-	CheckNumbers              *string
-	WillCollectMoneyLater     *bool
-	IsVerified                *bool
-	Spreaders                 []string
-	Customer                  CustomerType
-	Purchases                 []ProductsType
-	DeliveryId                *int // Not in archived GraphQL
-	Comments                  *string
+	OrderId                   string           `json:"orderId"`
+	OwnerId                   string           `json:"ownerId"`
+	LastModifiedTime          string           `json:"lastModifiedTime"`
+	SpecialInstructions       *string          `json:"specialInstructions"`
+	AmountFromDonations       *string          `json:"amountFromDonations"`
+	AmountFromPurchases       *string          `json:"amountFromPurchases"`
+	AmountFromCashCollected   *string          `json:"amountFromCashCollected"`
+	AmountFromChecksCollected *string          `json:"amountFromChecksCollected"`
+	AmountTotalCollected      *string          `json:"amountTotalCollected"`
+	CheckNumbers              *string          `json:"checkNumbers"`
+	Checks                    []OrderCheckType `json:"checks"`
+	KnownAddrId               *string          `json:"knownAddrId"`
+	WillCollectMoneyLater     *bool            `json:"willCollectMoneyLater"`
+	IsVerified                *bool            `json:"isVerified"`
+	Spreaders                 []string         `json:"spreaders"`
+	Customer                  CustomerType     `json:"customer"`
+	Purchases                 []ProductsType   `json:"purchases"`
+	DeliveryId                *int             `json:"deliveryId"` // Not in archived GraphQL
+	Comments                  *string          `json:"comments"`
 }
 
 // //////////////////////////////////////////////////////////////////////////
@@ -626,6 +613,9 @@ type MulchOrderMoneyCollectedType struct {
 	AmountTotalCollected           *string
 	AmountTotalFromCashCollected   *string
 	AmountTotalFromChecksCollected *string
+	// What has gone into bank deposits
+	AmountTotalFromCashDeposited   *string
+	AmountTotalFromChecksDeposited *string
 	DeliveryId                     *int
 }
 
@@ -635,244 +625,57 @@ type GetMulchOrdersParams struct {
 	ExcludeOwnerId        string
 	SpreaderId            string
 	DoGetSpreadOrdersOnly bool
-	GqlFields             []string
-}
-
-// //////////////////////////////////////////////////////////////////////////
-func GetMulchOrdersMoneyCollected(params GetMulchOrdersParams) []MulchOrderMoneyCollectedType {
-	////////////////////////////////////////////////////////////////////////////
-	//
-	gql2sql := func(orderOutput *MulchOrderMoneyCollectedType) ([]string, []interface{}, string) {
-		sqlFields := []string{}
-		inputs := []interface{}{}
-		joinSql := ""
-		for _, gqlField := range params.GqlFields {
-			// log.Println(gqlField)
-			switch gqlField {
-			case "ownerId":
-				inputs = append(inputs, &orderOutput.OwnerId)
-				sqlFields = append(sqlFields, "order_owner_id")
-			case "deliveryId":
-				inputs = append(inputs, &orderOutput.DeliveryId)
-				sqlFields = append(sqlFields, "delivery_id")
-			case "amountTotalCollected":
-				inputs = append(inputs, &orderOutput.AmountTotalCollected)
-				sqlFields = append(sqlFields, "SUM(total_amount_collected)::string")
-			case "amountTotalFromCashCollected":
-				inputs = append(inputs, &orderOutput.AmountTotalFromCashCollected)
-				sqlFields = append(sqlFields, "SUM(cash_amount_collected)::string")
-			case "amountTotalFromChecksCollected":
-				inputs = append(inputs, &orderOutput.AmountTotalFromChecksCollected)
-				sqlFields = append(sqlFields, "SUM(check_amount_collected)::string")
-			default:
-				// log.Println("Do not know how to handle mulch orders money collected GraphQL Field: ", gqlField)
-			}
-		}
-		return sqlFields, inputs, joinSql
-	}
-
-	order := MulchOrderMoneyCollectedType{}
-	sqlFields, _, joinSql := gql2sql(&order)
+	Neighborhood          string
+	DeliveryId            *int
+	IsVerified            *bool
+	WillCollectMoneyLater *bool
+	ModifiedAfter         *time.Time
+	ModifiedBefore        *time.Time
+	// Case insensitive match against customer name and address
+	Search string
+	// Gql field name to sort by (see mulchOrderSortColumns).  Ties are broken by orderId
+	SortBy           string
+	IsSortDescending bool
+	// 0 means no limit
+	Limit int
+	// Only the orders after AfterKey and before BeforeKey in the sort order
+	// (keyset paging)
+	AfterKey  *MulchOrderSortKey
+	BeforeKey *MulchOrderSortKey
+	GqlFields []string
+}
 
+// //////////////////////////////////////////////////////////////////////////
+// If some rows couldn't be read the rest are returned along with a
+// RowScanError.  Same for GetMulchOrders and GetMulchTimecards.
+func GetMulchOrdersMoneyCollected(ctx context.Context, params GetMulchOrdersParams) ([]MulchOrderMoneyCollectedType, error) {
 	if len(params.OwnerId) == 0 {
 		log.Println("Retrieving mulch orders money collected.")
 	} else {
 		log.Println("Retrieving mulch orders money collected. OwnerId: ", params.OwnerId)
 	}
 
-	doQuery := func() (pgx.Rows, error) {
-		sqlCmd := fmt.Sprintf("select %s from mulch_orders %s", strings.Join(sqlFields, ","), joinSql)
-		if len(params.OwnerId) != 0 {
-			sqlCmd = sqlCmd + " where order_owner_id=$1 group by order_owner_id, delivery_id"
-			log.Println("SqlCmd: ", sqlCmd)
-			return Db.Query(context.Background(), sqlCmd, params.OwnerId)
-		} else {
-			sqlCmd = sqlCmd + " group by order_owner_id, delivery_id"
-			log.Println("SqlCmd: ", sqlCmd)
-			return Db.Query(context.Background(), sqlCmd)
-		}
-	}
-
-	orders := []MulchOrderMoneyCollectedType{}
-	rows, err := doQuery()
+	orders, err := frStore.GetMulchOrdersMoneyCollected(ctx, params)
 	if err != nil {
-		log.Println("Mulch Orders query failed", err)
-		return orders
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		order := MulchOrderMoneyCollectedType{}
-		_, inputs, _ := gql2sql(&order)
-		err = rows.Scan(inputs...)
-		if err != nil {
-			log.Println("Reading mulch order money collection row failed: ", err)
-			continue
-		}
-		orders = append(orders, order)
-	}
-
-	if err := rows.Err(); err != nil {
-		log.Println("Reading mulch order money collection rows had an issue: ", err)
-		return []MulchOrderMoneyCollectedType{}
-	}
-	return orders
-}
-
-// //////////////////////////////////////////////////////////////////////////
-func mulchOrderGql2SqlMap(gqlFields []string, orderOutput *MulchOrderType, queryBuilder *goqu.SelectDataset) (*goqu.SelectDataset, []interface{}) {
-	sqlFields := []interface{}{}
-	inputs := []interface{}{}
-	for _, gqlField := range gqlFields {
-		// log.Println(gqlField)
-		switch gqlField {
-		case "orderId":
-			inputs = append(inputs, &orderOutput.OrderId)
-			sqlFields = append(sqlFields, goqu.L("mulch_orders.order_id"))
-		case "ownerId":
-			inputs = append(inputs, &orderOutput.OwnerId)
-			sqlFields = append(sqlFields, "order_owner_id")
-		case "amountTotalCollected":
-			inputs = append(inputs, &orderOutput.AmountTotalCollected)
-			sqlFields = append(sqlFields, goqu.L("total_amount_collected::string"))
-		case "purchases":
-			inputs = append(inputs, &orderOutput.Purchases)
-			sqlFields = append(sqlFields, goqu.L("purchases::jsonb"))
-		case "last_modified_time":
-			inputs = append(inputs, &orderOutput.LastModifiedTime)
-			sqlFields = append(sqlFields, "last_modified_time")
-		case "comments":
-			inputs = append(inputs, &orderOutput.Comments)
-			sqlFields = append(sqlFields, "comments")
-		case "specialInstructions":
-			inputs = append(inputs, &orderOutput.SpecialInstructions)
-			sqlFields = append(sqlFields, "special_instructions")
-		case "amountFromDonations":
-			inputs = append(inputs, &orderOutput.AmountFromDonations)
-			sqlFields = append(sqlFields, goqu.L("amount_from_donations::string"))
-		case "amountFromPurchases":
-			inputs = append(inputs, &orderOutput.AmountFromPurchases)
-			sqlFields = append(sqlFields, goqu.L("amount_from_purchases::string"))
-		case "amountFromCashCollected":
-			inputs = append(inputs, &orderOutput.AmountFromCashCollected)
-			sqlFields = append(sqlFields, goqu.L("cash_amount_collected::string"))
-		case "amountFromChecksCollected":
-			inputs = append(inputs, &orderOutput.AmountFromChecksCollected)
-			sqlFields = append(sqlFields, goqu.L("check_amount_collected::string"))
-		case "checkNumbers":
-			inputs = append(inputs, &orderOutput.CheckNumbers)
-			sqlFields = append(sqlFields, goqu.L("check_numbers::string"))
-		case "deliveryId":
-			inputs = append(inputs, &orderOutput.DeliveryId)
-			sqlFields = append(sqlFields, "delivery_id")
-		case "willCollectMoneyLater":
-			inputs = append(inputs, &orderOutput.WillCollectMoneyLater)
-			sqlFields = append(sqlFields, "will_collect_money_later")
-		case "isVerified":
-			inputs = append(inputs, &orderOutput.IsVerified)
-			sqlFields = append(sqlFields, "is_verified")
-		case "spreaders":
-			inputs = append(inputs, &orderOutput.Spreaders)
-			sqlFields = append(sqlFields, "spreaders")
-			if queryBuilder != nil {
-				queryBuilder = queryBuilder.LeftJoin(goqu.T("mulch_spreaders"),
-					goqu.On(goqu.Ex{"mulch_orders.order_id": goqu.I("mulch_spreaders.order_id")}),
-				)
-			}
-		case "customer":
-			inputs = append(inputs, &orderOutput.Customer.Name)
-			sqlFields = append(sqlFields, "customer_name")
-			inputs = append(inputs, &orderOutput.Customer.Addr1)
-			sqlFields = append(sqlFields, "customer_addr1")
-			inputs = append(inputs, &orderOutput.Customer.Addr2)
-			sqlFields = append(sqlFields, "customer_addr2")
-			inputs = append(inputs, &orderOutput.Customer.City)
-			sqlFields = append(sqlFields, "customer_city")
-			inputs = append(inputs, &orderOutput.Customer.Zipcode)
-			sqlFields = append(sqlFields, "customer_zipcode")
-			inputs = append(inputs, &orderOutput.Customer.Phone)
-			sqlFields = append(sqlFields, "customer_phone")
-			inputs = append(inputs, &orderOutput.Customer.Email)
-			sqlFields = append(sqlFields, "customer_email")
-			inputs = append(inputs, &orderOutput.Customer.Neighborhood)
-			sqlFields = append(sqlFields, "customer_neighborhood")
-		default:
-			log.Println("Do not know how to handle mulch order GraphQL Field: ", gqlField)
-		}
+		log.Println("Mulch Orders money collected query failed", err)
+		return orders, err
 	}
-	if queryBuilder != nil {
-		queryBuilder = queryBuilder.Select(sqlFields...)
-	}
-	return queryBuilder, inputs
+	return orders, nil
 }
 
 // //////////////////////////////////////////////////////////////////////////
-func GetMulchOrders(params GetMulchOrdersParams) []MulchOrderType {
-	order := MulchOrderType{}
-
-	doQuery := func() (pgx.Rows, error) {
-		queryBuilder := goqu.Dialect("postgres").From("mulch_orders")
-
-		queryBuilder, _ = mulchOrderGql2SqlMap(params.GqlFields, &order, queryBuilder)
-
-		if len(params.OwnerId) != 0 {
-			log.Println("Retrieving mulch orders. OwnerId: ", params.OwnerId)
-			queryBuilder = queryBuilder.Where(goqu.Ex{"order_owner_id": params.OwnerId})
-		}
-
-		if len(params.ExcludeOwnerId) != 0 {
-			log.Println("Retrieving mulch orders that exclude OwnerId: ", params.ExcludeOwnerId)
-			queryBuilder = queryBuilder.Where(goqu.Ex{"order_owner_id": goqu.Op{"neq": params.ExcludeOwnerId}})
-		}
-
-		if params.DoGetSpreadOrdersOnly {
-			queryBuilder = queryBuilder.Where(goqu.L(`purchases @> '[{"productId": "spreading"}]'`))
-		}
-
-		if len(params.SpreaderId) != 0 {
-			// if spreaders aren't in the GQL query then we need to make the join here.
-			if !slices.Contains(params.GqlFields, "spreaders") {
-				queryBuilder = queryBuilder.LeftJoin(goqu.T("mulch_spreaders"),
-					goqu.On(goqu.Ex{"mulch_orders.order_id": goqu.I("mulch_spreaders.order_id")}),
-				)
-			}
-
-			queryBuilder = queryBuilder.Where(goqu.V(params.SpreaderId).Eq(goqu.Any(goqu.L("spreaders"))))
-		}
-
-		sqlCmd, args, err := queryBuilder.ToSQL()
-		if err != nil {
-			return nil, err
-		}
-		log.Println("SqlCmd: ", sqlCmd)
-		return Db.Query(context.Background(), sqlCmd, args...)
-	}
-
-	orders := []MulchOrderType{}
-	rows, err := doQuery()
+func GetMulchOrders(ctx context.Context, params GetMulchOrdersParams) ([]MulchOrderType, error) {
+	orders, err := frStore.GetMulchOrders(ctx, params)
 	if err != nil {
 		log.Println("Mulch Orders query failed", err)
-		return orders
+		return orders, err
 	}
-	defer rows.Close()
-
-	// Process query results
-	for rows.Next() {
-		order := MulchOrderType{}
-		_, inputs := mulchOrderGql2SqlMap(params.GqlFields, &order, nil)
-		err = rows.Scan(inputs...)
-		if err != nil {
-			log.Println("Reading mulch order row failed: ", err)
-			continue
+	if slices.Contains(params.GqlFields, "checks") {
+		if err := fillCheckDepositStatus(ctx, frStore, orders); err != nil {
+			return orders, err
 		}
-		orders = append(orders, order)
-	}
-
-	if err := rows.Err(); err != nil {
-		log.Println("Reading mulch order rows had an issue: ", err)
-		return []MulchOrderType{}
 	}
-	return orders
+	return orders, nil
 }
 
 // //////////////////////////////////////////////////////////////////////////
@@ -882,187 +685,35 @@ type GetMulchOrderParams struct {
 }
 
 // //////////////////////////////////////////////////////////////////////////
-func GetMulchOrder(params GetMulchOrderParams) MulchOrderType {
+func GetMulchOrder(ctx context.Context, params GetMulchOrderParams) (MulchOrderType, error) {
 	log.Println("Retrieving mulch order. OrderId: ", params.OrderId)
 
-	order := MulchOrderType{}
-	queryBuilder := goqu.Dialect("postgres").From("mulch_orders").Where(goqu.Ex{"mulch_orders.order_id": params.OrderId})
-	queryBuilder, inputs := mulchOrderGql2SqlMap(params.GqlFields, &order, queryBuilder)
-
-	sqlCmd, args, err := queryBuilder.ToSQL()
+	order, err := frStore.GetMulchOrder(ctx, params)
 	if err != nil {
-		log.Println("Mulch order query builder for: ", params.OrderId, " failed", err)
-		return order
-	}
-	log.Println("SqlCmd: ", sqlCmd)
-	if err = Db.QueryRow(context.Background(), sqlCmd, args...).Scan(inputs...); err != nil {
 		log.Println("Mulch order query for: ", params.OrderId, " failed", err)
+		if errors.Is(err, ErrNotFound) {
+			return order, &NotFoundError{Message: fmt.Sprintf("order %s was not found", params.OrderId)}
+		}
+		return order, err
+	}
+	if slices.Contains(params.GqlFields, "checks") {
+		if err := fillCheckDepositStatus(ctx, frStore, []MulchOrderType{order}); err != nil {
+			return order, err
+		}
 	}
 	// log.Println("Purchases: ", order.Purchases)
-	return order
+	return order, nil
 }
 
 // //////////////////////////////////////////////////////////////////////////
-func OrderType2Sql(order MulchOrderType) ([]string, []string, []interface{}) {
-	order.LastModifiedTime = time.Now().UTC().Format(time.RFC3339)
-	values := []interface{}{}
-	valIdxs := []string{}
-	valIdx := 1
-	sqlFields := []string{}
-
-	// Sometimes orders come in with "0" for amount fields and we do
-	// not want to put those in the database so this strips them out
-	strip_0_from_str := func(amount *string) {
-		if *amount == "0" {
-			values = append(values, nil)
-			valIdxs = append(valIdxs, fmt.Sprintf("$%d", valIdx))
-		} else {
-			values = append(values, *amount)
-			valIdxs = append(valIdxs, fmt.Sprintf("$%d::decimal", valIdx))
-		}
-		valIdx++
-	}
-
-	// Do OrderID first because it is always there
-	sqlFields = append(sqlFields, "order_id")
-	values = append(values, order.OrderId)
-	valIdxs = append(valIdxs, fmt.Sprintf("$%d::uuid", valIdx))
-	valIdx++
-
-	sqlFields = append(sqlFields, "last_modified_time")
-	values = append(values, order.LastModifiedTime)
-	valIdxs = append(valIdxs, fmt.Sprintf("$%d::timestamp", valIdx))
-	valIdx++
-
-	if len(order.OwnerId) != 0 {
-		sqlFields = append(sqlFields, "order_owner_id")
-		values = append(values, order.OwnerId)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
-		valIdx++
-	}
-	if len(order.Purchases) != 0 {
-		sqlFields = append(sqlFields, "purchases")
-		values = append(values, order.Purchases)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::jsonb", valIdx))
-		valIdx++
-	}
-	if nil != order.Comments {
-		sqlFields = append(sqlFields, "comments")
-		values = append(values, *order.Comments)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
-		valIdx++
-	}
-	if nil != order.SpecialInstructions {
-		sqlFields = append(sqlFields, "special_instructions")
-		values = append(values, *order.SpecialInstructions)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
-		valIdx++
-	}
-	if nil != order.AmountFromDonations {
-		sqlFields = append(sqlFields, "amount_from_donations")
-		strip_0_from_str(order.AmountFromDonations)
-	}
-	if nil != order.AmountFromPurchases {
-		sqlFields = append(sqlFields, "amount_from_purchases")
-		strip_0_from_str(order.AmountFromPurchases)
-	}
-	if nil != order.AmountFromCashCollected {
-		sqlFields = append(sqlFields, "cash_amount_collected")
-		strip_0_from_str(order.AmountFromCashCollected)
-	}
-	if nil != order.AmountFromChecksCollected {
-		sqlFields = append(sqlFields, "check_amount_collected")
-		strip_0_from_str(order.AmountFromChecksCollected)
-	}
-	if nil != order.AmountTotalCollected {
-		sqlFields = append(sqlFields, "total_amount_collected")
-		strip_0_from_str(order.AmountTotalCollected)
-	}
-	if nil != order.CheckNumbers {
-		sqlFields = append(sqlFields, "check_numbers")
-		values = append(values, *order.CheckNumbers)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
-		valIdx++
-	}
-	if nil != order.DeliveryId {
-		sqlFields = append(sqlFields, "delivery_id")
-		values = append(values, *order.DeliveryId)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::int", valIdx))
-		valIdx++
-	}
-	if nil != order.WillCollectMoneyLater {
-		sqlFields = append(sqlFields, "will_collect_money_later")
-		values = append(values, *order.WillCollectMoneyLater)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::bool", valIdx))
-		valIdx++
-	}
-	if nil != order.IsVerified {
-		sqlFields = append(sqlFields, "is_verified")
-		values = append(values, *order.IsVerified)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::bool", valIdx))
-		valIdx++
-	}
-	if len(order.Customer.Name) != 0 {
-		sqlFields = append(sqlFields, "customer_name")
-		values = append(values, order.Customer.Name)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
-		valIdx++
-	}
-	if len(order.Customer.Addr1) != 0 {
-		sqlFields = append(sqlFields, "customer_addr1")
-		values = append(values, order.Customer.Addr1)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
-		valIdx++
-	}
-	if nil != order.Customer.Addr2 {
-		sqlFields = append(sqlFields, "customer_addr2")
-		values = append(values, *order.Customer.Addr2)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
-		valIdx++
-	}
-	if nil != order.Customer.City {
-		sqlFields = append(sqlFields, "customer_city")
-		values = append(values, *order.Customer.City)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
-		valIdx++
-	}
-	if nil != order.Customer.Zipcode {
-		sqlFields = append(sqlFields, "customer_zipcode")
-		values = append(values, *order.Customer.Zipcode)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::int", valIdx))
-		valIdx++
-	}
-	if len(order.Customer.Phone) != 0 {
-		sqlFields = append(sqlFields, "customer_phone")
-		values = append(values, order.Customer.Phone)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
-		valIdx++
-	}
-	if nil != order.Customer.Email {
-		sqlFields = append(sqlFields, "customer_email")
-		values = append(values, *order.Customer.Email)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
-		valIdx++
-	}
-	if len(order.Customer.Neighborhood) != 0 {
-		sqlFields = append(sqlFields, "customer_neighborhood")
-		values = append(values, order.Customer.Neighborhood)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
-		valIdx++
-	}
-
-	return sqlFields, valIdxs, values
-}
-
-// //////////////////////////////////////////////////////////////////////////
-func CreateMulchOrder(ctx context.Context, order MulchOrderType) (string, error) {
+func CreateMulchOrder(ctx context.Context, order MulchOrderType, doOverrideLock bool) (string, error) {
 	log.Println("Creating Order: ", order)
 
 	if len(order.OrderId) == 0 {
-		return "", errors.New("orderId must be provided for a new record")
+		return "", newValidationError("orderId", "orderId must be provided for a new record")
 	}
 	if len(order.OwnerId) == 0 {
-		return "", errors.New("ownerId must be provided for a new record")
+		return "", newValidationError("ownerId", "ownerId must be provided for a new record")
 	}
 
 	if err := verifyUidAllowedFromCtx(ctx, order.OwnerId); err != nil {
@@ -1070,102 +721,201 @@ func CreateMulchOrder(ctx context.Context, order MulchOrderType) (string, error)
 	}
 
 	if len(order.Customer.Neighborhood) == 0 || order.Customer.Neighborhood == "none" {
-		return "", errors.New("neighborhood must be provided for a new record")
+		return "", newValidationError("customer.neighborhood", "neighborhood must be provided for a new record")
 	}
 	if len(order.Customer.Name) == 0 {
-		return "", errors.New("name must be provided for a new record")
+		return "", newValidationError("customer.name", "name must be provided for a new record")
 	}
 	if len(order.Customer.Addr1) == 0 {
-		return "", errors.New("address 1 must be provided for a new record")
+		return "", newValidationError("customer.addr1", "address 1 must be provided for a new record")
 	}
 	if len(order.Customer.Phone) == 0 {
-		return "", errors.New("phone must be provided for a new record")
+		return "", newValidationError("customer.phone", "phone must be provided for a new record")
+	}
+	if order.AmountTotalCollected == nil || len(*order.AmountTotalCollected) == 0 {
+		return "", newValidationError("amountTotalCollected", "order purchases are empty and must be provided for a new record")
+	}
+
+	if err := validateOrderPricing(ctx, order); err != nil {
+		return "", err
+	}
+	keepBouncedChecks(nil, order.Checks)
+	if err := validateOrderChecks(order); err != nil {
+		return "", err
 	}
-	if len(*order.AmountTotalCollected) == 0 {
-		return "", errors.New("order purchases are empty and must be provided for a new record")
+	if len(order.Checks) != 0 && (order.CheckNumbers == nil || len(*order.CheckNumbers) == 0) {
+		checkNumbers := joinCheckNumbers(order.Checks)
+		order.CheckNumbers = &checkNumbers
 	}
-	sqlFields, valIdxs, values := OrderType2Sql(order)
 
-	sqlCmd := fmt.Sprintf("insert into mulch_orders(%s) values (%s)",
-		strings.Join(sqlFields, ","), strings.Join(valIdxs, ","))
+	if err := verifyOrderChangeAllowed(ctx, frStore, nil, &order, doOverrideLock); err != nil {
+		return "", err
+	}
 
-	log.Println("Creating Order sqlCmd: ", sqlCmd)
-	_, err := Db.Exec(context.Background(), sqlCmd, values...)
+	isGeocodeNeeded := setOrderKnownAddr(ctx, frStore, &order)
+
+	order.LastModifiedTime = makeLastModifiedTime()
+	err := frStore.InTx(ctx, func(tx FrStore) error {
+		if err := tx.InsertMulchOrder(ctx, order); err != nil {
+			return err
+		}
+		return recordMulchOrderAuditEntry(ctx, tx, "createMulchOrder", nil, order.OrderId)
+	})
 	if err != nil {
 		return "", err
 	}
+	if isGeocodeNeeded {
+		geocodeOrderAddrLater(order.OrderId)
+	}
 
 	return order.OrderId, nil
 }
 
 // //////////////////////////////////////////////////////////////////////////
-func UpdateMulchOrder(ctx context.Context, order MulchOrderType) (bool, error) {
-	log.Println("Updating Order: ", order)
+// If expectedLastModifiedTime is given the update fails with a ConflictError
+// when the order has been changed since then.
+func UpdateMulchOrder(ctx context.Context, order MulchOrderType, gqlFields []string,
+	expectedLastModifiedTime string, doOverrideLock bool) (bool, error) {
+	log.Println("Updating Order: ", order, " fields: ", gqlFields)
 
 	if len(order.OrderId) == 0 {
-		return false, errors.New("orderId must be provided for updated record")
-	}
-	if len(order.OwnerId) == 0 {
-		return false, errors.New("ownerId must be provided for updated record")
+		return false, newValidationError("orderId", "orderId must be provided for updated record")
 	}
 
-	if err := verifyUidAllowedFromCtx(ctx, order.OwnerId); err != nil {
+	// Need what is there now to know what is actually changing
+	existingOrder, err := frStore.GetMulchOrder(ctx, GetMulchOrderParams{OrderId: order.OrderId, GqlFields: allMulchOrderGqlFields})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return false, &NotFoundError{Message: fmt.Sprintf("order %s can not be updated: %s", order.OrderId, err)}
+		}
 		return false, err
 	}
 
-	sqlFields, valIdxs, values := OrderType2Sql(order)
+	if err := verifyUidAllowedFromCtx(ctx, existingOrder.OwnerId); err != nil {
+		return false, err
+	}
 
-	sqlCmd := fmt.Sprintf("insert into mulch_orders(%s) values (%s)",
-		strings.Join(sqlFields, ","), strings.Join(valIdxs, ","))
+	// Only the fields that were given are changed. orderId can't be changed so skip it
+	updateFields := []string{}
+	for _, field := range gqlFields {
+		if slices.Contains(allMulchOrderUpdateFields, field) && !slices.Contains(updateFields, field) {
+			updateFields = append(updateFields, field)
+		}
+	}
 
-	// Start Database Operations
-	trxn, err := Db.Begin(context.Background())
-	if err != nil {
+	updatedOrder := existingOrder
+	if err := applyMulchOrderFields(&updatedOrder, order, updateFields); err != nil {
 		return false, err
 	}
+	if slices.Contains(updateFields, "checks") {
+		keepBouncedChecks(existingOrder.Checks, updatedOrder.Checks)
+		if !slices.Contains(updateFields, "checkNumbers") {
+			checkNumbers := joinCheckNumbers(updatedOrder.Checks)
+			updatedOrder.CheckNumbers = &checkNumbers
+			updateFields = append(updateFields, "checkNumbers")
+		}
+	}
+	if missing := getMissingMulchOrderFields(updatedOrder); len(missing) != 0 {
+		return false, &ValidationError{Message: fmt.Sprintf("%s can not be cleared", strings.Join(missing, ", ")), Fields: missing}
+	}
 
-	log.Println("Deleting order for update: ", order.OrderId)
-	_, err = trxn.Exec(context.Background(), "delete from mulch_orders where order_id = $1", order.OrderId)
-	if err != nil {
-		trxn.Rollback(context.Background())
-		log.Println("Failed to delete order for updating: ", order.OrderId, " failed because: ", err)
-		return false, err
+	// Moving the order to someone else has to be allowed for them too
+	if updatedOrder.OwnerId != existingOrder.OwnerId {
+		if err := verifyUidAllowedFromCtx(ctx, updatedOrder.OwnerId); err != nil {
+			return false, err
+		}
 	}
-	log.Println("Updating(by inserting) Order sqlCmd: ", sqlCmd)
-	_, err = trxn.Exec(context.Background(), sqlCmd, values...)
-	if err != nil {
-		trxn.Rollback(context.Background())
+
+	if err := verifyOrderChangeAllowed(ctx, frStore, &existingOrder, &updatedOrder, doOverrideLock); err != nil {
 		return false, err
 	}
 
-	log.Println("About to make a commitment")
-	err = trxn.Commit(context.Background())
+	// Orders priced before a product price change are left alone unless what
+	// they cost is being changed
+	if slices.ContainsFunc(updateFields, func(field string) bool { return slices.Contains(mulchOrderPricingFields, field) }) {
+		if err := validateOrderPricing(ctx, updatedOrder); err != nil {
+			return false, err
+		}
+		if err := validateOrderChecks(updatedOrder); err != nil {
+			return false, err
+		}
+	}
+
+	// The order is linked to where its new address is
+	isGeocodeNeeded := false
+	if slices.ContainsFunc(updateFields, func(field string) bool {
+		return field == "customer.addr1" || field == "customer.city" || field == "customer.zipcode"
+	}) {
+		isGeocodeNeeded = setOrderKnownAddr(ctx, frStore, &updatedOrder)
+		updateFields = append(updateFields, "knownAddrId")
+	}
+
+	updatedOrder.LastModifiedTime = makeLastModifiedTime()
+	err = frStore.InTx(ctx, func(tx FrStore) error {
+		// The revision and audit entry are of the order as it is in tx since it
+		// could have changed after it was read above
+		txOrder, err := tx.GetMulchOrder(ctx, GetMulchOrderParams{OrderId: order.OrderId, GqlFields: mulchOrderRevisionGqlFields})
+		if errors.Is(err, ErrNotFound) {
+			return &NotFoundError{Message: fmt.Sprintf("order %s can not be updated: %s", order.OrderId, err)}
+		}
+		if err != nil {
+			return err
+		}
+		if err := saveMulchOrderRevision(ctx, tx, txOrder, "updateMulchOrder"); err != nil {
+			return err
+		}
+		// The version is checked by the update itself so nothing can change it in between
+		err = tx.UpdateMulchOrder(ctx, updatedOrder, updateFields, expectedLastModifiedTime)
+		if errors.Is(err, ErrStaleVersion) {
+			err = newConflictError("mulchOrder", order.OrderId, txOrder.LastModifiedTime, txOrder)
+			log.Println("Order: ", order.OrderId, " update failed: ", err)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		// The audit entries of orders don't have their spreaders
+		txOrder.Spreaders = nil
+		return recordMulchOrderAuditEntry(ctx, tx, "updateMulchOrder", &txOrder, order.OrderId)
+	})
 	if err != nil {
 		return false, err
 	}
+	if isGeocodeNeeded {
+		geocodeOrderAddrLater(order.OrderId)
+	}
 	return true, nil
 }
 
 // //////////////////////////////////////////////////////////////////////////
-func DeleteMulchOrder(ctx context.Context, orderId string) (bool, error) {
+func DeleteMulchOrder(ctx context.Context, orderId string, doOverrideLock bool) (bool, error) {
 	log.Println("Deleteing Order with order id: ", orderId)
 
 	// Because we want to validate that the order owner or admin are the only 2 people that can delete
 	//  we have to pull the order up first to get the original order id
-	sqlCmd := "select order_owner_id::string from mulch_orders where mulch_orders.order_id=$1"
-	log.Println("SqlCmd: ", sqlCmd)
-
-	var orderOwner string
-	if err := Db.QueryRow(context.Background(), sqlCmd, orderId).Scan(&orderOwner); err != nil {
+	order, err := frStore.GetMulchOrder(ctx, GetMulchOrderParams{OrderId: orderId, GqlFields: allMulchOrderGqlFields})
+	if err != nil {
 		log.Println("Delete Mulch order query for: ", orderId, " failed because:", err)
 		return false, err
 	}
 
-	if err := verifyUidAllowedFromCtx(ctx, orderOwner); err != nil {
+	if err := verifyUidAllowedFromCtx(ctx, order.OwnerId); err != nil {
+		return false, err
+	}
+
+	if err := verifyOrderChangeAllowed(ctx, frStore, &order, nil, doOverrideLock); err != nil {
 		return false, err
 	}
 
-	_, err := Db.Exec(context.Background(), "delete from mulch_orders where order_id=$1", orderId)
+	err = frStore.InTx(ctx, func(tx FrStore) error {
+		if err := snapshotMulchOrder(ctx, tx, orderId, "deleteMulchOrder"); err != nil {
+			return err
+		}
+		if err := tx.DeleteMulchOrder(ctx, orderId); err != nil {
+			return err
+		}
+		return recordAuditEntry(ctx, tx, "deleteMulchOrder", "mulchOrder", orderId, order, nil)
+	})
 	if err != nil {
 		return false, err
 	}
@@ -1221,50 +971,15 @@ type FrConfigType struct {
 	MulchDeliveryConfigs *[]MulchDeliveryConfigType `json:"mulchDeliveryConfigs"`
 	Products             []ProductType              `json:"products"`
 	FinalizationData     *FinalizationDataType      `json:"finalizationData"`
+	AllocationPolicy     *AllocationPolicyType      `json:"allocationPolicy"`
+	InventoryPolicy      *InventoryPolicyType       `json:"inventoryPolicy"`
 }
 
 // //////////////////////////////////////////////////////////////////////////
-func GetFundraiserConfig(gqlFields []string) (FrConfigType, error) {
+func GetFundraiserConfig(ctx context.Context, gqlFields []string) (FrConfigType, error) {
 	log.Println("Retrieving Fundraiser Config")
 
-	frConfig := FrConfigType{}
-	params := []interface{}{}
-	sqlFields := []string{}
-
-	for _, gqlField := range gqlFields {
-		switch gqlField {
-		case "kind":
-			params = append(params, &frConfig.Kind)
-			sqlFields = append(sqlFields, "kind")
-		case "description":
-			params = append(params, &frConfig.Description)
-			sqlFields = append(sqlFields, "description")
-		case "lastModifiedTime":
-			params = append(params, &frConfig.LastModifiedTime)
-			sqlFields = append(sqlFields, "last_modified_time::string")
-		case "mulchDeliveryConfigs":
-			params = append(params, &frConfig.MulchDeliveryConfigs)
-			sqlFields = append(sqlFields, "mulch_delivery_configs::jsonb")
-		case "products":
-			params = append(params, &frConfig.Products)
-			sqlFields = append(sqlFields, "products::jsonb")
-		case "finalizationData":
-			params = append(params, &frConfig.FinalizationData)
-			sqlFields = append(sqlFields, "finalization_data::jsonb")
-		case "isLocked":
-			params = append(params, &frConfig.IsLocked)
-			sqlFields = append(sqlFields, "is_locked")
-		case "users":
-		case "neighborhoods":
-			// Skipping because it is handled seperately
-		default:
-			return frConfig, fmt.Errorf("unknown fundraiser config field: %s", gqlField)
-		}
-	}
-
-	sqlCmd := fmt.Sprintf("select %s from fundraiser_config", strings.Join(sqlFields, ","))
-	log.Println("SqlCmd: ", sqlCmd)
-	err := Db.QueryRow(context.Background(), sqlCmd).Scan(params...)
+	frConfig, err := frStore.GetFundraiserConfig(ctx, gqlFields)
 	if err != nil {
 		log.Println("Fundraiser config query failed", err)
 		return FrConfigType{}, err
@@ -1272,81 +987,6 @@ func GetFundraiserConfig(gqlFields []string) (FrConfigType, error) {
 	return frConfig, nil
 }
 
-// //////////////////////////////////////////////////////////////////////////
-func FrConfigType2Sql(frConfig FrConfigType) ([]string, []string, []interface{}) {
-	values := []interface{}{}
-	sqlFields := []string{}
-	valIdxs := []string{}
-	valIdx := 1
-	if len(frConfig.Kind) != 0 {
-		sqlFields = append(sqlFields, "kind")
-		values = append(values, frConfig.Kind)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
-		valIdx++
-	}
-	if len(frConfig.Description) != 0 {
-		sqlFields = append(sqlFields, "description")
-		values = append(values, frConfig.Description)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
-		valIdx++
-	}
-	if len(frConfig.Products) != 0 {
-		sqlFields = append(sqlFields, "products")
-		values = append(values, frConfig.Products)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::jsonb", valIdx))
-		valIdx++
-	}
-	if nil != frConfig.MulchDeliveryConfigs {
-		sqlFields = append(sqlFields, "mulch_delivery_configs")
-		values = append(values, *frConfig.MulchDeliveryConfigs)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::jsonb", valIdx))
-		valIdx++
-	}
-	if nil != frConfig.FinalizationData {
-		sqlFields = append(sqlFields, "finalization_data")
-		values = append(values, *frConfig.FinalizationData)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::jsonb", valIdx))
-		valIdx++
-	}
-	if nil != frConfig.IsLocked {
-		// Unfortunately hard to detect if this is set or not
-		sqlFields = append(sqlFields, "is_locked")
-		values = append(values, *frConfig.IsLocked)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::bool", valIdx))
-		valIdx++
-	}
-
-	// Always do timestamp
-	sqlFields = append(sqlFields, "last_modified_time")
-	values = append(values, frConfig.LastModifiedTime)
-	valIdxs = append(valIdxs, fmt.Sprintf("$%d::timestamp", valIdx))
-	return sqlFields, valIdxs, values
-}
-
-// //////////////////////////////////////////////////////////////////////////
-func setFundraiserConfigWithTrxn(ctx context.Context, trxn *pgx.Tx, frConfig FrConfigType) error {
-	frConfig.LastModifiedTime = time.Now().UTC().Format(time.RFC3339)
-	log.Println("Setting Fundraiding Config (with Trxn): ", frConfig)
-
-	log.Println("Deleting existing record")
-	_, err := (*trxn).Exec(ctx, "delete from fundraiser_config")
-	if err != nil {
-		return err
-	}
-
-	sqlFields, valIdxs, values := FrConfigType2Sql(frConfig)
-
-	sqlCmd := fmt.Sprintf("insert into fundraiser_config(%s) values (%s)",
-		strings.Join(sqlFields, ","), strings.Join(valIdxs, ","))
-
-	log.Println("Setting Config SqlCmd: ", sqlCmd)
-	_, err = (*trxn).Exec(context.Background(), sqlCmd, values...)
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
 // //////////////////////////////////////////////////////////////////////////
 func convertTzStrDateToEpoch(targetDate string, tzStr string) (uint32, error) {
 	// log.Println("Loading Timezone: ", tzStr)
@@ -1404,20 +1044,17 @@ func SetFundraiserConfig(ctx context.Context, frConfig FrConfigType) (bool, erro
 	if err := calcMulchDeliveriesEpochs(&frConfig); err != nil {
 		return false, err
 	}
-
-	// Start Database Operations
-	trxn, err := Db.Begin(context.Background())
-	if err != nil {
+	if _, err := parseAllocationPolicy(frConfig.AllocationPolicy); err != nil {
 		return false, err
 	}
-
-	if err := setFundraiserConfigWithTrxn(ctx, &trxn, frConfig); err != nil {
-		trxn.Rollback(context.Background())
+	if _, _, err := parseInventoryPolicy(frConfig.InventoryPolicy); err != nil {
 		return false, err
 	}
 
-	log.Println("About to make a commitment")
-	err = trxn.Commit(context.Background())
+	frConfig.LastModifiedTime = makeLastModifiedTime()
+	err := recordFundraiserConfigChange(ctx, "setConfig", func(tx FrStore) error {
+		return tx.SetFundraiserConfig(ctx, frConfig)
+	})
 	if err != nil {
 		return false, err
 	}
@@ -1425,32 +1062,17 @@ func SetFundraiserConfig(ctx context.Context, frConfig FrConfigType) (bool, erro
 }
 
 // //////////////////////////////////////////////////////////////////////////
-func updateFundraiserConfigWithTrxn(ctx context.Context, trxn *pgx.Tx, frConfig FrConfigType) error {
-	frConfig.LastModifiedTime = time.Now().UTC().Format(time.RFC3339)
+func updateFundraiserConfigWithTrxn(ctx context.Context, tx FrStore, frConfig FrConfigType) error {
+	frConfig.LastModifiedTime = makeLastModifiedTime()
 	log.Println("Updating Fundraiding Config (with Trxn): ", frConfig)
 
-	sqlFields, valIdxs, values := FrConfigType2Sql(frConfig)
-
-	updateSqlFlds := []string{}
-	for i, f := range sqlFields {
-		updateSqlFlds = append(updateSqlFlds, fmt.Sprintf("%s=%s", f, valIdxs[i]))
-	}
-
-	sqlCmd := fmt.Sprintf(
-		"UPDATE fundraiser_config SET %s WHERE last_modified_time=(SELECT last_modified_time FROM fundraiser_config LIMIT 1)",
-		strings.Join(updateSqlFlds, ","))
-
-	log.Println("Update Config SqlCmd: ", sqlCmd)
-	_, err := (*trxn).Exec(ctx, sqlCmd, values...)
-	if err != nil {
-		return err
-	}
-	return nil
+	return tx.UpdateFundraiserConfig(ctx, frConfig, "")
 }
 
 // //////////////////////////////////////////////////////////////////////////
-func UpdateFundraiserConfig(ctx context.Context, frConfig FrConfigType) (bool, error) {
-	frConfig.LastModifiedTime = time.Now().UTC().Format(time.RFC3339)
+// If expectedLastModifiedTime is given the update fails with a ConflictError
+// when the config has been changed since then.
+func UpdateFundraiserConfig(ctx context.Context, frConfig FrConfigType, expectedLastModifiedTime string) (bool, error) {
 	log.Println("Updating Fundraiding Config")
 
 	if err := VerifyAdminTokenFromCtx(ctx); err != nil {
@@ -1460,20 +1082,27 @@ func UpdateFundraiserConfig(ctx context.Context, frConfig FrConfigType) (bool, e
 	if err := calcMulchDeliveriesEpochs(&frConfig); err != nil {
 		return false, err
 	}
-
-	// Start Database Operations
-	trxn, err := Db.Begin(ctx)
-	if err != nil {
+	if _, err := parseAllocationPolicy(frConfig.AllocationPolicy); err != nil {
 		return false, err
 	}
-
-	if err := updateFundraiserConfigWithTrxn(ctx, &trxn, frConfig); err != nil {
-		trxn.Rollback(context.Background())
+	if _, _, err := parseInventoryPolicy(frConfig.InventoryPolicy); err != nil {
 		return false, err
 	}
 
-	log.Println("About to make a commitment")
-	err = trxn.Commit(context.Background())
+	frConfig.LastModifiedTime = makeLastModifiedTime()
+	err := recordFundraiserConfigChange(ctx, "updateConfig", func(tx FrStore) error {
+		err := tx.UpdateFundraiserConfig(ctx, frConfig, expectedLastModifiedTime)
+		if errors.Is(err, ErrStaleVersion) {
+			currentConfig, err := tx.GetFundraiserConfig(ctx, allFrConfigGqlFields)
+			if err != nil {
+				return err
+			}
+			err = newConflictError("fundraiserConfig", currentConfig.Kind, currentConfig.LastModifiedTime, currentConfig)
+			log.Println("Config update failed: ", err)
+			return err
+		}
+		return err
+	})
 	if err != nil {
 		return false, err
 	}
@@ -1492,121 +1121,23 @@ type NeighborhoodInfo struct {
 }
 
 // //////////////////////////////////////////////////////////////////////////
-func GetNeighborhoods(gqlFields []string) ([]NeighborhoodInfo, error) {
+func GetNeighborhoods(ctx context.Context, gqlFields []string) ([]NeighborhoodInfo, error) {
 	log.Println("Retrieving Fundraiser Neighborhoods")
 
-	neighborhoods := []NeighborhoodInfo{}
-	sqlFields := []string{}
-
-	for _, gqlField := range gqlFields {
-		switch gqlField {
-		case "name":
-			sqlFields = append(sqlFields, "name")
-		case "zipcode":
-			sqlFields = append(sqlFields, "zipcode")
-		case "city":
-			sqlFields = append(sqlFields, "city")
-		case "isVisible":
-			sqlFields = append(sqlFields, "is_visible")
-		case "distributionPoint":
-			sqlFields = append(sqlFields, "dist_pt")
-		default:
-			return neighborhoods, fmt.Errorf("unknown fundraiser neighborhood field: %s", gqlField)
-		}
-	}
-
-	sqlCmd := fmt.Sprintf("select %s from neighborhoods", strings.Join(sqlFields, ","))
-	rows, err := Db.Query(context.Background(), sqlCmd)
+	neighborhoods, err := frStore.GetNeighborhoods(ctx, gqlFields)
 	if err != nil {
 		log.Println("Neighborhood query failed", err)
 		return neighborhoods, err
 	}
-	defer rows.Close()
-
-	for rows.Next() {
-		hood := NeighborhoodInfo{}
-		inputs := []interface{}{}
-		for _, gqlField := range gqlFields {
-			switch gqlField {
-			case "name":
-				inputs = append(inputs, &hood.Name)
-			case "zipcode":
-				inputs = append(inputs, &hood.Zipcode)
-			case "city":
-				inputs = append(inputs, &hood.City)
-			case "isVisible":
-				inputs = append(inputs, &hood.IsVisible)
-			case "distributionPoint":
-				inputs = append(inputs, &hood.DistributionPoint)
-			default:
-				return neighborhoods, fmt.Errorf("unknown fundraiser neighborhood field: %s", gqlField)
-			}
-		}
-		err = rows.Scan(inputs...)
-		if err != nil {
-			log.Println("Reading Neighborhood row failed: ", err)
-			continue
-		}
-		neighborhoods = append(neighborhoods, hood)
-	}
-
-	if err := rows.Err(); err != nil {
-		log.Println("Reading Neighborhood rows had an issue: ", err)
-		return []NeighborhoodInfo{}, err
-	}
 	return neighborhoods, nil
 }
 
 // //////////////////////////////////////////////////////////////////////////
-func FrHoodType2Sql(hood NeighborhoodInfo, isUpdate bool) ([]string, []string, []interface{}) {
-	values := []interface{}{}
-	sqlFields := []string{}
-	valIdxs := []string{}
-	valIdx := 1
-
-	// We don't use Name when we are updating
-	if !isUpdate && len(hood.Name) != 0 {
-		sqlFields = append(sqlFields, "name")
-		values = append(values, hood.Name)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
-		valIdx++
-	}
-	if nil != hood.Zipcode {
-		sqlFields = append(sqlFields, "zipcode")
-		values = append(values, *hood.Zipcode)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::int", valIdx))
-		valIdx++
-	}
-	if nil != hood.City {
-		sqlFields = append(sqlFields, "city")
-		values = append(values, *hood.City)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
-		valIdx++
-	}
-	if nil != hood.DistributionPoint {
-		sqlFields = append(sqlFields, "dist_pt")
-		values = append(values, *hood.DistributionPoint)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
-		valIdx++
-	}
-	if nil != hood.IsVisible {
-		// Unfortunately hard to detect if this is set or not
-		sqlFields = append(sqlFields, "is_visible")
-		values = append(values, *hood.IsVisible)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::bool", valIdx))
-		valIdx++
-	}
-
-	// Always do timestamp
-	sqlFields = append(sqlFields, "last_modified_time")
-	values = append(values, hood.LastModifiedTime)
-	valIdxs = append(valIdxs, fmt.Sprintf("$%d::timestamp", valIdx))
-	return sqlFields, valIdxs, values
-}
-
-// //////////////////////////////////////////////////////////////////////////
-func AddOrUpdateNeighborhoods(ctx context.Context, hoods []NeighborhoodInfo) (bool, error) {
-	lastModifiedTime := time.Now().UTC().Format(time.RFC3339)
+// expectedLastModifiedTimes is keyed by neighborhood name.  If one is given
+// for an existing neighborhood nothing is changed and a ConflictError is
+// returned when that neighborhood has been changed since then.
+func AddOrUpdateNeighborhoods(ctx context.Context, hoods []NeighborhoodInfo, expectedLastModifiedTimes map[string]string) (bool, error) {
+	lastModifiedTime := makeLastModifiedTime()
 	log.Println("Adding Neighborhoods at: ", lastModifiedTime)
 
 	// If it was empty we don't need to do anything
@@ -1619,74 +1150,72 @@ func AddOrUpdateNeighborhoods(ctx context.Context, hoods []NeighborhoodInfo) (bo
 		return false, err
 	}
 
-	existingHoods, err := GetNeighborhoods([]string{"name"})
+	existingHoods, err := GetNeighborhoods(ctx, allNeighborhoodGqlFields)
 	if err != nil {
 		return false, err
 	}
+	existingHoodsBefore := slices.Clone(existingHoods)
 
-	doesAlreadyExist := func(newHood string) bool {
-		for _, existingHood := range existingHoods {
-			if existingHood.Name == newHood {
-				return true
+	findExisting := func(newHood string) *NeighborhoodInfo {
+		for idx := range existingHoods {
+			if existingHoods[idx].Name == newHood {
+				return &existingHoods[idx]
 			}
 		}
-		return false
-	}
-
-	// Start Database Operations
-	trxn, err := Db.Begin(context.Background())
-	if err != nil {
-		return false, err
+		return nil
 	}
 
-	genSqlCmd := func(hood NeighborhoodInfo, isUpdate bool) (string, []interface{}) {
-		hood.LastModifiedTime = lastModifiedTime
-		sqlFields, valIdxs, values := FrHoodType2Sql(hood, isUpdate)
-		sqlCmd := ""
-		if isUpdate {
-			log.Println("Neighborhood: ", hood.Name, " already exists and so updating")
-			updateSqlFlds := []string{}
-			for i, f := range sqlFields {
-				updateSqlFlds = append(updateSqlFlds, fmt.Sprintf("%s=%s", f, valIdxs[i]))
+	err = frStore.InTx(ctx, func(tx FrStore) error {
+		for _, hood := range hoods {
+			hood.LastModifiedTime = lastModifiedTime
+			if findExisting(hood.Name) != nil {
+				log.Println("Neighborhood: ", hood.Name, " already exists and so updating")
+				err := tx.UpdateNeighborhood(ctx, hood, expectedLastModifiedTimes[hood.Name])
+				if errors.Is(err, ErrStaleVersion) {
+					currentHoods, err := tx.GetNeighborhoods(ctx, append([]string{"lastModifiedTime"}, allNeighborhoodGqlFields...))
+					if err != nil {
+						return err
+					}
+					idx := slices.IndexFunc(currentHoods, func(h NeighborhoodInfo) bool { return h.Name == hood.Name })
+					if idx == -1 {
+						return &NotFoundError{Message: fmt.Sprintf("neighborhood: %s does not exist", hood.Name)}
+					}
+					err = newConflictError("neighborhood", hood.Name, currentHoods[idx].LastModifiedTime, currentHoods[idx])
+					log.Println("Neighborhoods update failed: ", err)
+					return err
+				}
+				if err != nil {
+					return err
+				}
+			} else {
+				log.Println("Neighborhood: ", hood.Name, " does not exists and so adding it")
+				if err := tx.InsertNeighborhood(ctx, hood); err != nil {
+					return err
+				}
 			}
-
-			sqlCmd = fmt.Sprintf(
-				"UPDATE neighborhoods SET %s WHERE name = '%s'",
-				strings.Join(updateSqlFlds, ","),
-				hood.Name,
-			)
-		} else {
-			log.Println("Neighborhood: ", hood.Name, " does not exists and so adding it")
-			sqlCmd = fmt.Sprintf("insert into neighborhoods(%s) values (%s)",
-				strings.Join(sqlFields, ","), strings.Join(valIdxs, ","))
+			existingHoods = append(existingHoods, hood)
 		}
 
-		log.Println("Neighborhood SqlCmd: ", sqlCmd)
-		return sqlCmd, values
-	}
-
-	for _, hood := range hoods {
-
-		sqlCmd, values := genSqlCmd(hood, doesAlreadyExist(hood.Name))
-
-		_, err = trxn.Exec(context.Background(), sqlCmd, values...)
+		// Updates only change the fields given so audit against what is stored now
+		updatedHoods, err := tx.GetNeighborhoods(ctx, allNeighborhoodGqlFields)
 		if err != nil {
-			trxn.Rollback(context.Background())
-			return false, err
+			return err
+		}
+		hoodNames := []string{}
+		for _, hood := range hoods {
+			hoodNames = append(hoodNames, hood.Name)
+		}
+		hoodKey := func(hood NeighborhoodInfo) string { return hood.Name }
+		err = recordBulkAuditEntries(ctx, tx, "addOrUpdateNeighborhoods", "neighborhood", hoodNames,
+			existingHoodsBefore, updatedHoods, hoodKey)
+		if err != nil {
+			return err
 		}
-		existingHoods = append(existingHoods, hood)
-	}
-
-	// Even though neighborhoods aren't a part of the fundariser config table we still treat it like it is so
-	// trigger time update to force re-download of config data.
-
-	if err := updateFundraiserConfigWithTrxn(ctx, &trxn, FrConfigType{}); err != nil {
-		trxn.Rollback(context.Background())
-		return false, err
-	}
 
-	log.Println("About to make a commitment")
-	err = trxn.Commit(context.Background())
+		// Even though neighborhoods aren't a part of the fundariser config table we still treat it like it is so
+		// trigger time update to force re-download of config data.
+		return updateFundraiserConfigWithTrxn(ctx, tx, FrConfigType{})
+	})
 	if err != nil {
 		return false, err
 	}
@@ -1705,90 +1234,11 @@ type MulchTimecardType struct {
 }
 
 // //////////////////////////////////////////////////////////////////////////
-func mulchTimecardGql2SqlMap(gqlFields []string, tc *MulchTimecardType) ([]string, []interface{}) {
-	sqlFields := []string{}
-	inputs := []interface{}{}
-
-	for _, gqlField := range gqlFields {
-		switch gqlField {
-		case "id":
-			inputs = append(inputs, &tc.Id)
-			sqlFields = append(sqlFields, "uid")
-		case "deliveryId":
-			inputs = append(inputs, &tc.DeliveryId)
-			sqlFields = append(sqlFields, "delivery_id")
-		case "lastModifiedTime":
-			inputs = append(inputs, &tc.LastModifiedTime)
-			sqlFields = append(sqlFields, "last_modified_time::string")
-		case "timeIn":
-			inputs = append(inputs, &tc.TimeIn)
-			sqlFields = append(sqlFields, "time_in::string")
-		case "timeOut":
-			inputs = append(inputs, &tc.TimeOut)
-			sqlFields = append(sqlFields, "time_out::string")
-		case "timeTotal":
-			inputs = append(inputs, &tc.TimeTotal)
-			sqlFields = append(sqlFields, "time_total::string")
-		default:
-			log.Println("Do not know how to handle GraphQL Field: ", gqlField)
-		}
-	}
-	return sqlFields, inputs
-}
-
-// //////////////////////////////////////////////////////////////////////////
-func GetMulchTimecards(id string, deliveryId int, gqlFields []string) ([]MulchTimecardType, error) {
-	timecards := []MulchTimecardType{}
-
-	tc := MulchTimecardType{} // This is pretty much throw away probably should change to nil
-	sqlFields, _ := mulchTimecardGql2SqlMap(gqlFields, &tc)
-
-	sqlCmd := fmt.Sprintf("SELECT %s FROM mulch_delivery_timecards", strings.Join(sqlFields, ","))
-	log.Println("Retrieving Timecards: ", sqlCmd)
-
-	values := []interface{}{}
-	valIdx := 1
-	sqlFields = []string{} // Reset for WHERE entries
-	if len(id) != 0 || deliveryId != -1 {
-		sqlCmd = sqlCmd + " WHERE"
-	}
-
-	if len(id) != 0 {
-		log.Println("Timecards User Id: ", id)
-		values = append(values, id)
-		sqlFields = append(sqlFields, fmt.Sprintf("uid=$%d", valIdx))
-		valIdx++
-	}
-	if deliveryId != -1 {
-		log.Println("Timecards DeliveryId: ", deliveryId)
-		values = append(values, deliveryId)
-		sqlFields = append(sqlFields, fmt.Sprintf("delivery_id=$%d", valIdx))
-		valIdx++
-	}
-
-	sqlCmd = fmt.Sprintf("%s %s", sqlCmd, strings.Join(sqlFields, " AND "))
-	rows, err := Db.Query(context.Background(), sqlCmd, values...)
+func GetMulchTimecards(ctx context.Context, id string, deliveryId int, gqlFields []string) ([]MulchTimecardType, error) {
+	timecards, err := frStore.GetMulchTimecards(ctx, id, deliveryId, gqlFields)
 	if err != nil {
 		log.Println("Timecard query Failed", err)
-		return timecards, nil
-	}
-
-	defer rows.Close()
-
-	for rows.Next() {
-		tc := MulchTimecardType{}
-		_, inputs := mulchTimecardGql2SqlMap(gqlFields, &tc)
-		err = rows.Scan(inputs...)
-		if err != nil {
-			log.Println("Reading timecard row failed: ", err)
-			continue
-		}
-		timecards = append(timecards, tc)
-	}
-
-	if err := rows.Err(); err != nil {
-		log.Println("Reading timecard rows had an issue: ", err)
-		return []MulchTimecardType{}, nil
+		return timecards, err
 	}
 	return timecards, nil
 }
@@ -1802,35 +1252,38 @@ func SetMulchTimecards(ctx context.Context, timecards []MulchTimecardType) (bool
 		return false, err
 	}
 
-	// Start Database Operations
-	trxn, err := Db.Begin(context.Background())
-	if err != nil {
-		return false, err
-	}
-
-	for _, timecard := range timecards {
-		log.Println("Deleting existing record if it exists: ", timecard.Id)
-		_, err = trxn.Exec(context.Background(),
-			"delete from mulch_delivery_timecards where uid = $1 and delivery_id = $2",
-			timecard.Id, timecard.DeliveryId)
-		if err != nil {
-			trxn.Rollback(context.Background())
-			return false, err
-		}
-		if len(timecard.TimeTotal) > 0 && timecard.TimeTotal != "00:00:00" {
-			sqlCmd := "insert into mulch_delivery_timecards(uid, delivery_id, last_modified_time, time_in, time_out, time_total) " +
-				"values ($1, $2, $3::timestamp, $4::time, $5::time, $6::time)"
-			log.Println("Setting Timecard SqlCmd: ", sqlCmd)
-			_, err = trxn.Exec(context.Background(), sqlCmd,
-				timecard.Id, timecard.DeliveryId, lastModifiedTime, timecard.TimeIn, timecard.TimeOut, timecard.TimeTotal)
+	err := frStore.InTx(ctx, func(tx FrStore) error {
+		for _, timecard := range timecards {
+			existingTimecards, err := tx.GetMulchTimecards(ctx, timecard.Id, timecard.DeliveryId, allMulchTimecardGqlFields)
 			if err != nil {
-				trxn.Rollback(context.Background())
-				return false, err
+				return err
+			}
+
+			log.Println("Deleting existing record if it exists: ", timecard.Id)
+			if err := tx.DeleteMulchTimecard(ctx, timecard.Id, timecard.DeliveryId); err != nil {
+				return err
+			}
+
+			var newTimecard *MulchTimecardType
+			if len(timecard.TimeTotal) > 0 && timecard.TimeTotal != "00:00:00" {
+				timecard.LastModifiedTime = lastModifiedTime
+				if err := tx.InsertMulchTimecard(ctx, timecard); err != nil {
+					return err
+				}
+				newTimecard = &timecard
+			}
+
+			var existingTimecard *MulchTimecardType
+			if len(existingTimecards) != 0 {
+				existingTimecard = &existingTimecards[0]
+			}
+			targetKey := fmt.Sprintf("%s:%d", timecard.Id, timecard.DeliveryId)
+			if err := recordAuditEntry(ctx, tx, "setMulchTimecards", "mulchTimecard", targetKey, existingTimecard, newTimecard); err != nil {
+				return err
 			}
 		}
-	}
-	log.Println("About to make a commitment")
-	err = trxn.Commit(context.Background())
+		return nil
+	})
 	if err != nil {
 		return false, err
 	}
@@ -1857,164 +1310,23 @@ type GetUsersParams struct {
 }
 
 // //////////////////////////////////////////////////////////////////////////
-func GetUsers(params GetUsersParams) ([]UserInfo, error) {
+func GetUsers(ctx context.Context, params GetUsersParams) ([]UserInfo, error) {
 	log.Println("Retrieving Fundraiser Users")
 
-	users := []UserInfo{}
-
-	getSqlFields := func() ([]string, bool, error) {
-		sqlFieldSet := make(map[string]struct{})
-		sqlFields := []string{}
-		doWantFullNames := false
-		exists := struct{}{}
-		for _, gqlField := range params.GqlFields {
-			switch gqlField {
-			case "firstName":
-				sqlFieldSet["first_name"] = exists
-			case "lastName":
-				sqlFieldSet["last_name"] = exists
-			case "name":
-				doWantFullNames = true
-				sqlFieldSet["first_name"] = exists
-				sqlFieldSet["last_name"] = exists
-			case "id":
-				sqlFieldSet["id"] = exists
-			case "group":
-				sqlFieldSet["group_id"] = exists
-			case "hasAuthCreds":
-				sqlFieldSet["has_auth_creds"] = exists
-			default:
-				return sqlFields, false, fmt.Errorf("unknown fundraiser user field: %s", gqlField)
-			}
-		}
-		for k := range sqlFieldSet {
-			sqlFields = append(sqlFields, k)
-		}
-		return sqlFields, doWantFullNames, nil
-	}
-
-	sqlFields, doWantFullNames, err := getSqlFields()
-	if err != nil {
-		return users, err
-	}
-
-	sqlCmd := fmt.Sprintf("select %s from users", strings.Join(sqlFields, ","))
-	if params.ShowUsersWithoutAuthCreds {
-		sqlCmd = sqlCmd + " where not has_auth_creds"
-	}
-
-	rows, err := Db.Query(context.Background(), sqlCmd)
+	users, err := frStore.GetUsers(ctx, params)
 	if err != nil {
 		log.Println("User query failed", err)
 		return users, err
 	}
-	defer rows.Close()
-
-	for rows.Next() {
-		user := UserInfo{}
-		inputs := []interface{}{}
-		for _, fld := range sqlFields {
-			switch fld {
-			case "first_name":
-				inputs = append(inputs, &user.FirstName)
-			case "last_name":
-				inputs = append(inputs, &user.LastName)
-			case "id":
-				inputs = append(inputs, &user.Id)
-			case "group_id":
-				inputs = append(inputs, &user.Group)
-			case "has_auth_creds":
-				inputs = append(inputs, &user.HasAuthCreds)
-			default:
-				return users, fmt.Errorf("unknown fundraiser user db field: %s", fld)
-			}
-		}
-		err = rows.Scan(inputs...)
-		if err != nil {
-			log.Println("Reading User row failed: ", err)
-			continue
-		}
-		if doWantFullNames {
-			user.Name = fmt.Sprintf("%s %s", user.FirstName, user.LastName)
-		}
-		users = append(users, user)
-	}
-
-	if err := rows.Err(); err != nil {
-		log.Println("Reading User rows had an issue: ", err)
-		return []UserInfo{}, err
-	}
 	return users, nil
 }
 
 // //////////////////////////////////////////////////////////////////////////
-func FrUsers2Sql(user UserInfo, isUpdate bool) ([]string, []string, []interface{}) {
-	values := []interface{}{}
-	sqlFields := []string{}
-	valIdxs := []string{}
-	valIdx := 1
-
-	if !isUpdate {
-		// These are fields we don't change when updating
-
-		if len(user.Id) != 0 {
-			sqlFields = append(sqlFields, "id")
-			values = append(values, user.Id)
-			valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
-			valIdx++
-		}
-
-		if len(user.FirstName) != 0 {
-			sqlFields = append(sqlFields, "first_name")
-			values = append(values, user.FirstName)
-			valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
-			valIdx++
-		}
-
-		if len(user.LastName) != 0 {
-			sqlFields = append(sqlFields, "last_name")
-			values = append(values, user.LastName)
-			valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
-			valIdx++
-		}
-
-		// If we aren't updating then we are creating and so we always set these
-		sqlFields = append(sqlFields, "has_auth_creds")
-		values = append(values, false)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::bool", valIdx))
-		valIdx++
-
-		sqlFields = append(sqlFields, "created_time")
-		values = append(values, user.CreatedTime)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::timestamp", valIdx))
-		valIdx++
-	} else {
-		if nil != user.HasAuthCreds {
-			// Unfortunately hard to detect if this is set or not
-			sqlFields = append(sqlFields, "has_auth_creds")
-			values = append(values, *user.HasAuthCreds)
-			valIdxs = append(valIdxs, fmt.Sprintf("$%d::bool", valIdx))
-			valIdx++
-		}
-	}
-
-	if len(user.Group) != 0 {
-		sqlFields = append(sqlFields, "group_id")
-		values = append(values, user.Group)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
-		valIdx++
-	}
-
-	// Always do timestamp
-	sqlFields = append(sqlFields, "last_modified_time")
-	values = append(values, user.LastModifiedTime)
-	valIdxs = append(valIdxs, fmt.Sprintf("$%d::timestamp", valIdx))
-	return sqlFields, valIdxs, values
-}
-
-// //////////////////////////////////////////////////////////////////////////
-func AddOrUpdateUsers(ctx context.Context, users []UserInfo) (bool, error) {
-	lastModifiedTime := time.Now().UTC().Format(time.RFC3339)
+// expectedLastModifiedTimes is keyed by user id.  If one is given for an
+// existing user nothing is changed and a ConflictError is returned when that
+// user has been changed since then.
+func AddOrUpdateUsers(ctx context.Context, users []UserInfo, expectedLastModifiedTimes map[string]string) (bool, error) {
+	lastModifiedTime := makeLastModifiedTime()
 	log.Println("Setting Users at: ", lastModifiedTime)
 
 	if len(users) == 0 {
@@ -2025,86 +1337,85 @@ func AddOrUpdateUsers(ctx context.Context, users []UserInfo) (bool, error) {
 		return false, err
 	}
 
-	existingUsers, err := GetUsers(GetUsersParams{GqlFields: []string{"id"}})
+	existingUsers, err := GetUsers(ctx, GetUsersParams{GqlFields: allUserGqlFields})
 	if err != nil {
 		return false, err
 	}
+	existingUsersBefore := slices.Clone(existingUsers)
 
-	doesAlreadyExist := func(uid string) bool {
-		for _, existingUser := range existingUsers {
-			if existingUser.Id == uid {
-				return true
+	findExisting := func(uid string) *UserInfo {
+		for idx := range existingUsers {
+			if existingUsers[idx].Id == uid {
+				return &existingUsers[idx]
 			}
 		}
-		return false
-	}
-
-	// Start Database Operations
-	trxn, err := Db.Begin(context.Background())
-	if err != nil {
-		return false, err
+		return nil
 	}
 
-	isDirty := false
 	isAddingUsers := false
-	for _, user := range users {
-		if len(user.Id) == 0 {
-			continue
-		}
-		user.LastModifiedTime = lastModifiedTime
-		if doesAlreadyExist(user.Id) {
-			log.Println("User: ", user.Id, " already exists so updating")
-
-			sqlFields, valIdxs, values := FrUsers2Sql(user, true)
-
-			updateSqlFlds := []string{}
-			for i, f := range sqlFields {
-				updateSqlFlds = append(updateSqlFlds, fmt.Sprintf("%s=%s", f, valIdxs[i]))
+	err = frStore.InTx(ctx, func(tx FrStore) error {
+		isDirty := false
+		for _, user := range users {
+			if len(user.Id) == 0 {
+				continue
 			}
+			user.LastModifiedTime = lastModifiedTime
+			if findExisting(user.Id) != nil {
+				log.Println("User: ", user.Id, " already exists so updating")
+				err := tx.UpdateUser(ctx, user, expectedLastModifiedTimes[user.Id])
+				if errors.Is(err, ErrStaleVersion) {
+					currentUsers, err := tx.GetUsers(ctx, GetUsersParams{GqlFields: append([]string{"lastModifiedTime"}, allUserGqlFields...)})
+					if err != nil {
+						return err
+					}
+					idx := slices.IndexFunc(currentUsers, func(u UserInfo) bool { return u.Id == user.Id })
+					if idx == -1 {
+						return &NotFoundError{Message: fmt.Sprintf("user: %s does not exist", user.Id)}
+					}
+					err = newConflictError("user", user.Id, currentUsers[idx].LastModifiedTime, currentUsers[idx])
+					log.Println("Users update failed: ", err)
+					return err
+				}
+				if err != nil {
+					return err
+				}
+			} else {
+				log.Println("User: ", user.Id, " does not exists")
+				user.CreatedTime = lastModifiedTime
+				if err := tx.InsertUser(ctx, user); err != nil {
+					return err
+				}
+				isAddingUsers = true
+			}
+			existingUsers = append(existingUsers, user)
+			isDirty = true
+		}
 
-			sqlCmd := fmt.Sprintf(
-				"UPDATE users SET %s WHERE id = '%s'",
-				strings.Join(updateSqlFlds, ","),
-				user.Id,
-			)
-
-			log.Println("Updating user SqlCmd: ", sqlCmd)
-			_, err = trxn.Exec(context.Background(), sqlCmd, values...)
+		if isDirty {
+			// Updates only change the fields given so audit against what is stored now
+			updatedUsers, err := tx.GetUsers(ctx, GetUsersParams{GqlFields: allUserGqlFields})
 			if err != nil {
-				trxn.Rollback(context.Background())
-				return false, err
+				return err
 			}
-		} else {
-			log.Println("User: ", user.Id, " does not exists")
-			user.CreatedTime = lastModifiedTime
-			sqlFields, valIdxs, values := FrUsers2Sql(user, false)
-
-			sqlCmd := fmt.Sprintf("insert into users(%s) values (%s)",
-				strings.Join(sqlFields, ","), strings.Join(valIdxs, ","))
-
-			log.Println("Adding user SqlCmd: ", sqlCmd)
-			_, err = trxn.Exec(context.Background(), sqlCmd, values...)
+			uids := []string{}
+			for _, user := range users {
+				if len(user.Id) != 0 {
+					uids = append(uids, user.Id)
+				}
+			}
+			userKey := func(user UserInfo) string { return user.Id }
+			err = recordBulkAuditEntries(ctx, tx, "addOrUpdateUsers", "user", uids,
+				existingUsersBefore, updatedUsers, userKey)
 			if err != nil {
-				trxn.Rollback(context.Background())
-				return false, err
+				return err
 			}
-			isAddingUsers = true
-		}
-		existingUsers = append(existingUsers, user)
-		isDirty = true
-	}
 
-	if isDirty {
-		// Even though users aren't a part of the fundariser config table we still treat it like it is so
-		// trigger time update to force re-download of config data.
-		if err := updateFundraiserConfigWithTrxn(ctx, &trxn, FrConfigType{}); err != nil {
-			trxn.Rollback(context.Background())
-			return false, err
+			// Even though users aren't a part of the fundariser config table we still treat it like it is so
+			// trigger time update to force re-download of config data.
+			return updateFundraiserConfigWithTrxn(ctx, tx, FrConfigType{})
 		}
-	}
-
-	log.Println("About to make a commitment")
-	err = trxn.Commit(context.Background())
+		return nil
+	})
 	if err != nil {
 		return false, err
 	}
@@ -2129,11 +1440,11 @@ type NewIssue struct {
 
 // //////////////////////////////////////////////////////////////////////////
 var newIssueGql = `
-mutation CreateIssue {
+mutation CreateIssue($title: String!, $body: String) {
   createIssue(input: {
 		repositoryId: "MDEwOlJlcG9zaXRvcnkzMDQ5ODg5MDE=",
-		title: "***TITLE***",
-		body: "***BODY***",
+		title: $title,
+		body: $body,
 		labelIds: ["MDU6TGFiZWwyNDM0MzA3ODIy", "LA_kwDOEi3C5c7dGLgb"],
 		assigneeIds:["MDQ6VXNlcjM0OTQ5Mg=="]
 	}) {
@@ -2150,13 +1461,14 @@ func CreateIssue(issue NewIssue) (bool, error) {
 	url := "https://api.github.com/graphql"
 
 	title := fmt.Sprint("[", issue.Id, "] ", issue.Title)
-	newIssueReq := strings.ReplaceAll(newIssueGql, "***TITLE***", title)
-	newIssueReq = strings.ReplaceAll(newIssueReq, "***BODY***", issue.Body)
 
-	type GReq struct {
-		Query string `json:"query"`
+	gqlReq := GqlRequest{
+		Query: newIssueGql,
+		Variables: map[string]interface{}{
+			"title": title,
+			"body":  issue.Body,
+		},
 	}
-	gqlReq := GReq{Query: newIssueReq}
 
 	reqBytes, err := json.Marshal(gqlReq)
 	if err != nil {
@@ -2188,35 +1500,33 @@ func CreateIssue(issue NewIssue) (bool, error) {
 }
 
 // //////////////////////////////////////////////////////////////////////////
-func SetSpreaders(orderId string, spreaders []string) (bool, error) {
+func SetSpreaders(ctx context.Context, orderId string, spreaders []string) (bool, error) {
 	if len(orderId) == 0 {
-		return false, errors.New("orderId must be provided")
+		return false, newValidationError("orderId", "orderId must be provided")
 	}
 
-	// Start Database Operations
-	trxn, err := Db.Begin(context.Background())
-	if err != nil {
-		return false, err
-	}
-
-	log.Println("Deleting existing record")
-	_, err = trxn.Exec(context.Background(), "delete from mulch_spreaders where order_id = $1", orderId)
-	if err != nil {
-		trxn.Rollback(context.Background())
-		return false, err
-	}
-
-	if len(spreaders) > 0 {
-		sqlCmd := "insert into mulch_spreaders(order_id, spreaders) values ($1, $2)"
-		_, err = trxn.Exec(context.Background(), sqlCmd, orderId, spreaders)
+	err := frStore.InTx(ctx, func(tx FrStore) error {
+		order, err := tx.GetMulchOrder(ctx, GetMulchOrderParams{OrderId: orderId, GqlFields: mulchOrderRevisionGqlFields})
+		if errors.Is(err, ErrNotFound) {
+			return &NotFoundError{Message: fmt.Sprintf("order %s can not have its spreaders set: %s", orderId, err)}
+		}
 		if err != nil {
-			trxn.Rollback(context.Background())
-			return false, err
+			return err
+		}
+		if err := verifyUidAllowedFromCtx(ctx, order.OwnerId); err != nil {
+			return err
 		}
-	}
 
-	log.Println("About to make a commitment")
-	err = trxn.Commit(context.Background())
+		// Saved like any other change so it shows in the history and can be restored
+		if err := saveMulchOrderRevision(ctx, tx, order, "setSpreaders"); err != nil {
+			return err
+		}
+		if err := tx.SetSpreaders(ctx, orderId, spreaders); err != nil {
+			return err
+		}
+		return recordAuditEntry(ctx, tx, "setSpreaders", "mulchOrder", orderId,
+			map[string][]string{"spreaders": order.Spreaders}, map[string][]string{"spreaders": spreaders})
+	})
 	if err != nil {
 		return false, err
 	}
@@ -2225,84 +1535,15 @@ func SetSpreaders(orderId string, spreaders []string) (bool, error) {
 
 // //////////////////////////////////////////////////////////////////////////
 type AllocationItemType struct {
-	Uid                       string
-	BagsSold                  *int
-	BagsSpread                *string
-	DeliveryMinutes           *string
-	TotalDonations            *string
-	AllocationsFromBagsSold   *string
-	AllocationsFromBagsSpread *string
-	AllocationsFromDelivery   *string
-	AllocationsTotal          string
-}
-
-// //////////////////////////////////////////////////////////////////////////
-func AllocItemType2Sql(item AllocationItemType) ([]string, []string, []interface{}) {
-	values := []interface{}{}
-	sqlFields := []string{}
-	valIdxs := []string{}
-	valIdx := 1
-
-	sqlFields = append(sqlFields, "uid")
-	values = append(values, item.Uid)
-	valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
-	valIdx++
-
-	sqlFields = append(sqlFields, "allocation_total")
-	values = append(values, item.AllocationsTotal)
-	valIdxs = append(valIdxs, fmt.Sprintf("$%d::decimal", valIdx))
-	valIdx++
-
-	if nil != item.BagsSold {
-		sqlFields = append(sqlFields, "bags_sold")
-		values = append(values, *item.BagsSold)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::int", valIdx))
-		valIdx++
-	}
-
-	if nil != item.BagsSpread {
-		sqlFields = append(sqlFields, "bags_spread")
-		values = append(values, *item.BagsSpread)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::decimal", valIdx))
-		valIdx++
-	}
-
-	if nil != item.DeliveryMinutes {
-		sqlFields = append(sqlFields, "delivery_minutes")
-		values = append(values, *item.DeliveryMinutes)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::decimal", valIdx))
-		valIdx++
-	}
-
-	if nil != item.TotalDonations {
-		sqlFields = append(sqlFields, "total_donations")
-		values = append(values, *item.TotalDonations)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::decimal", valIdx))
-		valIdx++
-	}
-
-	if nil != item.AllocationsFromBagsSold {
-		sqlFields = append(sqlFields, "allocation_from_bags_sold")
-		values = append(values, *item.AllocationsFromBagsSold)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::decimal", valIdx))
-		valIdx++
-	}
-
-	if nil != item.AllocationsFromBagsSpread {
-		sqlFields = append(sqlFields, "allocation_from_bags_spread")
-		values = append(values, *item.AllocationsFromBagsSpread)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::decimal", valIdx))
-		valIdx++
-	}
-
-	if nil != item.AllocationsFromDelivery {
-		sqlFields = append(sqlFields, "allocation_from_delivery")
-		values = append(values, *item.AllocationsFromDelivery)
-		valIdxs = append(valIdxs, fmt.Sprintf("$%d::decimal", valIdx))
-		valIdx++
-	}
-
-	return sqlFields, valIdxs, values
+	Uid                       string  `json:"uid"`
+	BagsSold                  *int    `json:"bagsSold"`
+	BagsSpread                *string `json:"bagsSpread"`
+	DeliveryMinutes           *string `json:"deliveryMinutes"`
+	TotalDonations            *string `json:"totalDonations"`
+	AllocationsFromBagsSold   *string `json:"allocationsFromBagsSold"`
+	AllocationsFromBagsSpread *string `json:"allocationsFromBagsSpread"`
+	AllocationsFromDelivery   *string `json:"allocationsFromDelivery"`
+	AllocationsTotal          string  `json:"allocationsTotal"`
 }
 
 // //////////////////////////////////////////////////////////////////////////
@@ -2313,90 +1554,46 @@ func SetFrCloseoutAllocations(ctx context.Context, allocations []AllocationItemT
 		return false, err
 	}
 
-	trxn, err := Db.Begin(context.Background())
-	if err != nil {
-		return false, err
-	}
-
-	log.Println("Deleting existing records")
-	_, err = trxn.Exec(context.Background(), "delete from allocation_summary")
-	if err != nil {
-		trxn.Rollback(context.Background())
-		return false, err
-	}
-
 	for _, item := range allocations {
 		if len(item.Uid) == 0 {
-			trxn.Rollback(context.Background())
 			errMsg := fmt.Sprint("UID not in record: ", item)
 			log.Println(errMsg)
-			return false, errors.New(errMsg)
-		}
-		sqlFields, valIdxs, values := AllocItemType2Sql(item)
-		sqlCmd := fmt.Sprintf("insert into allocation_summary(%s) values (%s)",
-			strings.Join(sqlFields, ","), strings.Join(valIdxs, ","))
-		log.Println("Adding Allocation for ", item.Uid, " SqlCmd: ", sqlCmd)
-		_, err = trxn.Exec(context.Background(), sqlCmd, values...)
-		if err != nil {
-			trxn.Rollback(context.Background())
-			return false, err
+			return false, newValidationError("uid", "%s", errMsg)
 		}
-
 	}
-	log.Println("About to make a commitment")
-	err = trxn.Commit(context.Background())
-	if err != nil {
-		return false, err
-	}
-	return true, nil
-}
-
-const (
-	DROP_ORDER_TABLE_SQL   = "drop table allocation_summary, mulch_delivery_timecards, mulch_orders, mulch_spreaders"
-	MULCH_ORDERS_TABLE_SQL = `
-CREATE TABLE mulch_orders (order_id UUID PRIMARY KEY DEFAULT gen_random_uuid(), order_owner_id STRING, cash_amount_collected DECIMAL(13, 4),
- check_amount_collected DECIMAL(13, 4), check_numbers STRING, amount_from_donations DECIMAL(13, 4), amount_from_purchases DECIMAL(13, 4),
- will_collect_money_later BOOL, total_amount_collected DECIMAL(13,4), special_instructions STRING, is_verified BOOL, last_modified_time TIMESTAMP,
- purchases JSONB, delivery_id INT, customer_addr1 STRING, customer_addr2 STRING, customer_zipcode INT, customer_city STRING,
- customer_neighborhood STRING, known_addr_id UUID, customer_email STRING, customer_phone STRING, customer_name STRING, comments STRING)
-`
-)
 
-const (
-	MULCH_SPREADERS_TABLE_SQL         = "CREATE TABLE mulch_spreaders (order_id UUID PRIMARY KEY, spreaders STRING[])"
-	MULCH_DELIVERY_TIMECARD_TABLE_SQL = `CREATE TABLE mulch_delivery_timecards (uid STRING, delivery_id INT, last_modified_time ` +
-		`TIMESTAMP, time_in TIME, time_out TIME, time_total TIME, PRIMARY KEY (uid, delivery_id, time_in))`
-)
-
-const ALLOCATION_SUMMARY_TABLE_SQL = `CREATE TABLE allocation_summary (uid STRING PRIMARY KEY, bags_sold INT, bags_spread DECIMAL(13,4), ` +
-	`delivery_minutes DECIMAL(13,4), total_donations DECIMAL(13,4), allocation_from_bags_sold DECIMAL(13,4), allocation_from_bags_spread DECIMAL(13,4), ` +
-	`allocation_from_delivery DECIMAL(13,4), allocation_total DECIMAL(13,4))`
-
-const (
-	DROP_USERS_TABLE_SQL = "drop table users"
-	USERS_TABLE_SQL      = `CREATE TABLE users (id STRING, group_id STRING, first_name STRING, last_name STRING, ` +
-		`created_time TIMESTAMP, last_modified_time TIMESTAMP, has_auth_creds BOOL)`
-)
-
-// //////////////////////////////////////////////////////////////////////////
-func resetOrderTables(_ context.Context, trxn *pgx.Tx) error {
-	resetSqlCmds := [...]string{
-		DROP_ORDER_TABLE_SQL,
-		MULCH_ORDERS_TABLE_SQL,
-		MULCH_SPREADERS_TABLE_SQL,
-		MULCH_DELIVERY_TIMECARD_TABLE_SQL,
-		ALLOCATION_SUMMARY_TABLE_SQL,
-	}
+	err := frStore.InTx(ctx, func(tx FrStore) error {
+		existingAllocations := make(map[string]*AllocationItemType)
+		for _, item := range allocations {
+			existing, err := tx.GetAllocation(ctx, item.Uid)
+			if err == nil {
+				existingAllocations[item.Uid] = &existing
+			} else if !errors.Is(err, ErrNotFound) {
+				return err
+			}
+		}
 
-	for _, sqlCmd := range resetSqlCmds {
-		if _, err := (*trxn).Exec(context.Background(), sqlCmd); err != nil {
+		if err := tx.SetAllocations(ctx, allocations); err != nil {
 			return err
 		}
+
+		for _, item := range allocations {
+			err := recordAuditEntry(ctx, tx, "setFundraiserCloseoutAllocations", "allocation", item.Uid,
+				existingAllocations[item.Uid], item)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
 	}
-	return nil
+	return true, nil
 }
 
 // //////////////////////////////////////////////////////////////////////////
+// Resetting the orders deletes them.  CloseSeason archives them first.
 func ResetFundraisingData(ctx context.Context, doResetUsers bool, doResetOrders bool) (bool, error) {
 	log.Printf("Setting Fr Data: users: %t  doResetOrders: %t", doResetUsers, doResetOrders)
 
@@ -2408,42 +1605,22 @@ func ResetFundraisingData(ctx context.Context, doResetUsers bool, doResetOrders
 		return false, err
 	}
 
-	trxn, err := Db.Begin(context.Background())
-	if err != nil {
-		return false, err
-	}
-
-	if doResetUsers {
-		log.Println("Resetting users data ")
-		_, err = trxn.Exec(context.Background(), DROP_USERS_TABLE_SQL)
-		if err != nil {
-			trxn.Rollback(context.Background())
-			return false, err
-		}
-		_, err = trxn.Exec(context.Background(), USERS_TABLE_SQL)
-		if err != nil {
-			trxn.Rollback(context.Background())
-			return false, err
-		}
-	}
-
-	if doResetOrders {
-		log.Println("Resetting orders data")
-		err = resetOrderTables(ctx, &trxn)
-		if err != nil {
-			trxn.Rollback(context.Background())
-			return false, err
+	err := frStore.InTx(ctx, func(tx FrStore) error {
+		if doResetUsers {
+			log.Println("Resetting users data ")
+			if err := tx.ResetUsers(ctx); err != nil {
+				return err
+			}
 		}
 
-		frConfig := FrConfigType{FinalizationData: &FinalizationDataType{}, MulchDeliveryConfigs: &[]MulchDeliveryConfigType{}}
-		if err := updateFundraiserConfigWithTrxn(ctx, &trxn, frConfig); err != nil {
-			trxn.Rollback(context.Background())
-			return false, err
+		if doResetOrders {
+			if err := resetOrderDataWithTrxn(ctx, tx, false); err != nil {
+				return err
+			}
 		}
-	}
-
-	log.Println("About to make a commitment")
-	err = trxn.Commit(context.Background())
+		return recordAuditEntry(ctx, tx, "resetFundraisingData", "fundraiser", "", nil,
+			map[string]bool{"doResetUsers": doResetUsers, "doResetOrders": doResetOrders})
+	})
 	if err != nil {
 		return false, err
 	}
@@ -2459,32 +1636,52 @@ type GeocodedAddress struct {
 }
 
 // //////////////////////////////////////////////////////////////////////////
+// Addresses already in known_addrs are used before asking the geocoder.
+// What the geocoder finds is saved there for next time.
 func GetAddrFromLatLng(ctx context.Context, lat float64, lng float64) (*GeocodedAddress, error) {
 	log.Printf("Reverse Geocoding lat/lng: (%.6f, %.6f)", lat, lng)
-	resolvedAddress, err := GEOCODER.ReverseGeocode(lat, lng)
+
+	// Only the addresses near it are read
+	knownAddrs, err := frStore.GetKnownAddrsInBox(ctx, makeGeoBox(lat, lng, reverseGeocodeCacheMiles))
 	if err != nil {
 		return nil, err
 	}
-	if resolvedAddress != nil {
-		log.Printf("Detailed address: %#v", resolvedAddress)
-		retVal := GeocodedAddress{
-			HouseNumber: resolvedAddress.HouseNumber,
-			Street:      resolvedAddress.Street,
-			City:        resolvedAddress.City,
-		}
-		zipcode, err := strconv.Atoi(resolvedAddress.Postcode)
-		if err != nil {
-			log.Printf("Failed to convert postcode: %s", resolvedAddress.Postcode)
-		} else {
-			retVal.Zipcode = zipcode
-		}
+	if known := findNearestKnownAddr(knownAddrs, lat, lng, reverseGeocodeCacheMiles); known != nil {
+		retVal := knownAddrToGeocodedAddress(*known)
+		log.Printf("Address of (%.6f,%.6f) is known: %v", lat, lng, *retVal)
+		return retVal, nil
+	}
 
-		log.Printf("Address of (%.6f,%.6f) is %v", lat, lng, retVal)
-		return &retVal, nil
-	} else {
+	retVal, err := getGeocoder().ReverseGeocode(ctx, lat, lng)
+	if err != nil {
+		return nil, err
+	}
+	if retVal == nil {
 		log.Println("got <nil> address")
+		return nil, nil
+	}
+	log.Printf("Address of (%.6f,%.6f) is %v", lat, lng, *retVal)
+
+	if len(retVal.Street) != 0 {
+		now := time.Now().UTC().Format(time.RFC3339)
+		known := KnownAddrType{
+			Addr:             strings.TrimSpace(retVal.HouseNumber + " " + retVal.Street),
+			Lat:              strconv.FormatFloat(lat, 'f', 7, 64),
+			Lng:              strconv.FormatFloat(lng, 'f', 7, 64),
+			LastModifiedTime: now,
+			CreatedTime:      now,
+		}
+		if len(retVal.City) != 0 {
+			known.City = &retVal.City
+		}
+		if retVal.Zipcode != 0 {
+			known.Zipcode = &retVal.Zipcode
+		}
+		if _, err := frStore.InsertKnownAddr(ctx, known); err != nil {
+			log.Println("Saving known address failed: ", err)
+		}
 	}
-	return nil, nil
+	return retVal, nil
 }
 
 ////////////////////////////////////////////////////////////////////////////
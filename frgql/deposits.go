@@ -0,0 +1,448 @@
+package frgql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Format of the deposit date
+const DEPOSIT_DATE_FORMAT = "2006-01-02"
+
+// //////////////////////////////////////////////////////////////////////////
+// Money of an order that went into a deposit.  CheckNumber is empty when all
+// of the order's cash and checks were deposited together otherwise it is a
+// single check.  The amounts are what was deposited at the time so later
+// changes to the order show up as undeposited.
+type BankDepositItemType struct {
+	OrderId          string `json:"orderId"`
+	CheckNumber      string `json:"checkNumber"`
+	AmountFromCash   string `json:"amountFromCash"`
+	AmountFromChecks string `json:"amountFromChecks"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// A deposit in the ledger.  AmountUnmatched is the part of the deposit not
+// accounted for by its items.
+type BankDepositType struct {
+	Id               string                `json:"id"`
+	DepositDate      string                `json:"depositDate"`
+	AmountFromCash   string                `json:"amountFromCash"`
+	AmountFromChecks string                `json:"amountFromChecks"`
+	AmountTotal      string                `json:"amountTotal"`
+	AmountUnmatched  string                `json:"amountUnmatched"`
+	Comments         *string               `json:"comments"`
+	CreatedBy        string                `json:"createdBy"`
+	CreatedTime      string                `json:"createdTime"`
+	Items            []BankDepositItemType `json:"items"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+type BankDepositCheckType struct {
+	OrderId     string `json:"orderId"`
+	CheckNumber string `json:"checkNumber"`
+	Amount      string `json:"amount"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// What recordBankDeposit is given.  OrderIds are orders whose cash and
+// checks were all deposited and Checks are checks deposited on their own.
+type BankDepositParams struct {
+	DepositDate      string                 `json:"depositDate"`
+	AmountFromCash   string                 `json:"amountFromCash"`
+	AmountFromChecks string                 `json:"amountFromChecks"`
+	Comments         *string                `json:"comments"`
+	OrderIds         []string               `json:"orderIds"`
+	Checks           []BankDepositCheckType `json:"checks"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Money collected against money deposited for a seller (OwnerId) or a
+// delivery (DeliveryId)
+type DepositReconciliationRowType struct {
+	OwnerId                   *string `json:"ownerId"`
+	DeliveryId                *int    `json:"deliveryId"`
+	AmountFromCashCollected   string  `json:"amountFromCashCollected"`
+	AmountFromChecksCollected string  `json:"amountFromChecksCollected"`
+	AmountTotalCollected      string  `json:"amountTotalCollected"`
+	AmountFromCashDeposited   string  `json:"amountFromCashDeposited"`
+	AmountFromChecksDeposited string  `json:"amountFromChecksDeposited"`
+	AmountTotalDeposited      string  `json:"amountTotalDeposited"`
+	AmountUndeposited         string  `json:"amountUndeposited"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+type DepositReconciliationType struct {
+	Sellers    []DepositReconciliationRowType `json:"sellers"`
+	Deliveries []DepositReconciliationRowType `json:"deliveries"`
+	Totals     DepositReconciliationRowType   `json:"totals"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Fills in the amounts worked out from the rest of the deposit
+func calcBankDepositAmounts(deposit *BankDepositType) error {
+	cash, err := parseAmount(&deposit.AmountFromCash)
+	if err != nil {
+		return fmt.Errorf("deposit: %s amountFromCash: %w", deposit.Id, err)
+	}
+	checks, err := parseAmount(&deposit.AmountFromChecks)
+	if err != nil {
+		return fmt.Errorf("deposit: %s amountFromChecks: %w", deposit.Id, err)
+	}
+	matched := decimal.Zero
+	for _, item := range deposit.Items {
+		itemCash, err := parseAmount(&item.AmountFromCash)
+		if err != nil {
+			return fmt.Errorf("deposit: %s order: %s amountFromCash: %w", deposit.Id, item.OrderId, err)
+		}
+		itemChecks, err := parseAmount(&item.AmountFromChecks)
+		if err != nil {
+			return fmt.Errorf("deposit: %s order: %s amountFromChecks: %w", deposit.Id, item.OrderId, err)
+		}
+		matched = matched.Add(itemCash).Add(itemChecks)
+	}
+	deposit.AmountTotal = cash.Add(checks).StringFixedBank(4)
+	deposit.AmountUnmatched = cash.Add(checks).Sub(matched).StringFixedBank(4)
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Turns params into the deposit to record.  Orders have to exist and their
+// money can only be deposited once: an order that was deposited as a whole
+// can't be again or have checks deposited and a check can't be deposited
+// twice or for more than the order's checks.
+func makeBankDeposit(ctx context.Context, tx FrStore, params BankDepositParams) (BankDepositType, error) {
+	deposit := BankDepositType{
+		Comments:    params.Comments,
+		CreatedTime: time.Now().UTC().Format(time.RFC3339),
+		Items:       []BankDepositItemType{},
+	}
+
+	depositDate, err := time.Parse(DEPOSIT_DATE_FORMAT, params.DepositDate)
+	if err != nil {
+		return deposit, newValidationError("depositDate", "depositDate must be YYYY-MM-DD not: %s", params.DepositDate)
+	}
+	deposit.DepositDate = depositDate.Format(DEPOSIT_DATE_FORMAT)
+
+	cash, err := parseAmount(&params.AmountFromCash)
+	if err != nil || cash.IsNegative() {
+		return deposit, newValidationError("amountFromCash", "amountFromCash is not an amount: %s", params.AmountFromCash)
+	}
+	checks, err := parseAmount(&params.AmountFromChecks)
+	if err != nil || checks.IsNegative() {
+		return deposit, newValidationError("amountFromChecks", "amountFromChecks is not an amount: %s", params.AmountFromChecks)
+	}
+	if cash.Add(checks).IsZero() {
+		return deposit, newValidationError("", "a deposit needs an amountFromCash or amountFromChecks")
+	}
+	deposit.AmountFromCash = cash.StringFixedBank(4)
+	deposit.AmountFromChecks = checks.StringFixedBank(4)
+
+	existingDeposits, err := tx.GetBankDeposits(ctx)
+	if err != nil {
+		return deposit, err
+	}
+	wholeOrders := make(map[string]bool)
+	checksDeposited := make(map[string]decimal.Decimal)
+	checkNumbers := make(map[string]bool)
+	for _, existing := range existingDeposits {
+		for _, item := range existing.Items {
+			if len(item.CheckNumber) == 0 {
+				wholeOrders[item.OrderId] = true
+				continue
+			}
+			amount, _ := parseAmount(&item.AmountFromChecks)
+			checksDeposited[item.OrderId] = checksDeposited[item.OrderId].Add(amount)
+			checkNumbers[item.OrderId+"/"+item.CheckNumber] = true
+		}
+	}
+
+	getOrder := func(field string, orderId string) (MulchOrderType, error) {
+		order, err := tx.GetMulchOrder(ctx, GetMulchOrderParams{
+			OrderId:   orderId,
+			GqlFields: []string{"orderId", "amountFromCashCollected", "amountFromChecksCollected"},
+		})
+		if errors.Is(err, ErrNotFound) {
+			return order, newValidationError(field, "order: %s does not exist", orderId)
+		}
+		return order, err
+	}
+
+	for idx, orderId := range params.OrderIds {
+		field := fmt.Sprintf("orderIds.%d", idx)
+		if wholeOrders[orderId] {
+			return deposit, newValidationError(field, "order: %s has already been deposited", orderId)
+		}
+		if _, ok := checksDeposited[orderId]; ok {
+			return deposit, newValidationError(field, "order: %s has checks that were deposited on their own", orderId)
+		}
+		order, err := getOrder(field, orderId)
+		if err != nil {
+			return deposit, err
+		}
+		orderCash, _ := parseAmount(order.AmountFromCashCollected)
+		orderChecks, _ := parseAmount(order.AmountFromChecksCollected)
+		wholeOrders[orderId] = true
+		deposit.Items = append(deposit.Items, BankDepositItemType{
+			OrderId:          orderId,
+			AmountFromCash:   orderCash.StringFixedBank(4),
+			AmountFromChecks: orderChecks.StringFixedBank(4),
+		})
+	}
+
+	for idx, check := range params.Checks {
+		field := fmt.Sprintf("checks.%d", idx)
+		checkNumber := strings.TrimSpace(check.CheckNumber)
+		if len(checkNumber) == 0 {
+			return deposit, newValidationError(field+".checkNumber", "checks[%d] needs a checkNumber", idx)
+		}
+		amount, err := parseAmount(&check.Amount)
+		if err != nil || !amount.IsPositive() {
+			return deposit, newValidationError(field+".amount", "checks[%d] amount is not an amount: %s", idx, check.Amount)
+		}
+		if wholeOrders[check.OrderId] {
+			return deposit, newValidationError(field+".orderId", "order: %s has already been deposited", check.OrderId)
+		}
+		if checkNumbers[check.OrderId+"/"+checkNumber] {
+			return deposit, newValidationError(field+".checkNumber", "check: %s of order: %s has already been deposited",
+				checkNumber, check.OrderId)
+		}
+		order, err := getOrder(field+".orderId", check.OrderId)
+		if err != nil {
+			return deposit, err
+		}
+		orderChecks, _ := parseAmount(order.AmountFromChecksCollected)
+		deposited := checksDeposited[check.OrderId].Add(amount)
+		if deposited.GreaterThan(orderChecks) {
+			return deposit, newValidationError(field+".amount", "checks deposited for order: %s would be %s but only %s was collected",
+				check.OrderId, deposited.StringFixedBank(4), orderChecks.StringFixedBank(4))
+		}
+		checksDeposited[check.OrderId] = deposited
+		checkNumbers[check.OrderId+"/"+checkNumber] = true
+		deposit.Items = append(deposit.Items, BankDepositItemType{
+			OrderId:          check.OrderId,
+			CheckNumber:      checkNumber,
+			AmountFromCash:   decimal.Zero.StringFixedBank(4),
+			AmountFromChecks: amount.StringFixedBank(4),
+		})
+	}
+
+	return deposit, calcBankDepositAmounts(&deposit)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Adds a deposit to the ledger and returns its id.  Admin only
+func RecordBankDeposit(ctx context.Context, params BankDepositParams) (string, error) {
+	log.Printf("Recording bank deposit of: %s cash: %s checks: %s orders: %d checks: %d", params.DepositDate,
+		params.AmountFromCash, params.AmountFromChecks, len(params.OrderIds), len(params.Checks))
+
+	if err := VerifyAdminTokenFromCtx(ctx); err != nil {
+		return "", err
+	}
+	claims, err := parseTokenClaimsFromCtx(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	depositId := ""
+	err = frStore.InTx(ctx, func(tx FrStore) error {
+		deposit, err := makeBankDeposit(ctx, tx, params)
+		if err != nil {
+			return err
+		}
+		deposit.CreatedBy = claims.userId()
+
+		depositId, err = tx.InsertBankDeposit(ctx, deposit)
+		if err != nil {
+			return err
+		}
+		deposit.Id = depositId
+		return recordAuditEntry(ctx, tx, "recordBankDeposit", "bankDeposit", depositId, nil, deposit)
+	})
+	if err != nil {
+		log.Println("Recording bank deposit failed: ", err)
+		return "", err
+	}
+	return depositId, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Removes a deposit recorded by mistake.  Admin only
+func DeleteBankDeposit(ctx context.Context, depositId string) (bool, error) {
+	log.Println("Deleting bank deposit: ", depositId)
+
+	if err := VerifyAdminTokenFromCtx(ctx); err != nil {
+		return false, err
+	}
+
+	err := frStore.InTx(ctx, func(tx FrStore) error {
+		deposit, err := tx.GetBankDeposit(ctx, depositId)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return &NotFoundError{Message: fmt.Sprintf("deposit: %s does not exist", depositId)}
+			}
+			return err
+		}
+		if err := tx.DeleteBankDeposit(ctx, depositId); err != nil {
+			return err
+		}
+		return recordAuditEntry(ctx, tx, "deleteBankDeposit", "bankDeposit", depositId, deposit, nil)
+	})
+	if err != nil {
+		log.Println("Deleting bank deposit failed: ", err)
+		return false, err
+	}
+	return true, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the ledger oldest deposit first.  Admin only
+func GetBankDeposits(ctx context.Context) ([]BankDepositType, error) {
+	log.Println("Retrieving bank deposits")
+
+	if err := VerifyAdminTokenFromCtx(ctx); err != nil {
+		return nil, err
+	}
+	deposits, err := frStore.GetBankDeposits(ctx)
+	if err != nil {
+		log.Println("Bank deposits query failed: ", err)
+		return nil, err
+	}
+	for idx := range deposits {
+		if err := calcBankDepositAmounts(&deposits[idx]); err != nil {
+			return nil, err
+		}
+	}
+	return deposits, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the total of every deposit in the ledger.  false if there aren't
+// any deposits.
+func getBankDepositedTotal(ctx context.Context, store FrStore) (string, bool, error) {
+	deposits, err := store.GetBankDeposits(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	if len(deposits) == 0 {
+		return "", false, nil
+	}
+	total := decimal.Zero
+	for _, deposit := range deposits {
+		for _, amount := range []string{deposit.AmountFromCash, deposit.AmountFromChecks} {
+			amt, err := parseAmount(&amount)
+			if err != nil {
+				return "", false, fmt.Errorf("deposit: %s: %w", deposit.Id, err)
+			}
+			total = total.Add(amt)
+		}
+	}
+	return total.StringFixedBank(4), true, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Shows the money collected that hasn't made it into a deposit for every
+// seller and every delivery.  ownerId of "" is everyone and only for admins
+func GetDepositReconciliation(ctx context.Context, ownerId string) (DepositReconciliationType, error) {
+	log.Println("Retrieving deposit reconciliation. OwnerId: ", ownerId)
+
+	if err := verifyUidAllowedFromCtx(ctx, ownerId); err != nil {
+		return DepositReconciliationType{}, err
+	}
+
+	collected, err := frStore.GetMulchOrdersMoneyCollected(ctx, GetMulchOrdersParams{
+		OwnerId: ownerId,
+		GqlFields: []string{
+			"ownerId", "deliveryId", "amountTotalFromCashCollected", "amountTotalFromChecksCollected",
+			"amountTotalFromCashDeposited", "amountTotalFromChecksDeposited",
+		},
+	})
+	if err != nil {
+		log.Println("Deposit reconciliation query failed: ", err)
+		return DepositReconciliationType{}, err
+	}
+
+	type rowSums struct {
+		cashCollected, checksCollected, cashDeposited, checksDeposited decimal.Decimal
+	}
+	addTo := func(sums *rowSums, row MulchOrderMoneyCollectedType) error {
+		amounts := []struct {
+			sum    *decimal.Decimal
+			amount *string
+		}{
+			{&sums.cashCollected, row.AmountTotalFromCashCollected},
+			{&sums.checksCollected, row.AmountTotalFromChecksCollected},
+			{&sums.cashDeposited, row.AmountTotalFromCashDeposited},
+			{&sums.checksDeposited, row.AmountTotalFromChecksDeposited},
+		}
+		for _, item := range amounts {
+			amt, err := parseAmount(item.amount)
+			if err != nil {
+				return fmt.Errorf("money collected by: %s: %w", row.OwnerId, err)
+			}
+			*item.sum = item.sum.Add(amt)
+		}
+		return nil
+	}
+	toRow := func(sums *rowSums) DepositReconciliationRowType {
+		collected := sums.cashCollected.Add(sums.checksCollected)
+		deposited := sums.cashDeposited.Add(sums.checksDeposited)
+		return DepositReconciliationRowType{
+			AmountFromCashCollected:   sums.cashCollected.StringFixedBank(4),
+			AmountFromChecksCollected: sums.checksCollected.StringFixedBank(4),
+			AmountTotalCollected:      collected.StringFixedBank(4),
+			AmountFromCashDeposited:   sums.cashDeposited.StringFixedBank(4),
+			AmountFromChecksDeposited: sums.checksDeposited.StringFixedBank(4),
+			AmountTotalDeposited:      deposited.StringFixedBank(4),
+			AmountUndeposited:         collected.Sub(deposited).StringFixedBank(4),
+		}
+	}
+
+	totals := &rowSums{}
+	sellers := make(map[string]*rowSums)
+	deliveries := make(map[int]*rowSums)
+	for _, row := range collected {
+		if _, ok := sellers[row.OwnerId]; !ok {
+			sellers[row.OwnerId] = &rowSums{}
+		}
+		deliveryId := 0
+		if row.DeliveryId != nil {
+			deliveryId = *row.DeliveryId
+		}
+		if _, ok := deliveries[deliveryId]; !ok {
+			deliveries[deliveryId] = &rowSums{}
+		}
+		for _, sums := range []*rowSums{totals, sellers[row.OwnerId], deliveries[deliveryId]} {
+			if err := addTo(sums, row); err != nil {
+				return DepositReconciliationType{}, err
+			}
+		}
+	}
+
+	reconciliation := DepositReconciliationType{
+		Sellers:    []DepositReconciliationRowType{},
+		Deliveries: []DepositReconciliationRowType{},
+		Totals:     toRow(totals),
+	}
+	for _, sellerId := range slices.Sorted(maps.Keys(sellers)) {
+		row := toRow(sellers[sellerId])
+		row.OwnerId = &sellerId
+		reconciliation.Sellers = append(reconciliation.Sellers, row)
+	}
+	for _, deliveryId := range slices.Sorted(maps.Keys(deliveries)) {
+		row := toRow(deliveries[deliveryId])
+		if deliveryId != 0 {
+			// 0 is the orders without a delivery
+			row.DeliveryId = &deliveryId
+		}
+		reconciliation.Deliveries = append(reconciliation.Deliveries, row)
+	}
+	return reconciliation, nil
+}
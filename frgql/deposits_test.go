@@ -0,0 +1,121 @@
+package frgql
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// //////////////////////////////////////////////////////////////////////////
+// testOrderId1 was paid 24 in cash and testOrderId2 20 in checks.  An
+// earlier deposit has check 1001 of testOrderId2 and all of testOrderId3.
+func TestMakeBankDeposit(t *testing.T) {
+	store := NewMemStore()
+	ctx := context.Background()
+
+	checks, none := "20.0000", "0"
+	order1 := makeTestOrder(testOrderId1, "scout1")
+	order2 := makeTestOrder(testOrderId2, "scout1")
+	order2.AmountFromCashCollected, order2.AmountFromChecksCollected = &none, &checks
+	order3 := makeTestOrder(testOrderId3, "scout1")
+	for _, order := range []MulchOrderType{order1, order2, order3} {
+		mustNotFail(t, store.InsertMulchOrder(ctx, order))
+	}
+	_, err := store.InsertBankDeposit(ctx, BankDepositType{
+		DepositDate: "2024-04-01", AmountFromCash: "24.0000", AmountFromChecks: "5.0000",
+		Items: []BankDepositItemType{
+			{OrderId: testOrderId2, CheckNumber: "1001", AmountFromCash: "0.0000", AmountFromChecks: "5.0000"},
+			{OrderId: testOrderId3, AmountFromCash: "24.0000", AmountFromChecks: "0.0000"},
+		},
+	})
+	mustNotFail(t, err)
+
+	tests := []struct {
+		name            string
+		params          BankDepositParams
+		errorFields     []string
+		amountUnmatched string
+		numItems        int
+	}{
+		{
+			name: "whole order and a check",
+			params: BankDepositParams{DepositDate: "2024-04-08", AmountFromCash: "30", AmountFromChecks: "15",
+				OrderIds: []string{testOrderId1}, Checks: []BankDepositCheckType{{OrderId: testOrderId2, CheckNumber: "1002", Amount: "15"}}},
+			amountUnmatched: "6.0000",
+			numItems:        2,
+		},
+		{
+			name:            "nothing matched",
+			params:          BankDepositParams{DepositDate: "2024-04-08", AmountFromCash: "1,000"},
+			amountUnmatched: "1000.0000",
+		},
+		{
+			name:        "bad date",
+			params:      BankDepositParams{DepositDate: "04/08/2024", AmountFromCash: "10"},
+			errorFields: []string{"depositDate"},
+		},
+		{
+			name:        "negative cash",
+			params:      BankDepositParams{DepositDate: "2024-04-08", AmountFromCash: "-10"},
+			errorFields: []string{"amountFromCash"},
+		},
+		{
+			name:        "no money",
+			params:      BankDepositParams{DepositDate: "2024-04-08"},
+			errorFields: []string{},
+		},
+		{
+			name:        "order that doesn't exist",
+			params:      BankDepositParams{DepositDate: "2024-04-08", AmountFromCash: "10", OrderIds: []string{"nope"}},
+			errorFields: []string{"orderIds.0"},
+		},
+		{
+			name:        "order already deposited",
+			params:      BankDepositParams{DepositDate: "2024-04-08", AmountFromCash: "10", OrderIds: []string{testOrderId3}},
+			errorFields: []string{"orderIds.0"},
+		},
+		{
+			name:        "order with checks deposited on their own",
+			params:      BankDepositParams{DepositDate: "2024-04-08", AmountFromCash: "10", OrderIds: []string{testOrderId2}},
+			errorFields: []string{"orderIds.0"},
+		},
+		{
+			name: "check already deposited",
+			params: BankDepositParams{DepositDate: "2024-04-08", AmountFromChecks: "5",
+				Checks: []BankDepositCheckType{{OrderId: testOrderId2, CheckNumber: "1001", Amount: "5"}}},
+			errorFields: []string{"checks.0.checkNumber"},
+		},
+		{
+			name: "checks over what was collected",
+			params: BankDepositParams{DepositDate: "2024-04-08", AmountFromChecks: "16",
+				Checks: []BankDepositCheckType{{OrderId: testOrderId2, CheckNumber: "1002", Amount: "16"}}},
+			errorFields: []string{"checks.0.amount"},
+		},
+		{
+			name: "same check twice in a deposit",
+			params: BankDepositParams{DepositDate: "2024-04-08", AmountFromChecks: "10",
+				Checks: []BankDepositCheckType{
+					{OrderId: testOrderId2, CheckNumber: "1002", Amount: "5"},
+					{OrderId: testOrderId2, CheckNumber: "1002", Amount: "5"},
+				}},
+			errorFields: []string{"checks.1.checkNumber"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			deposit, err := makeBankDeposit(ctx, store, test.params)
+			if test.errorFields != nil {
+				var validationErr *ValidationError
+				if !errors.As(err, &validationErr) || !slices.Equal(validationErr.Fields, test.errorFields) {
+					t.Errorf("got error: %v not a validation error of: %v", err, test.errorFields)
+				}
+				return
+			}
+			mustNotFail(t, err)
+			if deposit.AmountUnmatched != test.amountUnmatched || len(deposit.Items) != test.numItems {
+				t.Errorf("deposit: %+v", deposit)
+			}
+		})
+	}
+}
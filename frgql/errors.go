@@ -0,0 +1,260 @@
+package frgql
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// Values of extensions.code on GraphQL errors
+const (
+	// The GraphQL request itself couldn't be parsed or is not valid for the schema
+	ErrCodeBadRequest      = "BAD_REQUEST"
+	ErrCodeUnauthenticated = "UNAUTHENTICATED"
+	ErrCodeForbidden       = "FORBIDDEN"
+	ErrCodeValidation      = "VALIDATION_FAILED"
+	ErrCodeNotFound        = "NOT_FOUND"
+	ErrCodeConflict        = "CONFLICT"
+	ErrCodeInternal        = "INTERNAL"
+)
+
+// //////////////////////////////////////////////////////////////////////////
+// The GraphQL request couldn't be decoded or run
+type BadRequestError struct {
+	Message string
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (e *BadRequestError) Error() string {
+	return e.Message
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (e *BadRequestError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": ErrCodeBadRequest}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// There is no token or it couldn't be verified
+type UnauthenticatedError struct {
+	Message string
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (e *UnauthenticatedError) Error() string {
+	return e.Message
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (e *UnauthenticatedError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": ErrCodeUnauthenticated}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// The user is known but isn't allowed to do this
+type ForbiddenError struct {
+	Message string
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (e *ForbiddenError) Error() string {
+	return e.Message
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (e *ForbiddenError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": ErrCodeForbidden}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Something given was wrong.  Fields are the dotted paths of the input fields
+// at fault (i.e. customer.phone) when they are known.
+type ValidationError struct {
+	Message string
+	Fields  []string
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (e *ValidationError) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{"code": ErrCodeValidation}
+	if len(e.Fields) != 0 {
+		extensions["fields"] = e.Fields
+	}
+	return extensions
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns a ValidationError about a single input field.  field can be empty
+// if it isn't about one field.
+func newValidationError(field string, format string, args ...any) error {
+	validationErr := &ValidationError{Message: fmt.Sprintf(format, args...)}
+	if len(field) != 0 {
+		validationErr.Fields = []string{field}
+	}
+	return validationErr
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// The record being asked about doesn't exist.  errors.Is(err, ErrNotFound)
+// is true for these.
+type NotFoundError struct {
+	Message string
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (e *NotFoundError) Error() string {
+	return e.Message
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (e *NotFoundError) Unwrap() error {
+	return ErrNotFound
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (e *NotFoundError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": ErrCodeNotFound}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returned when a record was changed by someone else after the client read
+// it.  Current is the record as it is now so the client can merge their
+// changes into it and try again.
+type ConflictError struct {
+	TargetKind              string
+	TargetKey               string
+	CurrentLastModifiedTime string
+	Current                 any
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("conflict: %s %s was changed by someone else (lastModifiedTime is now %s)",
+		e.TargetKind, e.TargetKey, e.CurrentLastModifiedTime)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Added to the GraphQL error so the client gets the current version
+func (e *ConflictError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code":                    ErrCodeConflict,
+		"targetKind":              e.TargetKind,
+		"targetKey":               e.TargetKey,
+		"currentLastModifiedTime": e.CurrentLastModifiedTime,
+		"current":                 e.Current,
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Anything else, i.e. the db is down.  Errors that aren't one of the types
+// above are treated as this.
+type InternalError struct {
+	Err error
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (e *InternalError) Error() string {
+	return e.Err.Error()
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (e *InternalError) Unwrap() error {
+	return e.Err
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (e *InternalError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": ErrCodeInternal}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returned by MakeGqlQueryWithVars when some of the data came back along with
+// the errors.  Err is the first error.  The response is still a success (200)
+// since the data that is there is good.
+type PartialResultError struct {
+	Err error
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (e *PartialResultError) Error() string {
+	return e.Err.Error()
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (e *PartialResultError) Unwrap() error {
+	return e.Err
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the GraphQL error extensions (always with a code) for an error a
+// resolver returned.  The typed error can be wrapped.
+func getErrorExtensions(err error) map[string]interface{} {
+	var extendedErr gqlerrors.ExtendedError
+	if errors.As(err, &extendedErr) {
+		return extendedErr.Extensions()
+	}
+	if errors.Is(err, ErrNotFound) {
+		return (&NotFoundError{Message: err.Error()}).Extensions()
+	}
+	return (&InternalError{Err: err}).Extensions()
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the extensions for an error in a GraphQL response.  Errors that
+// didn't come from a resolver are problems with the request itself.
+func getFormattedErrorExtensions(formattedErr gqlerrors.FormattedError) map[string]interface{} {
+	err := formattedErr.OriginalError()
+	if locatedErr, ok := err.(*gqlerrors.Error); ok {
+		err = locatedErr.OriginalError
+	}
+	if err == nil {
+		return (&BadRequestError{}).Extensions()
+	}
+	return getErrorExtensions(err)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the code of an error returned by MakeGqlQueryWithVars
+func GetErrorCode(err error) string {
+	var extensions map[string]interface{}
+	var formattedErr gqlerrors.FormattedError
+	if errors.As(err, &formattedErr) && formattedErr.Extensions != nil {
+		extensions = formattedErr.Extensions
+	} else {
+		extensions = getErrorExtensions(err)
+	}
+	if code, ok := extensions["code"].(string); ok {
+		return code
+	}
+	return ErrCodeInternal
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the HTTP status a failed GraphQL request should be sent back with
+func GetHttpStatusForError(err error) int {
+	var partialErr *PartialResultError
+	if errors.As(err, &partialErr) {
+		return http.StatusOK
+	}
+	switch GetErrorCode(err) {
+	case ErrCodeBadRequest, ErrCodeValidation:
+		return http.StatusBadRequest
+	case ErrCodeUnauthenticated:
+		return http.StatusUnauthorized
+	case ErrCodeForbidden:
+		return http.StatusForbidden
+	case ErrCodeNotFound:
+		return http.StatusNotFound
+	case ErrCodeConflict:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
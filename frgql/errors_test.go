@@ -0,0 +1,71 @@
+package frgql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// //////////////////////////////////////////////////////////////////////////
+func TestGetHttpStatusForError(t *testing.T) {
+	tests := []struct {
+		err        error
+		statusCode int
+	}{
+		{&BadRequestError{Message: "bad"}, http.StatusBadRequest},
+		{newValidationError("orderId", "orderId must be provided"), http.StatusBadRequest},
+		{&UnauthenticatedError{Message: "no token"}, http.StatusUnauthorized},
+		{&ForbiddenError{Message: "not yours"}, http.StatusForbidden},
+		{&NotFoundError{Message: "missing"}, http.StatusNotFound},
+		{fmt.Errorf("reading order: %w", ErrNotFound), http.StatusNotFound},
+		{&ConflictError{TargetKind: "mulchOrder", TargetKey: testOrderId1}, http.StatusConflict},
+		{errors.New("connection refused"), http.StatusInternalServerError},
+		// Whatever did resolve is sent back with the errors
+		{&PartialResultError{Err: &ForbiddenError{Message: "not yours"}}, http.StatusOK},
+		{&PartialResultError{Err: errors.New("connection refused")}, http.StatusOK},
+	}
+	for _, test := range tests {
+		if statusCode := GetHttpStatusForError(test.err); statusCode != test.statusCode {
+			t.Errorf("%T: %v returned: %d expected: %d", test.err, test.err, statusCode, test.statusCode)
+		}
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// The code of the error is in the response and the error that is returned
+func TestGqlErrorCodes(t *testing.T) {
+	newTestMemStore(t)
+	tests := []struct {
+		name string
+		ctx  context.Context
+		gql  string
+		code string
+	}{
+		{"doesn't parse", newTestCtx(t, "scout1", false), "{ mulchOrder(", ErrCodeBadRequest},
+		{"unknown field", newTestCtx(t, "scout1", false), "{ noSuchField }", ErrCodeBadRequest},
+		{"no token", context.Background(), "{ auditLog { operation } }", ErrCodeUnauthenticated},
+		{"not an admin", newTestCtx(t, "scout1", false), "{ auditLog { operation } }", ErrCodeForbidden},
+		{"missing order", newTestCtx(t, "scout1", false),
+			fmt.Sprintf("{ mulchOrder(orderId: %q) { orderId } }", testOrderId1), ErrCodeNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp, err := MakeGqlQuery(test.ctx, test.gql)
+			if code := GetErrorCode(err); code != test.code {
+				t.Errorf("returned: %v with code: %s expected: %s", err, code, test.code)
+			}
+			result := struct {
+				Errors []struct {
+					Extensions map[string]interface{} `json:"extensions"`
+				} `json:"errors"`
+			}{}
+			mustNotFail(t, json.Unmarshal(resp, &result))
+			if len(result.Errors) == 0 || result.Errors[0].Extensions["code"] != test.code {
+				t.Errorf("response: %s", resp)
+			}
+		})
+	}
+}
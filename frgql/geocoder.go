@@ -0,0 +1,596 @@
+package frgql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultOsmUrl       = "https://nominatim.openstreetmap.org/"
+	defaultOsmUserAgent = "T27FundraisingLambda (https://github.com/cch71/T27FundraisingLambda)"
+	// The OSM usage policy allows at most 1 request a second
+	osmMinRequestInterval = time.Second
+	// How long geocoding an order's address after it is saved waits on the
+	// geocoder before giving up.  The Lambda waits for it before responding
+	// so it is kept short.
+	orderGeocodeTimeout = 5 * time.Second
+	// A cached address this close to a location is used for getAddress
+	reverseGeocodeCacheMiles = 0.02
+)
+
+// //////////////////////////////////////////////////////////////////////////
+type GeoLocation struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+type GeocodeQuery struct {
+	Addr    string
+	City    *string
+	Zipcode *int
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Looks up the location of an address and the address at a location.  Both
+// return nil (and no error) when there is nothing found.
+type Geocoder interface {
+	Geocode(ctx context.Context, query GeocodeQuery) (*GeoLocation, error)
+	ReverseGeocode(ctx context.Context, lat float64, lng float64) (*GeocodedAddress, error)
+}
+
+var (
+	geocoderOnce sync.Once
+	geocoder     Geocoder
+	// Geocoding started after orders were saved
+	pendingOrderGeocodes pendingWork
+)
+
+// //////////////////////////////////////////////////////////////////////////
+// Counts work running in the background.  Unlike a sync.WaitGroup it can be
+// waited on until a ctx is done and started again while an earlier wait
+// gave up.
+type pendingWork struct {
+	mutex sync.Mutex
+	num   int
+	// Closed when num drops to 0
+	done chan struct{}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (w *pendingWork) add() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.num == 0 {
+		w.done = make(chan struct{})
+	}
+	w.num++
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (w *pendingWork) finish() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.num--
+	if w.num == 0 {
+		close(w.done)
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Waits for the work running now to finish or for ctx to be done
+func (w *pendingWork) wait(ctx context.Context) error {
+	w.mutex.Lock()
+	num, done := w.num, w.done
+	w.mutex.Unlock()
+	if num == 0 {
+		return nil
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Sets the geocoder used by the api.  When it isn't set it comes from the
+// environment (see GeocoderFromEnv) the first time it is needed.
+func SetGeocoder(g Geocoder) {
+	geocoderOnce.Do(func() {})
+	geocoder = g
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func getGeocoder() Geocoder {
+	geocoderOnce.Do(func() {
+		g, err := GeocoderFromEnv()
+		if err != nil {
+			log.Println("Geocoder setup failed so OpenStreetMap is used: ", err)
+			g = NewOsmGeocoder(defaultOsmUrl, defaultOsmUserAgent)
+		}
+		geocoder = g
+	})
+	return geocoder
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// GEOCODER_FILE uses a file of known addresses instead of a geocoding
+// service.  Otherwise OpenStreetMap Nominatim is used at GEOCODER_URL (the
+// public server by default) identified by GEOCODER_USER_AGENT.
+func GeocoderFromEnv() (Geocoder, error) {
+	if file := os.Getenv("GEOCODER_FILE"); len(file) != 0 {
+		return NewFileGeocoder(file)
+	}
+	osmUrl := os.Getenv("GEOCODER_URL")
+	if len(osmUrl) == 0 {
+		osmUrl = defaultOsmUrl
+	}
+	userAgent := os.Getenv("GEOCODER_USER_AGENT")
+	if len(userAgent) == 0 {
+		userAgent = defaultOsmUserAgent
+	}
+	return NewOsmGeocoder(osmUrl, userAgent), nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// OpenStreetMap Nominatim geocoder.  Requests are spaced out by
+// MinRequestInterval and identify the app by UserAgent as the OSM usage
+// policy asks.
+type OsmGeocoder struct {
+	BaseUrl            string
+	UserAgent          string
+	MinRequestInterval time.Duration
+	client             *http.Client
+	mutex              sync.Mutex
+	nextRequestTime    time.Time
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func NewOsmGeocoder(baseUrl string, userAgent string) *OsmGeocoder {
+	return &OsmGeocoder{
+		BaseUrl:            strings.TrimSuffix(baseUrl, "/") + "/",
+		UserAgent:          userAgent,
+		MinRequestInterval: osmMinRequestInterval,
+		client:             &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Waits for this request's turn.  Each caller reserves the next slot so
+// concurrent requests are spaced out too.
+func (g *OsmGeocoder) waitForTurn(ctx context.Context) error {
+	g.mutex.Lock()
+	now := time.Now()
+	requestTime := g.nextRequestTime
+	if requestTime.Before(now) {
+		requestTime = now
+	}
+	g.nextRequestTime = requestTime.Add(g.MinRequestInterval)
+	g.mutex.Unlock()
+
+	timer := time.NewTimer(time.Until(requestTime))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (g *OsmGeocoder) get(ctx context.Context, path string, params url.Values, result any) error {
+	if err := g.waitForTurn(ctx); err != nil {
+		return err
+	}
+	params.Set("format", "jsonv2")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.BaseUrl+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", g.UserAgent)
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("geocoder %s request failed: %s", path, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (g *OsmGeocoder) Geocode(ctx context.Context, query GeocodeQuery) (*GeoLocation, error) {
+	parts := []string{query.Addr}
+	if query.City != nil && len(*query.City) != 0 {
+		parts = append(parts, *query.City)
+	}
+	if query.Zipcode != nil {
+		parts = append(parts, strconv.Itoa(*query.Zipcode))
+	}
+
+	results := []struct {
+		Lat string `json:"lat"`
+		Lon string `json:"lon"`
+	}{}
+	err := g.get(ctx, "search", url.Values{"q": {strings.Join(parts, ", ")}, "limit": {"1"}}, &results)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, nil
+	}
+	lat, latErr := strconv.ParseFloat(results[0].Lat, 64)
+	lng, lngErr := strconv.ParseFloat(results[0].Lon, 64)
+	if latErr != nil || lngErr != nil {
+		return nil, fmt.Errorf("geocoder returned bad coordinates: (%s, %s)", results[0].Lat, results[0].Lon)
+	}
+	return &GeoLocation{Lat: lat, Lng: lng}, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (g *OsmGeocoder) ReverseGeocode(ctx context.Context, lat float64, lng float64) (*GeocodedAddress, error) {
+	result := struct {
+		Error   string `json:"error"`
+		Address struct {
+			HouseNumber string `json:"house_number"`
+			Road        string `json:"road"`
+			City        string `json:"city"`
+			Town        string `json:"town"`
+			Village     string `json:"village"`
+			Postcode    string `json:"postcode"`
+		} `json:"address"`
+	}{}
+	params := url.Values{
+		"lat": {strconv.FormatFloat(lat, 'f', 6, 64)},
+		"lon": {strconv.FormatFloat(lng, 'f', 6, 64)},
+	}
+	if err := g.get(ctx, "reverse", params, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Error) != 0 {
+		log.Printf("Reverse geocoding (%.6f, %.6f) found nothing: %s", lat, lng, result.Error)
+		return nil, nil
+	}
+
+	addr := &GeocodedAddress{HouseNumber: result.Address.HouseNumber, Street: result.Address.Road}
+	for _, city := range []string{result.Address.City, result.Address.Town, result.Address.Village} {
+		if len(city) != 0 {
+			addr.City = city
+			break
+		}
+	}
+	if zipcode, err := strconv.Atoi(result.Address.Postcode); err == nil {
+		addr.Zipcode = zipcode
+	} else {
+		log.Printf("Failed to convert postcode: %s", result.Address.Postcode)
+	}
+	return addr, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Geocoder backed by a JSON file of known addresses (the same fields as
+// known_addrs).  Used for testing and working offline.
+type FileGeocoder struct {
+	addrs []KnownAddrType
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func NewFileGeocoder(file string) (*FileGeocoder, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	g := &FileGeocoder{}
+	if err := json.Unmarshal(data, &g.addrs); err != nil {
+		return nil, fmt.Errorf("geocoder file: %s: %w", file, err)
+	}
+	log.Printf("Geocoding with: %d addresses from: %s", len(g.addrs), file)
+	return g, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (g *FileGeocoder) Geocode(ctx context.Context, query GeocodeQuery) (*GeoLocation, error) {
+	normalizedAddr := normalizeAddr(query.Addr)
+	candidates := []KnownAddrType{}
+	for _, addr := range g.addrs {
+		if normalizeAddr(addr.Addr) == normalizedAddr {
+			candidates = append(candidates, addr)
+		}
+	}
+	known := matchKnownAddr(candidates, query.City, query.Zipcode)
+	if known == nil {
+		return nil, nil
+	}
+	lat, lng, ok := parseKnownAddrLocation(*known)
+	if !ok {
+		return nil, nil
+	}
+	return &GeoLocation{Lat: lat, Lng: lng}, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (g *FileGeocoder) ReverseGeocode(ctx context.Context, lat float64, lng float64) (*GeocodedAddress, error) {
+	if known := findNearestKnownAddr(g.addrs, lat, lng, reverseGeocodeCacheMiles); known != nil {
+		return knownAddrToGeocodedAddress(*known), nil
+	}
+	return nil, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Latitudes and longitudes (in degrees) a search for known addresses is
+// limited to
+type GeoBoxType struct {
+	MinLat float64
+	MaxLat float64
+	MinLng float64
+	MaxLng float64
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the box around lat/lng that holds every point within miles of it.
+// A degree of longitude gets shorter away from the equator.
+func makeGeoBox(lat float64, lng float64, miles float64) GeoBoxType {
+	milesPerDegree := earthRadiusMiles * math.Pi / 180
+	latDelta := miles / milesPerDegree
+	lngDelta := 180.0
+	if cosLat := math.Cos(lat * math.Pi / 180); cosLat > 0.001 {
+		lngDelta = math.Min(latDelta/cosLat, 180)
+	}
+	return GeoBoxType{MinLat: lat - latDelta, MaxLat: lat + latDelta, MinLng: lng - lngDelta, MaxLng: lng + lngDelta}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (box GeoBoxType) contains(lat float64, lng float64) bool {
+	return lat >= box.MinLat && lat <= box.MaxLat && lng >= box.MinLng && lng <= box.MaxLng
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the known address closest to lat/lng if it is within maxMiles
+func findNearestKnownAddr(addrs []KnownAddrType, lat float64, lng float64, maxMiles float64) *KnownAddrType {
+	var nearest *KnownAddrType
+	nearestMiles := maxMiles
+	for idx := range addrs {
+		addrLat, addrLng, ok := parseKnownAddrLocation(addrs[idx])
+		if !ok {
+			continue
+		}
+		if miles := haversineMiles(lat, lng, addrLat, addrLng); miles <= nearestMiles {
+			nearest, nearestMiles = &addrs[idx], miles
+		}
+	}
+	return nearest
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Splits the house number off of a known address
+func knownAddrToGeocodedAddress(known KnownAddrType) *GeocodedAddress {
+	addr := &GeocodedAddress{Street: strings.TrimSpace(known.Addr)}
+	if houseNumber, street, ok := strings.Cut(addr.Street, " "); ok {
+		if _, err := strconv.Atoi(houseNumber); err == nil {
+			addr.HouseNumber, addr.Street = houseNumber, strings.TrimSpace(street)
+		}
+	}
+	if known.City != nil {
+		addr.City = *known.City
+	}
+	if known.Zipcode != nil {
+		addr.Zipcode = *known.Zipcode
+	}
+	return addr
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// A cached address is only used when it has the city and zipcode that were
+// asked for.  One saved without them could be the same street somewhere else.
+func findCachedKnownAddr(candidates []KnownAddrType, query GeocodeQuery) *KnownAddrType {
+	for idx, known := range candidates {
+		if query.Zipcode != nil && (known.Zipcode == nil || *known.Zipcode != *query.Zipcode) {
+			continue
+		}
+		if query.City != nil && len(strings.TrimSpace(*query.City)) != 0 && (known.City == nil ||
+			!strings.EqualFold(strings.TrimSpace(*known.City), strings.TrimSpace(*query.City))) {
+			continue
+		}
+		return &candidates[idx]
+	}
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the address in known_addrs matching query without asking the
+// geocoder.  nil when it isn't there.
+func getCachedKnownAddr(ctx context.Context, store FrStore, query GeocodeQuery) (*KnownAddrType, error) {
+	normalizedAddr := normalizeAddr(query.Addr)
+	if len(normalizedAddr) == 0 {
+		return nil, nil
+	}
+	candidates, err := store.GetKnownAddrsByNormalizedAddr(ctx, normalizedAddr)
+	if err != nil {
+		return nil, err
+	}
+	return findCachedKnownAddr(candidates, query), nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the known address of addr geocoding it and saving it in
+// known_addrs when it isn't there yet.  Addresses the geocoder can't find are
+// saved without coordinates so they aren't looked up again.  nil when there
+// is no address.
+func locateAddr(ctx context.Context, store FrStore, query GeocodeQuery) (*KnownAddrType, error) {
+	if len(normalizeAddr(query.Addr)) == 0 {
+		return nil, nil
+	}
+	cached, err := getCachedKnownAddr(ctx, store, query)
+	if err != nil || cached != nil {
+		return cached, err
+	}
+
+	location, err := getGeocoder().Geocode(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	known := KnownAddrType{
+		Addr:             strings.TrimSpace(query.Addr),
+		City:             query.City,
+		Zipcode:          query.Zipcode,
+		LastModifiedTime: now,
+		CreatedTime:      now,
+	}
+	if location != nil {
+		known.Lat = strconv.FormatFloat(location.Lat, 'f', 7, 64)
+		known.Lng = strconv.FormatFloat(location.Lng, 'f', 7, 64)
+	} else {
+		log.Println("Geocoder could not find: ", query.Addr)
+	}
+	if known.Id, err = store.InsertKnownAddr(ctx, known); err != nil {
+		return nil, err
+	}
+	return &known, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func getOrderGeocodeQuery(order MulchOrderType) GeocodeQuery {
+	return GeocodeQuery{
+		Addr:    order.Customer.Addr1,
+		City:    order.Customer.City,
+		Zipcode: order.Customer.Zipcode,
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Links the order to the known address of its customer's address if it is
+// already in known_addrs.  The geocoder isn't asked so saving an order never
+// waits on it.  Returns true when the address still has to be geocoded (see
+// geocodeOrderAddrLater).
+func setOrderKnownAddr(ctx context.Context, store FrStore, order *MulchOrderType) bool {
+	order.KnownAddrId = nil
+	query := getOrderGeocodeQuery(*order)
+	if len(normalizeAddr(query.Addr)) == 0 {
+		return false
+	}
+	known, err := getCachedKnownAddr(ctx, store, query)
+	if err != nil {
+		// It is looked up again when the order is geocoded
+		log.Println("Looking up known address of order: ", order.OrderId, " failed: ", err)
+		return true
+	}
+	if known == nil {
+		return true
+	}
+	order.KnownAddrId = &known.Id
+	return false
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Geocodes the address of an order and links the order to it.  Only
+// knownAddrId is changed and the order keeps its lastModifiedTime so clients
+// editing it don't get a conflict.  An order changed in the meantime is left
+// alone since saving it linked it again.  Returns whether the order was
+// linked.
+func linkOrderKnownAddr(ctx context.Context, store FrStore, orderId string) (bool, error) {
+	order, err := store.GetMulchOrder(ctx, GetMulchOrderParams{OrderId: orderId, GqlFields: allMulchOrderGqlFields})
+	if err != nil {
+		return false, err
+	}
+	known, err := locateAddr(ctx, store, getOrderGeocodeQuery(order))
+	if err != nil || known == nil {
+		return false, err
+	}
+	if order.KnownAddrId != nil && *order.KnownAddrId == known.Id {
+		return false, nil
+	}
+
+	order.KnownAddrId = &known.Id
+	err = store.UpdateMulchOrder(ctx, order, []string{"knownAddrId"}, order.LastModifiedTime)
+	if errors.Is(err, ErrStaleVersion) {
+		log.Println("Order: ", orderId, " changed while it was being geocoded")
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Geocodes the order's address in the background once it has been saved.
+// See WaitForOrderGeocodes.  This is best effort.  Orders it doesn't get to
+// (or when the geocoder is down) are linked by GeocodeOrderAddrs.
+func geocodeOrderAddrLater(orderId string) {
+	store := frStore
+	pendingOrderGeocodes.add()
+	go func() {
+		defer pendingOrderGeocodes.finish()
+		ctx, cancel := context.WithTimeout(context.Background(), orderGeocodeTimeout)
+		defer cancel()
+		if _, err := linkOrderKnownAddr(ctx, store, orderId); err != nil {
+			log.Println("Geocoding address of order: ", orderId, " failed: ", err)
+		}
+	}()
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Waits for the geocoding started after orders were saved to finish or for
+// ctx to be done.  Hosts that are frozen or stopped once they respond (i.e.
+// the Lambda) call it first so the geocoding isn't cut off.
+func WaitForOrderGeocodes(ctx context.Context) error {
+	return pendingOrderGeocodes.wait(ctx)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Geocodes the addresses of the orders that aren't linked to a known address
+// and links them.  At most limit orders are geocoded (all of them when limit
+// is 0 or less) since the geocoder is only asked once a second.  Returns the
+// number of orders linked.  Admin only
+func GeocodeOrderAddrs(ctx context.Context, limit int) (int, error) {
+	log.Println("Geocoding order addresses")
+
+	if err := VerifyAdminTokenFromCtx(ctx); err != nil {
+		return 0, err
+	}
+
+	orders, err := frStore.GetMulchOrders(ctx, GetMulchOrdersParams{GqlFields: []string{"orderId", "knownAddrId"}})
+	if err != nil {
+		return 0, err
+	}
+	numTried, numLinked := 0, 0
+	for _, order := range orders {
+		if order.KnownAddrId != nil {
+			continue
+		}
+		if limit > 0 && numTried >= limit {
+			break
+		}
+		numTried++
+		isLinked, err := linkOrderKnownAddr(ctx, frStore, order.OrderId)
+		if err != nil {
+			// The geocoder being down shouldn't stop the rest being tried
+			log.Println("Geocoding address of order: ", order.OrderId, " failed: ", err)
+			if ctx.Err() != nil {
+				return numLinked, ctx.Err()
+			}
+			continue
+		}
+		if isLinked {
+			numLinked++
+		}
+	}
+	log.Printf("Linked: %d of the: %d orders geocoded", numLinked, numTried)
+	return numLinked, nil
+}
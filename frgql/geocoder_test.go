@@ -0,0 +1,213 @@
+package frgql
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+// //////////////////////////////////////////////////////////////////////////
+// Geocoder that knows locations by normalized address and counts the calls.
+// When blocked is set calls wait for it to be closed (or their ctx).
+type testGeocoder struct {
+	mutex     sync.Mutex
+	locations map[string]GeoLocation
+	addrs     map[GeoLocation]GeocodedAddress
+	numCalls  int
+	blocked   chan struct{}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (g *testGeocoder) call(ctx context.Context) error {
+	g.mutex.Lock()
+	g.numCalls++
+	blocked := g.blocked
+	g.mutex.Unlock()
+	if blocked == nil {
+		return nil
+	}
+	select {
+	case <-blocked:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (g *testGeocoder) Geocode(ctx context.Context, query GeocodeQuery) (*GeoLocation, error) {
+	if err := g.call(ctx); err != nil {
+		return nil, err
+	}
+	if location, ok := g.locations[normalizeAddr(query.Addr)]; ok {
+		return &location, nil
+	}
+	return nil, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (g *testGeocoder) ReverseGeocode(ctx context.Context, lat float64, lng float64) (*GeocodedAddress, error) {
+	if err := g.call(ctx); err != nil {
+		return nil, err
+	}
+	if addr, ok := g.addrs[GeoLocation{Lat: lat, Lng: lng}]; ok {
+		return &addr, nil
+	}
+	return nil, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (g *testGeocoder) getNumCalls() int {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.numCalls
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Makes g the geocoder until the test is done
+func setTestGeocoder(t *testing.T, g *testGeocoder) {
+	prevGeocoder := getGeocoder()
+	SetGeocoder(g)
+	t.Cleanup(func() { SetGeocoder(prevGeocoder) })
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// The order saved without a known address is linked once the geocoding
+// started after the save is waited for
+func TestWaitForOrderGeocodes(t *testing.T) {
+	store := newTestMemStore(t)
+	ctx := context.Background()
+	g := &testGeocoder{locations: map[string]GeoLocation{normalizeAddr("12 Main St"): {Lat: 39.1, Lng: -94.5}}}
+	setTestGeocoder(t, g)
+
+	order := makeTestOrder(testOrderId1, "scout1")
+	mustNotFail(t, store.InsertMulchOrder(ctx, order))
+	geocodeOrderAddrLater(testOrderId1)
+	mustNotFail(t, WaitForOrderGeocodes(ctx))
+
+	linked, err := store.GetMulchOrder(ctx, GetMulchOrderParams{OrderId: testOrderId1, GqlFields: allMulchOrderGqlFields})
+	mustNotFail(t, err)
+	if linked.KnownAddrId == nil {
+		t.Fatal("order wasn't linked to a known address")
+	}
+	if linked.LastModifiedTime != order.LastModifiedTime {
+		t.Errorf("linking changed lastModifiedTime: %s to: %s", order.LastModifiedTime, linked.LastModifiedTime)
+	}
+
+	// Waiting stops when its ctx is done even if the geocoder isn't
+	g.blocked = make(chan struct{})
+	order2 := makeTestOrder(testOrderId2, "scout1")
+	order2.Customer.Addr1 = "40 Elm St"
+	mustNotFail(t, store.InsertMulchOrder(ctx, order2))
+	geocodeOrderAddrLater(testOrderId2)
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if err := WaitForOrderGeocodes(waitCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("waiting on a blocked geocoder returned: %v", err)
+	}
+	close(g.blocked)
+	mustNotFail(t, WaitForOrderGeocodes(ctx))
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Points just inside and outside of the miles in each direction
+func TestMakeGeoBox(t *testing.T) {
+	for _, lat := range []float64{0, 39.1, -60} {
+		box := makeGeoBox(lat, -94.5, 0.02)
+		milesPerDegree := earthRadiusMiles * math.Pi / 180
+		latDelta := 0.02 / milesPerDegree
+		lngDelta := latDelta / math.Cos(lat*math.Pi/180)
+		for _, scale := range []float64{-0.99, 0.99} {
+			if !box.contains(lat+latDelta*scale, -94.5) || !box.contains(lat, -94.5+lngDelta*scale) {
+				t.Errorf("box: %+v around lat: %f is missing a point at %f of the miles", box, lat, scale)
+			}
+		}
+		for _, scale := range []float64{-1.01, 1.01} {
+			if box.contains(lat+latDelta*scale, -94.5) || box.contains(lat, -94.5+lngDelta*scale) {
+				t.Errorf("box: %+v around lat: %f has a point at %f of the miles", box, lat, scale)
+			}
+		}
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// The geocoder is only asked about addresses that aren't in known_addrs and
+// what it finds, or doesn't, is saved there
+func TestLocateAddr(t *testing.T) {
+	store := newTestMemStore(t)
+	ctx := context.Background()
+	g := &testGeocoder{locations: map[string]GeoLocation{normalizeAddr("12 Main St"): {Lat: 39.1, Lng: -94.5}}}
+	setTestGeocoder(t, g)
+
+	zipcode, otherZipcode := 64101, 64102
+	steps := []struct {
+		name      string
+		query     GeocodeQuery
+		isLocated bool
+		numCalls  int
+		numAddrs  int
+	}{
+		{"miss", GeocodeQuery{Addr: "12 Main St", Zipcode: &zipcode}, true, 1, 1},
+		{"hit written differently", GeocodeQuery{Addr: "12 main street.", Zipcode: &zipcode}, true, 1, 1},
+		{"same street in another zipcode", GeocodeQuery{Addr: "12 Main St", Zipcode: &otherZipcode}, true, 2, 2},
+		{"not found", GeocodeQuery{Addr: "1 Nowhere Ln"}, false, 3, 3},
+		{"not found again", GeocodeQuery{Addr: "1 Nowhere Lane"}, false, 3, 3},
+	}
+	firstId := ""
+	for _, step := range steps {
+		known, err := locateAddr(ctx, store, step.query)
+		mustNotFail(t, err)
+		if known == nil {
+			t.Fatalf("%s: no known address", step.name)
+		}
+		if _, _, isLocated := parseKnownAddrLocation(*known); isLocated != step.isLocated {
+			t.Errorf("%s: located: %t", step.name, isLocated)
+		}
+		if len(firstId) == 0 {
+			firstId = known.Id
+		} else if step.name == "hit written differently" && known.Id != firstId {
+			t.Errorf("%s: got: %s not the cached: %s", step.name, known.Id, firstId)
+		}
+		if numCalls := g.getNumCalls(); numCalls != step.numCalls {
+			t.Errorf("%s: geocoder was called: %d times not: %d", step.name, numCalls, step.numCalls)
+		}
+		if numAddrs := len(store.data.knownAddrs); numAddrs != step.numAddrs {
+			t.Errorf("%s: %d known addresses not: %d", step.name, numAddrs, step.numAddrs)
+		}
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// A known address near the location is used before reverse geocoding and
+// what is reverse geocoded is saved
+func TestGetAddrFromLatLng(t *testing.T) {
+	store := newTestMemStore(t)
+	ctx := context.Background()
+	g := &testGeocoder{addrs: map[GeoLocation]GeocodedAddress{
+		{Lat: 39.1, Lng: -94.5}: {HouseNumber: "12", Street: "Main St", City: "Springfield", Zipcode: 64101},
+	}}
+	setTestGeocoder(t, g)
+
+	addr, err := GetAddrFromLatLng(ctx, 39.1, -94.5)
+	mustNotFail(t, err)
+	if addr == nil || addr.Street != "Main St" || g.getNumCalls() != 1 || len(store.data.knownAddrs) != 1 {
+		t.Fatalf("miss returned: %+v after: %d calls", addr, g.getNumCalls())
+	}
+
+	// About 35 feet away
+	addr, err = GetAddrFromLatLng(ctx, 39.1001, -94.5)
+	mustNotFail(t, err)
+	if addr == nil || addr.HouseNumber != "12" || addr.Street != "Main St" || addr.Zipcode != 64101 || g.getNumCalls() != 1 {
+		t.Errorf("hit returned: %+v after: %d calls", addr, g.getNumCalls())
+	}
+
+	// About 700 feet away
+	addr, err = GetAddrFromLatLng(ctx, 39.102, -94.5)
+	mustNotFail(t, err)
+	if addr != nil || g.getNumCalls() != 2 || len(store.data.knownAddrs) != 1 {
+		t.Errorf("far away returned: %+v after: %d calls", addr, g.getNumCalls())
+	}
+}
@@ -7,7 +7,6 @@ toolchain go1.24.2
 require github.com/graphql-go/graphql v0.8.1
 
 require (
-	github.com/codingsince1985/geo-golang v1.8.5
 	github.com/deckarep/golang-set/v2 v2.8.0
 	github.com/doug-martin/goqu/v9 v9.19.0
 	github.com/golang-jwt/jwt/v5 v5.2.2
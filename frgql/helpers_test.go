@@ -0,0 +1,98 @@
+package frgql
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testIssuer = "https://idp.test/realms/t27"
+
+var testSigningKey *rsa.PrivateKey
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns a JWKS document with the public part of key under kid
+func makeTestJwks(t *testing.T, kid string, key *rsa.PrivateKey) []byte {
+	t.Helper()
+	jwks := map[string]interface{}{"keys": []map[string]string{{
+		"kid": kid,
+		"kty": "RSA",
+		"use": "sig",
+		"alg": "RS256",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}}
+	data, err := json.Marshal(jwks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Verifies tokens against a generated key pair (see newTestCtx) and makes an
+// empty MemStore the store
+func newTestMemStore(t *testing.T) *MemStore {
+	t.Helper()
+	if testSigningKey == nil {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testSigningKey = key
+	}
+
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(jwksFile, makeTestJwks(t, "test", testSigningKey), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetTokenVerifier(TokenVerifierConfig{JwksFile: jwksFile, Issuer: testIssuer}); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewMemStore()
+	SetStore(store)
+	return store
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns a token for uid signed with key
+func makeTestToken(t *testing.T, key *rsa.PrivateKey, kid string, uid string, isAdmin bool) string {
+	t.Helper()
+	roles := []string{"/FrUsers"}
+	if isAdmin {
+		roles = append(roles, "/FrAdmins")
+	}
+	claims := T27FrClaims{
+		Id:    uid,
+		Roles: roles,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    testIssuer,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns a context with a token for uid the way the hosts pass it along
+func newTestCtx(t *testing.T, uid string, isAdmin bool) context.Context {
+	t.Helper()
+	return context.WithValue(context.Background(), "T27FrAuthorization",
+		makeTestToken(t, testSigningKey, "test", uid, isAdmin))
+}
@@ -0,0 +1,461 @@
+package frgql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"strconv"
+
+	"github.com/shopspring/decimal"
+)
+
+// Added to the bags sold when suggesting a supplier order and there isn't
+// a bufferPercentage in the config
+var defaultInventoryBufferPercentage = decimal.NewFromInt(5)
+
+// //////////////////////////////////////////////////////////////////////////
+// Mulch for a delivery.  BagsOrdered is what was ordered from the supplier,
+// BagsReceived what showed up and BagsLeftover what was left once the
+// delivery was done.  MulchCost is what the supplier charged for it.
+type MulchInventoryType struct {
+	DeliveryId       int     `json:"deliveryId"`
+	Supplier         *string `json:"supplier"`
+	BagsOrdered      int     `json:"bagsOrdered"`
+	Pallets          int     `json:"pallets"`
+	BagsReceived     int     `json:"bagsReceived"`
+	BagsLeftover     int     `json:"bagsLeftover"`
+	BagsDamaged      int     `json:"bagsDamaged"`
+	MulchCost        *string `json:"mulchCost"`
+	Comments         *string `json:"comments"`
+	LastModifiedBy   string  `json:"lastModifiedBy"`
+	LastModifiedTime string  `json:"lastModifiedTime"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// How supplier orders are suggested.  Stored in the fundraiser config.
+type InventoryPolicyType struct {
+	// Percentage added to the bags sold (default 5)
+	BufferPercentage *string `json:"bufferPercentage"`
+	// Suggestions are rounded up to whole pallets when this is set
+	BagsPerPallet *int `json:"bagsPerPallet"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Inventory of a delivery (DeliveryId) or all of them against the bags sold.
+// BagsAvailable is what was received less what was damaged.
+// BagsExpectedLeftover is what should have been left after delivering what
+// was sold and BagsUnaccounted is how many fewer were actually left.
+type MulchInventoryReportRowType struct {
+	DeliveryId           *int   `json:"deliveryId"`
+	HasInventory         bool   `json:"hasInventory"`
+	BagsOrdered          int    `json:"bagsOrdered"`
+	Pallets              int    `json:"pallets"`
+	BagsReceived         int    `json:"bagsReceived"`
+	BagsLeftover         int    `json:"bagsLeftover"`
+	BagsDamaged          int    `json:"bagsDamaged"`
+	MulchCost            string `json:"mulchCost"`
+	BagsSold             int    `json:"bagsSold"`
+	BagsAvailable        int    `json:"bagsAvailable"`
+	BagsExpectedLeftover int    `json:"bagsExpectedLeftover"`
+	BagsUnaccounted      int    `json:"bagsUnaccounted"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// BagsSoldWithoutDelivery are bags of orders that don't have a delivery yet
+type MulchInventoryReportType struct {
+	Deliveries              []MulchInventoryReportRowType `json:"deliveries"`
+	Totals                  MulchInventoryReportRowType   `json:"totals"`
+	BagsSoldWithoutDelivery int                           `json:"bagsSoldWithoutDelivery"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// What to order from the supplier for a delivery.  BagsNeeded is the bags
+// sold plus the buffer and BagsToOrder is that rounded up to whole pallets.
+// AdditionalBagsToOrder is what still has to be ordered on top of the
+// delivery's inventory record.
+type SupplierOrderSuggestionType struct {
+	DeliveryId            int     `json:"deliveryId"`
+	BagsSold              int     `json:"bagsSold"`
+	BufferPercentage      string  `json:"bufferPercentage"`
+	BagsNeeded            int     `json:"bagsNeeded"`
+	BagsPerPallet         *int    `json:"bagsPerPallet"`
+	PalletsToOrder        *int    `json:"palletsToOrder"`
+	BagsToOrder           int     `json:"bagsToOrder"`
+	BagsAlreadyOrdered    int     `json:"bagsAlreadyOrdered"`
+	AdditionalBagsToOrder int     `json:"additionalBagsToOrder"`
+	Supplier              *string `json:"supplier"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Checks the policy.  A nil policy is the default one.
+func parseInventoryPolicy(policy *InventoryPolicyType) (decimal.Decimal, *int, error) {
+	bufferPercentage := defaultInventoryBufferPercentage
+	if policy == nil {
+		return bufferPercentage, nil, nil
+	}
+	if policy.BufferPercentage != nil && len(*policy.BufferPercentage) != 0 {
+		amt, err := decimal.NewFromString(*policy.BufferPercentage)
+		if err != nil || amt.IsNegative() {
+			return bufferPercentage, nil, newValidationError("inventoryPolicy.bufferPercentage",
+				"bufferPercentage must be a percentage of 0 or more not: %s", *policy.BufferPercentage)
+		}
+		bufferPercentage = amt
+	}
+	if policy.BagsPerPallet != nil && *policy.BagsPerPallet < 1 {
+		return bufferPercentage, nil, newValidationError("inventoryPolicy.bagsPerPallet",
+			"bagsPerPallet must be at least 1 not: %d", *policy.BagsPerPallet)
+	}
+	return bufferPercentage, policy.BagsPerPallet, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the bags sold for each delivery.  Orders without a delivery are
+// under 0.
+func getBagsSoldByDelivery(ctx context.Context, store FrStore) (map[int]int, error) {
+	orders, err := store.GetMulchOrders(ctx, GetMulchOrdersParams{
+		SortBy:    "orderId",
+		GqlFields: []string{"orderId", "deliveryId", "purchases"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	bagsSold := make(map[int]int)
+	for _, order := range orders {
+		deliveryId := 0
+		if order.DeliveryId != nil {
+			deliveryId = *order.DeliveryId
+		}
+		for _, purchase := range order.Purchases {
+			if purchase.ProductId == "bags" {
+				bagsSold[deliveryId] += purchase.NumSold
+			}
+		}
+	}
+	return bagsSold, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the deliveries in the config.  Without a config there aren't any.
+func getMulchDeliveryConfigs(ctx context.Context, store FrStore) ([]MulchDeliveryConfigType, error) {
+	frConfig, err := store.GetFundraiserConfig(ctx, []string{"mulchDeliveryConfigs"})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if frConfig.MulchDeliveryConfigs == nil {
+		return nil, nil
+	}
+	return *frConfig.MulchDeliveryConfigs, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Checks the counts of an inventory record and that its delivery is one in
+// the config
+func validateMulchInventory(ctx context.Context, store FrStore, inventory MulchInventoryType) error {
+	deliveries, err := getMulchDeliveryConfigs(ctx, store)
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(deliveries, func(delivery MulchDeliveryConfigType) bool {
+		return delivery.Id == inventory.DeliveryId
+	}) {
+		return newValidationError("deliveryId", "delivery: %d is not in the fundraiser config", inventory.DeliveryId)
+	}
+
+	counts := []struct {
+		field string
+		count int
+	}{
+		{"bagsOrdered", inventory.BagsOrdered},
+		{"pallets", inventory.Pallets},
+		{"bagsReceived", inventory.BagsReceived},
+		{"bagsLeftover", inventory.BagsLeftover},
+		{"bagsDamaged", inventory.BagsDamaged},
+	}
+	for _, count := range counts {
+		if count.count < 0 {
+			return newValidationError(count.field, "%s can't be negative: %d", count.field, count.count)
+		}
+	}
+	if inventory.BagsLeftover+inventory.BagsDamaged > inventory.BagsReceived {
+		return &ValidationError{
+			Message: fmt.Sprintf("bagsLeftover (%d) and bagsDamaged (%d) are more than bagsReceived (%d)",
+				inventory.BagsLeftover, inventory.BagsDamaged, inventory.BagsReceived),
+			Fields: []string{"bagsLeftover", "bagsDamaged", "bagsReceived"},
+		}
+	}
+	if inventory.MulchCost != nil && len(*inventory.MulchCost) != 0 {
+		amt, err := parseAmount(inventory.MulchCost)
+		if err != nil || amt.IsNegative() {
+			return newValidationError("mulchCost", "mulchCost must be an amount of 0 or more not: %s", *inventory.MulchCost)
+		}
+	}
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Adds or replaces the inventory record of a delivery.  Admin only
+func SetMulchInventory(ctx context.Context, inventory MulchInventoryType) (bool, error) {
+	log.Printf("Setting mulch inventory of delivery: %d ordered: %d received: %d leftover: %d damaged: %d",
+		inventory.DeliveryId, inventory.BagsOrdered, inventory.BagsReceived, inventory.BagsLeftover, inventory.BagsDamaged)
+
+	if err := VerifyAdminTokenFromCtx(ctx); err != nil {
+		return false, err
+	}
+	claims, err := parseTokenClaimsFromCtx(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	err = frStore.InTx(ctx, func(tx FrStore) error {
+		if err := validateMulchInventory(ctx, tx, inventory); err != nil {
+			return err
+		}
+		if inventory.MulchCost != nil && len(*inventory.MulchCost) != 0 {
+			mulchCost, _ := parseAmount(inventory.MulchCost)
+			mulchCostStr := mulchCost.StringFixedBank(4)
+			inventory.MulchCost = &mulchCostStr
+		} else {
+			inventory.MulchCost = nil
+		}
+		inventory.LastModifiedBy = claims.userId()
+		inventory.LastModifiedTime = makeLastModifiedTime()
+
+		var before *MulchInventoryType
+		existing, err := tx.GetMulchInventory(ctx, inventory.DeliveryId)
+		if err == nil {
+			before = &existing
+		} else if !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		if err := tx.UpsertMulchInventory(ctx, inventory); err != nil {
+			return err
+		}
+		return recordAuditEntry(ctx, tx, "setMulchInventory", "mulchInventory",
+			strconv.Itoa(inventory.DeliveryId), before, inventory)
+	})
+	if err != nil {
+		log.Println("Setting mulch inventory failed: ", err)
+		return false, err
+	}
+	return true, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Admin only
+func DeleteMulchInventory(ctx context.Context, deliveryId int) (bool, error) {
+	log.Println("Deleting mulch inventory of delivery: ", deliveryId)
+
+	if err := VerifyAdminTokenFromCtx(ctx); err != nil {
+		return false, err
+	}
+
+	err := frStore.InTx(ctx, func(tx FrStore) error {
+		inventory, err := tx.GetMulchInventory(ctx, deliveryId)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return &NotFoundError{Message: fmt.Sprintf("delivery: %d has no mulch inventory", deliveryId)}
+			}
+			return err
+		}
+		if err := tx.DeleteMulchInventory(ctx, deliveryId); err != nil {
+			return err
+		}
+		return recordAuditEntry(ctx, tx, "deleteMulchInventory", "mulchInventory",
+			strconv.Itoa(deliveryId), inventory, nil)
+	})
+	if err != nil {
+		log.Println("Deleting mulch inventory failed: ", err)
+		return false, err
+	}
+	return true, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Admin only
+func GetMulchInventories(ctx context.Context) ([]MulchInventoryType, error) {
+	log.Println("Retrieving mulch inventory")
+
+	if err := VerifyAdminTokenFromCtx(ctx); err != nil {
+		return nil, err
+	}
+	inventories, err := frStore.GetMulchInventories(ctx)
+	if err != nil {
+		log.Println("Mulch inventory query failed: ", err)
+		return nil, err
+	}
+	return inventories, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Compares the inventory of every delivery (those in the config, with
+// records or with orders) against the bags sold.  Admin only
+func GetMulchInventoryReport(ctx context.Context) (MulchInventoryReportType, error) {
+	log.Println("Building mulch inventory report")
+
+	if err := VerifyAdminTokenFromCtx(ctx); err != nil {
+		return MulchInventoryReportType{}, err
+	}
+
+	inventories, err := frStore.GetMulchInventories(ctx)
+	if err != nil {
+		return MulchInventoryReportType{}, err
+	}
+	bagsSold, err := getBagsSoldByDelivery(ctx, frStore)
+	if err != nil {
+		log.Println("Mulch inventory report orders query failed: ", err)
+		return MulchInventoryReportType{}, err
+	}
+	deliveries, err := getMulchDeliveryConfigs(ctx, frStore)
+	if err != nil {
+		return MulchInventoryReportType{}, err
+	}
+
+	inventoryByDelivery := make(map[int]MulchInventoryType)
+	for _, inventory := range inventories {
+		inventoryByDelivery[inventory.DeliveryId] = inventory
+	}
+	deliveryIds := make(map[int]bool)
+	for _, delivery := range deliveries {
+		deliveryIds[delivery.Id] = true
+	}
+	for deliveryId := range inventoryByDelivery {
+		deliveryIds[deliveryId] = true
+	}
+	for deliveryId := range bagsSold {
+		if deliveryId != 0 {
+			deliveryIds[deliveryId] = true
+		}
+	}
+
+	report := MulchInventoryReportType{
+		Deliveries:              []MulchInventoryReportRowType{},
+		BagsSoldWithoutDelivery: bagsSold[0],
+	}
+	totalMulchCost := decimal.Zero
+	for _, deliveryId := range slices.Sorted(maps.Keys(deliveryIds)) {
+		row := MulchInventoryReportRowType{DeliveryId: &deliveryId, BagsSold: bagsSold[deliveryId], MulchCost: "0.0000"}
+		if inventory, ok := inventoryByDelivery[deliveryId]; ok {
+			row.HasInventory = true
+			row.BagsOrdered = inventory.BagsOrdered
+			row.Pallets = inventory.Pallets
+			row.BagsReceived = inventory.BagsReceived
+			row.BagsLeftover = inventory.BagsLeftover
+			row.BagsDamaged = inventory.BagsDamaged
+			if inventory.MulchCost != nil {
+				mulchCost, err := parseAmount(inventory.MulchCost)
+				if err != nil {
+					return MulchInventoryReportType{}, fmt.Errorf("delivery: %d mulchCost: %w", deliveryId, err)
+				}
+				row.MulchCost = mulchCost.StringFixedBank(4)
+				totalMulchCost = totalMulchCost.Add(mulchCost)
+			}
+			row.BagsAvailable = row.BagsReceived - row.BagsDamaged
+			row.BagsExpectedLeftover = row.BagsAvailable - row.BagsSold
+			row.BagsUnaccounted = row.BagsExpectedLeftover - row.BagsLeftover
+		}
+		report.Deliveries = append(report.Deliveries, row)
+
+		report.Totals.HasInventory = report.Totals.HasInventory || row.HasInventory
+		report.Totals.BagsOrdered += row.BagsOrdered
+		report.Totals.Pallets += row.Pallets
+		report.Totals.BagsReceived += row.BagsReceived
+		report.Totals.BagsLeftover += row.BagsLeftover
+		report.Totals.BagsDamaged += row.BagsDamaged
+		report.Totals.BagsSold += row.BagsSold
+		report.Totals.BagsAvailable += row.BagsAvailable
+		report.Totals.BagsExpectedLeftover += row.BagsExpectedLeftover
+		report.Totals.BagsUnaccounted += row.BagsUnaccounted
+	}
+	report.Totals.MulchCost = totalMulchCost.StringFixedBank(4)
+	return report, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Suggests how many bags to order from the supplier for a delivery from
+// what has been sold so far.  bufferPercentage and bagsPerPallet override
+// the config's inventory policy.  Admin only
+func GetSupplierOrderSuggestion(ctx context.Context, deliveryId int, bufferPercentage *string, bagsPerPallet *int) (SupplierOrderSuggestionType, error) {
+	log.Println("Suggesting supplier order for delivery: ", deliveryId)
+
+	if err := VerifyAdminTokenFromCtx(ctx); err != nil {
+		return SupplierOrderSuggestionType{}, err
+	}
+
+	frConfig, err := frStore.GetFundraiserConfig(ctx, []string{"inventoryPolicy"})
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return SupplierOrderSuggestionType{}, err
+	}
+	policy := InventoryPolicyType{}
+	if frConfig.InventoryPolicy != nil {
+		policy = *frConfig.InventoryPolicy
+	}
+	if bufferPercentage != nil {
+		policy.BufferPercentage = bufferPercentage
+	}
+	if bagsPerPallet != nil {
+		policy.BagsPerPallet = bagsPerPallet
+	}
+	buffer, palletSize, err := parseInventoryPolicy(&policy)
+	if err != nil {
+		return SupplierOrderSuggestionType{}, err
+	}
+
+	bagsSold, err := getBagsSoldByDelivery(ctx, frStore)
+	if err != nil {
+		return SupplierOrderSuggestionType{}, err
+	}
+	suggestion := SupplierOrderSuggestionType{
+		DeliveryId:       deliveryId,
+		BagsSold:         bagsSold[deliveryId],
+		BufferPercentage: buffer.StringFixedBank(2),
+		BagsPerPallet:    palletSize,
+	}
+	suggestion.BagsNeeded = int(decimal.NewFromInt(int64(suggestion.BagsSold)).
+		Mul(buffer.Add(decimal.NewFromInt(100))).Div(decimal.NewFromInt(100)).Ceil().IntPart())
+	suggestion.BagsToOrder = suggestion.BagsNeeded
+	if palletSize != nil {
+		pallets := (suggestion.BagsNeeded + *palletSize - 1) / *palletSize
+		suggestion.PalletsToOrder = &pallets
+		suggestion.BagsToOrder = pallets * *palletSize
+	}
+
+	inventory, err := frStore.GetMulchInventory(ctx, deliveryId)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return SupplierOrderSuggestionType{}, err
+	}
+	suggestion.BagsAlreadyOrdered = inventory.BagsOrdered
+	suggestion.Supplier = inventory.Supplier
+	suggestion.AdditionalBagsToOrder = max(suggestion.BagsToOrder-suggestion.BagsAlreadyOrdered, 0)
+
+	log.Printf("Delivery: %d sold: %d suggest ordering: %d bags (%d more)", deliveryId,
+		suggestion.BagsSold, suggestion.BagsToOrder, suggestion.AdditionalBagsToOrder)
+	return suggestion, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the total mulch cost of the inventory records.  false if none of
+// them have a cost.
+func getInventoryMulchCostTotal(ctx context.Context, store FrStore) (string, bool, error) {
+	inventories, err := store.GetMulchInventories(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	total, hasCost := decimal.Zero, false
+	for _, inventory := range inventories {
+		if inventory.MulchCost == nil {
+			continue
+		}
+		amt, err := parseAmount(inventory.MulchCost)
+		if err != nil {
+			return "", false, fmt.Errorf("delivery: %d mulchCost: %w", inventory.DeliveryId, err)
+		}
+		total, hasCost = total.Add(amt), true
+	}
+	return total.StringFixedBank(4), hasCost, nil
+}
@@ -0,0 +1,160 @@
+package frgql
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// //////////////////////////////////////////////////////////////////////////
+// Deliveries 1 to 3 with 10 bags sold in delivery 1, 9 in delivery 2 and 3
+// without a delivery.  Supplier orders get a 10% buffer in pallets of 50.
+func insertTestInventoryOrders(t *testing.T, ctx context.Context, store FrStore) {
+	t.Helper()
+	bufferPercentage, bagsPerPallet := "10", 50
+	mustNotFail(t, store.SetFundraiserConfig(ctx, FrConfigType{
+		Products: testProducts,
+		MulchDeliveryConfigs: &[]MulchDeliveryConfigType{
+			{Id: 1, Date: "2025-03-08"}, {Id: 2, Date: "2025-04-12"}, {Id: 3, Date: "2025-05-10"},
+		},
+		InventoryPolicy:  &InventoryPolicyType{BufferPercentage: &bufferPercentage, BagsPerPallet: &bagsPerPallet},
+		LastModifiedTime: makeLastModifiedTime(),
+	}))
+
+	mustNotFail(t, store.InsertMulchOrder(ctx, makeTestOrder(testOrderId1, "scout1")))
+	moreBags := makeTestOrder(testOrderId2, "scout1")
+	moreBags.Purchases[0].NumSold = 6
+	mustNotFail(t, store.InsertMulchOrder(ctx, moreBags))
+	laterDelivery := makeTestOrder(testOrderId3, "scout2")
+	deliveryId := 2
+	laterDelivery.DeliveryId = &deliveryId
+	laterDelivery.Purchases[0].NumSold = 9
+	mustNotFail(t, store.InsertMulchOrder(ctx, laterDelivery))
+	noDelivery := makeTestOrder("0b5d3f3e-6a4f-4b39-9d2a-1e1d6b8f0004", "scout2")
+	noDelivery.DeliveryId = nil
+	noDelivery.Purchases[0].NumSold = 3
+	mustNotFail(t, store.InsertMulchOrder(ctx, noDelivery))
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func TestSetMulchInventoryValidation(t *testing.T) {
+	store := newTestMemStore(t)
+	insertTestInventoryOrders(t, context.Background(), store)
+	adminCtx := newTestCtx(t, "admin1", true)
+
+	tests := []struct {
+		name   string
+		modify func(inventory *MulchInventoryType)
+		fields []string
+	}{
+		{"delivery not in the config", func(inventory *MulchInventoryType) { inventory.DeliveryId = 9 }, []string{"deliveryId"}},
+		{"negative count", func(inventory *MulchInventoryType) { inventory.Pallets = -1 }, []string{"pallets"}},
+		{"more left than received", func(inventory *MulchInventoryType) { inventory.BagsLeftover = 19 },
+			[]string{"bagsLeftover", "bagsDamaged", "bagsReceived"}},
+		{"bad cost", func(inventory *MulchInventoryType) {
+			mulchCost := "abc"
+			inventory.MulchCost = &mulchCost
+		}, []string{"mulchCost"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			inventory := MulchInventoryType{DeliveryId: 1, BagsOrdered: 20, BagsReceived: 20, BagsDamaged: 2}
+			test.modify(&inventory)
+			if _, err := SetMulchInventory(adminCtx, inventory); !slices.Equal(getValidationFields(err), test.fields) {
+				t.Errorf("got error: %v not one for: %v", err, test.fields)
+			}
+		})
+	}
+
+	inventories, err := GetMulchInventories(adminCtx)
+	mustNotFail(t, err)
+	if len(inventories) != 0 {
+		t.Errorf("invalid inventory was saved: %+v", inventories)
+	}
+	var forbiddenErr *ForbiddenError
+	if _, err := SetMulchInventory(newTestCtx(t, "scout1", false), MulchInventoryType{DeliveryId: 1}); !errors.As(err, &forbiddenErr) {
+		t.Errorf("a scout setting the inventory returned: %v not a ForbiddenError", err)
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// The bags sold come off what was received to give what should be left and
+// the inventory is archived when the season is closed
+func TestMulchInventoryReport(t *testing.T) {
+	store := newTestMemStore(t)
+	insertTestInventoryOrders(t, context.Background(), store)
+	adminCtx := newTestCtx(t, "admin1", true)
+
+	mulchCost := "150.5"
+	_, err := SetMulchInventory(adminCtx, MulchInventoryType{DeliveryId: 1, BagsOrdered: 20, Pallets: 1,
+		BagsReceived: 20, BagsLeftover: 5, BagsDamaged: 2, MulchCost: &mulchCost})
+	mustNotFail(t, err)
+	inventories, err := GetMulchInventories(adminCtx)
+	mustNotFail(t, err)
+	if len(inventories) != 1 || *inventories[0].MulchCost != "150.5000" || inventories[0].LastModifiedBy != "admin1" {
+		t.Errorf("inventories: %+v", inventories)
+	}
+
+	report, err := GetMulchInventoryReport(adminCtx)
+	mustNotFail(t, err)
+	if len(report.Deliveries) != 3 || report.BagsSoldWithoutDelivery != 3 {
+		t.Fatalf("report: %+v", report)
+	}
+	row := report.Deliveries[0]
+	if *row.DeliveryId != 1 || !row.HasInventory || row.BagsSold != 10 || row.BagsAvailable != 18 ||
+		row.BagsExpectedLeftover != 8 || row.BagsUnaccounted != 3 || row.MulchCost != "150.5000" {
+		t.Errorf("delivery 1: %+v", row)
+	}
+	if row := report.Deliveries[1]; *row.DeliveryId != 2 || row.HasInventory || row.BagsSold != 9 || row.BagsExpectedLeftover != 0 {
+		t.Errorf("delivery 2: %+v", row)
+	}
+	totals := report.Totals
+	if !totals.HasInventory || totals.BagsOrdered != 20 || totals.BagsSold != 19 || totals.BagsAvailable != 18 ||
+		totals.BagsUnaccounted != 3 || totals.MulchCost != "150.5000" {
+		t.Errorf("totals: %+v", totals)
+	}
+
+	// 10 bags and 10% is 11 which is one pallet
+	suggestion, err := GetSupplierOrderSuggestion(adminCtx, 1, nil, nil)
+	mustNotFail(t, err)
+	if suggestion.BagsNeeded != 11 || *suggestion.PalletsToOrder != 1 || suggestion.BagsToOrder != 50 ||
+		suggestion.BagsAlreadyOrdered != 20 || suggestion.AdditionalBagsToOrder != 30 || suggestion.BufferPercentage != "10.00" {
+		t.Errorf("suggestion: %+v", suggestion)
+	}
+	noBuffer, palletSize := "0", 4
+	suggestion, err = GetSupplierOrderSuggestion(adminCtx, 1, &noBuffer, &palletSize)
+	mustNotFail(t, err)
+	if suggestion.BagsNeeded != 10 || suggestion.BagsToOrder != 12 || suggestion.AdditionalBagsToOrder != 0 {
+		t.Errorf("suggestion with overrides: %+v", suggestion)
+	}
+	palletSize = 0
+	if _, err := GetSupplierOrderSuggestion(adminCtx, 1, nil, &palletSize); !slices.Equal(
+		getValidationFields(err), []string{"inventoryPolicy.bagsPerPallet"}) {
+		t.Errorf("empty pallets returned: %v", err)
+	}
+
+	var notFoundErr *NotFoundError
+	if _, err := DeleteMulchInventory(adminCtx, 2); !errors.As(err, &notFoundErr) {
+		t.Errorf("deleting an inventory that doesn't exist returned: %v not a NotFoundError", err)
+	}
+	var forbiddenErr *ForbiddenError
+	if _, err := GetMulchInventoryReport(newTestCtx(t, "scout1", false)); !errors.As(err, &forbiddenErr) {
+		t.Errorf("a scout reading the report returned: %v not a ForbiddenError", err)
+	}
+
+	_, err = CloseSeason(adminCtx, 2024)
+	mustNotFail(t, err)
+	archived := store.data.seasons[2024].contents.MulchInventories
+	if len(archived) != 1 || archived[0].DeliveryId != 1 || *archived[0].MulchCost != "150.5000" {
+		t.Errorf("archived inventory: %+v", archived)
+	}
+	inventories, err = GetMulchInventories(adminCtx)
+	mustNotFail(t, err)
+	if len(inventories) != 0 {
+		t.Errorf("inventory left after closing the season: %+v", inventories)
+	}
+	if _, err := DeleteMulchInventory(adminCtx, 1); !errors.As(err, &notFoundErr) {
+		t.Errorf("deleting archived inventory returned: %v not a NotFoundError", err)
+	}
+}
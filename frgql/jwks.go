@@ -0,0 +1,365 @@
+package frgql
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// //////////////////////////////////////////////////////////////////////////
+// Settings used to verify the bearer tokens handed to the GraphQL api.
+// Either JwksUrl or JwksFile must be provided.  JwksFile is handy for
+// offline/local testing with a generated key pair.
+type TokenVerifierConfig struct {
+	JwksUrl  string
+	JwksFile string
+	Issuer   string
+	Audience string
+	// How long a fetched key set is trusted before it is re-fetched
+	CacheTtl time.Duration
+	// Minimum time between fetches of the key set, failed ones included
+	MinRefreshInterval time.Duration
+}
+
+const (
+	defaultJwksCacheTtl           = 12 * time.Hour
+	defaultJwksMinRefreshInterval = time.Minute
+	jwtClockLeeway                = 30 * time.Second
+)
+
+var (
+	tokenVerifierMutex sync.Mutex
+	tokenVerifier      *jwksKeySet
+)
+
+// //////////////////////////////////////////////////////////////////////////
+// Builds the token verifier config from the environment.  If the JWKS
+// location/issuer is not explicitly given it is derived from the Keycloak
+// settings that the cli already uses.
+func TokenVerifierConfigFromEnv() TokenVerifierConfig {
+	cfg := TokenVerifierConfig{
+		JwksUrl:  os.Getenv("JWT_JWKS_URL"),
+		JwksFile: os.Getenv("JWT_JWKS_FILE"),
+		Issuer:   os.Getenv("JWT_ISSUER"),
+		Audience: os.Getenv("JWT_AUDIENCE"),
+	}
+
+	kcUrl := strings.TrimSuffix(os.Getenv("KEYCLOAK_URL"), "/")
+	kcRealm := os.Getenv("KEYCLOAK_REALM")
+	if len(kcUrl) != 0 && len(kcRealm) != 0 {
+		realmUrl := fmt.Sprintf("%s/realms/%s", kcUrl, kcRealm)
+		if len(cfg.Issuer) == 0 {
+			cfg.Issuer = realmUrl
+		}
+		if len(cfg.JwksUrl) == 0 && len(cfg.JwksFile) == 0 {
+			cfg.JwksUrl = realmUrl + "/protocol/openid-connect/certs"
+		}
+	}
+	return cfg
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Sets the token verifier.  Normally the verifier is lazily created from the
+// environment but this allows callers (tests, alternate hosts) to override it.
+func SetTokenVerifier(cfg TokenVerifierConfig) error {
+	ks, err := newJwksKeySet(cfg)
+	if err != nil {
+		return err
+	}
+	tokenVerifierMutex.Lock()
+	defer tokenVerifierMutex.Unlock()
+	tokenVerifier = ks
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func getTokenVerifier() (*jwksKeySet, error) {
+	tokenVerifierMutex.Lock()
+	defer tokenVerifierMutex.Unlock()
+	if tokenVerifier == nil {
+		ks, err := newJwksKeySet(TokenVerifierConfigFromEnv())
+		if err != nil {
+			return nil, err
+		}
+		tokenVerifier = ks
+	}
+	return tokenVerifier, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Cached set of public keys keyed by the JWK key id.  The mutex guards the
+// fields below it and is never held while the key set is being fetched.
+type jwksKeySet struct {
+	cfg         TokenVerifierConfig
+	mutex       sync.Mutex
+	keys        map[string]interface{}
+	fetchedAt   time.Time
+	lastAttempt time.Time
+	lastErr     error
+	// Closed when the fetch in progress is done.  nil when there isn't one.
+	fetchDone chan struct{}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func newJwksKeySet(cfg TokenVerifierConfig) (*jwksKeySet, error) {
+	if len(cfg.JwksUrl) == 0 && len(cfg.JwksFile) == 0 {
+		return nil, errors.New("token verification is not configured: JWT_JWKS_URL or JWT_JWKS_FILE is required")
+	}
+	if len(cfg.Issuer) == 0 {
+		return nil, errors.New("token verification is not configured: JWT_ISSUER is required")
+	}
+	if len(cfg.Audience) == 0 {
+		log.Println("JWT_AUDIENCE is not set so token audience will not be verified")
+	}
+	if cfg.CacheTtl == 0 {
+		cfg.CacheTtl = defaultJwksCacheTtl
+	}
+	if cfg.MinRefreshInterval == 0 {
+		cfg.MinRefreshInterval = defaultJwksMinRefreshInterval
+	}
+	return &jwksKeySet{cfg: cfg}, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Verifies the signature, issuer, audience and expiry of the token and only
+// then returns the claims.
+func (ks *jwksKeySet) verify(tokenStr string) (*T27FrClaims, error) {
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(ks.cfg.Issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(jwtClockLeeway),
+	}
+	if len(ks.cfg.Audience) != 0 {
+		parserOpts = append(parserOpts, jwt.WithAudience(ks.cfg.Audience))
+	}
+
+	claims := &T27FrClaims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, ks.keyFunc, parserOpts...)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("token is not valid")
+	}
+	return claims, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// jwt.Keyfunc that finds the verification key for the token.
+func (ks *jwksKeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	keys, err := ks.getKeys(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(kid) == 0 {
+		// Without a key id we can only be sure which key to use if there is just one
+		if len(keys) == 1 {
+			for _, key := range keys {
+				return key, nil
+			}
+		}
+		return nil, errors.New("token has no key id")
+	}
+
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("no key found for key id: %s", kid)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the key set, re-fetching it when it is older than CacheTtl or the
+// key id isn't known (the IdP rotated keys).  Fetches are no more often than
+// MinRefreshInterval whether or not the last one failed so an IdP outage
+// doesn't turn every request into a fetch.  Only one fetch runs at a time
+// and while it does requests keep using the keys from before; only when
+// there aren't any do they wait for it.
+func (ks *jwksKeySet) getKeys(kid string) (map[string]interface{}, error) {
+	for {
+		ks.mutex.Lock()
+		now := time.Now()
+		_, isKnown := ks.keys[kid]
+		isStale := ks.keys == nil || now.Sub(ks.fetchedAt) > ks.cfg.CacheTtl
+		canFetch := ks.lastAttempt.IsZero() || now.Sub(ks.lastAttempt) > ks.cfg.MinRefreshInterval
+		if ks.fetchDone != nil && ks.keys == nil {
+			fetchDone := ks.fetchDone
+			ks.mutex.Unlock()
+			<-fetchDone
+			continue
+		}
+		if !(isStale || !isKnown) || !canFetch || ks.fetchDone != nil {
+			keys, lastErr := ks.keys, ks.lastErr
+			ks.mutex.Unlock()
+			if keys == nil {
+				return nil, fmt.Errorf("jwks is not available: %w", lastErr)
+			}
+			return keys, nil
+		}
+		ks.fetchDone = make(chan struct{})
+		ks.lastAttempt = now
+		ks.mutex.Unlock()
+
+		keys, err := ks.loadKeys()
+
+		ks.mutex.Lock()
+		if err != nil {
+			log.Println("Failed to load JWKS: ", err)
+			ks.lastErr = err
+		} else {
+			ks.keys, ks.fetchedAt, ks.lastErr = keys, time.Now(), nil
+		}
+		// If the fetch failed we keep using the keys from before
+		keys = ks.keys
+		close(ks.fetchDone)
+		ks.fetchDone = nil
+		ks.mutex.Unlock()
+
+		if keys == nil {
+			return nil, err
+		}
+		return keys, nil
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (ks *jwksKeySet) loadKeys() (map[string]interface{}, error) {
+	var data []byte
+	var err error
+
+	if len(ks.cfg.JwksFile) != 0 {
+		data, err = os.ReadFile(ks.cfg.JwksFile)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		log.Println("Fetching JWKS from: ", ks.cfg.JwksUrl)
+		httpCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		req, err := http.NewRequestWithContext(httpCtx, "GET", ks.cfg.JwksUrl, nil)
+		if err != nil {
+			return nil, err
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("fetching jwks failed with status: %d", res.StatusCode)
+		}
+		data, err = io.ReadAll(res.Body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return parseJwks(data)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+type jwkType struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Parses a JWKS document into public keys.  Keys that aren't for signing or
+// are of an unsupported type are skipped.
+func parseJwks(data []byte) (map[string]interface{}, error) {
+	jwks := struct {
+		Keys []jwkType `json:"keys"`
+	}{}
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]interface{})
+	for _, jwk := range jwks.Keys {
+		if len(jwk.Use) != 0 && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			log.Println("Skipping JWK: ", jwk.Kid, " because: ", err)
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("jwks did not contain any usable signing keys")
+	}
+	return keys, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (jwk *jwkType) publicKey() (interface{}, error) {
+	decode := func(v string) (*big.Int, error) {
+		b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(v, "="))
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decode(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve: %s", jwk.Crv)
+		}
+		x, err := decode(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type: %s", jwk.Kty)
+	}
+}
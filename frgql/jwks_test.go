@@ -0,0 +1,171 @@
+package frgql
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// //////////////////////////////////////////////////////////////////////////
+func signTestToken(t *testing.T, method jwt.SigningMethod, key any, kid string, claims T27FrClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if len(kid) != 0 {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Writes a JWKS file with rsaKey as "rsa" and the P-256 ecKey as "ec"
+func writeTestJwksFile(t *testing.T, rsaKey *rsa.PrivateKey, ecKey *ecdsa.PrivateKey) string {
+	t.Helper()
+	jwks := struct {
+		Keys []map[string]string `json:"keys"`
+	}{}
+	mustNotFail(t, json.Unmarshal(makeTestJwks(t, "rsa", rsaKey), &jwks))
+	jwks.Keys = append(jwks.Keys, map[string]string{
+		"kid": "ec",
+		"kty": "EC",
+		"crv": "P-256",
+		"x":   base64.RawURLEncoding.EncodeToString(ecKey.X.FillBytes(make([]byte, 32))),
+		"y":   base64.RawURLEncoding.EncodeToString(ecKey.Y.FillBytes(make([]byte, 32))),
+	}, map[string]string{
+		// Only signing keys are used
+		"kid": "enc", "kty": "RSA", "use": "enc", "n": "AQAB", "e": "AQAB",
+	})
+	data, err := json.Marshal(jwks)
+	mustNotFail(t, err)
+
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	mustNotFail(t, os.WriteFile(jwksFile, data, 0600))
+	return jwksFile
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func TestTokenVerification(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	mustNotFail(t, err)
+	otherRsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	mustNotFail(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	mustNotFail(t, err)
+
+	ks, err := newJwksKeySet(TokenVerifierConfig{
+		JwksFile: writeTestJwksFile(t, rsaKey, ecKey),
+		Issuer:   testIssuer,
+		Audience: "t27fr",
+	})
+	mustNotFail(t, err)
+
+	makeClaims := func(modify func(claims *T27FrClaims)) T27FrClaims {
+		claims := T27FrClaims{
+			Id:    "scout1",
+			Roles: []string{"/FrUsers", "/FrAdmins"},
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    testIssuer,
+				Audience:  jwt.ClaimStrings{"t27fr"},
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			},
+		}
+		if modify != nil {
+			modify(&claims)
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		isValid bool
+	}{
+		{"rsa", signTestToken(t, jwt.SigningMethodRS256, rsaKey, "rsa", makeClaims(nil)), true},
+		{"ec", signTestToken(t, jwt.SigningMethodES256, ecKey, "ec", makeClaims(nil)), true},
+		{"expired within the leeway", signTestToken(t, jwt.SigningMethodRS256, rsaKey, "rsa", makeClaims(func(claims *T27FrClaims) {
+			claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-jwtClockLeeway / 2))
+		})), true},
+		{"wrong key", signTestToken(t, jwt.SigningMethodRS256, otherRsaKey, "rsa", makeClaims(nil)), false},
+		{"key id of another key", signTestToken(t, jwt.SigningMethodRS256, rsaKey, "ec", makeClaims(nil)), false},
+		{"unknown key id", signTestToken(t, jwt.SigningMethodRS256, rsaKey, "gone", makeClaims(nil)), false},
+		// There is more than one key so the one to use isn't known
+		{"no key id", signTestToken(t, jwt.SigningMethodRS256, rsaKey, "", makeClaims(nil)), false},
+		{"not an encryption key", signTestToken(t, jwt.SigningMethodRS256, rsaKey, "enc", makeClaims(nil)), false},
+		{"wrong issuer", signTestToken(t, jwt.SigningMethodRS256, rsaKey, "rsa", makeClaims(func(claims *T27FrClaims) {
+			claims.Issuer = "https://idp.test/realms/other"
+		})), false},
+		{"wrong audience", signTestToken(t, jwt.SigningMethodRS256, rsaKey, "rsa", makeClaims(func(claims *T27FrClaims) {
+			claims.Audience = jwt.ClaimStrings{"other"}
+		})), false},
+		{"expired", signTestToken(t, jwt.SigningMethodRS256, rsaKey, "rsa", makeClaims(func(claims *T27FrClaims) {
+			claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+		})), false},
+		{"no expiry", signTestToken(t, jwt.SigningMethodRS256, rsaKey, "rsa", makeClaims(func(claims *T27FrClaims) {
+			claims.ExpiresAt = nil
+		})), false},
+		// The public key must not be usable as an HMAC secret
+		{"hmac", signTestToken(t, jwt.SigningMethodHS256, []byte("secret"), "rsa", makeClaims(nil)), false},
+		{"not a token", "not.a.token", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claims, err := ks.verify(test.token)
+			if !test.isValid {
+				if err == nil {
+					t.Errorf("token was accepted: %+v", claims)
+				}
+				return
+			}
+			mustNotFail(t, err)
+			if claims.userId() != "scout1" || !claims.isAdmin() {
+				t.Errorf("claims: %+v", claims)
+			}
+		})
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Tokens in the context are verified with the verifier that was set
+func TestParseTokenClaimsFromCtx(t *testing.T) {
+	newTestMemStore(t)
+
+	claims, err := parseTokenClaimsFromCtx(newTestCtx(t, "scout1", false))
+	mustNotFail(t, err)
+	if claims.userId() != "scout1" || claims.isAdmin() {
+		t.Errorf("claims: %+v", claims)
+	}
+
+	var forbiddenErr *ForbiddenError
+	if err := VerifyAdminTokenFromCtx(newTestCtx(t, "scout1", false)); !errors.As(err, &forbiddenErr) {
+		t.Errorf("non admin returned: %v not a ForbiddenError", err)
+	}
+	mustNotFail(t, VerifyAdminTokenFromCtx(newTestCtx(t, "admin1", true)))
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	mustNotFail(t, err)
+	for name, ctx := range map[string]context.Context{
+		"no token":    context.Background(),
+		"empty token": context.WithValue(context.Background(), "T27FrAuthorization", ""),
+		"wrong key": context.WithValue(context.Background(), "T27FrAuthorization",
+			makeTestToken(t, otherKey, "test", "scout1", true)),
+	} {
+		var unauthenticatedErr *UnauthenticatedError
+		if _, err := parseTokenClaimsFromCtx(ctx); !errors.As(err, &unauthenticatedErr) {
+			t.Errorf("%s returned: %v not an UnauthenticatedError", name, err)
+		}
+	}
+}
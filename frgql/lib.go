@@ -3,25 +3,161 @@ package frgql
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
+	"sync"
 
 	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
 )
 
-////////////////////////////////////////////////////////////////////////////
-//
+// //////////////////////////////////////////////////////////////////////////
+// GraphQL request body as sent by clients
+type GqlRequest struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+	OperationName string                 `json:"operationName,omitempty"`
+}
+
+// Context key for the variables as they were sent.  graphql-go drops fields
+// that are explicitly null when it coerces variables so resolvers that need to
+// know about nulls look at these.
+type gqlRawVariablesCtxKey struct{}
+
+// Context key for the errors resolvers report while still returning data.
+// They are added to the response errors after the query has run.
+type gqlPartialErrorsCtxKey struct{}
+
+// //////////////////////////////////////////////////////////////////////////
+type gqlPartialErrors struct {
+	mu   sync.Mutex
+	errs []gqlerrors.FormattedError
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns what a resolver should return for result and err.  When err is a
+// RowScanError the rows that could be read are still returned and err is
+// added to the response errors against this field.
+func resolvePartialResult(p graphql.ResolveParams, result interface{}, err error) (interface{}, error) {
+	if err == nil {
+		return result, nil
+	}
+
+	var rowScanErr *RowScanError
+	partialErrs, ok := p.Context.Value(gqlPartialErrorsCtxKey{}).(*gqlPartialErrors)
+	if !ok || !errors.As(err, &rowScanErr) {
+		return nil, err
+	}
+
+	formattedErr := gqlerrors.FormatError(
+		gqlerrors.NewLocatedError(err, gqlerrors.FieldASTsToNodeASTs(p.Info.FieldASTs)))
+	formattedErr.Path = p.Info.Path.AsArray()
+	formattedErr.Extensions = getErrorExtensions(err)
+
+	partialErrs.mu.Lock()
+	defer partialErrs.mu.Unlock()
+	partialErrs.errs = append(partialErrs.errs, formattedErr)
+	return result, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
 func MakeGqlQuery(ctx context.Context, gql string) ([]byte, error) {
-	params := graphql.Params{Schema: FrSchema, RequestString: gql, Context: ctx}
+	return MakeGqlQueryWithVars(ctx, gql, nil, "")
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Same as MakeGqlQuery but passes the variables and operation name through
+// to the GraphQL executor so that values never have to be spliced into gql.
+//
+// The full GraphQL response (data and errors) is always returned.  Every error
+// in it has an extensions.code and the first one is also returned as the
+// error so callers can pick a status with GetHttpStatusForError.  When some
+// fields still have data it is wrapped in a PartialResultError.
+func MakeGqlQueryWithVars(
+	ctx context.Context, gql string, variables map[string]interface{}, operationName string,
+) ([]byte, error) {
+	partialErrs := &gqlPartialErrors{}
+	ctx = context.WithValue(ctx, gqlRawVariablesCtxKey{}, variables)
+	ctx = context.WithValue(ctx, gqlPartialErrorsCtxKey{}, partialErrs)
+
+	params := graphql.Params{
+		Schema:         FrSchema,
+		RequestString:  gql,
+		VariableValues: variables,
+		OperationName:  operationName,
+		Context:        ctx,
+	}
 	r := graphql.Do(params)
-	if len(r.Errors) > 0 {
-		log.Printf("failed to execute graphql operation:\n%s\n, errors: %+v", gql, r.Errors)
-		return nil, r.Errors[0]
+	for idx := range r.Errors {
+		r.Errors[idx].Extensions = getFormattedErrorExtensions(r.Errors[idx])
 	}
+	r.Errors = append(r.Errors, partialErrs.errs...)
 
 	rJSON, err := json.Marshal(r)
 	if err != nil {
 		log.Println("Error encoding JSON results: ", err, " for gql: ", gql)
 		return nil, err
 	}
+
+	if len(r.Errors) > 0 {
+		log.Printf("failed to execute graphql operation:\n%s\n, errors: %+v", gql, r.Errors)
+		if hasGqlData(r.Data) {
+			return rJSON, &PartialResultError{Err: r.Errors[0]}
+		}
+		return rJSON, r.Errors[0]
+	}
 	return rJSON, nil
 }
+
+// //////////////////////////////////////////////////////////////////////////
+// Whether any of the top level fields of the response data resolved
+func hasGqlData(data interface{}) bool {
+	fields, ok := data.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	for _, value := range fields {
+		if value != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the type (query, mutation or subscription) of the operation in gql
+// that operationName picks.  Empty when that can't be told, for example gql
+// doesn't parse, in which case running it will report why.
+func GetGqlOperationType(gql string, operationName string) string {
+	doc, err := parser.Parse(parser.ParseParams{Source: gql})
+	if err != nil {
+		return ""
+	}
+	opType, numOps := "", 0
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		numOps++
+		if len(operationName) == 0 || (op.Name != nil && op.Name.Value == operationName) {
+			opType = op.Operation
+		}
+	}
+	if len(operationName) == 0 && numOps != 1 {
+		return ""
+	}
+	return opType
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns a GraphQL response with just err in it for failures that happen
+// before the query can be run
+func MakeGqlErrorResp(err error) []byte {
+	formattedErr := gqlerrors.FormatError(err)
+	formattedErr.Extensions = getErrorExtensions(err)
+	rJSON, _ := json.Marshal(graphql.Result{Errors: []gqlerrors.FormattedError{formattedErr}})
+	return rJSON
+}
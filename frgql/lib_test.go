@@ -0,0 +1,133 @@
+package frgql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+const testOrderQueriesGql = `
+query MulchOrder($orderId: String!) {
+  mulchOrder(orderId: $orderId) { orderId ownerId customer { name } }
+}
+query MulchOrderOwner($orderId: String!) {
+  mulchOrder(orderId: $orderId) { ownerId }
+}`
+
+// //////////////////////////////////////////////////////////////////////////
+// MemStore whose order queries fail and whose timecard queries can only read
+// some of the rows
+type failingQueryStore struct {
+	*MemStore
+}
+
+var errTestDbDown = errors.New("connection refused")
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *failingQueryStore) GetMulchOrders(ctx context.Context, params GetMulchOrdersParams) ([]MulchOrderType, error) {
+	return nil, errTestDbDown
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *failingQueryStore) GetMulchTimecards(ctx context.Context, id string, deliveryId int, gqlFields []string) ([]MulchTimecardType, error) {
+	return []MulchTimecardType{{Id: "scout1", DeliveryId: 1}}, newRowScanError([]error{errors.New("bad timeIn")})
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Variables are passed through to the executor and operationName picks the
+// operation of a document with several
+func TestMakeGqlQueryWithVars(t *testing.T) {
+	store := newTestMemStore(t)
+	ctx := newTestCtx(t, "scout1", false)
+	mustNotFail(t, store.InsertMulchOrder(ctx, makeTestOrder(testOrderId1, "scout1")))
+	variables := map[string]interface{}{"orderId": testOrderId1}
+
+	resp, err := MakeGqlQueryWithVars(ctx, testOrderQueriesGql, variables, "MulchOrder")
+	mustNotFail(t, err)
+	result := struct {
+		Data struct {
+			MulchOrder map[string]interface{} `json:"mulchOrder"`
+		} `json:"data"`
+	}{}
+	mustNotFail(t, json.Unmarshal(resp, &result))
+	order := result.Data.MulchOrder
+	if order["orderId"] != testOrderId1 || order["customer"].(map[string]interface{})["name"] != "Pat Doe" {
+		t.Errorf("MulchOrder returned: %s", resp)
+	}
+
+	resp, err = MakeGqlQueryWithVars(ctx, testOrderQueriesGql, variables, "MulchOrderOwner")
+	mustNotFail(t, err)
+	result.Data.MulchOrder = nil
+	mustNotFail(t, json.Unmarshal(resp, &result))
+	if _, hasOrderId := result.Data.MulchOrder["orderId"]; hasOrderId || result.Data.MulchOrder["ownerId"] != "scout1" {
+		t.Errorf("MulchOrderOwner returned: %s", resp)
+	}
+
+	if _, err := MakeGqlQueryWithVars(ctx, testOrderQueriesGql, variables, ""); err == nil {
+		t.Error("no operationName for a document with two operations didn't fail")
+	}
+	if _, err := MakeGqlQueryWithVars(ctx, testOrderQueriesGql, nil, "MulchOrder"); err == nil {
+		t.Error("a missing required variable didn't fail")
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func TestGetGqlOperationType(t *testing.T) {
+	tests := []struct {
+		gql           string
+		operationName string
+		opType        string
+	}{
+		{"{ __typename }", "", "query"},
+		{"mutation { deleteMulchOrder(orderId: \"1\") }", "", "mutation"},
+		{"query Q { __typename } mutation M { __typename }", "M", "mutation"},
+		{"query Q { __typename } mutation M { __typename }", "Q", "query"},
+		// Left for the executor to reject
+		{"query Q { __typename } mutation M { __typename }", "", ""},
+		{"query Q { __typename }", "Missing", ""},
+		{"{", "", ""},
+	}
+	for _, test := range tests {
+		if opType := GetGqlOperationType(test.gql, test.operationName); opType != test.opType {
+			t.Errorf("%q operationName: %q returned: %q expected: %q", test.gql, test.operationName, opType, test.opType)
+		}
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Store errors are sent back instead of empty results.  Rows that could be
+// read are sent back along with the error for the ones that couldn't.
+func TestStoreErrorsInResponse(t *testing.T) {
+	SetStore(&failingQueryStore{MemStore: newTestMemStore(t)})
+	ctx := newTestCtx(t, "scout1", false)
+	result := struct {
+		Data   map[string][]map[string]interface{} `json:"data"`
+		Errors []struct {
+			Path       []interface{}          `json:"path"`
+			Extensions map[string]interface{} `json:"extensions"`
+		} `json:"errors"`
+	}{}
+
+	resp, err := MakeGqlQuery(ctx, "{ mulchOrders { orderId } }")
+	if err == nil || GetHttpStatusForError(err) != http.StatusInternalServerError {
+		t.Errorf("failed orders query returned: %v", err)
+	}
+	mustNotFail(t, json.Unmarshal(resp, &result))
+	if result.Data["mulchOrders"] != nil || len(result.Errors) != 1 || result.Errors[0].Extensions["code"] != ErrCodeInternal {
+		t.Errorf("failed orders query response: %s", resp)
+	}
+
+	result.Data, result.Errors = nil, nil
+	resp, err = MakeGqlQuery(ctx, "{ mulchTimecards { id deliveryId } }")
+	var partialErr *PartialResultError
+	if !errors.As(err, &partialErr) || GetHttpStatusForError(err) != http.StatusOK {
+		t.Errorf("partly read timecards query returned: %v", err)
+	}
+	mustNotFail(t, json.Unmarshal(resp, &result))
+	if len(result.Data["mulchTimecards"]) != 1 || result.Data["mulchTimecards"][0]["id"] != "scout1" ||
+		len(result.Errors) != 1 || result.Errors[0].Path[0] != "mulchTimecards" {
+		t.Errorf("partly read timecards query response: %s", resp)
+	}
+}
@@ -0,0 +1,187 @@
+package frgql
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+)
+
+// //////////////////////////////////////////////////////////////////////////
+// Order to load on the truck.  Purchases are the order's line items without
+// what was charged for them.
+type ManifestOrderType struct {
+	OrderId             string         `json:"orderId"`
+	CustomerName        string         `json:"customerName"`
+	Addr1               string         `json:"addr1"`
+	Addr2               *string        `json:"addr2"`
+	City                *string        `json:"city"`
+	Phone               string         `json:"phone"`
+	SpecialInstructions *string        `json:"specialInstructions"`
+	NumBags             int            `json:"numBags"`
+	NumBagsToSpread     int            `json:"numBagsToSpread"`
+	Purchases           []ProductsType `json:"purchases"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+type ManifestNeighborhoodType struct {
+	Neighborhood    string              `json:"neighborhood"`
+	NumBags         int                 `json:"numBags"`
+	NumBagsToSpread int                 `json:"numBagsToSpread"`
+	Orders          []ManifestOrderType `json:"orders"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// DistributionPoint is empty for neighborhoods that don't have one
+type ManifestDistributionPointType struct {
+	DistributionPoint string                     `json:"distributionPoint"`
+	NumBags           int                        `json:"numBags"`
+	NumBagsToSpread   int                        `json:"numBagsToSpread"`
+	Neighborhoods     []ManifestNeighborhoodType `json:"neighborhoods"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+type DeliveryManifestType struct {
+	DeliveryId         int                             `json:"deliveryId"`
+	DeliveryDate       *string                         `json:"deliveryDate"`
+	NumOrders          int                             `json:"numOrders"`
+	NumBags            int                             `json:"numBags"`
+	NumBagsToSpread    int                             `json:"numBagsToSpread"`
+	DistributionPoints []ManifestDistributionPointType `json:"distributionPoints"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// What gets loaded for a delivery grouped by distribution point and then
+// neighborhood.  Orders without bags or spreading (donations) aren't
+// delivered so they are left out.  Admin only
+func GetDeliveryManifest(ctx context.Context, deliveryId int) (DeliveryManifestType, error) {
+	log.Println("Building delivery manifest for delivery: ", deliveryId)
+
+	if err := VerifyAdminTokenFromCtx(ctx); err != nil {
+		return DeliveryManifestType{}, err
+	}
+
+	manifest := DeliveryManifestType{
+		DeliveryId:         deliveryId,
+		DistributionPoints: []ManifestDistributionPointType{},
+	}
+	frConfig, err := frStore.GetFundraiserConfig(ctx, []string{"mulchDeliveryConfigs"})
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return DeliveryManifestType{}, err
+	}
+	if frConfig.MulchDeliveryConfigs != nil {
+		for _, delivery := range *frConfig.MulchDeliveryConfigs {
+			if delivery.Id == deliveryId {
+				manifest.DeliveryDate = &delivery.Date
+			}
+		}
+	}
+
+	hoods, err := frStore.GetNeighborhoods(ctx, []string{"name", "distributionPoint"})
+	if err != nil {
+		return DeliveryManifestType{}, err
+	}
+	distPts := make(map[string]string)
+	for _, hood := range hoods {
+		if hood.DistributionPoint != nil {
+			distPts[hood.Name] = strings.TrimSpace(*hood.DistributionPoint)
+		}
+	}
+
+	orders, err := frStore.GetMulchOrders(ctx, GetMulchOrdersParams{
+		DeliveryId: &deliveryId,
+		SortBy:     "orderId",
+		GqlFields:  []string{"orderId", "customer", "specialInstructions", "purchases"},
+	})
+	if err != nil {
+		log.Println("Delivery manifest orders query failed: ", err)
+		return DeliveryManifestType{}, err
+	}
+
+	distPtIdxs := make(map[string]int)
+	hoodIdxs := make(map[string]int)
+	for _, order := range orders {
+		manifestOrder := ManifestOrderType{
+			OrderId:             order.OrderId,
+			CustomerName:        order.Customer.Name,
+			Addr1:               order.Customer.Addr1,
+			Addr2:               order.Customer.Addr2,
+			City:                order.Customer.City,
+			Phone:               order.Customer.Phone,
+			SpecialInstructions: order.SpecialInstructions,
+			Purchases:           []ProductsType{},
+		}
+		for _, purchase := range order.Purchases {
+			switch purchase.ProductId {
+			case "bags":
+				manifestOrder.NumBags += purchase.NumSold
+			case "spreading":
+				manifestOrder.NumBagsToSpread += purchase.NumSold
+			}
+			if purchase.NumSold != 0 {
+				manifestOrder.Purchases = append(manifestOrder.Purchases,
+					ProductsType{ProductId: purchase.ProductId, NumSold: purchase.NumSold})
+			}
+		}
+		if manifestOrder.NumBags == 0 && manifestOrder.NumBagsToSpread == 0 {
+			continue
+		}
+
+		distPt := distPts[order.Customer.Neighborhood]
+		distPtIdx, ok := distPtIdxs[distPt]
+		if !ok {
+			distPtIdx = len(manifest.DistributionPoints)
+			distPtIdxs[distPt] = distPtIdx
+			manifest.DistributionPoints = append(manifest.DistributionPoints, ManifestDistributionPointType{
+				DistributionPoint: distPt,
+				Neighborhoods:     []ManifestNeighborhoodType{},
+			})
+		}
+		manifestDistPt := &manifest.DistributionPoints[distPtIdx]
+
+		hoodIdx, ok := hoodIdxs[order.Customer.Neighborhood]
+		if !ok {
+			hoodIdx = len(manifestDistPt.Neighborhoods)
+			hoodIdxs[order.Customer.Neighborhood] = hoodIdx
+			manifestDistPt.Neighborhoods = append(manifestDistPt.Neighborhoods, ManifestNeighborhoodType{
+				Neighborhood: order.Customer.Neighborhood,
+				Orders:       []ManifestOrderType{},
+			})
+		}
+		manifestHood := &manifestDistPt.Neighborhoods[hoodIdx]
+
+		manifestHood.Orders = append(manifestHood.Orders, manifestOrder)
+		manifestHood.NumBags += manifestOrder.NumBags
+		manifestHood.NumBagsToSpread += manifestOrder.NumBagsToSpread
+		manifestDistPt.NumBags += manifestOrder.NumBags
+		manifestDistPt.NumBagsToSpread += manifestOrder.NumBagsToSpread
+		manifest.NumBags += manifestOrder.NumBags
+		manifest.NumBagsToSpread += manifestOrder.NumBagsToSpread
+		manifest.NumOrders++
+	}
+
+	// Sorted so the pages can be handed out in order with the neighborhoods
+	// without a distribution point last.  Within a neighborhood orders are by
+	// street address.
+	slices.SortFunc(manifest.DistributionPoints, func(a, b ManifestDistributionPointType) int {
+		if (len(a.DistributionPoint) == 0) != (len(b.DistributionPoint) == 0) {
+			return len(b.DistributionPoint) - len(a.DistributionPoint)
+		}
+		return strings.Compare(a.DistributionPoint, b.DistributionPoint)
+	})
+	for _, distPt := range manifest.DistributionPoints {
+		slices.SortFunc(distPt.Neighborhoods, func(a, b ManifestNeighborhoodType) int {
+			return strings.Compare(a.Neighborhood, b.Neighborhood)
+		})
+		for _, hood := range distPt.Neighborhoods {
+			slices.SortStableFunc(hood.Orders, func(a, b ManifestOrderType) int {
+				return strings.Compare(normalizeAddr(a.Addr1), normalizeAddr(b.Addr1))
+			})
+		}
+	}
+
+	log.Printf("Delivery: %d manifest has %d orders with %d bags (%d to spread)",
+		deliveryId, manifest.NumOrders, manifest.NumBags, manifest.NumBagsToSpread)
+	return manifest, nil
+}
@@ -0,0 +1,88 @@
+package frgql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// //////////////////////////////////////////////////////////////////////////
+// Orders are grouped by distribution point and neighborhood with the bags
+// added up at each level
+func TestGetDeliveryManifest(t *testing.T) {
+	store := newTestMemStore(t)
+	ctx := context.Background()
+	adminCtx := newTestCtx(t, "admin1", true)
+
+	mustNotFail(t, store.SetFundraiserConfig(ctx, FrConfigType{
+		Products:             testProducts,
+		MulchDeliveryConfigs: &[]MulchDeliveryConfigType{{Id: 1, Date: "2025-03-08"}, {Id: 2, Date: "2025-04-12"}},
+		LastModifiedTime:     makeLastModifiedTime(),
+	}))
+	for _, hood := range []struct{ name, distPt string }{{"Oak Hills", "North Church"}, {"Elm Park", " Avenue School "}} {
+		mustNotFail(t, store.InsertNeighborhood(ctx, NeighborhoodInfo{Name: hood.name, DistributionPoint: &hood.distPt,
+			LastModifiedTime: makeLastModifiedTime()}))
+	}
+
+	numOrders := 0
+	insertOrder := func(neighborhood string, addr1 string, deliveryId int, purchases ...ProductsType) string {
+		numOrders++
+		order := makeTestOrder(fmt.Sprintf("0b5d3f3e-6a4f-4b39-9d2a-1e1d6b8f01%02d", numOrders), "scout1")
+		order.Customer.Neighborhood, order.Customer.Addr1 = neighborhood, addr1
+		order.DeliveryId = &deliveryId
+		order.Purchases = purchases
+		mustNotFail(t, store.InsertMulchOrder(ctx, order))
+		return order.OrderId
+	}
+	laterOak := insertOrder("Oak Hills", "20 Main St", 1, ProductsType{ProductId: "bags", NumSold: 4})
+	earlierOak := insertOrder("Oak Hills", "12 Main Street", 1,
+		ProductsType{ProductId: "bags", NumSold: 2}, ProductsType{ProductId: "spreading", NumSold: 2})
+	elm := insertOrder("Elm Park", "5 Elm Ave", 1, ProductsType{ProductId: "bags", NumSold: 3})
+	nowhere := insertOrder("Lost Acres", "1 Far Rd", 1, ProductsType{ProductId: "bags", NumSold: 1})
+	// Not delivered
+	insertOrder("Oak Hills", "30 Main St", 1, ProductsType{ProductId: "donation", NumSold: 0})
+	insertOrder("Oak Hills", "40 Main St", 2, ProductsType{ProductId: "bags", NumSold: 9})
+
+	manifest, err := GetDeliveryManifest(adminCtx, 1)
+	mustNotFail(t, err)
+	if *manifest.DeliveryDate != "2025-03-08" || manifest.NumOrders != 4 || manifest.NumBags != 10 || manifest.NumBagsToSpread != 2 {
+		t.Errorf("manifest: %+v", manifest)
+	}
+
+	// Neighborhoods without a distribution point are last
+	distPts := manifest.DistributionPoints
+	if len(distPts) != 3 || distPts[0].DistributionPoint != "Avenue School" || distPts[1].DistributionPoint != "North Church" ||
+		distPts[2].DistributionPoint != "" {
+		t.Fatalf("distribution points: %+v", distPts)
+	}
+	if distPts[1].NumBags != 6 || distPts[1].NumBagsToSpread != 2 || len(distPts[1].Neighborhoods) != 1 {
+		t.Errorf("North Church: %+v", distPts[1])
+	}
+	getOrderIds := func(hood ManifestNeighborhoodType) []string {
+		ids := []string{}
+		for _, order := range hood.Orders {
+			ids = append(ids, order.OrderId)
+		}
+		return ids
+	}
+	oakHills := distPts[1].Neighborhoods[0]
+	if oakHills.Neighborhood != "Oak Hills" || oakHills.NumBags != 6 ||
+		!slices.Equal(getOrderIds(oakHills), []string{earlierOak, laterOak}) {
+		t.Errorf("Oak Hills: %+v", oakHills)
+	}
+	if spread := oakHills.Orders[0]; spread.NumBags != 2 || spread.NumBagsToSpread != 2 || len(spread.Purchases) != 2 ||
+		spread.Purchases[0].AmountCharged != "" {
+		t.Errorf("order with spreading: %+v", spread)
+	}
+	if !slices.Equal(getOrderIds(distPts[0].Neighborhoods[0]), []string{elm}) ||
+		!slices.Equal(getOrderIds(distPts[2].Neighborhoods[0]), []string{nowhere}) {
+		t.Errorf("distribution points: %+v", distPts)
+	}
+
+	var forbiddenErr *ForbiddenError
+	if _, err := GetDeliveryManifest(newTestCtx(t, "scout1", false), 1); !errors.As(err, &forbiddenErr) {
+		t.Errorf("a scout reading the manifest returned: %v not a ForbiddenError", err)
+	}
+}
@@ -0,0 +1,434 @@
+package frgql
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Migrations are migrations/NNNN_<name>.up.sql with a matching .down.sql.
+// Statements in them have to end with a ; at the end of a line.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+var migrationFileRe = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+const SCHEMA_MIGRATIONS_TABLE_SQL = `CREATE TABLE IF NOT EXISTS schema_migrations (version INT PRIMARY KEY, ` +
+	`name STRING, applied_time TIMESTAMP)`
+
+// Migrations that fill in existing rows with Go code after their SQL in the
+// same transaction
+var migrationBackfills = map[int]func(ctx context.Context, trxn pgx.Tx) error{
+	13: backfillKnownAddrNormalizedAddrs,
+	15: backfillKnownAddrCoordinates,
+}
+
+// Postgres (and CockroachDB) code for a table that doesn't exist
+const undefinedTableCode = "42P01"
+
+var (
+	schemaCheckOnce sync.Once
+	schemaCheckErr  error
+)
+
+// //////////////////////////////////////////////////////////////////////////
+type MigrationType struct {
+	Version int
+	Name    string
+	UpSql   string
+	DownSql string
+}
+
+// //////////////////////////////////////////////////////////////////////////
+type MigrationStatusType struct {
+	Version     int
+	Name        string
+	IsApplied   bool
+	AppliedTime string
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the embedded migrations in version order
+func loadMigrations() ([]MigrationType, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make(map[int]*MigrationType)
+	for _, entry := range entries {
+		matches := migrationFileRe.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			return nil, fmt.Errorf("migration file: %s is not named NNNN_<name>.(up|down).sql", entry.Name())
+		}
+		version, _ := strconv.Atoi(matches[1])
+		contents, err := migrationFiles.ReadFile("migrations/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := migrations[version]
+		if !ok {
+			migration = &MigrationType{Version: version, Name: matches[2]}
+			migrations[version] = migration
+		} else if migration.Name != matches[2] {
+			return nil, fmt.Errorf("migration version: %d is used by both %s and %s", version, migration.Name, matches[2])
+		}
+		if matches[3] == "up" {
+			migration.UpSql = string(contents)
+		} else {
+			migration.DownSql = string(contents)
+		}
+	}
+
+	sorted := []MigrationType{}
+	for _, migration := range migrations {
+		if len(migration.UpSql) == 0 || len(migration.DownSql) == 0 {
+			return nil, fmt.Errorf("migration: %04d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		sorted = append(sorted, *migration)
+	}
+	slices.SortFunc(sorted, func(a, b MigrationType) int { return a.Version - b.Version })
+	return sorted, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Splits a migration file into its statements dropping the -- comments
+func splitMigrationSql(migrationSql string) []string {
+	statements := []string{}
+	current := []string{}
+	for _, line := range strings.Split(migrationSql, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if len(trimmed) == 0 || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current = append(current, line)
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.Join(current, "\n"))
+			current = []string{}
+		}
+	}
+	if len(current) != 0 {
+		statements = append(statements, strings.Join(current, "\n"))
+	}
+	return statements
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the version the embedded migrations bring the schema to
+func LatestSchemaVersion() (int, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return 0, err
+	}
+	if len(migrations) == 0 {
+		return 0, nil
+	}
+	return migrations[len(migrations)-1].Version, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func getMigrationDb() (*pgxpool.Pool, error) {
+	if Db == nil {
+		return nil, errors.New("migrations need a database connection (OpenDb)")
+	}
+	return Db, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the applied versions and when they were applied.  Only reads so
+// it can be used by the schema check.  Nothing has been applied when there
+// is no schema_migrations table yet (MigrateUp creates it).
+func getAppliedMigrations(ctx context.Context, db *pgxpool.Pool) (map[int]string, error) {
+	applied := make(map[int]string)
+	rows, err := db.Query(ctx, "SELECT version, applied_time::string FROM schema_migrations")
+	if isUndefinedTableErr(err) {
+		return applied, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		version, appliedTime := 0, ""
+		if err := rows.Scan(&version, &appliedTime); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedTime
+	}
+	if err := rows.Err(); isUndefinedTableErr(err) {
+		return applied, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func isUndefinedTableErr(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == undefinedTableCode
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the version the database schema is at.  0 means nothing has been
+// applied.
+func GetSchemaVersion(ctx context.Context) (int, error) {
+	db, err := getMigrationDb()
+	if err != nil {
+		return 0, err
+	}
+	applied, err := getAppliedMigrations(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+	version := 0
+	for appliedVersion := range applied {
+		version = max(version, appliedVersion)
+	}
+	return version, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns every embedded migration and whether it has been applied
+func GetMigrationStatus(ctx context.Context) ([]MigrationStatusType, error) {
+	db, err := getMigrationDb()
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := getAppliedMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []MigrationStatusType{}
+	for _, migration := range migrations {
+		appliedTime, isApplied := applied[migration.Version]
+		statuses = append(statuses, MigrationStatusType{
+			Version:     migration.Version,
+			Name:        migration.Name,
+			IsApplied:   isApplied,
+			AppliedTime: appliedTime,
+		})
+	}
+	return statuses, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Runs a single migration and records (or removes) it in schema_migrations
+// in one transaction
+func runMigration(ctx context.Context, db *pgxpool.Pool, migration MigrationType, isUp bool) error {
+	migrationSql, direction := migration.DownSql, "down"
+	if isUp {
+		migrationSql, direction = migration.UpSql, "up"
+	}
+	log.Printf("Running migration: %04d_%s %s", migration.Version, migration.Name, direction)
+
+	return pgx.BeginFunc(ctx, db, func(trxn pgx.Tx) error {
+		for _, sqlCmd := range splitMigrationSql(migrationSql) {
+			if _, err := trxn.Exec(ctx, sqlCmd); err != nil {
+				return fmt.Errorf("migration: %04d_%s %s failed: %w", migration.Version, migration.Name, direction, err)
+			}
+		}
+		if backfill, ok := migrationBackfills[migration.Version]; ok && isUp {
+			if err := backfill(ctx, trxn); err != nil {
+				return fmt.Errorf("migration: %04d_%s backfill failed: %w", migration.Version, migration.Name, err)
+			}
+		}
+		if isUp {
+			_, err := trxn.Exec(ctx, "INSERT INTO schema_migrations(version, name, applied_time) VALUES ($1, $2, $3::timestamp)",
+				migration.Version, migration.Name, time.Now().UTC().Format(time.RFC3339))
+			return err
+		}
+		_, err := trxn.Exec(ctx, "DELETE FROM schema_migrations WHERE version=$1", migration.Version)
+		return err
+	})
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Sets normalized_addr of the known addresses saved before it was added so
+// they are found by GetKnownAddrsByNormalizedAddr
+func backfillKnownAddrNormalizedAddrs(ctx context.Context, trxn pgx.Tx) error {
+	rows, err := trxn.Query(ctx, "SELECT id::string, COALESCE(addr, '') FROM known_addrs WHERE normalized_addr IS NULL")
+	if err != nil {
+		return err
+	}
+	addrs := make(map[string]string)
+	for rows.Next() {
+		id, addr := "", ""
+		if err := rows.Scan(&id, &addr); err != nil {
+			rows.Close()
+			return err
+		}
+		addrs[id] = addr
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, addr := range addrs {
+		_, err := trxn.Exec(ctx, "UPDATE known_addrs SET normalized_addr = $1 WHERE id = $2::uuid", normalizeAddr(addr), id)
+		if err != nil {
+			return err
+		}
+	}
+	log.Printf("Normalized: %d known addresses", len(addrs))
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Sets lat_deg and lng_deg of the known addresses saved before they were
+// added so they are found by GetKnownAddrsInBox.  Ones whose lat or lng
+// isn't a number are left without them.
+func backfillKnownAddrCoordinates(ctx context.Context, trxn pgx.Tx) error {
+	rows, err := trxn.Query(ctx, "SELECT id::string, COALESCE(lat, ''), COALESCE(lng, '') FROM known_addrs "+
+		"WHERE lat_deg IS NULL AND lat IS NOT NULL AND lng IS NOT NULL")
+	if err != nil {
+		return err
+	}
+	addrs := []KnownAddrType{}
+	for rows.Next() {
+		addr := KnownAddrType{}
+		if err := rows.Scan(&addr.Id, &addr.Lat, &addr.Lng); err != nil {
+			rows.Close()
+			return err
+		}
+		addrs = append(addrs, addr)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	numLocated := 0
+	for _, addr := range addrs {
+		lat, lng, ok := parseKnownAddrLocation(addr)
+		if !ok {
+			continue
+		}
+		_, err := trxn.Exec(ctx, "UPDATE known_addrs SET lat_deg = $1, lng_deg = $2 WHERE id = $3::uuid", lat, lng, addr.Id)
+		if err != nil {
+			return err
+		}
+		numLocated++
+	}
+	log.Printf("Set the coordinates of: %d of: %d known addresses", numLocated, len(addrs))
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Applies the migrations that haven't been up to and including toVersion.
+// toVersion of 0 means all of them.
+func MigrateUp(ctx context.Context, toVersion int) error {
+	db, err := getMigrationDb()
+	if err != nil {
+		return err
+	}
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+	if _, err := db.Exec(ctx, SCHEMA_MIGRATIONS_TABLE_SQL); err != nil {
+		return err
+	}
+	applied, err := getAppliedMigrations(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range migrations {
+		if toVersion != 0 && migration.Version > toVersion {
+			break
+		}
+		if _, isApplied := applied[migration.Version]; isApplied {
+			continue
+		}
+		if err := runMigration(ctx, db, migration, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Reverts the applied migrations newer than toVersion newest first
+func MigrateDown(ctx context.Context, toVersion int) error {
+	db, err := getMigrationDb()
+	if err != nil {
+		return err
+	}
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+	applied, err := getAppliedMigrations(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range slices.Backward(migrations) {
+		if migration.Version <= toVersion {
+			break
+		}
+		if _, isApplied := applied[migration.Version]; !isApplied {
+			continue
+		}
+		if err := runMigration(ctx, db, migration, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns an error if the database schema isn't at the version this code
+// was built for.  Nothing to check without a database (in-memory store).
+// The schema is only read and only once per process (each Lambda cold
+// start) so the result, pass or fail, is kept.
+func VerifySchemaVersion(ctx context.Context) error {
+	schemaCheckOnce.Do(func() {
+		schemaCheckErr = checkSchemaVersion(ctx)
+	})
+	return schemaCheckErr
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func checkSchemaVersion(ctx context.Context) error {
+	if Db == nil {
+		return nil
+	}
+
+	expected, err := LatestSchemaVersion()
+	if err != nil {
+		return err
+	}
+	current, err := GetSchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if current != expected {
+		return fmt.Errorf("database schema is at version %d but version %d is expected (see t27frcli migrate)",
+			current, expected)
+	}
+	return nil
+}
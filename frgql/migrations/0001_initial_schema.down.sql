@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS known_addrs;
+DROP TABLE IF EXISTS allocation_summary;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS neighborhoods;
+DROP TABLE IF EXISTS fundraiser_config;
+DROP TABLE IF EXISTS mulch_delivery_timecards;
+DROP TABLE IF EXISTS mulch_spreaders;
+DROP TABLE IF EXISTS mulch_orders;
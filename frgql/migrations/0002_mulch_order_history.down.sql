@@ -0,0 +1 @@
+DROP TABLE IF EXISTS mulch_order_history;
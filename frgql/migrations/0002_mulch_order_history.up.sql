@@ -0,0 +1,3 @@
+CREATE TABLE IF NOT EXISTS mulch_order_history (
+    order_id UUID, revision INT, operation STRING, actor_id STRING, created_time TIMESTAMP,
+    order_snapshot JSONB, PRIMARY KEY (order_id, revision));
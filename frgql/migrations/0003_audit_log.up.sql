@@ -0,0 +1,5 @@
+-- The audit log is kept across resets of the fundraiser data
+CREATE TABLE IF NOT EXISTS audit_log (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(), created_time TIMESTAMP, actor_id STRING,
+    operation STRING, target_kind STRING, target_key STRING, diff JSONB,
+    INDEX (created_time DESC), INDEX (target_kind, target_key));
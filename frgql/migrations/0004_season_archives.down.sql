@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS archived_allocation_summary;
+DROP TABLE IF EXISTS archived_mulch_timecards;
+DROP TABLE IF EXISTS archived_mulch_orders;
+DROP TABLE IF EXISTS season_archives;
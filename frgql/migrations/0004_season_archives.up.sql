@@ -0,0 +1,15 @@
+-- Copies of the order data made by closeSeason before it resets it.  The
+-- records are stored as JSON the way the api returns them.
+CREATE TABLE IF NOT EXISTS season_archives (
+    season INT PRIMARY KEY, closed_time TIMESTAMP, closed_by STRING, fundraiser_config JSONB);
+
+CREATE TABLE IF NOT EXISTS archived_mulch_orders (
+    season INT, order_id UUID, order_owner_id STRING, order_snapshot JSONB,
+    PRIMARY KEY (season, order_id));
+
+CREATE TABLE IF NOT EXISTS archived_mulch_timecards (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(), season INT, uid STRING, timecard JSONB,
+    INDEX (season, uid));
+
+CREATE TABLE IF NOT EXISTS archived_allocation_summary (
+    season INT, uid STRING, allocation JSONB, PRIMARY KEY (season, uid));
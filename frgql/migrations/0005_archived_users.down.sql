@@ -0,0 +1,2 @@
+ALTER TABLE season_archives DROP COLUMN IF EXISTS is_imported;
+DROP TABLE IF EXISTS archived_users;
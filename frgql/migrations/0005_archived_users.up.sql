@@ -0,0 +1,7 @@
+-- Users as they were in an archived season so scouts stay in the patrol
+-- they were in that year
+CREATE TABLE IF NOT EXISTS archived_users (
+    season INT, uid STRING, user_snapshot JSONB, PRIMARY KEY (season, uid));
+
+-- Seasons loaded from another system's export instead of closed by closeSeason
+ALTER TABLE season_archives ADD COLUMN IF NOT EXISTS is_imported BOOL DEFAULT false;
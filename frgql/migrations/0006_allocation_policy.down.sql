@@ -0,0 +1 @@
+ALTER TABLE fundraiser_config DROP COLUMN IF EXISTS allocation_policy;
@@ -0,0 +1,2 @@
+-- Percentages and rules closeout splits the money with (see AllocationPolicyType)
+ALTER TABLE fundraiser_config ADD COLUMN IF NOT EXISTS allocation_policy JSONB;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS bank_deposit_items;
+DROP TABLE IF EXISTS bank_deposits;
@@ -0,0 +1,9 @@
+-- Ledger of the money taken to the bank.  Items are the orders (check_number
+-- of '') or single checks of orders that went into a deposit.
+CREATE TABLE IF NOT EXISTS bank_deposits (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(), deposit_date DATE, cash_amount DECIMAL(13, 4),
+    check_amount DECIMAL(13, 4), comments STRING, created_by STRING, created_time TIMESTAMP);
+
+CREATE TABLE IF NOT EXISTS bank_deposit_items (
+    deposit_id UUID, order_id UUID, check_number STRING, cash_amount DECIMAL(13, 4),
+    check_amount DECIMAL(13, 4), PRIMARY KEY (deposit_id, order_id, check_number), INDEX (order_id));
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS notifications;
+ALTER TABLE mulch_orders DROP COLUMN IF EXISTS checks;
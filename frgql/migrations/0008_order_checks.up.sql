@@ -0,0 +1,7 @@
+-- Structured checks of an order.  check_numbers is still kept for older clients
+ALTER TABLE mulch_orders ADD COLUMN IF NOT EXISTS checks JSONB;
+
+-- Messages to users about their records (e.g. a bounced check)
+CREATE TABLE IF NOT EXISTS notifications (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(), uid STRING, kind STRING, message STRING,
+    order_id UUID, created_time TIMESTAMP, is_read BOOL DEFAULT false, INDEX (uid, created_time));
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS known_addrs@known_addrs_normalized_addr_idx;
+ALTER TABLE known_addrs DROP COLUMN IF EXISTS normalized_addr;
@@ -0,0 +1,3 @@
+-- Geocoded customer addresses are looked up by their normalized address
+ALTER TABLE known_addrs ADD COLUMN IF NOT EXISTS normalized_addr STRING;
+CREATE INDEX IF NOT EXISTS known_addrs_normalized_addr_idx ON known_addrs (normalized_addr);
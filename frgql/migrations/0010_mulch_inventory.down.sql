@@ -0,0 +1,2 @@
+ALTER TABLE fundraiser_config DROP COLUMN IF EXISTS inventory_policy;
+DROP TABLE IF EXISTS mulch_inventory;
@@ -0,0 +1,8 @@
+-- What was ordered from the supplier, received and left over for a delivery
+CREATE TABLE IF NOT EXISTS mulch_inventory (
+    delivery_id INT PRIMARY KEY, supplier STRING, bags_ordered INT, pallets INT, bags_received INT,
+    bags_leftover INT, bags_damaged INT, mulch_cost DECIMAL(13, 4), comments STRING,
+    last_modified_by STRING, last_modified_time TIMESTAMP);
+
+-- Buffer and pallet size supplier orders are suggested with (see InventoryPolicyType)
+ALTER TABLE fundraiser_config ADD COLUMN IF NOT EXISTS inventory_policy JSONB;
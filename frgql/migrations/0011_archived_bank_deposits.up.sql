@@ -0,0 +1,3 @@
+-- Bank deposits (with their items) as they were when a season was closed
+CREATE TABLE IF NOT EXISTS archived_bank_deposits (
+    season INT, deposit_id UUID, deposit JSONB, PRIMARY KEY (season, deposit_id));
@@ -0,0 +1,4 @@
+-- Supplier orders and leftovers of each delivery as they were when a season
+-- was closed
+CREATE TABLE IF NOT EXISTS archived_mulch_inventory (
+    season INT, delivery_id INT, inventory JSONB, PRIMARY KEY (season, delivery_id));
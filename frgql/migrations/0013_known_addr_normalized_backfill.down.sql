@@ -0,0 +1 @@
+-- Nothing to undo.  0009 down drops normalized_addr.
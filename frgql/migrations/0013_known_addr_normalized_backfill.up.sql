@@ -0,0 +1,4 @@
+-- normalized_addr of the known_addrs saved before 0009 added it is filled in
+-- by backfillKnownAddrNormalizedAddrs (migrations.go) since it is normalized
+-- by normalizeAddr.  CockroachDB can't write to a column in the transaction
+-- that added it so this can't be part of 0009.
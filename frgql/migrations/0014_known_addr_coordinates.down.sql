@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS known_addrs@known_addrs_lat_lng_deg_idx;
+ALTER TABLE known_addrs DROP COLUMN IF EXISTS lng_deg;
+ALTER TABLE known_addrs DROP COLUMN IF EXISTS lat_deg;
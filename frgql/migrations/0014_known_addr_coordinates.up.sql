@@ -0,0 +1,5 @@
+-- Coordinates as numbers so the addresses near a location can be found with
+-- the index (lat and lng stay the strings they were saved as)
+ALTER TABLE known_addrs ADD COLUMN IF NOT EXISTS lat_deg FLOAT8;
+ALTER TABLE known_addrs ADD COLUMN IF NOT EXISTS lng_deg FLOAT8;
+CREATE INDEX IF NOT EXISTS known_addrs_lat_lng_deg_idx ON known_addrs (lat_deg, lng_deg);
@@ -0,0 +1 @@
+-- Nothing to undo.  0014 down drops lat_deg and lng_deg.
@@ -0,0 +1,2 @@
+-- lat_deg and lng_deg of the known_addrs saved before 0014 added them are
+-- filled in by backfillKnownAddrCoordinates (migrations.go) from lat and lng
@@ -0,0 +1,86 @@
+package frgql
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// //////////////////////////////////////////////////////////////////////////
+// The schema check treats a missing schema_migrations as nothing applied
+// but not other failures
+func TestIsUndefinedTableErr(t *testing.T) {
+	undefinedTableErr := fmt.Errorf("query failed: %w", &pgconn.PgError{Code: undefinedTableCode})
+	if !isUndefinedTableErr(undefinedTableErr) {
+		t.Errorf("%v isn't an undefined table error", undefinedTableErr)
+	}
+	for _, err := range []error{nil, errors.New("relation does not exist"), &pgconn.PgError{Code: "42501"}} {
+		if isUndefinedTableErr(err) {
+			t.Errorf("%v is an undefined table error", err)
+		}
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// A backfill is only run if there is a migration with its version
+func TestMigrationBackfillsHaveMigrations(t *testing.T) {
+	migrations, err := loadMigrations()
+	mustNotFail(t, err)
+	for version := range migrationBackfills {
+		if !slices.ContainsFunc(migrations, func(migration MigrationType) bool { return migration.Version == version }) {
+			t.Errorf("backfill for migration: %d which doesn't exist", version)
+		}
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// The embedded migrations are numbered 1..n without gaps and come back in
+// that order with both directions
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := loadMigrations()
+	mustNotFail(t, err)
+	if len(migrations) == 0 {
+		t.Fatal("no migrations")
+	}
+	for idx, migration := range migrations {
+		if migration.Version != idx+1 {
+			t.Errorf("migration: %d %s is at position: %d", migration.Version, migration.Name, idx+1)
+		}
+		if len(migration.UpSql) == 0 || len(migration.DownSql) == 0 {
+			t.Errorf("migration: %d %s is missing a direction", migration.Version, migration.Name)
+		}
+	}
+	latest, err := LatestSchemaVersion()
+	mustNotFail(t, err)
+	if latest != migrations[len(migrations)-1].Version {
+		t.Errorf("latest version: %d", latest)
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func TestSplitMigrationSql(t *testing.T) {
+	tests := []struct {
+		name       string
+		sql        string
+		statements []string
+	}{
+		{"one per line", "CREATE TABLE a (id INT);\nCREATE TABLE b (id INT);\n",
+			[]string{"CREATE TABLE a (id INT);", "CREATE TABLE b (id INT);"}},
+		{"across lines", "CREATE TABLE a (\n    id INT,\n    name STRING\n);\n",
+			[]string{"CREATE TABLE a (\n    id INT,\n    name STRING\n);"}},
+		{"comments and blank lines", "-- Adds a\n\nCREATE TABLE a (\n    -- the key\n    id INT\n);\n\n  -- done\n",
+			[]string{"CREATE TABLE a (\n    id INT\n);"}},
+		// A ; has to end the line to end the statement
+		{"; inside a line", "INSERT INTO a VALUES ('x;y');\n", []string{"INSERT INTO a VALUES ('x;y');"}},
+		{"no ; at the end", "DROP TABLE a;\nDROP TABLE b", []string{"DROP TABLE a;", "DROP TABLE b"}},
+		{"only comments", "-- Filled in by migrationBackfills\n", []string{}},
+	}
+	for _, test := range tests {
+		if statements := splitMigrationSql(test.sql); !slices.Equal(statements, test.statements) {
+			t.Errorf("%s: returned: %q expected: %q", test.name, statements, test.statements)
+		}
+	}
+}
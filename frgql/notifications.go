@@ -0,0 +1,84 @@
+package frgql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// //////////////////////////////////////////////////////////////////////////
+// Message to a user about something that happened to one of their records.
+// Kind is what happened (e.g. checkBounced).
+type NotificationType struct {
+	Id          string  `json:"id"`
+	Uid         string  `json:"uid"`
+	Kind        string  `json:"kind"`
+	Message     string  `json:"message"`
+	OrderId     *string `json:"orderId"`
+	CreatedTime string  `json:"createdTime"`
+	IsRead      bool    `json:"isRead"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Adds a notification for uid in tx
+func notifyUser(ctx context.Context, tx FrStore, uid string, kind string, orderId *string, message string) error {
+	log.Printf("Notifying: %s of: %s", uid, kind)
+	return tx.InsertNotification(ctx, NotificationType{
+		Uid:         uid,
+		Kind:        kind,
+		Message:     message,
+		OrderId:     orderId,
+		CreatedTime: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the notifications of uid newest first
+func GetNotifications(ctx context.Context, uid string, isUnreadOnly bool) ([]NotificationType, error) {
+	log.Println("Retrieving notifications for: ", uid)
+
+	if len(uid) == 0 {
+		return nil, newValidationError("uid", "uid must be given")
+	}
+	if err := verifyUidAllowedFromCtx(ctx, uid); err != nil {
+		return nil, err
+	}
+	notifications, err := frStore.GetNotifications(ctx, uid, isUnreadOnly)
+	if err != nil {
+		log.Println("Notifications query failed: ", err)
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Only the notification's user (or an admin) can mark it read
+func MarkNotificationRead(ctx context.Context, id string) (bool, error) {
+	log.Println("Marking notification read: ", id)
+
+	err := frStore.InTx(ctx, func(tx FrStore) error {
+		notification, err := tx.GetNotification(ctx, id)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return &NotFoundError{Message: fmt.Sprintf("notification: %s does not exist", id)}
+			}
+			return err
+		}
+		if err := verifyUidAllowedFromCtx(ctx, notification.Uid); err != nil {
+			return err
+		}
+		if err := tx.MarkNotificationRead(ctx, id); err != nil {
+			return err
+		}
+		readNotification := notification
+		readNotification.IsRead = true
+		return recordAuditEntry(ctx, tx, "markNotificationRead", "notification", id, notification, readNotification)
+	})
+	if err != nil {
+		log.Println("Marking notification read failed: ", err)
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,151 @@
+package frgql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// //////////////////////////////////////////////////////////////////////////
+// Snapshot of an order (with its spreaders) as it was right before it was
+// changed by Operation (i.e. updateMulchOrder, setSpreaders or
+// restoreMulchOrder).  Revisions start at 1 and increase with every change.
+type MulchOrderRevisionType struct {
+	OrderId     string         `json:"orderId"`
+	Revision    int            `json:"revision"`
+	Operation   string         `json:"operation"`
+	ActorId     string         `json:"actorId"`
+	CreatedTime string         `json:"createdTime"`
+	Order       MulchOrderType `json:"order"`
+}
+
+// The fields of an order saved in a revision
+var mulchOrderRevisionGqlFields = append([]string{"spreaders"}, allMulchOrderGqlFields...)
+
+// //////////////////////////////////////////////////////////////////////////
+// Saves the order as it currently is in tx as a new revision.  Does nothing
+// if the order doesn't exist yet.
+func snapshotMulchOrder(ctx context.Context, tx FrStore, orderId string, operation string) error {
+	order, err := tx.GetMulchOrder(ctx, GetMulchOrderParams{
+		OrderId:   orderId,
+		GqlFields: mulchOrderRevisionGqlFields,
+	})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	return saveMulchOrderRevision(ctx, tx, order, operation)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Saves order, read from tx with mulchOrderRevisionGqlFields, as a new
+// revision
+func saveMulchOrderRevision(ctx context.Context, tx FrStore, order MulchOrderType, operation string) error {
+	claims, err := parseTokenClaimsFromCtx(ctx)
+	if err != nil {
+		return err
+	}
+	orderId := order.OrderId
+
+	revision, err := tx.InsertMulchOrderRevision(ctx, MulchOrderRevisionType{
+		OrderId:     orderId,
+		Operation:   operation,
+		ActorId:     claims.userId(),
+		CreatedTime: time.Now().UTC().Format(time.RFC3339),
+		Order:       order,
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("Saved order: %s as revision: %d before %s", orderId, revision, operation)
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the saved revisions of an order oldest first.  Only the order owner
+// or an admin can see them.
+func GetMulchOrderHistory(ctx context.Context, orderId string) ([]MulchOrderRevisionType, error) {
+	log.Println("Retrieving order history for: ", orderId)
+
+	if len(orderId) == 0 {
+		return nil, newValidationError("orderId", "orderId must be provided")
+	}
+
+	history, err := frStore.GetMulchOrderHistory(ctx, orderId)
+	if err != nil {
+		log.Println("Order history query failed: ", err)
+		return nil, err
+	}
+
+	// The order may have been deleted so use the owner it had last
+	ownerId := ""
+	if order, err := frStore.GetMulchOrder(ctx, GetMulchOrderParams{OrderId: orderId, GqlFields: []string{"ownerId"}}); err == nil {
+		ownerId = order.OwnerId
+	} else if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	} else if len(history) != 0 {
+		ownerId = history[len(history)-1].Order.OwnerId
+	}
+	if err := verifyUidAllowedFromCtx(ctx, ownerId); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Puts an order (and its spreaders) back to how it was in the given revision.
+// Works for deleted orders too.  What the order is now is saved as a new
+// revision first so a restore can be undone.  Admin only
+func RestoreMulchOrder(ctx context.Context, orderId string, revision int) (bool, error) {
+	log.Printf("Restoring order: %s to revision: %d", orderId, revision)
+
+	if err := VerifyAdminTokenFromCtx(ctx); err != nil {
+		return false, err
+	}
+
+	orderRevision, err := frStore.GetMulchOrderRevision(ctx, orderId, revision)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return false, &NotFoundError{Message: fmt.Sprintf("order: %s does not have a revision: %d", orderId, revision)}
+		}
+		return false, err
+	}
+	order := orderRevision.Order
+	order.LastModifiedTime = makeLastModifiedTime()
+
+	err = frStore.InTx(ctx, func(tx FrStore) error {
+		var existingOrder *MulchOrderType
+		current, err := tx.GetMulchOrder(ctx, GetMulchOrderParams{OrderId: orderId, GqlFields: allMulchOrderGqlFields})
+		if err == nil {
+			existingOrder = &current
+		} else if !errors.Is(err, ErrNotFound) {
+			return err
+		}
+
+		if existingOrder != nil {
+			if err := snapshotMulchOrder(ctx, tx, orderId, "restoreMulchOrder"); err != nil {
+				return err
+			}
+			if err := tx.UpdateMulchOrder(ctx, order, allMulchOrderUpdateFields, ""); err != nil {
+				return err
+			}
+		} else {
+			if err := tx.InsertMulchOrder(ctx, order); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.SetSpreaders(ctx, orderId, order.Spreaders); err != nil {
+			return err
+		}
+		return recordMulchOrderAuditEntry(ctx, tx, "restoreMulchOrder", existingOrder, orderId)
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
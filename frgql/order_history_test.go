@@ -0,0 +1,106 @@
+package frgql
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// //////////////////////////////////////////////////////////////////////////
+// Spreader changes are in the order's history and can be restored
+func TestSetSpreadersHistory(t *testing.T) {
+	store := newTestMemStore(t)
+	ctx := context.Background()
+	ownerCtx := newTestCtx(t, "scout1", false)
+
+	mustNotFail(t, store.InsertMulchOrder(ctx, makeTestOrder(testOrderId1, "scout1")))
+	_, err := SetSpreaders(ownerCtx, testOrderId1, []string{"scout1", "scout2"})
+	mustNotFail(t, err)
+	_, err = SetSpreaders(ownerCtx, testOrderId1, []string{"scout3"})
+	mustNotFail(t, err)
+
+	history, err := GetMulchOrderHistory(ownerCtx, testOrderId1)
+	mustNotFail(t, err)
+	if len(history) != 2 || history[1].Operation != "setSpreaders" ||
+		!slices.Equal(history[1].Order.Spreaders, []string{"scout1", "scout2"}) {
+		t.Fatalf("history: %+v", history)
+	}
+
+	_, err = RestoreMulchOrder(newTestCtx(t, "admin1", true), testOrderId1, history[1].Revision)
+	mustNotFail(t, err)
+	order, err := store.GetMulchOrder(ctx, GetMulchOrderParams{OrderId: testOrderId1, GqlFields: mulchOrderRevisionGqlFields})
+	mustNotFail(t, err)
+	if !slices.Equal(order.Spreaders, []string{"scout1", "scout2"}) {
+		t.Errorf("restored spreaders: %v", order.Spreaders)
+	}
+
+	var forbiddenErr *ForbiddenError
+	if _, err := SetSpreaders(newTestCtx(t, "scout2", false), testOrderId1, nil); !errors.As(err, &forbiddenErr) {
+		t.Errorf("another scout setting spreaders returned: %v not a ForbiddenError", err)
+	}
+	var notFoundErr *NotFoundError
+	if _, err := SetSpreaders(ownerCtx, testOrderId2, []string{"scout1"}); !errors.As(err, &notFoundErr) {
+		t.Errorf("setting spreaders of a missing order returned: %v not a NotFoundError", err)
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Updated and deleted orders can be put back the way they were
+func TestRestoreMulchOrder(t *testing.T) {
+	store := newTestMemStore(t)
+	ctx := context.Background()
+	ownerCtx := newTestCtx(t, "scout1", false)
+	adminCtx := newTestCtx(t, "admin1", true)
+	mustNotFail(t, store.InsertMulchOrder(ctx, makeTestOrder(testOrderId1, "scout1")))
+
+	comments := "leave by the garage"
+	_, err := UpdateMulchOrder(ownerCtx, MulchOrderType{OrderId: testOrderId1, Comments: &comments},
+		[]string{"comments"}, "", false)
+	mustNotFail(t, err)
+	_, err = DeleteMulchOrder(ownerCtx, testOrderId1, false)
+	mustNotFail(t, err)
+
+	history, err := GetMulchOrderHistory(ownerCtx, testOrderId1)
+	mustNotFail(t, err)
+	if len(history) != 2 || history[0].Operation != "updateMulchOrder" || history[0].Order.Comments != nil ||
+		history[1].Operation != "deleteMulchOrder" || *history[1].Order.Comments != comments {
+		t.Fatalf("history: %+v", history)
+	}
+	var forbiddenErr *ForbiddenError
+	if _, err := GetMulchOrderHistory(newTestCtx(t, "scout2", false), testOrderId1); !errors.As(err, &forbiddenErr) {
+		t.Errorf("another scout reading the history returned: %v not a ForbiddenError", err)
+	}
+	if _, err := RestoreMulchOrder(ownerCtx, testOrderId1, 1); !errors.As(err, &forbiddenErr) {
+		t.Errorf("a scout restoring returned: %v not a ForbiddenError", err)
+	}
+
+	// The deleted order comes back as it was when it was deleted
+	_, err = RestoreMulchOrder(adminCtx, testOrderId1, 2)
+	mustNotFail(t, err)
+	order, err := store.GetMulchOrder(ctx, GetMulchOrderParams{OrderId: testOrderId1, GqlFields: allMulchOrderGqlFields})
+	mustNotFail(t, err)
+	if *order.Comments != comments || order.Customer.Name != "Pat Doe" {
+		t.Errorf("restored deleted order: %+v", order)
+	}
+
+	// Restoring the existing order saves it as a revision first
+	_, err = RestoreMulchOrder(adminCtx, testOrderId1, 1)
+	mustNotFail(t, err)
+	order, err = store.GetMulchOrder(ctx, GetMulchOrderParams{OrderId: testOrderId1, GqlFields: allMulchOrderGqlFields})
+	mustNotFail(t, err)
+	if order.Comments != nil {
+		t.Errorf("restored comments: %s", *order.Comments)
+	}
+	history, err = GetMulchOrderHistory(ownerCtx, testOrderId1)
+	mustNotFail(t, err)
+	if len(history) != 3 || history[2].Revision != 3 || history[2].Operation != "restoreMulchOrder" ||
+		*history[2].Order.Comments != comments {
+		t.Errorf("history after restoring: %+v", history)
+	}
+
+	var notFoundErr *NotFoundError
+	if _, err := RestoreMulchOrder(adminCtx, testOrderId1, 9); !errors.As(err, &notFoundErr) {
+		t.Errorf("restoring a missing revision returned: %v not a NotFoundError", err)
+	}
+}
@@ -0,0 +1,163 @@
+package frgql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// All of the order fields (minus spreaders which are stored separately)
+var allMulchOrderGqlFields = []string{
+	"orderId", "ownerId", "lastModifiedTime", "comments", "specialInstructions", "amountFromDonations",
+	"amountFromPurchases", "amountFromCashCollected", "amountFromChecksCollected",
+	"amountTotalCollected", "checkNumbers", "checks", "deliveryId", "willCollectMoneyLater",
+	"isVerified", "customer", "purchases", "knownAddrId",
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// A delivery is closed once its new order cutoff has passed.  Deliveries
+// without a cutoff are never closed.
+func isDeliveryClosed(delivery MulchDeliveryConfigType, now time.Time) bool {
+	return delivery.NewOrderCutoffDateAsEpoch != 0 && now.Unix() >= int64(delivery.NewOrderCutoffDateAsEpoch)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Checks the fundraiser lock and the delivery cutoffs before an order is
+// created (existing is nil), updated or deleted (updated is nil).
+//
+//   - Non-admins can't change anything once the fundraiser is locked
+//   - New orders can't be added to a delivery that is past its cutoff
+//   - Orders in a closed delivery can only have their money fields changed
+//     and can't be deleted
+//
+// Admins can skip all of this with doOverrideLock which is logged.
+func verifyOrderChangeAllowed(ctx context.Context, store FrStore, existing *MulchOrderType, updated *MulchOrderType, doOverrideLock bool) error {
+	claims, err := parseTokenClaimsFromCtx(ctx)
+	if err != nil {
+		return err
+	}
+
+	orderId := ""
+	if existing != nil {
+		orderId = existing.OrderId
+	} else if updated != nil {
+		orderId = updated.OrderId
+	}
+
+	if doOverrideLock {
+		if !claims.isAdmin() {
+			return &ForbiddenError{Message: "not authorized: only admins can override the fundraiser lock"}
+		}
+		log.Printf("LOCK OVERRIDE: admin: %s is overriding the fundraiser lock/cutoffs for order: %s", claims.userId(), orderId)
+		return nil
+	}
+
+	frConfig, err := store.GetFundraiserConfig(ctx, []string{"isLocked", "mulchDeliveryConfigs"})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			log.Println("No fundraiser config so there are no order locks to enforce")
+			return nil
+		}
+		return err
+	}
+
+	if frConfig.IsLocked != nil && *frConfig.IsLocked && !claims.isAdmin() {
+		return &ForbiddenError{Message: "fundraiser is locked and orders can no longer be changed"}
+	}
+
+	now := time.Now()
+	closedDeliveryId := func(deliveryId *int) (int, bool) {
+		if deliveryId == nil || frConfig.MulchDeliveryConfigs == nil {
+			return 0, false
+		}
+		for _, delivery := range *frConfig.MulchDeliveryConfigs {
+			if delivery.Id == *deliveryId {
+				return delivery.Id, isDeliveryClosed(delivery, now)
+			}
+		}
+		return 0, false
+	}
+
+	switch {
+	case existing == nil:
+		if deliveryId, isClosed := closedDeliveryId(updated.DeliveryId); isClosed {
+			return &ForbiddenError{Message: fmt.Sprintf("delivery %d is past its new order cutoff and is no longer accepting orders", deliveryId)}
+		}
+	case updated == nil:
+		if deliveryId, isClosed := closedDeliveryId(existing.DeliveryId); isClosed {
+			return &ForbiddenError{Message: fmt.Sprintf("order is in delivery %d which is closed and so can not be deleted", deliveryId)}
+		}
+	default:
+		deliveryId, isClosed := closedDeliveryId(existing.DeliveryId)
+		if !isClosed {
+			deliveryId, isClosed = closedDeliveryId(updated.DeliveryId)
+		}
+		if isClosed && !isMoneyOnlyOrderChange(*existing, *updated) {
+			return &ForbiddenError{Message: fmt.Sprintf("delivery %d is closed so only the money collected fields of the order can be changed", deliveryId)}
+		}
+	}
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns true if the only differences between the orders are in the money
+// collected fields (cash, checks, check numbers, collect later, verified)
+func isMoneyOnlyOrderChange(existing MulchOrderType, updated MulchOrderType) bool {
+	toDecimal := func(amount *string) decimal.Decimal {
+		if amount == nil {
+			return decimal.Zero
+		}
+		d, err := decimal.NewFromString(strings.ReplaceAll(*amount, ",", ""))
+		if err != nil {
+			return decimal.Zero
+		}
+		return d
+	}
+	eqAmount := func(a, b *string) bool {
+		return toDecimal(a).Equal(toDecimal(b))
+	}
+	eqStr := func(a, b *string) bool {
+		aVal, bVal := "", ""
+		if a != nil {
+			aVal = *a
+		}
+		if b != nil {
+			bVal = *b
+		}
+		return aVal == bVal
+	}
+	eqInt := func(a, b *int) bool {
+		if a == nil || b == nil {
+			return a == b
+		}
+		return *a == *b
+	}
+
+	if existing.OwnerId != updated.OwnerId ||
+		!eqInt(existing.DeliveryId, updated.DeliveryId) ||
+		!eqStr(existing.SpecialInstructions, updated.SpecialInstructions) ||
+		!eqStr(existing.Comments, updated.Comments) ||
+		!eqAmount(existing.AmountFromDonations, updated.AmountFromDonations) ||
+		!eqAmount(existing.AmountFromPurchases, updated.AmountFromPurchases) ||
+		!eqAmount(existing.AmountTotalCollected, updated.AmountTotalCollected) {
+		return false
+	}
+
+	ec, uc := existing.Customer, updated.Customer
+	if ec.Name != uc.Name || ec.Addr1 != uc.Addr1 || ec.Phone != uc.Phone || ec.Neighborhood != uc.Neighborhood ||
+		!eqStr(ec.Addr2, uc.Addr2) || !eqStr(ec.City, uc.City) || !eqStr(ec.Email, uc.Email) ||
+		!eqInt(ec.Zipcode, uc.Zipcode) {
+		return false
+	}
+
+	return slices.EqualFunc(existing.Purchases, updated.Purchases, func(a, b ProductsType) bool {
+		return a.ProductId == b.ProductId && a.NumSold == b.NumSold &&
+			eqAmount(&a.AmountCharged, &b.AmountCharged)
+	})
+}
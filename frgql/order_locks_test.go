@@ -0,0 +1,47 @@
+package frgql
+
+import "testing"
+
+// //////////////////////////////////////////////////////////////////////////
+func TestIsMoneyOnlyOrderChange(t *testing.T) {
+	str := func(val string) *string { return &val }
+
+	tests := []struct {
+		name        string
+		modify      func(order *MulchOrderType)
+		isMoneyOnly bool
+	}{
+		{"nothing changed", func(order *MulchOrderType) {}, true},
+		{"cash and checks", func(order *MulchOrderType) {
+			order.AmountFromCashCollected, order.AmountFromChecksCollected = str("4"), str("20")
+			order.CheckNumbers = str("1001")
+			order.Checks = []OrderCheckType{{CheckNumber: "1001", Amount: "20"}}
+		}, true},
+		{"collect later and verified", func(order *MulchOrderType) {
+			isSet := true
+			order.WillCollectMoneyLater, order.IsVerified = &isSet, &isSet
+		}, true},
+		{"same total written differently", func(order *MulchOrderType) { order.AmountTotalCollected = str("24.00") }, true},
+		{"total", func(order *MulchOrderType) { order.AmountTotalCollected = str("25") }, false},
+		{"donations", func(order *MulchOrderType) { order.AmountFromDonations = str("1") }, false},
+		{"bags sold", func(order *MulchOrderType) { order.Purchases[0].NumSold = 5 }, false},
+		{"product added", func(order *MulchOrderType) {
+			order.Purchases = append(order.Purchases, ProductsType{ProductId: "spreading", NumSold: 4, AmountCharged: "8"})
+		}, false},
+		{"owner", func(order *MulchOrderType) { order.OwnerId = "scout2" }, false},
+		{"delivery", func(order *MulchOrderType) { order.DeliveryId = nil }, false},
+		{"customer address", func(order *MulchOrderType) { order.Customer.Addr1 = "14 Main St" }, false},
+		{"customer city", func(order *MulchOrderType) { order.Customer.City = nil }, false},
+		{"special instructions", func(order *MulchOrderType) { order.SpecialInstructions = str("by the garage") }, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			existing := makeTestOrder(testOrderId1, "scout1")
+			updated := makeTestOrder(testOrderId1, "scout1")
+			test.modify(&updated)
+			if isMoneyOnly := isMoneyOnlyOrderChange(existing, updated); isMoneyOnly != test.isMoneyOnly {
+				t.Errorf("isMoneyOnlyOrderChange: %t", isMoneyOnly)
+			}
+		})
+	}
+}
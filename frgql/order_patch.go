@@ -0,0 +1,105 @@
+package frgql
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Order fields that can be changed by an update.  Customer fields are
+// given as customer.<field>
+var allMulchOrderUpdateFields = []string{
+	"ownerId", "comments", "specialInstructions", "amountFromDonations", "amountFromPurchases",
+	"amountFromCashCollected", "amountFromChecksCollected", "amountTotalCollected", "checkNumbers",
+	"checks", "deliveryId", "willCollectMoneyLater", "isVerified", "purchases",
+	"customer.name", "customer.addr1", "customer.addr2", "customer.city", "customer.zipcode",
+	"customer.phone", "customer.email", "customer.neighborhood", "knownAddrId",
+}
+
+// Fields an order always has to have so they can be changed but not cleared
+var requiredMulchOrderFields = []string{
+	"ownerId", "customer.name", "customer.addr1", "customer.phone", "customer.neighborhood",
+}
+
+// Fields that change what the order costs or how it was paid for
+var mulchOrderPricingFields = []string{
+	"amountFromDonations", "amountFromPurchases", "amountFromCashCollected",
+	"amountFromChecksCollected", "amountTotalCollected", "willCollectMoneyLater", "purchases", "checks",
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Copies the given fields from src to dst.  A field that is nil/empty in src
+// clears it in dst.
+func applyMulchOrderFields(dst *MulchOrderType, src MulchOrderType, fields []string) error {
+	for _, field := range fields {
+		switch field {
+		case "ownerId":
+			dst.OwnerId = src.OwnerId
+		case "comments":
+			dst.Comments = src.Comments
+		case "specialInstructions":
+			dst.SpecialInstructions = src.SpecialInstructions
+		case "amountFromDonations":
+			dst.AmountFromDonations = src.AmountFromDonations
+		case "amountFromPurchases":
+			dst.AmountFromPurchases = src.AmountFromPurchases
+		case "amountFromCashCollected":
+			dst.AmountFromCashCollected = src.AmountFromCashCollected
+		case "amountFromChecksCollected":
+			dst.AmountFromChecksCollected = src.AmountFromChecksCollected
+		case "amountTotalCollected":
+			dst.AmountTotalCollected = src.AmountTotalCollected
+		case "checkNumbers":
+			dst.CheckNumbers = src.CheckNumbers
+		case "checks":
+			dst.Checks = slices.Clone(src.Checks)
+		case "deliveryId":
+			dst.DeliveryId = src.DeliveryId
+		case "willCollectMoneyLater":
+			dst.WillCollectMoneyLater = src.WillCollectMoneyLater
+		case "isVerified":
+			dst.IsVerified = src.IsVerified
+		case "purchases":
+			dst.Purchases = slices.Clone(src.Purchases)
+		case "knownAddrId":
+			dst.KnownAddrId = src.KnownAddrId
+		case "customer.name":
+			dst.Customer.Name = src.Customer.Name
+		case "customer.addr1":
+			dst.Customer.Addr1 = src.Customer.Addr1
+		case "customer.addr2":
+			dst.Customer.Addr2 = src.Customer.Addr2
+		case "customer.city":
+			dst.Customer.City = src.Customer.City
+		case "customer.zipcode":
+			dst.Customer.Zipcode = src.Customer.Zipcode
+		case "customer.phone":
+			dst.Customer.Phone = src.Customer.Phone
+		case "customer.email":
+			dst.Customer.Email = src.Customer.Email
+		case "customer.neighborhood":
+			dst.Customer.Neighborhood = src.Customer.Neighborhood
+		default:
+			return fmt.Errorf("unknown mulch order field: %s", field)
+		}
+	}
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the required fields that are empty in order
+func getMissingMulchOrderFields(order MulchOrderType) []string {
+	missing := []string{}
+	values := map[string]string{
+		"ownerId":               order.OwnerId,
+		"customer.name":         order.Customer.Name,
+		"customer.addr1":        order.Customer.Addr1,
+		"customer.phone":        order.Customer.Phone,
+		"customer.neighborhood": order.Customer.Neighborhood,
+	}
+	for _, field := range requiredMulchOrderFields {
+		if len(values[field]) == 0 {
+			missing = append(missing, field)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,60 @@
+package frgql
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+const testUpdateMulchOrderGql = `
+mutation UpdateOrder($order: MulchOrderInputType!) {
+  updateMulchOrder(order: $order)
+}`
+
+// //////////////////////////////////////////////////////////////////////////
+// Only the fields given in the mutation are changed.  Explicit nulls clear
+// them.
+func TestUpdateMulchOrderLeavesOmittedFields(t *testing.T) {
+	store := newTestMemStore(t)
+	ctx := context.Background()
+	ownerCtx := newTestCtx(t, "scout1", false)
+
+	order := makeTestOrder(testOrderId1, "scout1")
+	email := "pat@example.com"
+	order.Customer.Email = &email
+	mustNotFail(t, store.InsertMulchOrder(ctx, order))
+
+	_, err := MakeGqlQueryWithVars(ownerCtx, testUpdateMulchOrderGql, map[string]interface{}{
+		"order": map[string]interface{}{
+			"orderId":  testOrderId1,
+			"comments": "leave by the garage",
+			"customer": map[string]interface{}{"email": nil},
+		},
+	}, "")
+	mustNotFail(t, err)
+
+	updated, err := store.GetMulchOrder(ctx, GetMulchOrderParams{OrderId: testOrderId1, GqlFields: allMulchOrderGqlFields})
+	mustNotFail(t, err)
+	if updated.Comments == nil || *updated.Comments != "leave by the garage" {
+		t.Errorf("comments: %v", updated.Comments)
+	}
+	if updated.Customer.Email != nil {
+		t.Errorf("email wasn't cleared: %s", *updated.Customer.Email)
+	}
+	if updated.Customer.Name != order.Customer.Name || updated.Customer.Addr1 != order.Customer.Addr1 ||
+		*updated.Customer.City != *order.Customer.City || *updated.AmountTotalCollected != *order.AmountTotalCollected ||
+		!slices.Equal(updated.Purchases, order.Purchases) {
+		t.Errorf("omitted fields changed from: %+v to: %+v", order, updated)
+	}
+
+	// Required fields can't be cleared
+	_, err = MakeGqlQueryWithVars(ownerCtx, testUpdateMulchOrderGql, map[string]interface{}{
+		"order": map[string]interface{}{
+			"orderId":  testOrderId1,
+			"customer": map[string]interface{}{"name": nil},
+		},
+	}, "")
+	if GetErrorCode(err) != ErrCodeValidation {
+		t.Errorf("clearing the customer name returned: %v", err)
+	}
+}
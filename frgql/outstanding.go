@@ -0,0 +1,379 @@
+package frgql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Aging buckets of the money owed.  An order is in the last bucket whose
+// MinDays it has been outstanding for.
+var outstandingAgingBuckets = []struct {
+	Label   string
+	MinDays int
+}{
+	{"0-7 days", 0},
+	{"8-14 days", 8},
+	{"15-30 days", 15},
+	{"over 30 days", 31},
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Order that still owes money.  DaysOutstanding is counted from the last
+// time the order was changed.
+type OutstandingOrderType struct {
+	OrderId               string `json:"orderId"`
+	OwnerId               string `json:"ownerId"`
+	DeliveryId            *int   `json:"deliveryId"`
+	CustomerName          string `json:"customerName"`
+	CustomerPhone         string `json:"customerPhone"`
+	AmountDue             string `json:"amountDue"`
+	AmountCollected       string `json:"amountCollected"`
+	AmountOwed            string `json:"amountOwed"`
+	WillCollectMoneyLater bool   `json:"willCollectMoneyLater"`
+	LastModifiedTime      string `json:"lastModifiedTime"`
+	DaysOutstanding       int    `json:"daysOutstanding"`
+	AgingBucket           string `json:"agingBucket"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+type OutstandingAgingType struct {
+	AgingBucket string `json:"agingBucket"`
+	NumOrders   int    `json:"numOrders"`
+	AmountOwed  string `json:"amountOwed"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Money owed to a seller (OwnerId) or in a delivery (DeliveryId)
+type OutstandingBalanceRowType struct {
+	OwnerId    *string                `json:"ownerId"`
+	DeliveryId *int                   `json:"deliveryId"`
+	NumOrders  int                    `json:"numOrders"`
+	AmountOwed string                 `json:"amountOwed"`
+	Aging      []OutstandingAgingType `json:"aging"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+type OutstandingBalancesType struct {
+	Orders     []OutstandingOrderType      `json:"orders"`
+	Sellers    []OutstandingBalanceRowType `json:"sellers"`
+	Deliveries []OutstandingBalanceRowType `json:"deliveries"`
+	Totals     OutstandingBalanceRowType   `json:"totals"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// A later payment of an order.  Either or both can be given.
+type OrderPaymentParams struct {
+	AmountFromCash string          `json:"amountFromCash"`
+	Check          *OrderCheckType `json:"check"`
+}
+
+// Order fields needed to work out what is owed
+var outstandingOrderGqlFields = []string{
+	"orderId", "ownerId", "lastModifiedTime", "deliveryId", "customer", "amountFromDonations",
+	"amountFromPurchases", "amountFromCashCollected", "amountFromChecksCollected",
+	"amountTotalCollected", "willCollectMoneyLater",
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns what the order costs (purchases + donations) and what has been
+// collected (cash + checks).  Older clients don't always send
+// amountFromPurchases so amountTotalCollected is used for them.
+func calcOrderAmountOwed(order MulchOrderType) (decimal.Decimal, decimal.Decimal, error) {
+	amount := func(field string, value *string) (decimal.Decimal, error) {
+		v, err := parseAmount(value)
+		if err != nil {
+			return v, fmt.Errorf("order: %s %s: %w", order.OrderId, field, err)
+		}
+		return v, nil
+	}
+
+	due, err := amount("amountTotalCollected", order.AmountTotalCollected)
+	if err != nil {
+		return due, due, err
+	}
+	if order.AmountFromPurchases != nil {
+		purchases, err := amount("amountFromPurchases", order.AmountFromPurchases)
+		if err != nil {
+			return due, due, err
+		}
+		donations, err := amount("amountFromDonations", order.AmountFromDonations)
+		if err != nil {
+			return due, due, err
+		}
+		due = purchases.Add(donations)
+	}
+
+	cash, err := amount("amountFromCashCollected", order.AmountFromCashCollected)
+	if err != nil {
+		return due, due, err
+	}
+	checks, err := amount("amountFromChecksCollected", order.AmountFromChecksCollected)
+	if err != nil {
+		return due, due, err
+	}
+	return due, cash.Add(checks), nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the aging bucket for the number of days
+func getAgingBucket(days int) string {
+	label := outstandingAgingBuckets[0].Label
+	for _, bucket := range outstandingAgingBuckets {
+		if days >= bucket.MinDays {
+			label = bucket.Label
+		}
+	}
+	return label
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the orders that still owe money grouped by seller and delivery and
+// aged by when the order was last changed.  An empty ownerId is admin only.
+func GetOutstandingBalances(ctx context.Context, ownerId string) (OutstandingBalancesType, error) {
+	log.Println("Retrieving outstanding balances. OwnerId: ", ownerId)
+
+	if err := verifyUidAllowedFromCtx(ctx, ownerId); err != nil {
+		return OutstandingBalancesType{}, err
+	}
+
+	// Oldest balances first
+	orders, err := frStore.GetMulchOrders(ctx, GetMulchOrdersParams{
+		OwnerId:   ownerId,
+		SortBy:    "lastModifiedTime",
+		GqlFields: outstandingOrderGqlFields,
+	})
+	if err != nil {
+		log.Println("Outstanding balances query failed: ", err)
+		return OutstandingBalancesType{}, err
+	}
+
+	type rowSums struct {
+		numOrders int
+		owed      decimal.Decimal
+		aging     map[string]*rowSums
+	}
+	newRowSums := func() *rowSums {
+		return &rowSums{aging: make(map[string]*rowSums)}
+	}
+	addTo := func(sums *rowSums, bucket string, owed decimal.Decimal) {
+		sums.numOrders++
+		sums.owed = sums.owed.Add(owed)
+		if _, ok := sums.aging[bucket]; !ok {
+			sums.aging[bucket] = &rowSums{}
+		}
+		sums.aging[bucket].numOrders++
+		sums.aging[bucket].owed = sums.aging[bucket].owed.Add(owed)
+	}
+	toRow := func(sums *rowSums) OutstandingBalanceRowType {
+		row := OutstandingBalanceRowType{
+			NumOrders:  sums.numOrders,
+			AmountOwed: sums.owed.StringFixedBank(4),
+			Aging:      []OutstandingAgingType{},
+		}
+		// Every bucket is returned so the rows line up in a report
+		for _, bucket := range outstandingAgingBuckets {
+			aging := OutstandingAgingType{AgingBucket: bucket.Label, AmountOwed: decimal.Zero.StringFixedBank(4)}
+			if bucketSums, ok := sums.aging[bucket.Label]; ok {
+				aging.NumOrders = bucketSums.numOrders
+				aging.AmountOwed = bucketSums.owed.StringFixedBank(4)
+			}
+			row.Aging = append(row.Aging, aging)
+		}
+		return row
+	}
+
+	now := time.Now().UTC()
+	balances := OutstandingBalancesType{
+		Orders:     []OutstandingOrderType{},
+		Sellers:    []OutstandingBalanceRowType{},
+		Deliveries: []OutstandingBalanceRowType{},
+	}
+	totals := newRowSums()
+	sellers := make(map[string]*rowSums)
+	deliveries := make(map[int]*rowSums)
+	for _, order := range orders {
+		due, collected, err := calcOrderAmountOwed(order)
+		if err != nil {
+			return OutstandingBalancesType{}, err
+		}
+		owed := due.Sub(collected)
+		if !owed.IsPositive() {
+			continue
+		}
+
+		daysOutstanding := 0
+		if lastModified, ok := parseLastModifiedTime(order.LastModifiedTime); ok {
+			daysOutstanding = max(int(now.Sub(lastModified).Hours()/24), 0)
+		} else {
+			log.Println("Order: ", order.OrderId, " lastModifiedTime can't be read so it is aged as new: ", order.LastModifiedTime)
+		}
+		bucket := getAgingBucket(daysOutstanding)
+
+		balances.Orders = append(balances.Orders, OutstandingOrderType{
+			OrderId:               order.OrderId,
+			OwnerId:               order.OwnerId,
+			DeliveryId:            order.DeliveryId,
+			CustomerName:          order.Customer.Name,
+			CustomerPhone:         order.Customer.Phone,
+			AmountDue:             due.StringFixedBank(4),
+			AmountCollected:       collected.StringFixedBank(4),
+			AmountOwed:            owed.StringFixedBank(4),
+			WillCollectMoneyLater: order.WillCollectMoneyLater != nil && *order.WillCollectMoneyLater,
+			LastModifiedTime:      order.LastModifiedTime,
+			DaysOutstanding:       daysOutstanding,
+			AgingBucket:           bucket,
+		})
+
+		if _, ok := sellers[order.OwnerId]; !ok {
+			sellers[order.OwnerId] = newRowSums()
+		}
+		deliveryId := 0
+		if order.DeliveryId != nil {
+			deliveryId = *order.DeliveryId
+		}
+		if _, ok := deliveries[deliveryId]; !ok {
+			deliveries[deliveryId] = newRowSums()
+		}
+		for _, sums := range []*rowSums{totals, sellers[order.OwnerId], deliveries[deliveryId]} {
+			addTo(sums, bucket, owed)
+		}
+	}
+
+	balances.Totals = toRow(totals)
+	for _, sellerId := range slices.Sorted(maps.Keys(sellers)) {
+		row := toRow(sellers[sellerId])
+		row.OwnerId = &sellerId
+		balances.Sellers = append(balances.Sellers, row)
+	}
+	for _, deliveryId := range slices.Sorted(maps.Keys(deliveries)) {
+		row := toRow(deliveries[deliveryId])
+		if deliveryId != 0 {
+			// 0 is the orders without a delivery
+			row.DeliveryId = &deliveryId
+		}
+		balances.Deliveries = append(balances.Deliveries, row)
+	}
+	return balances, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Adds a payment made after the order was taken to the money collected on
+// it.  The order is no longer willCollectMoneyLater once it is paid off.
+// Returns what is still owed.
+func RecordOrderPayment(ctx context.Context, orderId string, payment OrderPaymentParams) (string, error) {
+	log.Println("Recording payment for order: ", orderId)
+
+	cash, err := parseAmount(&payment.AmountFromCash)
+	if err != nil || cash.IsNegative() {
+		return "", newValidationError("amountFromCash", "amountFromCash is not a valid amount: %s", payment.AmountFromCash)
+	}
+	checkAmount := decimal.Zero
+	if payment.Check != nil {
+		checkAmount, err = parseAmount(&payment.Check.Amount)
+		if err != nil || !checkAmount.IsPositive() {
+			return "", newValidationError("check.amount", "check amount is not a valid amount: %s", payment.Check.Amount)
+		}
+	}
+	paid := cash.Add(checkAmount)
+	if !paid.IsPositive() {
+		return "", newValidationError("amountFromCash", "a payment needs an amountFromCash or a check")
+	}
+
+	// The order is read in the transaction and only updated if it is still at
+	// that version so two payments made at once can't both add to what it was
+	owed := decimal.Zero
+	err = frStore.InTx(ctx, func(tx FrStore) error {
+		existingOrder, err := tx.GetMulchOrder(ctx, GetMulchOrderParams{OrderId: orderId, GqlFields: allMulchOrderGqlFields})
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return &NotFoundError{Message: fmt.Sprintf("order %s was not found", orderId)}
+			}
+			return err
+		}
+		if err := verifyUidAllowedFromCtx(ctx, existingOrder.OwnerId); err != nil {
+			return err
+		}
+
+		due, collected, err := calcOrderAmountOwed(existingOrder)
+		if err != nil {
+			return err
+		}
+		owed = due.Sub(collected)
+		if paid.GreaterThan(owed) {
+			return newValidationError("amountFromCash", "payment of %s is more than the %s owed on order: %s",
+				paid.StringFixedBank(4), owed.StringFixedBank(4), orderId)
+		}
+
+		updatedOrder := existingOrder
+		updateFields := []string{"willCollectMoneyLater"}
+		if cash.IsPositive() {
+			cashCollected, err := parseAmount(existingOrder.AmountFromCashCollected)
+			if err != nil {
+				return fmt.Errorf("order: %s amountFromCashCollected: %w", orderId, err)
+			}
+			cashCollectedStr := cashCollected.Add(cash).StringFixedBank(4)
+			updatedOrder.AmountFromCashCollected = &cashCollectedStr
+			updateFields = append(updateFields, "amountFromCashCollected")
+		}
+		if payment.Check != nil {
+			checksCollected, err := parseAmount(existingOrder.AmountFromChecksCollected)
+			if err != nil {
+				return fmt.Errorf("order: %s amountFromChecksCollected: %w", orderId, err)
+			}
+			checksCollectedStr := checksCollected.Add(checkAmount).StringFixedBank(4)
+			updatedOrder.AmountFromChecksCollected = &checksCollectedStr
+
+			// Orders from older clients only have the check numbers
+			checkNumbers := payment.Check.CheckNumber
+			if len(existingOrder.Checks) != 0 || checksCollected.IsZero() {
+				check := *payment.Check
+				check.IsBounced, check.BouncedTime = false, nil
+				updatedOrder.Checks = append(slices.Clone(existingOrder.Checks), check)
+				checkNumbers = joinCheckNumbers(updatedOrder.Checks)
+				updateFields = append(updateFields, "checks")
+			} else if existingOrder.CheckNumbers != nil && len(*existingOrder.CheckNumbers) != 0 {
+				checkNumbers = *existingOrder.CheckNumbers + "," + checkNumbers
+			}
+			updatedOrder.CheckNumbers = &checkNumbers
+			updateFields = append(updateFields, "amountFromChecksCollected", "checkNumbers")
+		}
+		willCollectMoneyLater := paid.LessThan(owed)
+		updatedOrder.WillCollectMoneyLater = &willCollectMoneyLater
+
+		if err := validateOrderChecks(updatedOrder); err != nil {
+			return err
+		}
+		if err := verifyOrderChangeAllowed(ctx, tx, &existingOrder, &updatedOrder, false); err != nil {
+			return err
+		}
+
+		updatedOrder.LastModifiedTime = makeLastModifiedTime()
+		if err := snapshotMulchOrder(ctx, tx, orderId, "recordOrderPayment"); err != nil {
+			return err
+		}
+		err = tx.UpdateMulchOrder(ctx, updatedOrder, updateFields, existingOrder.LastModifiedTime)
+		if errors.Is(err, ErrStaleVersion) {
+			currentOrder, err := tx.GetMulchOrder(ctx, GetMulchOrderParams{OrderId: orderId, GqlFields: allMulchOrderGqlFields})
+			if err != nil {
+				return err
+			}
+			return newConflictError("mulchOrder", orderId, currentOrder.LastModifiedTime, currentOrder)
+		}
+		if err != nil {
+			return err
+		}
+		return recordMulchOrderAuditEntry(ctx, tx, "recordOrderPayment", &existingOrder, orderId)
+	})
+	if err != nil {
+		log.Println("Recording payment for order: ", orderId, " failed: ", err)
+		return "", err
+	}
+	return owed.Sub(paid).StringFixedBank(4), nil
+}
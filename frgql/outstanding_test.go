@@ -0,0 +1,138 @@
+package frgql
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// //////////////////////////////////////////////////////////////////////////
+func TestGetAgingBucket(t *testing.T) {
+	tests := map[int]string{0: "0-7 days", 7: "0-7 days", 8: "8-14 days", 15: "15-30 days", 30: "15-30 days", 31: "over 30 days"}
+	for days, expected := range tests {
+		if bucket := getAgingBucket(days); bucket != expected {
+			t.Errorf("%d days: %s not: %s", days, bucket, expected)
+		}
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// scout1 is owed 24 on an unpaid order in delivery 1 and has a paid order.
+// scout2 is owed 20 on a 20 day old order in delivery 2 and 10 on an order
+// from an older client without a delivery.
+func insertTestOutstandingOrders(t *testing.T, ctx context.Context, store FrStore) {
+	t.Helper()
+	unpaid := makeTestOrder(testOrderId1, "scout1")
+	unpaid.AmountFromCashCollected = nil
+	mustNotFail(t, store.InsertMulchOrder(ctx, unpaid))
+	mustNotFail(t, store.InsertMulchOrder(ctx, makeTestOrder(testOrderId2, "scout1")))
+
+	partlyPaid := makeTestOrder(testOrderId3, "scout2")
+	cash, deliveryId := "4", 2
+	partlyPaid.AmountFromCashCollected = &cash
+	partlyPaid.DeliveryId = &deliveryId
+	partlyPaid.LastModifiedTime = time.Now().UTC().Add(-20 * 24 * time.Hour).Format(lastModifiedTimeFormat)
+	mustNotFail(t, store.InsertMulchOrder(ctx, partlyPaid))
+
+	olderClient := makeTestOrder("0b5d3f3e-6a4f-4b39-9d2a-1e1d6b8f0004", "scout2")
+	total := "10"
+	olderClient.AmountFromPurchases, olderClient.AmountFromCashCollected = nil, nil
+	olderClient.AmountTotalCollected, olderClient.DeliveryId = &total, nil
+	mustNotFail(t, store.InsertMulchOrder(ctx, olderClient))
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func TestGetOutstandingBalances(t *testing.T) {
+	store := newTestMemStore(t)
+	insertTestOutstandingOrders(t, context.Background(), store)
+
+	balances, err := GetOutstandingBalances(newTestCtx(t, "admin1", true), "")
+	mustNotFail(t, err)
+	if len(balances.Orders) != 3 || balances.Orders[0].OrderId != testOrderId3 || balances.Orders[0].AgingBucket != "15-30 days" ||
+		balances.Orders[0].AmountDue != "24.0000" || balances.Orders[0].AmountCollected != "4.0000" {
+		t.Errorf("orders: %+v", balances.Orders)
+	}
+	if balances.Totals.NumOrders != 3 || balances.Totals.AmountOwed != "54.0000" || len(balances.Totals.Aging) != 4 ||
+		balances.Totals.Aging[0].NumOrders != 2 || balances.Totals.Aging[0].AmountOwed != "34.0000" ||
+		balances.Totals.Aging[2].AmountOwed != "20.0000" || balances.Totals.Aging[3].AmountOwed != "0.0000" {
+		t.Errorf("totals: %+v", balances.Totals)
+	}
+
+	sellers := map[string]string{}
+	for _, row := range balances.Sellers {
+		sellers[*row.OwnerId] = row.AmountOwed
+	}
+	if len(sellers) != 2 || sellers["scout1"] != "24.0000" || sellers["scout2"] != "30.0000" {
+		t.Errorf("sellers: %v", sellers)
+	}
+	// The orders without a delivery come first
+	deliveries := balances.Deliveries
+	if len(deliveries) != 3 || deliveries[0].DeliveryId != nil || deliveries[0].AmountOwed != "10.0000" ||
+		*deliveries[1].DeliveryId != 1 || deliveries[1].AmountOwed != "24.0000" || *deliveries[2].DeliveryId != 2 {
+		t.Errorf("deliveries: %+v", deliveries)
+	}
+
+	scoutCtx := newTestCtx(t, "scout1", false)
+	balances, err = GetOutstandingBalances(scoutCtx, "scout1")
+	mustNotFail(t, err)
+	if len(balances.Orders) != 1 || balances.Totals.AmountOwed != "24.0000" {
+		t.Errorf("scout1's balances: %+v", balances)
+	}
+	var forbiddenErr *ForbiddenError
+	if _, err := GetOutstandingBalances(scoutCtx, ""); !errors.As(err, &forbiddenErr) {
+		t.Errorf("a scout reading everyone's balances returned: %v not a ForbiddenError", err)
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func TestRecordOrderPayment(t *testing.T) {
+	store := newTestMemStore(t)
+	ctx := context.Background()
+	ownerCtx := newTestCtx(t, "scout1", false)
+	insertTestOutstandingOrders(t, ctx, store)
+	getOrder := func() MulchOrderType {
+		order, err := store.GetMulchOrder(ctx, GetMulchOrderParams{OrderId: testOrderId1, GqlFields: allMulchOrderGqlFields})
+		mustNotFail(t, err)
+		return order
+	}
+
+	owed, err := RecordOrderPayment(ownerCtx, testOrderId1, OrderPaymentParams{AmountFromCash: "4"})
+	mustNotFail(t, err)
+	if order := getOrder(); owed != "20.0000" || *order.AmountFromCashCollected != "4.0000" || !*order.WillCollectMoneyLater {
+		t.Errorf("owed: %s after paying cash: %+v", owed, order)
+	}
+
+	overpayment := &OrderCheckType{CheckNumber: "201", Amount: "30"}
+	if _, err := RecordOrderPayment(ownerCtx, testOrderId1, OrderPaymentParams{Check: overpayment}); !slices.Equal(
+		getValidationFields(err), []string{"amountFromCash"}) {
+		t.Errorf("paying more than is owed returned: %v", err)
+	}
+	if _, err := RecordOrderPayment(ownerCtx, testOrderId1, OrderPaymentParams{}); !slices.Equal(
+		getValidationFields(err), []string{"amountFromCash"}) {
+		t.Errorf("paying nothing returned: %v", err)
+	}
+	var forbiddenErr *ForbiddenError
+	if _, err := RecordOrderPayment(newTestCtx(t, "scout2", false), testOrderId1, OrderPaymentParams{AmountFromCash: "1"}); !errors.As(err, &forbiddenErr) {
+		t.Errorf("another scout paying returned: %v not a ForbiddenError", err)
+	}
+
+	owed, err = RecordOrderPayment(ownerCtx, testOrderId1, OrderPaymentParams{Check: &OrderCheckType{CheckNumber: "201", Amount: "20"}})
+	mustNotFail(t, err)
+	order := getOrder()
+	if owed != "0.0000" || *order.AmountFromChecksCollected != "20.0000" || *order.CheckNumbers != "201" ||
+		len(order.Checks) != 1 || *order.WillCollectMoneyLater {
+		t.Errorf("owed: %s after paying by check: %+v", owed, order)
+	}
+	balances, err := GetOutstandingBalances(ownerCtx, "scout1")
+	mustNotFail(t, err)
+	if len(balances.Orders) != 0 {
+		t.Errorf("paid off order still owes: %+v", balances.Orders)
+	}
+	history, err := store.GetMulchOrderHistory(ctx, testOrderId1)
+	mustNotFail(t, err)
+	if len(history) != 2 || history[1].Operation != "recordOrderPayment" {
+		t.Errorf("history: %+v", history)
+	}
+}
@@ -0,0 +1,247 @@
+package frgql
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const mulchOrderCursorPrefix = "mulchorder:"
+
+// Gql field that has to be read to know an order's sort key
+var mulchOrderSortKeyGqlFields = map[string]string{
+	"orderId":              "orderId",
+	"ownerId":              "ownerId",
+	"lastModifiedTime":     "lastModifiedTime",
+	"customerName":         "customer",
+	"neighborhood":         "customer",
+	"deliveryId":           "deliveryId",
+	"amountTotalCollected": "amountTotalCollected",
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Where an order is in the sorted results: the value of the sort field (nil
+// when it isn't set) and the orderId that breaks ties.  Cursors are these
+// encoded so pages are found by value instead of by position and orders
+// added or removed between pages don't shift them.
+type MulchOrderSortKey struct {
+	SortBy       string  `json:"sortBy"`
+	IsDescending bool    `json:"isDescending"`
+	Value        *string `json:"value"`
+	OrderId      string  `json:"orderId"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Relay style paging arguments.  Cursors are opaque to clients
+type PageArgs struct {
+	First  *int
+	Last   *int
+	After  string
+	Before string
+}
+
+// //////////////////////////////////////////////////////////////////////////
+type PageInfoType struct {
+	HasNextPage     bool
+	HasPreviousPage bool
+	StartCursor     *string
+	EndCursor       *string
+}
+
+// //////////////////////////////////////////////////////////////////////////
+type MulchOrderEdgeType struct {
+	Cursor string
+	Node   MulchOrderType
+}
+
+// //////////////////////////////////////////////////////////////////////////
+type MulchOrdersConnectionType struct {
+	Edges      []MulchOrderEdgeType
+	PageInfo   PageInfoType
+	TotalCount int
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func getMulchOrderSortKey(order MulchOrderType, sortBy string, isDescending bool) MulchOrderSortKey {
+	key := MulchOrderSortKey{SortBy: sortBy, IsDescending: isDescending, OrderId: order.OrderId}
+	switch sortBy {
+	case "ownerId":
+		key.Value = &order.OwnerId
+	case "lastModifiedTime":
+		key.Value = &order.LastModifiedTime
+	case "customerName":
+		key.Value = &order.Customer.Name
+	case "neighborhood":
+		key.Value = &order.Customer.Neighborhood
+	case "deliveryId":
+		if order.DeliveryId != nil {
+			deliveryId := strconv.Itoa(*order.DeliveryId)
+			key.Value = &deliveryId
+		}
+	case "amountTotalCollected":
+		key.Value = order.AmountTotalCollected
+	}
+	return key
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func encodeMulchOrderCursor(key MulchOrderSortKey) string {
+	keyJson, _ := json.Marshal(key)
+	return base64.StdEncoding.EncodeToString(append([]byte(mulchOrderCursorPrefix), keyJson...))
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// The cursor has to be for the same sort as params
+func decodeMulchOrderCursor(cursor string, params GetMulchOrdersParams) (*MulchOrderSortKey, error) {
+	decoded, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(decoded), mulchOrderCursorPrefix) {
+		return nil, &ValidationError{Message: fmt.Sprintf("invalid cursor: %s", cursor)}
+	}
+	key := MulchOrderSortKey{}
+	err = json.Unmarshal([]byte(strings.TrimPrefix(string(decoded), mulchOrderCursorPrefix)), &key)
+	if err != nil || len(key.OrderId) == 0 {
+		return nil, &ValidationError{Message: fmt.Sprintf("invalid cursor: %s", cursor)}
+	}
+	if key.SortBy != params.SortBy || key.IsDescending != params.IsSortDescending {
+		return nil, &ValidationError{Message: fmt.Sprintf("cursor: %s is for a different sortBy/sortDirection", cursor)}
+	}
+	return &key, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns a page of the orders that match params.  Cursors are the sort key
+// of an order so the same sort needs to be given when paging.  The counts
+// and the page are read in one transaction so they agree with each other.
+func GetMulchOrdersConnection(ctx context.Context, params GetMulchOrdersParams, page PageArgs) (MulchOrdersConnectionType, error) {
+	conn := MulchOrdersConnectionType{Edges: []MulchOrderEdgeType{}}
+
+	if page.First != nil && *page.First < 0 {
+		return conn, newValidationError("first", "first can not be negative")
+	}
+	if page.Last != nil && *page.Last < 0 {
+		return conn, newValidationError("last", "last can not be negative")
+	}
+
+	// Paging needs a stable order
+	if len(params.SortBy) == 0 {
+		params.SortBy = "orderId"
+	}
+	sortKeyGqlField, ok := mulchOrderSortKeyGqlFields[params.SortBy]
+	if !ok {
+		return conn, newValidationError("sortBy", "unknown mulch order sort field: %s", params.SortBy)
+	}
+	for _, gqlField := range []string{"orderId", sortKeyGqlField} {
+		if !slices.Contains(params.GqlFields, gqlField) {
+			params.GqlFields = append(params.GqlFields, gqlField)
+		}
+	}
+
+	var afterKey, beforeKey *MulchOrderSortKey
+	var err error
+	if len(page.After) != 0 {
+		if afterKey, err = decodeMulchOrderCursor(page.After, params); err != nil {
+			return conn, err
+		}
+	}
+	if len(page.Before) != 0 {
+		if beforeKey, err = decodeMulchOrderCursor(page.Before, params); err != nil {
+			return conn, err
+		}
+	}
+
+	var orders []MulchOrderType
+	var scanErr error
+	err = frStore.InTx(ctx, func(tx FrStore) error {
+		totalCount, err := tx.CountMulchOrders(ctx, params)
+		if err != nil {
+			log.Println("Failed counting mulch orders: ", err)
+			return err
+		}
+		conn.TotalCount = totalCount
+
+		// Whether there are orders outside of the cursors
+		if afterKey != nil {
+			afterParams := params
+			afterParams.AfterKey = afterKey
+			numAfter, err := tx.CountMulchOrders(ctx, afterParams)
+			if err != nil {
+				return err
+			}
+			conn.PageInfo.HasPreviousPage = numAfter < totalCount
+		}
+		if beforeKey != nil {
+			beforeParams := params
+			beforeParams.BeforeKey = beforeKey
+			numBefore, err := tx.CountMulchOrders(ctx, beforeParams)
+			if err != nil {
+				return err
+			}
+			conn.PageInfo.HasNextPage = numBefore < totalCount
+		}
+
+		// One more than asked for is read to know if there are more
+		pageParams := params
+		pageParams.AfterKey, pageParams.BeforeKey = afterKey, beforeKey
+		isFromEnd := page.Last != nil && page.First == nil
+		if isFromEnd {
+			// Read backwards from the before cursor (or the end)
+			pageParams.IsSortDescending = !params.IsSortDescending
+			pageParams.AfterKey, pageParams.BeforeKey = beforeKey, afterKey
+			pageParams.Limit = *page.Last + 1
+		} else if page.First != nil {
+			pageParams.Limit = *page.First + 1
+		}
+
+		// The rows that could be read are still returned with the RowScanError
+		var rowScanErr *RowScanError
+		orders, err = tx.GetMulchOrders(ctx, pageParams)
+		if errors.As(err, &rowScanErr) {
+			log.Println("Some mulch orders of the page could not be read: ", err)
+			scanErr = err
+		} else if err != nil {
+			log.Println("Failed retrieving mulch orders page: ", err)
+			return err
+		}
+		if isFromEnd {
+			if len(orders) > *page.Last {
+				orders = orders[:*page.Last]
+				conn.PageInfo.HasPreviousPage = true
+			}
+			slices.Reverse(orders)
+		} else {
+			if page.First != nil && len(orders) > *page.First {
+				orders = orders[:*page.First]
+				conn.PageInfo.HasNextPage = true
+			}
+			if page.Last != nil && len(orders) > *page.Last {
+				orders = orders[len(orders)-*page.Last:]
+				conn.PageInfo.HasPreviousPage = true
+			}
+		}
+		if slices.Contains(params.GqlFields, "checks") {
+			return fillCheckDepositStatus(ctx, tx, orders)
+		}
+		return nil
+	})
+	if err != nil {
+		return conn, err
+	}
+
+	for _, order := range orders {
+		conn.Edges = append(conn.Edges, MulchOrderEdgeType{
+			Cursor: encodeMulchOrderCursor(getMulchOrderSortKey(order, params.SortBy, params.IsSortDescending)),
+			Node:   order,
+		})
+	}
+	if len(conn.Edges) != 0 {
+		conn.PageInfo.StartCursor = &conn.Edges[0].Cursor
+		conn.PageInfo.EndCursor = &conn.Edges[len(conn.Edges)-1].Cursor
+	}
+	return conn, scanErr
+}
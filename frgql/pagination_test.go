@@ -0,0 +1,49 @@
+package frgql
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// //////////////////////////////////////////////////////////////////////////
+func TestMulchOrderCursor(t *testing.T) {
+	order := makeTestOrder(testOrderId1, "scout1")
+	params := GetMulchOrdersParams{SortBy: "customerName", IsSortDescending: true}
+	key := getMulchOrderSortKey(order, params.SortBy, params.IsSortDescending)
+
+	decoded, err := decodeMulchOrderCursor(encodeMulchOrderCursor(key), params)
+	mustNotFail(t, err)
+	if !reflect.DeepEqual(*decoded, key) {
+		t.Errorf("decoded: %+v not: %+v", *decoded, key)
+	}
+
+	// Orders without a delivery sort as nil
+	order.DeliveryId = nil
+	nilKey := getMulchOrderSortKey(order, "deliveryId", false)
+	decoded, err = decodeMulchOrderCursor(encodeMulchOrderCursor(nilKey), GetMulchOrdersParams{SortBy: "deliveryId"})
+	mustNotFail(t, err)
+	if decoded.Value != nil || decoded.OrderId != testOrderId1 {
+		t.Errorf("decoded: %+v", *decoded)
+	}
+
+	badCursors := []struct {
+		cursor string
+		params GetMulchOrdersParams
+	}{
+		{"not base64", params},
+		{base64.StdEncoding.EncodeToString([]byte(`{"orderId":"1"}`)), params},
+		{base64.StdEncoding.EncodeToString([]byte(mulchOrderCursorPrefix + "{")), params},
+		{base64.StdEncoding.EncodeToString([]byte(mulchOrderCursorPrefix + `{"sortBy":"customerName"}`)), params},
+		// Cursors are only good for the sort they came from
+		{encodeMulchOrderCursor(key), GetMulchOrdersParams{SortBy: "customerName"}},
+		{encodeMulchOrderCursor(key), GetMulchOrdersParams{SortBy: "ownerId", IsSortDescending: true}},
+	}
+	for _, bad := range badCursors {
+		var validationErr *ValidationError
+		if _, err := decodeMulchOrderCursor(bad.cursor, bad.params); !errors.As(err, &validationErr) {
+			t.Errorf("cursor: %s for: %+v returned: %v not a ValidationError", bad.cursor, bad.params, err)
+		}
+	}
+}
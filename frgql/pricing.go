@@ -0,0 +1,224 @@
+package frgql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// //////////////////////////////////////////////////////////////////////////
+type OrderQuoteItemType struct {
+	ProductId     string `json:"productId"`
+	NumSold       int    `json:"numSold"`
+	UnitPrice     string `json:"unitPrice"`
+	AmountCharged string `json:"amountCharged"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Server computed price breakdown of an order
+type OrderQuoteType struct {
+	Purchases           []OrderQuoteItemType `json:"purchases"`
+	AmountFromPurchases string               `json:"amountFromPurchases"`
+	AmountFromDonations string               `json:"amountFromDonations"`
+	AmountTotal         string               `json:"amountTotal"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Parses an amount as sent by clients.  Empty/missing amounts are 0 and
+// commas are ignored (ISSUE #108)
+func parseAmount(amount *string) (decimal.Decimal, error) {
+	if amount == nil || len(*amount) == 0 {
+		return decimal.Zero, nil
+	}
+	return decimal.NewFromString(strings.ReplaceAll(*amount, ",", ""))
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// The unit price for a product is the price of the largest price break that
+// numSold is greater than or the base unit price if there isn't one.
+func calcProductUnitPrice(product ProductType, numSold int) (decimal.Decimal, error) {
+	unitPrice := product.UnitPrice
+	breakGt := -1
+	for _, priceBreak := range product.PriceBreaks {
+		if numSold > priceBreak.Gt && priceBreak.Gt > breakGt {
+			breakGt = priceBreak.Gt
+			unitPrice = priceBreak.UnitPrice
+		}
+	}
+	price, err := parseAmount(&unitPrice)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("product %s has an invalid unit price: %s", product.Id, unitPrice)
+	}
+	return price, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func quoteOrder(products []ProductType, purchases []ProductsType, donations *string) (OrderQuoteType, error) {
+	quote := OrderQuoteType{Purchases: []OrderQuoteItemType{}}
+
+	purchasesTotal := decimal.Zero
+	for _, purchase := range purchases {
+		if purchase.NumSold < 0 {
+			return quote, newValidationError("purchases."+purchase.ProductId+".numSold",
+				"purchases.%s.numSold can not be negative", purchase.ProductId)
+		}
+		if purchase.NumSold == 0 {
+			continue
+		}
+
+		var product *ProductType
+		for idx := range products {
+			if products[idx].Id == purchase.ProductId {
+				product = &products[idx]
+				break
+			}
+		}
+		if product == nil {
+			return quote, newValidationError("purchases."+purchase.ProductId,
+				"purchases.%s is not a known product", purchase.ProductId)
+		}
+		if purchase.NumSold < product.MinUnits {
+			return quote, newValidationError("purchases."+purchase.ProductId+".numSold",
+				"purchases.%s.numSold must be at least %d", purchase.ProductId, product.MinUnits)
+		}
+
+		unitPrice, err := calcProductUnitPrice(*product, purchase.NumSold)
+		if err != nil {
+			return quote, err
+		}
+		amountCharged := unitPrice.Mul(decimal.NewFromInt(int64(purchase.NumSold)))
+		purchasesTotal = purchasesTotal.Add(amountCharged)
+
+		quote.Purchases = append(quote.Purchases, OrderQuoteItemType{
+			ProductId:     purchase.ProductId,
+			NumSold:       purchase.NumSold,
+			UnitPrice:     unitPrice.StringFixedBank(4),
+			AmountCharged: amountCharged.StringFixedBank(4),
+		})
+	}
+
+	donationsTotal, err := parseAmount(donations)
+	if err != nil {
+		return quote, newValidationError("amountFromDonations", "amountFromDonations is not a valid amount")
+	}
+	if donationsTotal.IsNegative() {
+		return quote, newValidationError("amountFromDonations", "amountFromDonations can not be negative")
+	}
+
+	quote.AmountFromPurchases = purchasesTotal.StringFixedBank(4)
+	quote.AmountFromDonations = donationsTotal.StringFixedBank(4)
+	quote.AmountTotal = purchasesTotal.Add(donationsTotal).StringFixedBank(4)
+	return quote, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func getConfiguredProducts(ctx context.Context) ([]ProductType, error) {
+	frConfig, err := frStore.GetFundraiserConfig(ctx, []string{"products"})
+	if err != nil {
+		return nil, err
+	}
+	return frConfig.Products, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Prices an order using the products in the fundraiser config
+func QuoteMulchOrder(ctx context.Context, purchases []ProductsType, donations *string) (OrderQuoteType, error) {
+	log.Println("Quoting order purchases: ", purchases)
+
+	products, err := getConfiguredProducts(ctx)
+	if err != nil {
+		log.Println("Failed to get products for quote: ", err)
+		return OrderQuoteType{}, err
+	}
+	return quoteOrder(products, purchases, donations)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Recomputes the order pricing and verifies that what the client sent matches
+//   - each purchase amountCharged matches the product pricing
+//   - amountFromPurchases is the sum of the purchases
+//   - purchases + donations = amountTotalCollected
+//   - cash + checks = amountTotalCollected unless willCollectMoneyLater
+func validateOrderPricing(ctx context.Context, order MulchOrderType) error {
+	products, err := getConfiguredProducts(ctx)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			log.Println("No fundraiser config so order pricing can not be validated")
+			return nil
+		}
+		return err
+	}
+
+	quote, err := quoteOrder(products, order.Purchases, order.AmountFromDonations)
+	if err != nil {
+		return err
+	}
+
+	mismatch := func(field string, given decimal.Decimal, expected string) error {
+		return newValidationError(field, "%s is %s but should be %s", field, given.StringFixedBank(4), expected)
+	}
+
+	for _, item := range quote.Purchases {
+		for _, purchase := range order.Purchases {
+			if purchase.ProductId != item.ProductId {
+				continue
+			}
+			given, err := parseAmount(&purchase.AmountCharged)
+			if err != nil {
+				return newValidationError("purchases."+item.ProductId+".amountCharged",
+					"purchases.%s.amountCharged is not a valid amount", item.ProductId)
+			}
+			if expected, _ := decimal.NewFromString(item.AmountCharged); !given.Equal(expected) {
+				return mismatch(fmt.Sprintf("purchases.%s.amountCharged", item.ProductId), given, item.AmountCharged)
+			}
+		}
+	}
+
+	amounts := []struct {
+		field  string
+		amount *string
+	}{
+		{"amountFromPurchases", order.AmountFromPurchases},
+		{"amountTotalCollected", order.AmountTotalCollected},
+		{"amountFromCashCollected", order.AmountFromCashCollected},
+		{"amountFromChecksCollected", order.AmountFromChecksCollected},
+	}
+	parsed := make(map[string]decimal.Decimal)
+	for _, item := range amounts {
+		v, err := parseAmount(item.amount)
+		if err != nil {
+			return newValidationError(item.field, "%s is not a valid amount", item.field)
+		}
+		if v.IsNegative() {
+			return newValidationError(item.field, "%s can not be negative", item.field)
+		}
+		parsed[item.field] = v
+	}
+
+	// Older clients don't always send amountFromPurchases
+	if order.AmountFromPurchases != nil {
+		if expected, _ := decimal.NewFromString(quote.AmountFromPurchases); !parsed["amountFromPurchases"].Equal(expected) {
+			return mismatch("amountFromPurchases", parsed["amountFromPurchases"], quote.AmountFromPurchases)
+		}
+	}
+
+	if expected, _ := decimal.NewFromString(quote.AmountTotal); !parsed["amountTotalCollected"].Equal(expected) {
+		return mismatch("amountTotalCollected", parsed["amountTotalCollected"], quote.AmountTotal)
+	}
+
+	if order.WillCollectMoneyLater == nil || !*order.WillCollectMoneyLater {
+		collected := parsed["amountFromCashCollected"].Add(parsed["amountFromChecksCollected"])
+		if !collected.Equal(parsed["amountTotalCollected"]) {
+			return &ValidationError{
+				Message: fmt.Sprintf("amountFromCashCollected + amountFromChecksCollected is %s but should be %s",
+					collected.StringFixedBank(4), parsed["amountTotalCollected"].StringFixedBank(4)),
+				Fields: []string{"amountFromCashCollected", "amountFromChecksCollected"},
+			}
+		}
+	}
+	return nil
+}
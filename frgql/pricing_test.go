@@ -0,0 +1,211 @@
+package frgql
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+var testProducts = []ProductType{
+	{Id: "bags", MinUnits: 5, UnitPrice: "4.50", PriceBreaks: []ProductPriceBreaks{
+		{Gt: 14, UnitPrice: "4.25"},
+		{Gt: 29, UnitPrice: "4.00"},
+	}},
+	{Id: "spreading", UnitPrice: "2.00"},
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the fields of a ValidationError (nil if err isn't one)
+func getValidationFields(err error) []string {
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		return nil
+	}
+	return validationErr.Fields
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func TestQuoteOrder(t *testing.T) {
+	donations := "1,000.50"
+	badDonations := "lots"
+	negativeDonations := "-1"
+
+	tests := []struct {
+		name        string
+		purchases   []ProductsType
+		donations   *string
+		total       string
+		unitPrices  []string
+		errorFields []string
+	}{
+		{
+			name:       "base price",
+			purchases:  []ProductsType{{ProductId: "bags", NumSold: 10}},
+			total:      "45.0000",
+			unitPrices: []string{"4.5000"},
+		},
+		{
+			name:       "price break is over gt",
+			purchases:  []ProductsType{{ProductId: "bags", NumSold: 15}, {ProductId: "spreading", NumSold: 15}},
+			total:      "93.7500",
+			unitPrices: []string{"4.2500", "2.0000"},
+		},
+		{
+			name:       "largest price break",
+			purchases:  []ProductsType{{ProductId: "bags", NumSold: 30}},
+			total:      "120.0000",
+			unitPrices: []string{"4.0000"},
+		},
+		{
+			name:       "at a price break's gt",
+			purchases:  []ProductsType{{ProductId: "bags", NumSold: 14}},
+			total:      "63.0000",
+			unitPrices: []string{"4.5000"},
+		},
+		{
+			name:       "donations only with commas",
+			purchases:  []ProductsType{{ProductId: "bags", NumSold: 0}},
+			donations:  &donations,
+			total:      "1000.5000",
+			unitPrices: []string{},
+		},
+		{
+			name:        "under min units",
+			purchases:   []ProductsType{{ProductId: "bags", NumSold: 4}},
+			errorFields: []string{"purchases.bags.numSold"},
+		},
+		{
+			name:        "negative",
+			purchases:   []ProductsType{{ProductId: "spreading", NumSold: -1}},
+			errorFields: []string{"purchases.spreading.numSold"},
+		},
+		{
+			name:        "unknown product",
+			purchases:   []ProductsType{{ProductId: "mulch", NumSold: 1}},
+			errorFields: []string{"purchases.mulch"},
+		},
+		{
+			name:        "bad donations",
+			donations:   &badDonations,
+			errorFields: []string{"amountFromDonations"},
+		},
+		{
+			name:        "negative donations",
+			donations:   &negativeDonations,
+			errorFields: []string{"amountFromDonations"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			quote, err := quoteOrder(testProducts, test.purchases, test.donations)
+			if test.errorFields != nil {
+				if fields := getValidationFields(err); !slices.Equal(fields, test.errorFields) {
+					t.Errorf("got error: %v with fields: %v not: %v", err, fields, test.errorFields)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if quote.AmountTotal != test.total {
+				t.Errorf("amountTotal: %s not: %s", quote.AmountTotal, test.total)
+			}
+			unitPrices := []string{}
+			for _, item := range quote.Purchases {
+				unitPrices = append(unitPrices, item.UnitPrice)
+			}
+			if !slices.Equal(unitPrices, test.unitPrices) {
+				t.Errorf("unit prices: %v not: %v", unitPrices, test.unitPrices)
+			}
+		})
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func TestValidateOrderPricing(t *testing.T) {
+	store := newTestMemStore(t)
+	ctx := context.Background()
+
+	makeOrder := func(modify func(order *MulchOrderType)) MulchOrderType {
+		order := makeTestOrder(testOrderId1, "scout1")
+		amountCharged, total := "22.50", "32.50"
+		donations, cash, checks := "10", "12.50", "20"
+		order.Purchases = []ProductsType{{ProductId: "bags", NumSold: 5, AmountCharged: amountCharged}}
+		order.AmountFromPurchases = &amountCharged
+		order.AmountFromDonations = &donations
+		order.AmountTotalCollected = &total
+		order.AmountFromCashCollected = &cash
+		order.AmountFromChecksCollected = &checks
+		if modify != nil {
+			modify(&order)
+		}
+		return order
+	}
+	str := func(val string) *string { return &val }
+
+	// Orders can't be checked before there are products
+	if err := validateOrderPricing(ctx, makeOrder(func(order *MulchOrderType) { order.AmountTotalCollected = str("1") })); err != nil {
+		t.Errorf("order without a config: %v", err)
+	}
+	mustNotFail(t, store.SetFundraiserConfig(ctx, FrConfigType{Products: testProducts, LastModifiedTime: makeLastModifiedTime()}))
+
+	tests := []struct {
+		name        string
+		modify      func(order *MulchOrderType)
+		errorFields []string
+	}{
+		{name: "matches the quote"},
+		{
+			name:   "older client without amountFromPurchases",
+			modify: func(order *MulchOrderType) { order.AmountFromPurchases = nil },
+		},
+		{
+			name: "collecting later",
+			modify: func(order *MulchOrderType) {
+				order.AmountFromCashCollected, order.AmountFromChecksCollected = nil, nil
+				willCollectMoneyLater := true
+				order.WillCollectMoneyLater = &willCollectMoneyLater
+			},
+		},
+		{
+			name:        "amount charged isn't the price",
+			modify:      func(order *MulchOrderType) { order.Purchases[0].AmountCharged = "20.00" },
+			errorFields: []string{"purchases.bags.amountCharged"},
+		},
+		{
+			name:        "purchases don't add up",
+			modify:      func(order *MulchOrderType) { order.AmountFromPurchases = str("25") },
+			errorFields: []string{"amountFromPurchases"},
+		},
+		{
+			name:        "total isn't purchases and donations",
+			modify:      func(order *MulchOrderType) { order.AmountFromDonations = str("5") },
+			errorFields: []string{"amountTotalCollected"},
+		},
+		{
+			name:        "cash and checks aren't the total",
+			modify:      func(order *MulchOrderType) { order.AmountFromCashCollected = str("10") },
+			errorFields: []string{"amountFromCashCollected", "amountFromChecksCollected"},
+		},
+		{
+			name:        "negative cash",
+			modify:      func(order *MulchOrderType) { order.AmountFromCashCollected = str("-12.50") },
+			errorFields: []string{"amountFromCashCollected"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateOrderPricing(ctx, makeOrder(test.modify))
+			if test.errorFields == nil {
+				if err != nil {
+					t.Errorf("valid order: %v", err)
+				}
+				return
+			}
+			if fields := getValidationFields(err); !slices.Equal(fields, test.errorFields) {
+				t.Errorf("got error: %v with fields: %v not: %v", err, fields, test.errorFields)
+			}
+		})
+	}
+}
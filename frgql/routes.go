@@ -0,0 +1,406 @@
+package frgql
+
+import (
+	"context"
+	"log"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// Mean radius of the earth for haversine distances
+const earthRadiusMiles = 3958.8
+
+// Street suffixes shortened so "123 Oak Street" and "123 oak st." are the
+// same address
+var addrAbbreviations = map[string]string{
+	"street": "st", "avenue": "ave", "road": "rd", "drive": "dr", "lane": "ln", "court": "ct",
+	"circle": "cir", "boulevard": "blvd", "place": "pl", "parkway": "pkwy", "terrace": "ter",
+	"trail": "trl", "highway": "hwy", "north": "n", "south": "s", "east": "e", "west": "w",
+}
+
+var addrSeparatorsRegex = regexp.MustCompile(`[^a-z0-9]+`)
+
+// //////////////////////////////////////////////////////////////////////////
+// Address with its coordinates.  lat/lng are kept as strings in the db.
+type KnownAddrType struct {
+	Id               string  `json:"id"`
+	Addr             string  `json:"addr"`
+	Zipcode          *int    `json:"zipcode"`
+	City             *string `json:"city"`
+	Lat              string  `json:"lat"`
+	Lng              string  `json:"lng"`
+	LastModifiedTime string  `json:"lastModifiedTime"`
+	CreatedTime      string  `json:"createdTime"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+type RoutePointType struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+	// True when the distribution point has no known address and the middle of
+	// the stops is used instead
+	IsEstimated bool `json:"isEstimated"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+type DeliveryStopType struct {
+	Sequence            int      `json:"sequence"`
+	OrderId             string   `json:"orderId"`
+	CustomerName        string   `json:"customerName"`
+	Addr1               string   `json:"addr1"`
+	Addr2               *string  `json:"addr2"`
+	City                *string  `json:"city"`
+	Zipcode             *int     `json:"zipcode"`
+	Phone               string   `json:"phone"`
+	Neighborhood        string   `json:"neighborhood"`
+	SpecialInstructions *string  `json:"specialInstructions"`
+	NumBags             int      `json:"numBags"`
+	Lat                 *float64 `json:"lat"`
+	Lng                 *float64 `json:"lng"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Stops of one vehicle in the order they should be delivered.  The distance
+// is from the distribution point to the last stop.
+type DeliveryRouteType struct {
+	Vehicle       int                `json:"vehicle"`
+	NumStops      int                `json:"numStops"`
+	NumBags       int                `json:"numBags"`
+	DistanceMiles string             `json:"distanceMiles"`
+	Stops         []DeliveryStopType `json:"stops"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// UnroutedStops are the orders whose address has no known coordinates
+type DeliveryRoutesType struct {
+	DeliveryId        int                 `json:"deliveryId"`
+	DistributionPoint string              `json:"distributionPoint"`
+	Start             RoutePointType      `json:"start"`
+	NumBags           int                 `json:"numBags"`
+	Routes            []DeliveryRouteType `json:"routes"`
+	UnroutedStops     []DeliveryStopType  `json:"unroutedStops"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Lower cases the address and shortens the street suffixes so the same
+// address written differently matches
+func normalizeAddr(addr string) string {
+	words := strings.Fields(addrSeparatorsRegex.ReplaceAllString(strings.ToLower(addr), " "))
+	for idx, word := range words {
+		if abbreviation, ok := addrAbbreviations[word]; ok {
+			words[idx] = abbreviation
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Picks the known address in the same place from candidates with the same
+// street address.  One whose zipcode (or city when there isn't one) differs
+// is somewhere else.  When there is more than one candidate the zipcode or
+// city has to match.
+func matchKnownAddr(candidates []KnownAddrType, city *string, zipcode *int) *KnownAddrType {
+	for idx, known := range candidates {
+		isMatch := len(candidates) == 1
+		if zipcode != nil && known.Zipcode != nil {
+			isMatch = *zipcode == *known.Zipcode
+		} else if city != nil && known.City != nil {
+			isMatch = strings.EqualFold(strings.TrimSpace(*city), strings.TrimSpace(*known.City))
+		}
+		if isMatch {
+			return &candidates[idx]
+		}
+	}
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the coordinates of a known address.  Not ok when the geocoder
+// couldn't find it.
+func parseKnownAddrLocation(known KnownAddrType) (float64, float64, bool) {
+	if len(strings.TrimSpace(known.Lat)) == 0 || len(strings.TrimSpace(known.Lng)) == 0 {
+		return 0, 0, false
+	}
+	lat, latErr := strconv.ParseFloat(strings.TrimSpace(known.Lat), 64)
+	lng, lngErr := strconv.ParseFloat(strings.TrimSpace(known.Lng), 64)
+	if latErr != nil || lngErr != nil {
+		log.Printf("Known address: %s has bad coordinates: (%s, %s)", known.Addr, known.Lat, known.Lng)
+		return 0, 0, false
+	}
+	return lat, lng, true
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Finds the coordinates of an address.  When the same street address is
+// known in more than one place the zipcode and then the city have to match.
+func findKnownAddr(knownAddrs map[string][]KnownAddrType, addr string, city *string, zipcode *int) (float64, float64, bool) {
+	known := matchKnownAddr(knownAddrs[normalizeAddr(addr)], city, zipcode)
+	if known == nil {
+		return 0, 0, false
+	}
+	return parseKnownAddrLocation(*known)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func haversineMiles(lat1, lng1, lat2, lng2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLng := toRad(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusMiles * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Splits the stops into numVehicles groups by sweeping around the start and
+// cutting where each group has its share of the bags.  The sweep starts at
+// the widest gap between stops so a group doesn't straddle it.
+func clusterDeliveryStops(start RoutePointType, stops []DeliveryStopType, numVehicles int) [][]DeliveryStopType {
+	if len(stops) == 0 {
+		return nil
+	}
+	numVehicles = max(1, min(numVehicles, len(stops)))
+
+	cosLat := math.Cos(start.Lat * math.Pi / 180)
+	angle := func(stop DeliveryStopType) float64 {
+		return math.Atan2(*stop.Lat-start.Lat, (*stop.Lng-start.Lng)*cosLat)
+	}
+	sorted := slices.Clone(stops)
+	slices.SortStableFunc(sorted, func(a, b DeliveryStopType) int {
+		aAngle, bAngle := angle(a), angle(b)
+		if aAngle != bAngle {
+			if aAngle < bAngle {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.OrderId, b.OrderId)
+	})
+
+	widestGapIdx, widestGap := 0, -1.0
+	for idx := range sorted {
+		prevAngle := angle(sorted[(idx+len(sorted)-1)%len(sorted)])
+		gap := angle(sorted[idx]) - prevAngle
+		if gap <= 0 {
+			gap += 2 * math.Pi
+		}
+		if gap > widestGap {
+			widestGapIdx, widestGap = idx, gap
+		}
+	}
+	sorted = slices.Concat(sorted[widestGapIdx:], sorted[:widestGapIdx])
+
+	totalBags := 0
+	for _, stop := range sorted {
+		totalBags += stop.NumBags
+	}
+
+	groups := make([][]DeliveryStopType, numVehicles)
+	bagsBefore := 0
+	for idx, stop := range sorted {
+		// A stop goes to the vehicle whose share of the bags its middle falls in
+		vehicle := 0
+		if totalBags > 0 {
+			vehicle = int((float64(bagsBefore) + float64(stop.NumBags)/2) * float64(numVehicles) / float64(totalBags))
+		} else {
+			vehicle = idx * numVehicles / len(sorted)
+		}
+		vehicle = min(vehicle, numVehicles-1)
+		groups[vehicle] = append(groups[vehicle], stop)
+		bagsBefore += stop.NumBags
+	}
+	return slices.DeleteFunc(groups, func(group []DeliveryStopType) bool { return len(group) == 0 })
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Orders the stops by always going to the nearest one next and then
+// improves that with 2-opt.  Returns the stops and the distance driven from
+// the start to the last stop.
+func planDeliveryRoute(start RoutePointType, stops []DeliveryStopType) ([]DeliveryStopType, float64) {
+	dist := func(a, b DeliveryStopType) float64 {
+		return haversineMiles(*a.Lat, *a.Lng, *b.Lat, *b.Lng)
+	}
+	startStop := DeliveryStopType{Lat: &start.Lat, Lng: &start.Lng}
+
+	// Index 0 is the start and stays there
+	path := []DeliveryStopType{startStop}
+	remaining := slices.Clone(stops)
+	for len(remaining) != 0 {
+		last := path[len(path)-1]
+		nearestIdx := 0
+		for idx := range remaining {
+			if dist(last, remaining[idx]) < dist(last, remaining[nearestIdx]) {
+				nearestIdx = idx
+			}
+		}
+		path = append(path, remaining[nearestIdx])
+		remaining = slices.Delete(remaining, nearestIdx, nearestIdx+1)
+	}
+
+	// The route doesn't come back to the start so the last stop has nothing
+	// after it
+	const minImprovement = 1e-9
+	for pass, isImproved := 0, true; isImproved && pass < 100; pass++ {
+		isImproved = false
+		for i := 1; i < len(path)-1; i++ {
+			for j := i + 1; j < len(path); j++ {
+				before := dist(path[i-1], path[i])
+				after := dist(path[i-1], path[j])
+				if j+1 < len(path) {
+					before += dist(path[j], path[j+1])
+					after += dist(path[i], path[j+1])
+				}
+				if after < before-minImprovement {
+					slices.Reverse(path[i : j+1])
+					isImproved = true
+				}
+			}
+		}
+	}
+
+	distance := 0.0
+	for idx := 1; idx < len(path); idx++ {
+		distance += dist(path[idx-1], path[idx])
+	}
+	return path[1:], distance
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Plans the routes for the orders of a delivery going to a distribution
+// point.  Only stored coordinates (known_addrs) are used, the ones the order
+// was geocoded to first.  Admin only
+func GetDeliveryRoutes(ctx context.Context, deliveryId int, distributionPoint string, numVehicles int) (DeliveryRoutesType, error) {
+	log.Printf("Planning delivery routes for delivery: %d from: %s with: %d vehicles", deliveryId, distributionPoint, numVehicles)
+
+	if err := VerifyAdminTokenFromCtx(ctx); err != nil {
+		return DeliveryRoutesType{}, err
+	}
+	if numVehicles < 1 {
+		return DeliveryRoutesType{}, newValidationError("numVehicles", "numVehicles must be at least 1")
+	}
+
+	hoods, err := frStore.GetNeighborhoods(ctx, []string{"name", "distributionPoint"})
+	if err != nil {
+		return DeliveryRoutesType{}, err
+	}
+	hoodNames := make(map[string]bool)
+	for _, hood := range hoods {
+		if hood.DistributionPoint != nil && strings.EqualFold(*hood.DistributionPoint, distributionPoint) {
+			hoodNames[hood.Name] = true
+		}
+	}
+	if len(hoodNames) == 0 {
+		return DeliveryRoutesType{}, newValidationError("distributionPoint",
+			"no neighborhoods use distribution point: %s", distributionPoint)
+	}
+
+	orders, err := frStore.GetMulchOrders(ctx, GetMulchOrdersParams{
+		DeliveryId: &deliveryId,
+		SortBy:     "orderId",
+		GqlFields:  []string{"orderId", "customer", "specialInstructions", "purchases", "knownAddrId"},
+	})
+	if err != nil {
+		log.Println("Delivery routes orders query failed: ", err)
+		return DeliveryRoutesType{}, err
+	}
+
+	addrs, err := frStore.GetKnownAddrs(ctx)
+	if err != nil {
+		return DeliveryRoutesType{}, err
+	}
+	knownAddrs := make(map[string][]KnownAddrType)
+	knownAddrsById := make(map[string]KnownAddrType)
+	for _, addr := range addrs {
+		key := normalizeAddr(addr.Addr)
+		knownAddrs[key] = append(knownAddrs[key], addr)
+		knownAddrsById[addr.Id] = addr
+	}
+
+	routes := DeliveryRoutesType{
+		DeliveryId:        deliveryId,
+		DistributionPoint: distributionPoint,
+		Routes:            []DeliveryRouteType{},
+		UnroutedStops:     []DeliveryStopType{},
+	}
+	stops := []DeliveryStopType{}
+	for _, order := range orders {
+		if !hoodNames[order.Customer.Neighborhood] {
+			continue
+		}
+		numBags := 0
+		for _, purchase := range order.Purchases {
+			if purchase.ProductId == "bags" {
+				numBags += purchase.NumSold
+			}
+		}
+		// Donation and spreading only orders have nothing to deliver
+		if numBags == 0 {
+			continue
+		}
+		routes.NumBags += numBags
+
+		stop := DeliveryStopType{
+			OrderId:             order.OrderId,
+			CustomerName:        order.Customer.Name,
+			Addr1:               order.Customer.Addr1,
+			Addr2:               order.Customer.Addr2,
+			City:                order.Customer.City,
+			Zipcode:             order.Customer.Zipcode,
+			Phone:               order.Customer.Phone,
+			Neighborhood:        order.Customer.Neighborhood,
+			SpecialInstructions: order.SpecialInstructions,
+			NumBags:             numBags,
+		}
+		// Where the order was geocoded to is used before matching its address
+		var lat, lng float64
+		ok := false
+		if order.KnownAddrId != nil {
+			if known, isLinked := knownAddrsById[*order.KnownAddrId]; isLinked {
+				lat, lng, ok = parseKnownAddrLocation(known)
+			}
+		}
+		if !ok {
+			lat, lng, ok = findKnownAddr(knownAddrs, order.Customer.Addr1, order.Customer.City, order.Customer.Zipcode)
+		}
+		if !ok {
+			log.Printf("Order: %s address: %s has no known coordinates", order.OrderId, order.Customer.Addr1)
+			routes.UnroutedStops = append(routes.UnroutedStops, stop)
+			continue
+		}
+		stop.Lat, stop.Lng = &lat, &lng
+		stops = append(stops, stop)
+	}
+
+	if lat, lng, ok := findKnownAddr(knownAddrs, distributionPoint, nil, nil); ok {
+		routes.Start = RoutePointType{Lat: lat, Lng: lng}
+	} else if len(stops) != 0 {
+		log.Printf("Distribution point: %s has no known address so the middle of the stops is used", distributionPoint)
+		routes.Start.IsEstimated = true
+		for _, stop := range stops {
+			routes.Start.Lat += *stop.Lat / float64(len(stops))
+			routes.Start.Lng += *stop.Lng / float64(len(stops))
+		}
+	}
+
+	for idx, group := range clusterDeliveryStops(routes.Start, stops, numVehicles) {
+		routeStops, distance := planDeliveryRoute(routes.Start, group)
+		route := DeliveryRouteType{
+			Vehicle:       idx + 1,
+			NumStops:      len(routeStops),
+			DistanceMiles: decimal.NewFromFloat(distance).StringFixed(2),
+			Stops:         routeStops,
+		}
+		for stopIdx := range route.Stops {
+			route.Stops[stopIdx].Sequence = stopIdx + 1
+			route.NumBags += route.Stops[stopIdx].NumBags
+		}
+		routes.Routes = append(routes.Routes, route)
+	}
+	log.Printf("Planned %d routes with %d unrouted stops", len(routes.Routes), len(routes.UnroutedStops))
+	return routes, nil
+}
@@ -0,0 +1,152 @@
+package frgql
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"testing"
+)
+
+var testRouteStart = RoutePointType{Lat: 39.0, Lng: -94.5}
+
+// //////////////////////////////////////////////////////////////////////////
+// Stop offset from testRouteStart by the given degrees
+func makeTestStop(orderId string, dLat float64, dLng float64, numBags int) DeliveryStopType {
+	lat, lng := testRouteStart.Lat+dLat, testRouteStart.Lng+dLng
+	return DeliveryStopType{OrderId: orderId, NumBags: numBags, Lat: &lat, Lng: &lng}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Two stops to the north, east, south and west of the start
+func makeTestCompassStops(numBags int) []DeliveryStopType {
+	return []DeliveryStopType{
+		makeTestStop("n1", 0.02, 0.001, numBags), makeTestStop("n2", 0.03, -0.001, numBags),
+		makeTestStop("e1", 0.001, 0.02, numBags), makeTestStop("e2", -0.001, 0.03, numBags),
+		makeTestStop("s1", -0.02, 0.001, numBags), makeTestStop("s2", -0.03, -0.001, numBags),
+		makeTestStop("w1", 0.001, -0.02, numBags), makeTestStop("w2", -0.001, -0.03, numBags),
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the order ids of the groups with the ids of a group sorted
+func getTestGroupIds(groups [][]DeliveryStopType) [][]string {
+	groupIds := [][]string{}
+	for _, group := range groups {
+		ids := []string{}
+		for _, stop := range group {
+			ids = append(ids, stop.OrderId)
+		}
+		slices.Sort(ids)
+		groupIds = append(groupIds, ids)
+	}
+	return groupIds
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func TestClusterDeliveryStops(t *testing.T) {
+	stops := makeTestCompassStops(5)
+
+	t.Run("one direction per vehicle", func(t *testing.T) {
+		groupIds := getTestGroupIds(clusterDeliveryStops(testRouteStart, stops, 4))
+		if len(groupIds) != 4 {
+			t.Fatalf("groups: %v", groupIds)
+		}
+		for _, ids := range groupIds {
+			if len(ids) != 2 || ids[0][0] != ids[1][0] {
+				t.Errorf("groups: %v", groupIds)
+			}
+		}
+	})
+
+	t.Run("every stop in one group", func(t *testing.T) {
+		for numVehicles := range 10 {
+			allIds := slices.Concat(getTestGroupIds(clusterDeliveryStops(testRouteStart, stops, numVehicles))...)
+			slices.Sort(allIds)
+			if len(allIds) != len(stops) || len(slices.Compact(allIds)) != len(stops) {
+				t.Errorf("%d vehicles: %v", numVehicles, allIds)
+			}
+		}
+	})
+
+	t.Run("vehicles", func(t *testing.T) {
+		// No more vehicles than stops and always at least one
+		expected := map[int]int{0: 1, 1: 1, 2: 2, 3: 3, 8: 8, 20: 8}
+		for numVehicles, numGroups := range expected {
+			if groups := clusterDeliveryStops(testRouteStart, stops, numVehicles); len(groups) != numGroups {
+				t.Errorf("%d vehicles: %d groups not: %d", numVehicles, len(groups), numGroups)
+			}
+		}
+		if groups := clusterDeliveryStops(testRouteStart, nil, 3); groups != nil {
+			t.Errorf("no stops: %v", groups)
+		}
+	})
+
+	t.Run("split by bags", func(t *testing.T) {
+		// Each vehicle gets its share of the 90 bags give or take the stop
+		// that is cut through
+		weighted := makeTestCompassStops(5)
+		weighted[0].NumBags, weighted[1].NumBags = 30, 30
+		for numVehicles := 2; numVehicles <= 3; numVehicles++ {
+			share := 90 / numVehicles
+			groups := clusterDeliveryStops(testRouteStart, weighted, numVehicles)
+			for _, group := range groups {
+				numBags := 0
+				for _, stop := range group {
+					numBags += stop.NumBags
+				}
+				if numBags < share-30 || numBags > share+30 {
+					t.Errorf("%d vehicles group of: %d bags %v", numVehicles, numBags, getTestGroupIds(groups))
+				}
+			}
+		}
+	})
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Stops along a road east of the start are delivered nearest first
+func TestPlanDeliveryRoute(t *testing.T) {
+	stops := []DeliveryStopType{
+		makeTestStop("far", 0, 0.03, 1), makeTestStop("near", 0, 0.01, 1), makeTestStop("middle", 0, 0.02, 1),
+	}
+	route, distance := planDeliveryRoute(testRouteStart, stops)
+
+	ids := []string{}
+	for _, stop := range route {
+		ids = append(ids, stop.OrderId)
+	}
+	if !slices.Equal(ids, []string{"near", "middle", "far"}) {
+		t.Errorf("route: %v", ids)
+	}
+	expected := haversineMiles(testRouteStart.Lat, testRouteStart.Lng, testRouteStart.Lat, testRouteStart.Lng+0.03)
+	if math.Abs(distance-expected) > 0.001 {
+		t.Errorf("distance: %f not: %f", distance, expected)
+	}
+	if route, distance := planDeliveryRoute(testRouteStart, nil); len(route) != 0 || distance != 0 {
+		t.Errorf("no stops: %v %f", route, distance)
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Stops zig-zagging along a road are never driven further than in the order
+// they were given
+func TestPlanDeliveryRouteZigZag(t *testing.T) {
+	stops := []DeliveryStopType{}
+	for idx := range 6 {
+		dLat := 0.002
+		if idx%2 == 1 {
+			dLat = -0.002
+		}
+		stops = append(stops, makeTestStop(fmt.Sprint("stop", idx), dLat, 0.01*float64(idx+1), 1))
+	}
+	_, distance := planDeliveryRoute(testRouteStart, stops)
+
+	given := 0.0
+	prev := DeliveryStopType{Lat: &testRouteStart.Lat, Lng: &testRouteStart.Lng}
+	for _, stop := range stops {
+		given += haversineMiles(*prev.Lat, *prev.Lng, *stop.Lat, *stop.Lng)
+		prev = stop
+	}
+	if distance > given+1e-9 {
+		t.Errorf("distance: %f is longer than the given order: %f", distance, given)
+	}
+}
@@ -2,9 +2,10 @@ package frgql
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	mapset "github.com/deckarep/golang-set/v2"
 	"github.com/graphql-go/graphql"
@@ -43,6 +44,131 @@ func getSelectedFields(selectionPath []string, resolveParams graphql.ResolvePara
 	return gqlOutFields.ToSlice()
 }
 
+// //////////////////////////////////////////////////////////////////////////
+// Returns the expectedLastModifiedTime given for each item in a list input
+// argument keyed by the item's keyField
+func getExpectedLastModifiedTimes(listArg interface{}, keyField string) map[string]string {
+	expectedLastModifiedTimes := make(map[string]string)
+	items, _ := listArg.([]interface{})
+	for _, item := range items {
+		fields, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		key, _ := fields[keyField].(string)
+		if expected, ok := fields["expectedLastModifiedTime"].(string); ok && len(expected) != 0 {
+			expectedLastModifiedTimes[key] = expected
+		}
+	}
+	return expectedLastModifiedTimes
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the dotted paths (i.e. customer.email) of the fields given in an
+// input object argument including the ones that were explicitly null.  Lists
+// are a single field.  Nulls can only be given through variables.
+func getInputFieldPaths(argName string, resolveParams graphql.ResolveParams) []string {
+	rawVariables, _ := resolveParams.Context.Value(gqlRawVariablesCtxKey{}).(map[string]interface{})
+	if rawVariables == nil {
+		rawVariables = resolveParams.Info.VariableValues
+	}
+
+	paths := []string{}
+	var addValuePaths func(prefix string, val interface{})
+	addValuePaths = func(prefix string, val interface{}) {
+		obj, isObj := val.(map[string]interface{})
+		if !isObj {
+			paths = append(paths, prefix)
+			return
+		}
+		for k, fieldVal := range obj {
+			addValuePaths(prefix+"."+k, fieldVal)
+		}
+	}
+	var addAstPaths func(prefix string, val ast.Value)
+	addAstPaths = func(prefix string, val ast.Value) {
+		switch val := val.(type) {
+		case *ast.Variable:
+			addValuePaths(prefix, rawVariables[val.Name.Value])
+		case *ast.ObjectValue:
+			for _, field := range val.Fields {
+				addAstPaths(prefix+"."+field.Name.Value, field.Value)
+			}
+		default:
+			paths = append(paths, prefix)
+		}
+	}
+
+	for _, field := range resolveParams.Info.FieldASTs {
+		for _, arg := range field.Arguments {
+			if arg.Name.Value == argName {
+				addAstPaths("", arg.Value)
+			}
+		}
+	}
+
+	for idx, path := range paths {
+		paths[idx] = strings.TrimPrefix(path, ".")
+	}
+	return paths
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Fills in the mulch order filter/sort params from the GraphQL args
+func parseMulchOrdersArgs(args map[string]interface{}, params *GetMulchOrdersParams) error {
+	if val, ok := args["ownerId"]; ok {
+		params.OwnerId = val.(string)
+	}
+	if val, ok := args["excludeOwnerId"]; ok {
+		params.ExcludeOwnerId = val.(string)
+	}
+	if val, ok := args["spreaderId"]; ok {
+		params.SpreaderId = val.(string)
+	}
+	if val, ok := args["doGetSpreadOrdersOnly"]; ok {
+		params.DoGetSpreadOrdersOnly = val.(bool)
+	}
+	if val, ok := args["neighborhood"]; ok {
+		params.Neighborhood = val.(string)
+	}
+	if val, ok := args["deliveryId"]; ok {
+		deliveryId := val.(int)
+		params.DeliveryId = &deliveryId
+	}
+	if val, ok := args["isVerified"]; ok {
+		isVerified := val.(bool)
+		params.IsVerified = &isVerified
+	}
+	if val, ok := args["willCollectMoneyLater"]; ok {
+		willCollectMoneyLater := val.(bool)
+		params.WillCollectMoneyLater = &willCollectMoneyLater
+	}
+	if val, ok := args["modifiedAfter"]; ok {
+		modifiedAfter, err := time.Parse(time.RFC3339, val.(string))
+		if err != nil {
+			return newValidationError("modifiedAfter", "modifiedAfter is not a RFC3339 time: %s", err)
+		}
+		params.ModifiedAfter = &modifiedAfter
+	}
+	if val, ok := args["modifiedBefore"]; ok {
+		modifiedBefore, err := time.Parse(time.RFC3339, val.(string))
+		if err != nil {
+			return newValidationError("modifiedBefore", "modifiedBefore is not a RFC3339 time: %s", err)
+		}
+		params.ModifiedBefore = &modifiedBefore
+	}
+	if val, ok := args["search"]; ok {
+		params.Search = val.(string)
+	}
+	if val, ok := args["sortBy"]; ok {
+		params.SortBy = val.(string)
+	}
+	if val, ok := args["sortDirection"]; ok {
+		params.IsSortDescending = val.(string) == "DESC"
+	}
+	return nil
+}
+
 // //////////////////////////////////////////////////////////////////////////
 func init() {
 	queryFields := make(map[string]*graphql.Field)
@@ -75,6 +201,29 @@ func init() {
 		},
 	})
 
+	checkDepositStatusEnum := graphql.NewEnum(graphql.EnumConfig{
+		Name:        "CheckDepositStatus",
+		Description: "Whether a check has gone into a bank deposit",
+		Values: graphql.EnumValueConfigMap{
+			CHECK_DEPOSITED:   &graphql.EnumValueConfig{Value: CHECK_DEPOSITED},
+			CHECK_UNDEPOSITED: &graphql.EnumValueConfig{Value: CHECK_UNDEPOSITED},
+		},
+	})
+
+	orderCheckType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "OrderCheckType",
+		Description: "Check an order was paid with",
+		Fields: graphql.Fields{
+			"checkNumber":   &graphql.Field{Type: graphql.String},
+			"amount":        &graphql.Field{Type: graphql.String},
+			"payer":         &graphql.Field{Type: graphql.String},
+			"isBounced":     &graphql.Field{Type: graphql.Boolean},
+			"bouncedTime":   &graphql.Field{Type: graphql.String},
+			"depositStatus": &graphql.Field{Type: checkDepositStatusEnum},
+			"depositId":     &graphql.Field{Type: graphql.String},
+		},
+	})
+
 	mulchOrderType := graphql.NewObject(graphql.ObjectConfig{
 		Name:        "MulchOrderType",
 		Description: "Mulch Order Record Type",
@@ -90,14 +239,21 @@ func init() {
 			"amountFromChecksCollected":      &graphql.Field{Type: graphql.String},
 			"amountTotalFromCashCollected":   &graphql.Field{Type: graphql.String},
 			"amountTotalFromChecksCollected": &graphql.Field{Type: graphql.String},
+			"amountTotalFromCashDeposited":   &graphql.Field{Type: graphql.String},
+			"amountTotalFromChecksDeposited": &graphql.Field{Type: graphql.String},
 			"amountTotalCollected":           &graphql.Field{Type: graphql.String},
 			"checkNumbers":                   &graphql.Field{Type: graphql.String},
+			"checks":                         &graphql.Field{Type: graphql.NewList(orderCheckType)},
 			"willCollectMoneyLater":          &graphql.Field{Type: graphql.Boolean},
 			"isVerified":                     &graphql.Field{Type: graphql.Boolean},
 			"customer":                       &graphql.Field{Type: customerType},
 			"purchases":                      &graphql.Field{Type: graphql.NewList(productType)},
 			"spreaders":                      &graphql.Field{Type: graphql.NewList(graphql.String)},
 			"deliveryId":                     &graphql.Field{Type: graphql.Int},
+			"knownAddrId": &graphql.Field{
+				Type:        graphql.String,
+				Description: "Known address (geocoded location) of the customer's address",
+			},
 		},
 	})
 
@@ -126,6 +282,16 @@ func init() {
 		},
 	})
 
+	orderCheckInputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "OrderCheckInputType",
+		Description: "Check an order was paid with",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"checkNumber": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"amount":      &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"payer":       &graphql.InputObjectFieldConfig{Type: graphql.String},
+		},
+	})
+
 	mulchOrderInputType := graphql.NewInputObject(graphql.InputObjectConfig{
 		Name:        "MulchOrderInputType",
 		Description: "Mulch Order Input Record Type",
@@ -140,6 +306,7 @@ func init() {
 			"amountFromChecksCollected": &graphql.InputObjectFieldConfig{Type: graphql.String},
 			"amountTotalCollected":      &graphql.InputObjectFieldConfig{Type: graphql.String},
 			"checkNumbers":              &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"checks":                    &graphql.InputObjectFieldConfig{Type: graphql.NewList(orderCheckInputType)},
 			"willCollectMoneyLater":     &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
 			"isVerified":                &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
 			"customer":                  &graphql.InputObjectFieldConfig{Type: customerInputType},
@@ -159,6 +326,10 @@ func init() {
 				Description: "The order entry",
 				Type:        mulchOrderInputType,
 			},
+			"doOverrideLock": &graphql.ArgumentConfig{
+				Description: "Admin only. Ignores the fundraiser lock and delivery cutoffs (this is logged)",
+				Type:        graphql.Boolean,
+			},
 		},
 		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 			log.Println("Creating Order: ", p.Args["order"])
@@ -170,7 +341,8 @@ func init() {
 
 			newMulchOrder := MulchOrderType{}
 			json.Unmarshal([]byte(jsonString), &newMulchOrder)
-			return CreateMulchOrder(p.Context, newMulchOrder)
+			doOverrideLock, _ := p.Args["doOverrideLock"].(bool)
+			return CreateMulchOrder(p.Context, newMulchOrder, doOverrideLock)
 		},
 	}
 
@@ -182,6 +354,14 @@ func init() {
 				Description: "The order entry",
 				Type:        graphql.NewNonNull(mulchOrderInputType),
 			},
+			"expectedLastModifiedTime": &graphql.ArgumentConfig{
+				Description: "If given the update fails with a CONFLICT error when the order has been changed since then",
+				Type:        graphql.String,
+			},
+			"doOverrideLock": &graphql.ArgumentConfig{
+				Description: "Admin only. Ignores the fundraiser lock and delivery cutoffs (this is logged)",
+				Type:        graphql.Boolean,
+			},
 		},
 		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 			jsonString, err := json.Marshal(p.Args["order"])
@@ -192,7 +372,10 @@ func init() {
 
 			updatedMulchOrder := MulchOrderType{}
 			json.Unmarshal([]byte(jsonString), &updatedMulchOrder)
-			return UpdateMulchOrder(p.Context, updatedMulchOrder)
+			expectedLastModifiedTime, _ := p.Args["expectedLastModifiedTime"].(string)
+			doOverrideLock, _ := p.Args["doOverrideLock"].(bool)
+			return UpdateMulchOrder(p.Context, updatedMulchOrder, getInputFieldPaths("order", p),
+				expectedLastModifiedTime, doOverrideLock)
 		},
 	}
 
@@ -204,9 +387,61 @@ func init() {
 				Description: "The id of the order that should be deleted",
 				Type:        graphql.NewNonNull(graphql.String),
 			},
+			"doOverrideLock": &graphql.ArgumentConfig{
+				Description: "Admin only. Ignores the fundraiser lock and delivery cutoffs (this is logged)",
+				Type:        graphql.Boolean,
+			},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			doOverrideLock, _ := p.Args["doOverrideLock"].(bool)
+			return DeleteMulchOrder(p.Context, p.Args["orderId"].(string), doOverrideLock)
+		},
+	}
+
+	//////////////////////////////////////////////////////////////////////////////
+	// Order History
+	mulchOrderRevisionType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "MulchOrderRevisionType",
+		Description: "Order as it was right before it was changed",
+		Fields: graphql.Fields{
+			"orderId":     &graphql.Field{Type: graphql.String},
+			"revision":    &graphql.Field{Type: graphql.Int},
+			"operation":   &graphql.Field{Type: graphql.String},
+			"actorId":     &graphql.Field{Type: graphql.String},
+			"createdTime": &graphql.Field{Type: graphql.String},
+			"order":       &graphql.Field{Type: mulchOrderType},
+		},
+	})
+
+	queryFields["orderHistory"] = &graphql.Field{
+		Type:        graphql.NewList(mulchOrderRevisionType),
+		Description: "Retrieves the saved revisions of an order oldest first",
+		Args: graphql.FieldConfigArgument{
+			"orderId": &graphql.ArgumentConfig{
+				Description: "The id of the order",
+				Type:        graphql.NewNonNull(graphql.String),
+			},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return GetMulchOrderHistory(p.Context, p.Args["orderId"].(string))
+		},
+	}
+
+	mutationFields["restoreMulchOrder"] = &graphql.Field{
+		Type:        graphql.Boolean,
+		Description: "Restores an order (even a deleted one) to a saved revision (admin only)",
+		Args: graphql.FieldConfigArgument{
+			"orderId": &graphql.ArgumentConfig{
+				Description: "The id of the order to restore",
+				Type:        graphql.NewNonNull(graphql.String),
+			},
+			"revision": &graphql.ArgumentConfig{
+				Description: "The revision from orderHistory to restore",
+				Type:        graphql.NewNonNull(graphql.Int),
+			},
 		},
 		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-			return DeleteMulchOrder(p.Context, p.Args["orderId"].(string))
+			return RestoreMulchOrder(p.Context, p.Args["orderId"].(string), p.Args["revision"].(int))
 		},
 	}
 
@@ -227,14 +462,39 @@ func init() {
 				OrderId:   p.Args["orderId"].(string),
 				GqlFields: getSelectedFields([]string{"mulchOrder"}, p),
 			}
-			return GetMulchOrder(params), nil
+			order, err := GetMulchOrder(p.Context, params)
+			if err != nil {
+				return nil, err
+			}
+			return order, nil
 		},
 	}
 
-	queryFields["mulchOrders"] = &graphql.Field{
-		Type:        graphql.NewList(mulchOrderType),
-		Description: "Retrieves order associated with ownerId",
-		Args: graphql.FieldConfigArgument{
+	mulchOrderSortFieldEnum := graphql.NewEnum(graphql.EnumConfig{
+		Name:        "MulchOrderSortField",
+		Description: "Fields that mulch orders can be sorted by",
+		Values: graphql.EnumValueConfigMap{
+			"orderId":              &graphql.EnumValueConfig{Value: "orderId"},
+			"ownerId":              &graphql.EnumValueConfig{Value: "ownerId"},
+			"lastModifiedTime":     &graphql.EnumValueConfig{Value: "lastModifiedTime"},
+			"customerName":         &graphql.EnumValueConfig{Value: "customerName"},
+			"neighborhood":         &graphql.EnumValueConfig{Value: "neighborhood"},
+			"deliveryId":           &graphql.EnumValueConfig{Value: "deliveryId"},
+			"amountTotalCollected": &graphql.EnumValueConfig{Value: "amountTotalCollected"},
+		},
+	})
+
+	sortDirectionEnum := graphql.NewEnum(graphql.EnumConfig{
+		Name: "SortDirection",
+		Values: graphql.EnumValueConfigMap{
+			"ASC":  &graphql.EnumValueConfig{Value: "ASC"},
+			"DESC": &graphql.EnumValueConfig{Value: "DESC"},
+		},
+	})
+
+	// Filter/sort args shared by the mulch order list queries
+	newMulchOrdersArgs := func() graphql.FieldConfigArgument {
+		return graphql.FieldConfigArgument{
 			"ownerId": &graphql.ArgumentConfig{
 				Description: "Narrows the search for orders with this owner id.",
 				Type:        graphql.String,
@@ -251,36 +511,209 @@ func init() {
 				Description: "Narrows the search for entries that have spread jobs in them",
 				Type:        graphql.Boolean,
 			},
-		},
+			"neighborhood": &graphql.ArgumentConfig{
+				Description: "Narrows the search for orders in this neighborhood",
+				Type:        graphql.String,
+			},
+			"deliveryId": &graphql.ArgumentConfig{
+				Description: "Narrows the search for orders in this delivery",
+				Type:        graphql.Int,
+			},
+			"isVerified": &graphql.ArgumentConfig{
+				Description: "Narrows the search for orders that are (or are not) verified",
+				Type:        graphql.Boolean,
+			},
+			"willCollectMoneyLater": &graphql.ArgumentConfig{
+				Description: "Narrows the search for orders that will (or will not) collect money later",
+				Type:        graphql.Boolean,
+			},
+			"modifiedAfter": &graphql.ArgumentConfig{
+				Description: "RFC3339 time. Narrows the search for orders modified at or after this time",
+				Type:        graphql.String,
+			},
+			"modifiedBefore": &graphql.ArgumentConfig{
+				Description: "RFC3339 time. Narrows the search for orders modified before this time",
+				Type:        graphql.String,
+			},
+			"search": &graphql.ArgumentConfig{
+				Description: "Case insensitive search of the customer name and address",
+				Type:        graphql.String,
+			},
+			"sortBy": &graphql.ArgumentConfig{
+				Description: "Field to sort by. Ties are broken by orderId",
+				Type:        mulchOrderSortFieldEnum,
+			},
+			"sortDirection": &graphql.ArgumentConfig{
+				Description: "Defaults to ASC",
+				Type:        sortDirectionEnum,
+			},
+		}
+	}
+
+	queryFields["mulchOrders"] = &graphql.Field{
+		Type:        graphql.NewList(mulchOrderType),
+		Description: "Retrieves order associated with ownerId",
+		Args:        newMulchOrdersArgs(),
 		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 			// if is_mulch_order getMulchOrder Else get etc...
 			params := GetMulchOrdersParams{
 				GqlFields: getSelectedFields([]string{"mulchOrders"}, p),
 			}
-			if val, ok := p.Args["ownerId"]; ok {
-				params.OwnerId = val.(string)
-			}
-			if val, ok := p.Args["excludeOwnerId"]; ok {
-				params.ExcludeOwnerId = val.(string)
-			}
-			if val, ok := p.Args["spreaderId"]; ok {
-				params.SpreaderId = val.(string)
-			}
-			if val, ok := p.Args["doGetSpreadOrdersOnly"]; ok {
-				params.DoGetSpreadOrdersOnly = val.(bool)
+			if err := parseMulchOrdersArgs(p.Args, &params); err != nil {
+				return nil, err
 			}
 			isLookingForMoneyCollected := false
 			for _, v := range params.GqlFields {
-				if v == "amountTotalFromCashCollected" || v == "amountTotalFromChecksCollected" {
+				if v == "amountTotalFromCashCollected" || v == "amountTotalFromChecksCollected" ||
+					v == "amountTotalFromCashDeposited" || v == "amountTotalFromChecksDeposited" {
 					isLookingForMoneyCollected = true
 					break
 				}
 			}
 			if isLookingForMoneyCollected {
-				return GetMulchOrdersMoneyCollected(params), nil
+				orders, err := GetMulchOrdersMoneyCollected(p.Context, params)
+				return resolvePartialResult(p, orders, err)
 			} else {
-				return GetMulchOrders(params), nil
+				orders, err := GetMulchOrders(p.Context, params)
+				return resolvePartialResult(p, orders, err)
+			}
+		},
+	}
+
+	pageInfoType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "PageInfoType",
+		Description: "Paging information for a connection",
+		Fields: graphql.Fields{
+			"hasNextPage":     &graphql.Field{Type: graphql.Boolean},
+			"hasPreviousPage": &graphql.Field{Type: graphql.Boolean},
+			"startCursor":     &graphql.Field{Type: graphql.String},
+			"endCursor":       &graphql.Field{Type: graphql.String},
+		},
+	})
+
+	mulchOrderEdgeType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "MulchOrderEdgeType",
+		Description: "Mulch order with its paging cursor",
+		Fields: graphql.Fields{
+			"cursor": &graphql.Field{Type: graphql.String},
+			"node":   &graphql.Field{Type: mulchOrderType},
+		},
+	})
+
+	mulchOrdersConnectionType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "MulchOrdersConnectionType",
+		Description: "Page of mulch orders",
+		Fields: graphql.Fields{
+			"edges":      &graphql.Field{Type: graphql.NewList(mulchOrderEdgeType)},
+			"pageInfo":   &graphql.Field{Type: pageInfoType},
+			"totalCount": &graphql.Field{Type: graphql.Int},
+		},
+	})
+
+	mulchOrdersConnectionArgs := newMulchOrdersArgs()
+	mulchOrdersConnectionArgs["first"] = &graphql.ArgumentConfig{
+		Description: "Returns the first n orders after the after cursor",
+		Type:        graphql.Int,
+	}
+	mulchOrdersConnectionArgs["after"] = &graphql.ArgumentConfig{
+		Description: "Cursor to start after",
+		Type:        graphql.String,
+	}
+	mulchOrdersConnectionArgs["last"] = &graphql.ArgumentConfig{
+		Description: "Returns the last n orders before the before cursor",
+		Type:        graphql.Int,
+	}
+	mulchOrdersConnectionArgs["before"] = &graphql.ArgumentConfig{
+		Description: "Cursor to end before",
+		Type:        graphql.String,
+	}
+
+	queryFields["mulchOrdersConnection"] = &graphql.Field{
+		Type:        mulchOrdersConnectionType,
+		Description: "Retrieves a page of orders using the same filters as mulchOrders",
+		Args:        mulchOrdersConnectionArgs,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			params := GetMulchOrdersParams{
+				GqlFields: getSelectedFields([]string{"mulchOrdersConnection", "edges", "node"}, p),
+			}
+			if len(params.GqlFields) == 0 {
+				params.GqlFields = []string{"orderId"}
+			}
+			if err := parseMulchOrdersArgs(p.Args, &params); err != nil {
+				return nil, err
+			}
+			page := PageArgs{}
+			if val, ok := p.Args["first"]; ok {
+				first := val.(int)
+				page.First = &first
+			}
+			if val, ok := p.Args["last"]; ok {
+				last := val.(int)
+				page.Last = &last
+			}
+			if val, ok := p.Args["after"]; ok {
+				page.After = val.(string)
+			}
+			if val, ok := p.Args["before"]; ok {
+				page.Before = val.(string)
+			}
+			conn, err := GetMulchOrdersConnection(p.Context, params, page)
+			return resolvePartialResult(p, conn, err)
+		},
+	}
+
+	//////////////////////////////////////////////////////////////////////////////
+	// Order Pricing Types
+	orderQuoteItemType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "OrderQuoteItemType",
+		Description: "Server computed pricing for a single purchase",
+		Fields: graphql.Fields{
+			"productId":     &graphql.Field{Type: graphql.String},
+			"numSold":       &graphql.Field{Type: graphql.Int},
+			"unitPrice":     &graphql.Field{Type: graphql.String},
+			"amountCharged": &graphql.Field{Type: graphql.String},
+		},
+	})
+
+	orderQuoteType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "OrderQuoteType",
+		Description: "Server computed pricing for an order",
+		Fields: graphql.Fields{
+			"purchases":           &graphql.Field{Type: graphql.NewList(orderQuoteItemType)},
+			"amountFromPurchases": &graphql.Field{Type: graphql.String},
+			"amountFromDonations": &graphql.Field{Type: graphql.String},
+			"amountTotal":         &graphql.Field{Type: graphql.String},
+		},
+	})
+
+	queryFields["quoteMulchOrder"] = &graphql.Field{
+		Type:        orderQuoteType,
+		Description: "Prices the purchases using the fundraiser product config",
+		Args: graphql.FieldConfigArgument{
+			"purchases": &graphql.ArgumentConfig{
+				Description: "The products and number sold",
+				Type:        graphql.NewList(productInputType),
+			},
+			"amountFromDonations": &graphql.ArgumentConfig{
+				Description: "Donation amount to include in the total",
+				Type:        graphql.String,
+			},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			jsonString, err := json.Marshal(p.Args["purchases"])
+			if err != nil {
+				fmt.Println("Error encoding JSON")
+				return nil, nil
+			}
+
+			purchases := []ProductsType{}
+			json.Unmarshal([]byte(jsonString), &purchases)
+
+			var donations *string
+			if val, ok := p.Args["amountFromDonations"].(string); ok {
+				donations = &val
 			}
+			return QuoteMulchOrder(p.Context, purchases, donations)
 		},
 	}
 
@@ -325,7 +758,8 @@ func init() {
 				deliveryId = val.(int)
 			}
 			gqlFields := getSelectedFields([]string{"mulchTimecards"}, p)
-			return GetMulchTimecards(id, deliveryId, gqlFields)
+			timecards, err := GetMulchTimecards(p.Context, id, deliveryId, gqlFields)
+			return resolvePartialResult(p, timecards, err)
 		},
 	}
 
@@ -377,6 +811,7 @@ func init() {
 			"city":              &graphql.Field{Type: graphql.String},
 			"isVisible":         &graphql.Field{Type: graphql.Boolean},
 			"distributionPoint": &graphql.Field{Type: graphql.String},
+			"lastModifiedTime":  &graphql.Field{Type: graphql.String},
 		},
 	})
 	queryFields["neighborhoods"] = &graphql.Field{
@@ -384,7 +819,7 @@ func init() {
 		Description: "Queries for list of neighborhoods",
 		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 			gqlFields := getSelectedFields([]string{"neighborhoods"}, p)
-			return GetNeighborhoods(gqlFields)
+			return GetNeighborhoods(p.Context, gqlFields)
 		},
 	}
 	neighborhoodInputType := graphql.NewInputObject(graphql.InputObjectConfig{
@@ -396,6 +831,10 @@ func init() {
 			"city":              &graphql.InputObjectFieldConfig{Type: graphql.String},
 			"isVisible":         &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
 			"distributionPoint": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"expectedLastModifiedTime": &graphql.InputObjectFieldConfig{
+				Description: "If given the update fails with a CONFLICT error when the neighborhood has been changed since then",
+				Type:        graphql.String,
+			},
 		},
 	})
 
@@ -420,7 +859,7 @@ func init() {
 				log.Println("Error decoding JSON to NeighborhoodInfo")
 				return nil, nil
 			}
-			return AddOrUpdateNeighborhoods(p.Context, hoods)
+			return AddOrUpdateNeighborhoods(p.Context, hoods, getExpectedLastModifiedTimes(p.Args["neighborhoods"], "name"))
 		},
 	}
 
@@ -430,12 +869,13 @@ func init() {
 		Name:        "UserInfoType",
 		Description: "User Info Type",
 		Fields: graphql.Fields{
-			"firstName":    &graphql.Field{Type: graphql.String},
-			"lastName":     &graphql.Field{Type: graphql.String},
-			"name":         &graphql.Field{Type: graphql.String},
-			"id":           &graphql.Field{Type: graphql.String},
-			"group":        &graphql.Field{Type: graphql.String},
-			"hasAuthCreds": &graphql.Field{Type: graphql.Boolean},
+			"firstName":        &graphql.Field{Type: graphql.String},
+			"lastName":         &graphql.Field{Type: graphql.String},
+			"name":             &graphql.Field{Type: graphql.String},
+			"id":               &graphql.Field{Type: graphql.String},
+			"group":            &graphql.Field{Type: graphql.String},
+			"hasAuthCreds":     &graphql.Field{Type: graphql.Boolean},
+			"lastModifiedTime": &graphql.Field{Type: graphql.String},
 		},
 	})
 
@@ -456,7 +896,7 @@ func init() {
 			if val, ok := p.Args["showOnlyUsersWithoutAuthCreds"]; ok {
 				params.ShowUsersWithoutAuthCreds = val.(bool)
 			}
-			return GetUsers(params)
+			return GetUsers(p.Context, params)
 		},
 	}
 
@@ -469,6 +909,10 @@ func init() {
 			"lastName":     &graphql.InputObjectFieldConfig{Type: graphql.String},
 			"group":        &graphql.InputObjectFieldConfig{Type: graphql.String},
 			"hasAuthCreds": &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+			"expectedLastModifiedTime": &graphql.InputObjectFieldConfig{
+				Description: "If given the update fails with a CONFLICT error when the user has been changed since then",
+				Type:        graphql.String,
+			},
 		},
 	})
 
@@ -493,7 +937,7 @@ func init() {
 				log.Println("Error decoding JSON to userinfo")
 				return nil, nil
 			}
-			return AddOrUpdateUsers(p.Context, users)
+			return AddOrUpdateUsers(p.Context, users, getExpectedLastModifiedTimes(p.Args["users"], "id"))
 		},
 	}
 
@@ -543,6 +987,25 @@ func init() {
 			"deliveryEarningsPerMinute":  &graphql.Field{Type: graphql.String},
 		},
 	})
+	allocationPolicyType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "AllocationPolicyType",
+		Description: "How the money is split at closeout.  Fields that aren't set use the defaults",
+		Fields: graphql.Fields{
+			"troopPercentage":       &graphql.Field{Type: graphql.String},
+			"deliveryPercentage":    &graphql.Field{Type: graphql.String},
+			"spreadingCreditPerBag": &graphql.Field{Type: graphql.String},
+			"minDeliveryMinutes":    &graphql.Field{Type: graphql.Int},
+			"maxAllocationPerScout": &graphql.Field{Type: graphql.String},
+		},
+	})
+	inventoryPolicyType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "InventoryPolicyType",
+		Description: "How supplier mulch orders are suggested.  Fields that aren't set use the defaults",
+		Fields: graphql.Fields{
+			"bufferPercentage": &graphql.Field{Type: graphql.String},
+			"bagsPerPallet":    &graphql.Field{Type: graphql.Int},
+		},
+	})
 	configType := graphql.NewObject(graphql.ObjectConfig{
 		Name:        "ConfigType",
 		Description: "Fundraiser config information",
@@ -554,6 +1017,8 @@ func init() {
 			"mulchDeliveryConfigs": &graphql.Field{Type: graphql.NewList(mulchDeliveryConfigType)},
 			"products":             &graphql.Field{Type: graphql.NewList(productConfigType)},
 			"finalizationData":     &graphql.Field{Type: finalizationDataConfigType},
+			"allocationPolicy":     &graphql.Field{Type: allocationPolicyType},
+			"inventoryPolicy":      &graphql.Field{Type: inventoryPolicyType},
 			"neighborhoods":        queryFields["neighborhoods"],
 			"users":                queryFields["users"],
 		},
@@ -563,7 +1028,7 @@ func init() {
 		Description: "Queries for Summary information based on Owner ID",
 		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 			gqlFields := getSelectedFields([]string{"config"}, p)
-			return GetFundraiserConfig(gqlFields)
+			return GetFundraiserConfig(p.Context, gqlFields)
 		},
 	}
 
@@ -609,8 +1074,46 @@ func init() {
 			"deliveryEarningsPerMinute":  &graphql.InputObjectFieldConfig{Type: graphql.String},
 		},
 	})
+	allocationPolicyInputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "AllocationPolicyInputType",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"troopPercentage": &graphql.InputObjectFieldConfig{
+				Type:        graphql.String,
+				Description: "Percentage of the profit kept by the troop (required to close out)",
+			},
+			"deliveryPercentage": &graphql.InputObjectFieldConfig{
+				Type:        graphql.String,
+				Description: "Percentage of the scouts' pool after spreading credits paid for delivery time (required to close out)",
+			},
+			"spreadingCreditPerBag": &graphql.InputObjectFieldConfig{
+				Type:        graphql.String,
+				Description: "Credit for every bag spread (default the spreading unit price)",
+			},
+			"minDeliveryMinutes": &graphql.InputObjectFieldConfig{
+				Type:        graphql.Int,
+				Description: "Scouts with fewer timecard minutes get no delivery credit (default 0)",
+			},
+			"maxAllocationPerScout": &graphql.InputObjectFieldConfig{
+				Type:        graphql.String,
+				Description: "Most a scout can be allocated, the rest goes to the troop (default no cap)",
+			},
+		},
+	})
+	inventoryPolicyInputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "InventoryPolicyInputType",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"bufferPercentage": &graphql.InputObjectFieldConfig{
+				Type:        graphql.String,
+				Description: "Percentage added to the bags sold when suggesting a supplier order (default 5)",
+			},
+			"bagsPerPallet": &graphql.InputObjectFieldConfig{
+				Type:        graphql.Int,
+				Description: "Suggested supplier orders are rounded up to whole pallets of this size (default no rounding)",
+			},
+		},
+	})
 	configInputType := graphql.NewInputObject(graphql.InputObjectConfig{
-		Name:        "ConfigType",
+		Name:        "ConfigInputType",
 		Description: "Fundraiser config information",
 		Fields: graphql.InputObjectConfigFieldMap{
 			"kind":                 &graphql.InputObjectFieldConfig{Type: graphql.String},
@@ -620,6 +1123,8 @@ func init() {
 			"mulchDeliveryConfigs": &graphql.InputObjectFieldConfig{Type: graphql.NewList(mulchDeliveryInputConfigType)},
 			"products":             &graphql.InputObjectFieldConfig{Type: graphql.NewList(productInputConfigType)},
 			"finalizationData":     &graphql.InputObjectFieldConfig{Type: finalizationDataInputConfigType},
+			"allocationPolicy":     &graphql.InputObjectFieldConfig{Type: allocationPolicyInputType},
+			"inventoryPolicy":      &graphql.InputObjectFieldConfig{Type: inventoryPolicyInputType},
 		},
 	})
 	mutationFields["setConfig"] = &graphql.Field{
@@ -652,6 +1157,10 @@ func init() {
 				Description: "The config entry",
 				Type:        configInputType,
 			},
+			"expectedLastModifiedTime": &graphql.ArgumentConfig{
+				Description: "If given the update fails with a CONFLICT error when the config has been changed since then",
+				Type:        graphql.String,
+			},
 		},
 		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 			// log.Println("Setting Config: ", p.Args["config"])
@@ -662,7 +1171,8 @@ func init() {
 			}
 			frConfig := FrConfigType{}
 			json.Unmarshal([]byte(jsonString), &frConfig)
-			return UpdateFundraiserConfig(p.Context, frConfig)
+			expectedLastModifiedTime, _ := p.Args["expectedLastModifiedTime"].(string)
+			return UpdateFundraiserConfig(p.Context, frConfig, expectedLastModifiedTime)
 		},
 	}
 
@@ -670,7 +1180,7 @@ func init() {
 	// Adds Spreaders to order
 	mutationFields["setSpreaders"] = &graphql.Field{
 		Type:        graphql.Boolean,
-		Description: "Sets spreader information for an order",
+		Description: "Sets spreader information for an order (order owner or admin)",
 		Args: graphql.FieldConfigArgument{
 			"orderId": &graphql.ArgumentConfig{
 				Description: "The id of the order associated with the spreaders",
@@ -686,13 +1196,13 @@ func init() {
 			orderId := p.Args["orderId"].(string)
 			jsonString, err := json.Marshal(p.Args["spreaders"])
 			if err != nil {
-				return false, errors.New("spreaders param not formatted correctly")
+				return false, newValidationError("spreaders", "spreaders param not formatted correctly")
 			}
 			spreaders := []string{}
 			if err := json.Unmarshal([]byte(jsonString), &spreaders); err != nil {
-				return false, errors.New("spreaders could not be decoded")
+				return false, newValidationError("spreaders", "spreaders could not be decoded")
 			}
-			return SetSpreaders(orderId, spreaders)
+			return SetSpreaders(p.Context, orderId, spreaders)
 		},
 	}
 
@@ -801,7 +1311,7 @@ func init() {
 			},
 		},
 		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-			return GetSummaryByOwnerId(p.Args["ownerId"].(string))
+			return GetSummaryByOwnerId(p.Context, p.Args["ownerId"].(string))
 		},
 	}
 
@@ -843,7 +1353,7 @@ func init() {
 			},
 		},
 		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-			return GetTroopSummary(p.Args["numTopSellers"].(int))
+			return GetTroopSummary(p.Context, p.Args["numTopSellers"].(int))
 		},
 	}
 
@@ -859,7 +1369,7 @@ func init() {
 		Type:        graphql.NewList(neighborhoodSummaryType),
 		Description: "Summary information for neighborhoods",
 		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-			return GetNeighborhoodSummary()
+			return GetNeighborhoodSummary(p.Context)
 		},
 	}
 
@@ -915,46 +1425,1113 @@ func init() {
 	}
 
 	//////////////////////////////////////////////////////////////////////////////
-	// Geolocation Address Type
-	addressType := graphql.NewObject(graphql.ObjectConfig{
-		Name:        "AddressType",
-		Description: "Address Information",
-		Fields: graphql.Fields{
-			"zipcode":     &graphql.Field{Type: graphql.Int},
-			"city":        &graphql.Field{Type: graphql.String},
-			"houseNumber": &graphql.Field{Type: graphql.String},
-			"street":      &graphql.Field{Type: graphql.String},
-		},
-	})
-	queryFields["getAddress"] = &graphql.Field{
-		Type:        addressType,
-		Description: "Retrieves Address from geo information",
+	// Season Archives
+	mutationFields["closeSeason"] = &graphql.Field{
+		Type: graphql.Boolean,
+		Description: "Archives the mulch orders, mulch spreaders, allocation summary, time cards and config " +
+			"under season and then resets them like resetFundraisingData(doResetOrders: true) (admin only)",
 		Args: graphql.FieldConfigArgument{
-			"lat": &graphql.ArgumentConfig{
-				Description: "Latitude",
-				Type:        graphql.NewNonNull(graphql.Float),
-			},
-			"lng": &graphql.ArgumentConfig{
-				Description: "Longitude",
-				Type:        graphql.NewNonNull(graphql.Float),
+			"season": &graphql.ArgumentConfig{
+				Description: "Year of the season being closed",
+				Type:        graphql.NewNonNull(graphql.Int),
 			},
 		},
 		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-			var lat, lng float64
-			if val, ok := p.Args["lat"]; ok {
-				lat = val.(float64)
-			}
-			if val, ok := p.Args["lng"]; ok {
-				lng = val.(float64)
-			}
-			return GetAddrFromLatLng(p.Context, lat, lng)
+			return CloseSeason(p.Context, p.Args["season"].(int))
 		},
 	}
 
-	schemaConfig := graphql.SchemaConfig{
-		Query:    graphql.NewObject(graphql.ObjectConfig{Name: "Query", Fields: graphql.Fields(queryFields)}),
-		Mutation: graphql.NewObject(graphql.ObjectConfig{Name: "Mutation", Fields: graphql.Fields(mutationFields)}),
-	}
+	archivedConfigType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "ArchivedConfigType",
+		Description: "Fundraiser config as it was when the season was closed",
+		Fields: graphql.Fields{
+			"kind":                 &graphql.Field{Type: graphql.String},
+			"description":          &graphql.Field{Type: graphql.String},
+			"lastModifiedTime":     &graphql.Field{Type: graphql.String},
+			"isLocked":             &graphql.Field{Type: graphql.Boolean},
+			"mulchDeliveryConfigs": &graphql.Field{Type: graphql.NewList(mulchDeliveryConfigType)},
+			"products":             &graphql.Field{Type: graphql.NewList(productConfigType)},
+			"finalizationData":     &graphql.Field{Type: finalizationDataConfigType},
+			"allocationPolicy":     &graphql.Field{Type: allocationPolicyType},
+			"inventoryPolicy":      &graphql.Field{Type: inventoryPolicyType},
+		},
+	})
+	allocationType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "AllocationType",
+		Description: "Closeout allocation for a scout",
+		Fields: graphql.Fields{
+			"uid":                       &graphql.Field{Type: graphql.String},
+			"bagsSold":                  &graphql.Field{Type: graphql.Int},
+			"bagsSpread":                &graphql.Field{Type: graphql.String},
+			"deliveryMinutes":           &graphql.Field{Type: graphql.String},
+			"totalDonations":            &graphql.Field{Type: graphql.String},
+			"allocationsFromBagsSold":   &graphql.Field{Type: graphql.String},
+			"allocationsFromBagsSpread": &graphql.Field{Type: graphql.String},
+			"allocationsFromDelivery":   &graphql.Field{Type: graphql.String},
+			"allocationsTotal":          &graphql.Field{Type: graphql.String},
+		},
+	})
+	seasonArchiveType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "SeasonArchiveType",
+		Description: "Read only data of a closed season",
+		Fields: graphql.Fields{
+			"season":     &graphql.Field{Type: graphql.Int},
+			"closedTime": &graphql.Field{Type: graphql.String},
+			"closedBy":   &graphql.Field{Type: graphql.String},
+			"isImported": &graphql.Field{Type: graphql.Boolean},
+			"config":     &graphql.Field{Type: archivedConfigType},
+			"mulchOrders": &graphql.Field{
+				Type:        graphql.NewList(mulchOrderType),
+				Description: "Orders (with spreaders) of the season.  Only admins can leave out ownerId",
+				Args: graphql.FieldConfigArgument{
+					"ownerId": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					ownerId, _ := p.Args["ownerId"].(string)
+					return GetArchivedMulchOrders(p.Context, p.Source.(SeasonArchiveType).Season, ownerId)
+				},
+			},
+			"mulchTimecards": &graphql.Field{
+				Type:        graphql.NewList(timecardType),
+				Description: "Timecards of the season.  Only admins can leave out id",
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, _ := p.Args["id"].(string)
+					return GetArchivedMulchTimecards(p.Context, p.Source.(SeasonArchiveType).Season, id)
+				},
+			},
+			"allocations": &graphql.Field{
+				Type:        graphql.NewList(allocationType),
+				Description: "Closeout allocations of the season.  Only admins can leave out uid",
+				Args: graphql.FieldConfigArgument{
+					"uid": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					uid, _ := p.Args["uid"].(string)
+					return GetArchivedAllocations(p.Context, p.Source.(SeasonArchiveType).Season, uid)
+				},
+			},
+		},
+	})
+	queryFields["seasons"] = &graphql.Field{
+		Type:        graphql.NewList(seasonArchiveType),
+		Description: "Retrieves the closed seasons newest first",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return GetSeasonArchives(p.Context)
+		},
+	}
+	queryFields["archivedSeason"] = &graphql.Field{
+		Type:        seasonArchiveType,
+		Description: "Retrieves a closed season",
+		Args: graphql.FieldConfigArgument{
+			"season": &graphql.ArgumentConfig{
+				Description: "Year of the closed season",
+				Type:        graphql.NewNonNull(graphql.Int),
+			},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			archive, err := GetSeasonArchive(p.Context, p.Args["season"].(int))
+			if err != nil {
+				return nil, err
+			}
+			return archive, nil
+		},
+	}
+
+	mutationFields["importSeason"] = &graphql.Field{
+		Type:        graphql.Boolean,
+		Description: "Loads a prior season exported from another system into the season archives (admin only)",
+		Args: graphql.FieldConfigArgument{
+			"season": &graphql.ArgumentConfig{
+				Description: "Year of the season being imported",
+				Type:        graphql.NewNonNull(graphql.Int),
+			},
+			"config":         &graphql.ArgumentConfig{Type: configInputType},
+			"mulchOrders":    &graphql.ArgumentConfig{Type: graphql.NewList(mulchOrderInputType)},
+			"mulchTimecards": &graphql.ArgumentConfig{Type: graphql.NewList(timecardInputType)},
+			"allocations":    &graphql.ArgumentConfig{Type: graphql.NewList(allocationsInputType)},
+			"users":          &graphql.ArgumentConfig{Type: graphql.NewList(userInputType)},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			jsonString, err := json.Marshal(p.Args)
+			if err != nil {
+				log.Println("Error encoding JSON")
+				return nil, err
+			}
+			seasonImport := struct {
+				Config         *FrConfigType        `json:"config"`
+				MulchOrders    []MulchOrderType     `json:"mulchOrders"`
+				MulchTimecards []MulchTimecardType  `json:"mulchTimecards"`
+				Allocations    []AllocationItemType `json:"allocations"`
+				Users          []UserInfo           `json:"users"`
+			}{}
+			if err := json.Unmarshal(jsonString, &seasonImport); err != nil {
+				log.Println("Error decoding JSON to season import")
+				return nil, &BadRequestError{Message: fmt.Sprint("season import could not be read: ", err)}
+			}
+			return ImportSeason(p.Context, p.Args["season"].(int), seasonImport.Config, SeasonArchiveContentsType{
+				MulchOrders:    seasonImport.MulchOrders,
+				MulchTimecards: seasonImport.MulchTimecards,
+				Allocations:    seasonImport.Allocations,
+				Users:          seasonImport.Users,
+			})
+		},
+	}
+
+	seasonStatsType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "SeasonStatsType",
+		Description: "Numbers for one season.  The *Change fields are the % change from the season before it",
+		Fields: graphql.Fields{
+			"season":                     &graphql.Field{Type: graphql.Int},
+			"numOrders":                  &graphql.Field{Type: graphql.Int},
+			"bagsSold":                   &graphql.Field{Type: graphql.Int},
+			"bagsToSpread":               &graphql.Field{Type: graphql.Int},
+			"bagsSpread":                 &graphql.Field{Type: graphql.String},
+			"deliveryMinutes":            &graphql.Field{Type: graphql.Int},
+			"donations":                  &graphql.Field{Type: graphql.String},
+			"totalAmountCollected":       &graphql.Field{Type: graphql.String},
+			"bagsSoldChange":             &graphql.Field{Type: graphql.String},
+			"bagsToSpreadChange":         &graphql.Field{Type: graphql.String},
+			"bagsSpreadChange":           &graphql.Field{Type: graphql.String},
+			"donationsChange":            &graphql.Field{Type: graphql.String},
+			"totalAmountCollectedChange": &graphql.Field{Type: graphql.String},
+		},
+	})
+	seasonComparisonRowType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "SeasonComparisonRowType",
+		Description: "A scout, patrol or neighborhood across the compared seasons",
+		Fields: graphql.Fields{
+			"key":     &graphql.Field{Type: graphql.String},
+			"name":    &graphql.Field{Type: graphql.String},
+			"seasons": &graphql.Field{Type: graphql.NewList(seasonStatsType)},
+		},
+	})
+	seasonComparisonType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "SeasonComparisonType",
+		Description: "Year over year comparison",
+		Fields: graphql.Fields{
+			"seasons":       &graphql.Field{Type: graphql.NewList(graphql.Int)},
+			"troop":         &graphql.Field{Type: graphql.NewList(seasonStatsType)},
+			"scouts":        &graphql.Field{Type: graphql.NewList(seasonComparisonRowType)},
+			"patrols":       &graphql.Field{Type: graphql.NewList(seasonComparisonRowType)},
+			"neighborhoods": &graphql.Field{Type: graphql.NewList(seasonComparisonRowType)},
+		},
+	})
+	queryFields["seasonComparison"] = &graphql.Field{
+		Type:        seasonComparisonType,
+		Description: "Compares seasons per scout, patrol, neighborhood and for the troop (admin only)",
+		Args: graphql.FieldConfigArgument{
+			"seasons": &graphql.ArgumentConfig{
+				Description: "Archived seasons to compare.  All of them if not given",
+				Type:        graphql.NewList(graphql.Int),
+			},
+			"currentSeason": &graphql.ArgumentConfig{
+				Description: "If given the live data is included as this season",
+				Type:        graphql.Int,
+			},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			seasons := []int{}
+			if val, ok := p.Args["seasons"].([]interface{}); ok {
+				for _, season := range val {
+					if season, ok := season.(int); ok {
+						seasons = append(seasons, season)
+					}
+				}
+			}
+			currentSeason, _ := p.Args["currentSeason"].(int)
+			comparison, err := GetSeasonComparison(p.Context, seasons, currentSeason)
+			if err != nil {
+				return nil, err
+			}
+			return comparison, nil
+		},
+	}
+
+	//////////////////////////////////////////////////////////////////////////////
+	// Closeout
+	closeoutType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "CloseoutType",
+		Description: "Finalization data and the allocation of every scout worked out at closeout",
+		Fields: graphql.Fields{
+			"finalizationData": &graphql.Field{Type: finalizationDataConfigType},
+			"allocations":      &graphql.Field{Type: graphql.NewList(allocationType)},
+		},
+	})
+	closeoutArgs := graphql.FieldConfigArgument{
+		"bankDeposited": &graphql.ArgumentConfig{
+			Description: "Total deposited in the bank.  Defaults to the bank deposit ledger's total or without any deposits the one in the config's finalizationData",
+			Type:        graphql.String,
+		},
+		"mulchCost": &graphql.ArgumentConfig{
+			Description: "What the mulch cost.  Defaults to the mulch inventory's total or without any costs there the one in the config's finalizationData",
+			Type:        graphql.String,
+		},
+	}
+	queryFields["previewCloseout"] = &graphql.Field{
+		Type:        closeoutType,
+		Description: "Works out the closeout without saving it (admin only)",
+		Args:        closeoutArgs,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			var bankDeposited, mulchCost *string
+			if val, ok := p.Args["bankDeposited"].(string); ok {
+				bankDeposited = &val
+			}
+			if val, ok := p.Args["mulchCost"].(string); ok {
+				mulchCost = &val
+			}
+			closeout, err := PreviewCloseout(p.Context, bankDeposited, mulchCost)
+			if err != nil {
+				return nil, err
+			}
+			return closeout, nil
+		},
+	}
+	mutationFields["commitCloseout"] = &graphql.Field{
+		Type:        closeoutType,
+		Description: "Works out the closeout and saves the finalization data and allocations (admin only)",
+		Args:        closeoutArgs,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			var bankDeposited, mulchCost *string
+			if val, ok := p.Args["bankDeposited"].(string); ok {
+				bankDeposited = &val
+			}
+			if val, ok := p.Args["mulchCost"].(string); ok {
+				mulchCost = &val
+			}
+			closeout, err := CommitCloseout(p.Context, bankDeposited, mulchCost)
+			if err != nil {
+				return nil, err
+			}
+			return closeout, nil
+		},
+	}
+
+	allocationSimulationItemType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "AllocationSimulationItemType",
+		Description: "A scout's simulated allocation next to the committed one",
+		Fields: graphql.Fields{
+			"uid":       &graphql.Field{Type: graphql.String},
+			"simulated": &graphql.Field{Type: allocationType},
+			"committed": &graphql.Field{Type: allocationType},
+			"totalDelta": &graphql.Field{
+				Type:        graphql.String,
+				Description: "Simulated allocationsTotal less the committed one",
+			},
+		},
+	})
+	allocationSimulationType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "AllocationSimulationType",
+		Description: "Closeout worked out with a what-if allocation policy",
+		Fields: graphql.Fields{
+			"policy":           &graphql.Field{Type: allocationPolicyType},
+			"finalizationData": &graphql.Field{Type: finalizationDataConfigType},
+			"allocations":      &graphql.Field{Type: graphql.NewList(allocationSimulationItemType)},
+			"totalDelta":       &graphql.Field{Type: graphql.String},
+		},
+	})
+	queryFields["simulateAllocations"] = &graphql.Field{
+		Type:        allocationSimulationType,
+		Description: "Works out the allocations with policy and compares them with the committed ones (admin only)",
+		Args: graphql.FieldConfigArgument{
+			"policy": &graphql.ArgumentConfig{
+				Description: "Overrides the fields it sets of the config's allocation policy",
+				Type:        allocationPolicyInputType,
+			},
+			"bankDeposited": closeoutArgs["bankDeposited"],
+			"mulchCost":     closeoutArgs["mulchCost"],
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			var policy *AllocationPolicyType
+			if val, ok := p.Args["policy"]; ok && val != nil {
+				jsonString, err := json.Marshal(val)
+				if err != nil {
+					return nil, err
+				}
+				policy = &AllocationPolicyType{}
+				if err := json.Unmarshal(jsonString, policy); err != nil {
+					return nil, err
+				}
+			}
+			var bankDeposited, mulchCost *string
+			if val, ok := p.Args["bankDeposited"].(string); ok {
+				bankDeposited = &val
+			}
+			if val, ok := p.Args["mulchCost"].(string); ok {
+				mulchCost = &val
+			}
+			simulation, err := SimulateAllocations(p.Context, policy, bankDeposited, mulchCost)
+			if err != nil {
+				return nil, err
+			}
+			return simulation, nil
+		},
+	}
+
+	//////////////////////////////////////////////////////////////////////////////
+	// Bank Deposits
+	bankDepositItemType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "BankDepositItemType",
+		Description: "Money of an order in a deposit.  checkNumber is empty when it is all of the order's money",
+		Fields: graphql.Fields{
+			"orderId":          &graphql.Field{Type: graphql.String},
+			"checkNumber":      &graphql.Field{Type: graphql.String},
+			"amountFromCash":   &graphql.Field{Type: graphql.String},
+			"amountFromChecks": &graphql.Field{Type: graphql.String},
+		},
+	})
+	bankDepositType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "BankDepositType",
+		Description: "Deposit in the bank deposit ledger",
+		Fields: graphql.Fields{
+			"id":               &graphql.Field{Type: graphql.String},
+			"depositDate":      &graphql.Field{Type: graphql.String},
+			"amountFromCash":   &graphql.Field{Type: graphql.String},
+			"amountFromChecks": &graphql.Field{Type: graphql.String},
+			"amountTotal":      &graphql.Field{Type: graphql.String},
+			"amountUnmatched": &graphql.Field{
+				Type:        graphql.String,
+				Description: "Part of the deposit not accounted for by its items",
+			},
+			"comments":    &graphql.Field{Type: graphql.String},
+			"createdBy":   &graphql.Field{Type: graphql.String},
+			"createdTime": &graphql.Field{Type: graphql.String},
+			"items":       &graphql.Field{Type: graphql.NewList(bankDepositItemType)},
+		},
+	})
+	queryFields["bankDeposits"] = &graphql.Field{
+		Type:        graphql.NewList(bankDepositType),
+		Description: "Bank deposit ledger oldest first (admin only)",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			deposits, err := GetBankDeposits(p.Context)
+			if err != nil {
+				return nil, err
+			}
+			return deposits, nil
+		},
+	}
+	depositReconciliationRowType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "DepositReconciliationRowType",
+		Description: "Money collected against money deposited for a seller or a delivery",
+		Fields: graphql.Fields{
+			"ownerId":                   &graphql.Field{Type: graphql.String},
+			"deliveryId":                &graphql.Field{Type: graphql.Int},
+			"amountFromCashCollected":   &graphql.Field{Type: graphql.String},
+			"amountFromChecksCollected": &graphql.Field{Type: graphql.String},
+			"amountTotalCollected":      &graphql.Field{Type: graphql.String},
+			"amountFromCashDeposited":   &graphql.Field{Type: graphql.String},
+			"amountFromChecksDeposited": &graphql.Field{Type: graphql.String},
+			"amountTotalDeposited":      &graphql.Field{Type: graphql.String},
+			"amountUndeposited":         &graphql.Field{Type: graphql.String},
+		},
+	})
+	queryFields["depositReconciliation"] = &graphql.Field{
+		Type: graphql.NewObject(graphql.ObjectConfig{
+			Name:        "DepositReconciliationType",
+			Description: "Collected but undeposited money",
+			Fields: graphql.Fields{
+				"sellers":    &graphql.Field{Type: graphql.NewList(depositReconciliationRowType)},
+				"deliveries": &graphql.Field{Type: graphql.NewList(depositReconciliationRowType)},
+				"totals":     &graphql.Field{Type: depositReconciliationRowType},
+			},
+		}),
+		Description: "Collected but undeposited money per seller and per delivery",
+		Args: graphql.FieldConfigArgument{
+			"ownerId": &graphql.ArgumentConfig{
+				Description: "Only this seller's orders.  Leaving it out is admin only",
+				Type:        graphql.String,
+			},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			ownerId, _ := p.Args["ownerId"].(string)
+			reconciliation, err := GetDepositReconciliation(p.Context, ownerId)
+			if err != nil {
+				return nil, err
+			}
+			return reconciliation, nil
+		},
+	}
+	bankDepositCheckInputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "BankDepositCheckInputType",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"orderId":     &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"checkNumber": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"amount":      &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+	bankDepositInputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "BankDepositInputType",
+		Description: "Deposit to add to the ledger",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"depositDate": &graphql.InputObjectFieldConfig{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "YYYY-MM-DD",
+			},
+			"amountFromCash":   &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"amountFromChecks": &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"comments":         &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"orderIds": &graphql.InputObjectFieldConfig{
+				Type:        graphql.NewList(graphql.String),
+				Description: "Orders whose cash and checks all went into the deposit",
+			},
+			"checks": &graphql.InputObjectFieldConfig{
+				Type:        graphql.NewList(bankDepositCheckInputType),
+				Description: "Checks that went into the deposit on their own",
+			},
+		},
+	})
+	mutationFields["recordBankDeposit"] = &graphql.Field{
+		Type:        graphql.String,
+		Description: "Adds a deposit to the bank deposit ledger and returns its id (admin only)",
+		Args: graphql.FieldConfigArgument{
+			"deposit": &graphql.ArgumentConfig{Type: graphql.NewNonNull(bankDepositInputType)},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			jsonString, err := json.Marshal(p.Args["deposit"])
+			if err != nil {
+				return nil, err
+			}
+			params := BankDepositParams{}
+			if err := json.Unmarshal(jsonString, &params); err != nil {
+				return nil, err
+			}
+			depositId, err := RecordBankDeposit(p.Context, params)
+			if err != nil {
+				return nil, err
+			}
+			return depositId, nil
+		},
+	}
+	mutationFields["deleteBankDeposit"] = &graphql.Field{
+		Type:        graphql.Boolean,
+		Description: "Removes a deposit recorded by mistake (admin only)",
+		Args: graphql.FieldConfigArgument{
+			"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return DeleteBankDeposit(p.Context, p.Args["id"].(string))
+		},
+	}
+
+	//////////////////////////////////////////////////////////////////////////////
+	// Bounced Checks and Notifications
+	mutationFields["markCheckBounced"] = &graphql.Field{
+		Type: graphql.Boolean,
+		Description: "Marks a check of an order as bounced.  The check amount comes off the order's " +
+			"amountFromChecksCollected, the order is set to willCollectMoneyLater and its owner is notified (admin only)",
+		Args: graphql.FieldConfigArgument{
+			"orderId":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			"checkNumber": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return MarkCheckBounced(p.Context, p.Args["orderId"].(string), p.Args["checkNumber"].(string))
+		},
+	}
+
+	notificationType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "NotificationType",
+		Description: "Message to a user about one of their records",
+		Fields: graphql.Fields{
+			"id":          &graphql.Field{Type: graphql.String},
+			"uid":         &graphql.Field{Type: graphql.String},
+			"kind":        &graphql.Field{Type: graphql.String},
+			"message":     &graphql.Field{Type: graphql.String},
+			"orderId":     &graphql.Field{Type: graphql.String},
+			"createdTime": &graphql.Field{Type: graphql.String},
+			"isRead":      &graphql.Field{Type: graphql.Boolean},
+		},
+	})
+	queryFields["notifications"] = &graphql.Field{
+		Type:        graphql.NewList(notificationType),
+		Description: "Retrieves the notifications of a user newest first (that user or admin)",
+		Args: graphql.FieldConfigArgument{
+			"uid": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			"isUnreadOnly": &graphql.ArgumentConfig{
+				Description: "Only returns the notifications that haven't been read",
+				Type:        graphql.Boolean,
+			},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			isUnreadOnly, _ := p.Args["isUnreadOnly"].(bool)
+			return GetNotifications(p.Context, p.Args["uid"].(string), isUnreadOnly)
+		},
+	}
+	mutationFields["markNotificationRead"] = &graphql.Field{
+		Type:        graphql.Boolean,
+		Description: "Marks a notification as read (its user or admin)",
+		Args: graphql.FieldConfigArgument{
+			"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return MarkNotificationRead(p.Context, p.Args["id"].(string))
+		},
+	}
 
-	FrSchema, _ = graphql.NewSchema(schemaConfig)
+	//////////////////////////////////////////////////////////////////////////////
+	// Outstanding Balances
+	outstandingAgingType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "OutstandingAgingType",
+		Description: "Money owed that has been outstanding for the aging bucket's number of days",
+		Fields: graphql.Fields{
+			"agingBucket": &graphql.Field{Type: graphql.String},
+			"numOrders":   &graphql.Field{Type: graphql.Int},
+			"amountOwed":  &graphql.Field{Type: graphql.String},
+		},
+	})
+	outstandingBalanceRowType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "OutstandingBalanceRowType",
+		Description: "Money owed for a seller or a delivery",
+		Fields: graphql.Fields{
+			"ownerId":    &graphql.Field{Type: graphql.String},
+			"deliveryId": &graphql.Field{Type: graphql.Int},
+			"numOrders":  &graphql.Field{Type: graphql.Int},
+			"amountOwed": &graphql.Field{Type: graphql.String},
+			"aging":      &graphql.Field{Type: graphql.NewList(outstandingAgingType)},
+		},
+	})
+	outstandingOrderType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "OutstandingOrderType",
+		Description: "Order that still owes money",
+		Fields: graphql.Fields{
+			"orderId":               &graphql.Field{Type: graphql.String},
+			"ownerId":               &graphql.Field{Type: graphql.String},
+			"deliveryId":            &graphql.Field{Type: graphql.Int},
+			"customerName":          &graphql.Field{Type: graphql.String},
+			"customerPhone":         &graphql.Field{Type: graphql.String},
+			"amountDue":             &graphql.Field{Type: graphql.String},
+			"amountCollected":       &graphql.Field{Type: graphql.String},
+			"amountOwed":            &graphql.Field{Type: graphql.String},
+			"willCollectMoneyLater": &graphql.Field{Type: graphql.Boolean},
+			"lastModifiedTime":      &graphql.Field{Type: graphql.String},
+			"daysOutstanding":       &graphql.Field{Type: graphql.Int},
+			"agingBucket":           &graphql.Field{Type: graphql.String},
+		},
+	})
+	queryFields["outstandingBalances"] = &graphql.Field{
+		Type: graphql.NewObject(graphql.ObjectConfig{
+			Name:        "OutstandingBalancesType",
+			Description: "Money still owed on orders",
+			Fields: graphql.Fields{
+				"orders":     &graphql.Field{Type: graphql.NewList(outstandingOrderType)},
+				"sellers":    &graphql.Field{Type: graphql.NewList(outstandingBalanceRowType)},
+				"deliveries": &graphql.Field{Type: graphql.NewList(outstandingBalanceRowType)},
+				"totals":     &graphql.Field{Type: outstandingBalanceRowType},
+			},
+		}),
+		Description: "Orders that still owe money (purchases + donations - cash and checks collected) per seller and " +
+			"per delivery, aged by when the order was last changed",
+		Args: graphql.FieldConfigArgument{
+			"ownerId": &graphql.ArgumentConfig{
+				Description: "Only this seller's orders.  Leaving it out is admin only",
+				Type:        graphql.String,
+			},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			ownerId, _ := p.Args["ownerId"].(string)
+			balances, err := GetOutstandingBalances(p.Context, ownerId)
+			if err != nil {
+				return nil, err
+			}
+			return balances, nil
+		},
+	}
+	mutationFields["recordOrderPayment"] = &graphql.Field{
+		Type:        graphql.String,
+		Description: "Adds a later payment to the money collected on an order and returns what is still owed",
+		Args: graphql.FieldConfigArgument{
+			"orderId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			"payment": &graphql.ArgumentConfig{
+				Type: graphql.NewNonNull(graphql.NewInputObject(graphql.InputObjectConfig{
+					Name:        "OrderPaymentInputType",
+					Description: "Cash and/or a check paid after the order was taken",
+					Fields: graphql.InputObjectConfigFieldMap{
+						"amountFromCash": &graphql.InputObjectFieldConfig{Type: graphql.String},
+						"check":          &graphql.InputObjectFieldConfig{Type: orderCheckInputType},
+					},
+				})),
+			},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			jsonString, err := json.Marshal(p.Args["payment"])
+			if err != nil {
+				return nil, err
+			}
+			payment := OrderPaymentParams{}
+			if err := json.Unmarshal(jsonString, &payment); err != nil {
+				return nil, err
+			}
+			amountOwed, err := RecordOrderPayment(p.Context, p.Args["orderId"].(string), payment)
+			if err != nil {
+				return nil, err
+			}
+			return amountOwed, nil
+		},
+	}
+
+	//////////////////////////////////////////////////////////////////////////////
+	// Delivery Routes
+	routePointType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "RoutePointType",
+		Fields: graphql.Fields{
+			"lat": &graphql.Field{Type: graphql.Float},
+			"lng": &graphql.Field{Type: graphql.Float},
+			"isEstimated": &graphql.Field{
+				Type:        graphql.Boolean,
+				Description: "The distribution point has no known address so the middle of the stops is used",
+			},
+		},
+	})
+	deliveryStopType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "DeliveryStopType",
+		Description: "Order to drop bags off at",
+		Fields: graphql.Fields{
+			"sequence":            &graphql.Field{Type: graphql.Int},
+			"orderId":             &graphql.Field{Type: graphql.String},
+			"customerName":        &graphql.Field{Type: graphql.String},
+			"addr1":               &graphql.Field{Type: graphql.String},
+			"addr2":               &graphql.Field{Type: graphql.String},
+			"city":                &graphql.Field{Type: graphql.String},
+			"zipcode":             &graphql.Field{Type: graphql.Int},
+			"phone":               &graphql.Field{Type: graphql.String},
+			"neighborhood":        &graphql.Field{Type: graphql.String},
+			"specialInstructions": &graphql.Field{Type: graphql.String},
+			"numBags":             &graphql.Field{Type: graphql.Int},
+			"lat":                 &graphql.Field{Type: graphql.Float},
+			"lng":                 &graphql.Field{Type: graphql.Float},
+		},
+	})
+	deliveryRouteType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "DeliveryRouteType",
+		Description: "Stops of one vehicle in delivery order",
+		Fields: graphql.Fields{
+			"vehicle":  &graphql.Field{Type: graphql.Int},
+			"numStops": &graphql.Field{Type: graphql.Int},
+			"numBags":  &graphql.Field{Type: graphql.Int},
+			"distanceMiles": &graphql.Field{
+				Type:        graphql.String,
+				Description: "Straight line miles from the distribution point through the stops",
+			},
+			"stops": &graphql.Field{Type: graphql.NewList(deliveryStopType)},
+		},
+	})
+	queryFields["deliveryRoutes"] = &graphql.Field{
+		Type: graphql.NewObject(graphql.ObjectConfig{
+			Name:        "DeliveryRoutesType",
+			Description: "Delivery routes from a distribution point",
+			Fields: graphql.Fields{
+				"deliveryId":        &graphql.Field{Type: graphql.Int},
+				"distributionPoint": &graphql.Field{Type: graphql.String},
+				"start":             &graphql.Field{Type: routePointType},
+				"numBags":           &graphql.Field{Type: graphql.Int},
+				"routes":            &graphql.Field{Type: graphql.NewList(deliveryRouteType)},
+				"unroutedStops": &graphql.Field{
+					Type:        graphql.NewList(deliveryStopType),
+					Description: "Orders whose address has no known coordinates",
+				},
+			},
+		}),
+		Description: "Splits the orders of a delivery going to a distribution point between vehicles and orders " +
+			"their stops.  Only stored coordinates are used (admin only)",
+		Args: graphql.FieldConfigArgument{
+			"deliveryId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+			"distributionPoint": &graphql.ArgumentConfig{
+				Description: "Orders from neighborhoods with this distribution point are routed",
+				Type:        graphql.NewNonNull(graphql.String),
+			},
+			"numVehicles": &graphql.ArgumentConfig{
+				Type:         graphql.Int,
+				DefaultValue: 1,
+			},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			numVehicles, _ := p.Args["numVehicles"].(int)
+			routes, err := GetDeliveryRoutes(p.Context, p.Args["deliveryId"].(int), p.Args["distributionPoint"].(string), numVehicles)
+			if err != nil {
+				return nil, err
+			}
+			return routes, nil
+		},
+	}
+
+	//////////////////////////////////////////////////////////////////////////////
+	// Delivery Manifest
+	manifestOrderType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "ManifestOrderType",
+		Description: "Order to load for a delivery",
+		Fields: graphql.Fields{
+			"orderId":             &graphql.Field{Type: graphql.String},
+			"customerName":        &graphql.Field{Type: graphql.String},
+			"addr1":               &graphql.Field{Type: graphql.String},
+			"addr2":               &graphql.Field{Type: graphql.String},
+			"city":                &graphql.Field{Type: graphql.String},
+			"phone":               &graphql.Field{Type: graphql.String},
+			"specialInstructions": &graphql.Field{Type: graphql.String},
+			"numBags":             &graphql.Field{Type: graphql.Int},
+			"numBagsToSpread":     &graphql.Field{Type: graphql.Int},
+			"purchases":           &graphql.Field{Type: graphql.NewList(productType)},
+		},
+	})
+	manifestNeighborhoodType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ManifestNeighborhoodType",
+		Fields: graphql.Fields{
+			"neighborhood":    &graphql.Field{Type: graphql.String},
+			"numBags":         &graphql.Field{Type: graphql.Int},
+			"numBagsToSpread": &graphql.Field{Type: graphql.Int},
+			"orders":          &graphql.Field{Type: graphql.NewList(manifestOrderType)},
+		},
+	})
+	manifestDistributionPointType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ManifestDistributionPointType",
+		Fields: graphql.Fields{
+			"distributionPoint": &graphql.Field{
+				Type:        graphql.String,
+				Description: "Empty for neighborhoods without a distribution point",
+			},
+			"numBags":         &graphql.Field{Type: graphql.Int},
+			"numBagsToSpread": &graphql.Field{Type: graphql.Int},
+			"neighborhoods":   &graphql.Field{Type: graphql.NewList(manifestNeighborhoodType)},
+		},
+	})
+	queryFields["deliveryManifest"] = &graphql.Field{
+		Type: graphql.NewObject(graphql.ObjectConfig{
+			Name:        "DeliveryManifestType",
+			Description: "What is loaded for a delivery by distribution point and neighborhood",
+			Fields: graphql.Fields{
+				"deliveryId":         &graphql.Field{Type: graphql.Int},
+				"deliveryDate":       &graphql.Field{Type: graphql.String},
+				"numOrders":          &graphql.Field{Type: graphql.Int},
+				"numBags":            &graphql.Field{Type: graphql.Int},
+				"numBagsToSpread":    &graphql.Field{Type: graphql.Int},
+				"distributionPoints": &graphql.Field{Type: graphql.NewList(manifestDistributionPointType)},
+			},
+		}),
+		Description: "Bags (and how many to spread) for each distribution point and neighborhood of a delivery " +
+			"with the orders (admin only)",
+		Args: graphql.FieldConfigArgument{
+			"deliveryId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			manifest, err := GetDeliveryManifest(p.Context, p.Args["deliveryId"].(int))
+			if err != nil {
+				return nil, err
+			}
+			return manifest, nil
+		},
+	}
+
+	//////////////////////////////////////////////////////////////////////////////
+	// Mulch Inventory
+	mulchInventoryType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "MulchInventoryType",
+		Description: "Mulch ordered from the supplier, received and left over for a delivery",
+		Fields: graphql.Fields{
+			"deliveryId":       &graphql.Field{Type: graphql.Int},
+			"supplier":         &graphql.Field{Type: graphql.String},
+			"bagsOrdered":      &graphql.Field{Type: graphql.Int},
+			"pallets":          &graphql.Field{Type: graphql.Int},
+			"bagsReceived":     &graphql.Field{Type: graphql.Int},
+			"bagsLeftover":     &graphql.Field{Type: graphql.Int},
+			"bagsDamaged":      &graphql.Field{Type: graphql.Int},
+			"mulchCost":        &graphql.Field{Type: graphql.String},
+			"comments":         &graphql.Field{Type: graphql.String},
+			"lastModifiedBy":   &graphql.Field{Type: graphql.String},
+			"lastModifiedTime": &graphql.Field{Type: graphql.String},
+		},
+	})
+	queryFields["mulchInventory"] = &graphql.Field{
+		Type:        graphql.NewList(mulchInventoryType),
+		Description: "Mulch inventory records by delivery (admin only)",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			inventories, err := GetMulchInventories(p.Context)
+			if err != nil {
+				return nil, err
+			}
+			return inventories, nil
+		},
+	}
+	mulchInventoryReportRowType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "MulchInventoryReportRowType",
+		Description: "Mulch inventory of a delivery against the bags sold",
+		Fields: graphql.Fields{
+			"deliveryId":           &graphql.Field{Type: graphql.Int},
+			"hasInventory":         &graphql.Field{Type: graphql.Boolean},
+			"bagsOrdered":          &graphql.Field{Type: graphql.Int},
+			"pallets":              &graphql.Field{Type: graphql.Int},
+			"bagsReceived":         &graphql.Field{Type: graphql.Int},
+			"bagsLeftover":         &graphql.Field{Type: graphql.Int},
+			"bagsDamaged":          &graphql.Field{Type: graphql.Int},
+			"mulchCost":            &graphql.Field{Type: graphql.String},
+			"bagsSold":             &graphql.Field{Type: graphql.Int},
+			"bagsAvailable":        &graphql.Field{Type: graphql.Int},
+			"bagsExpectedLeftover": &graphql.Field{Type: graphql.Int},
+			"bagsUnaccounted":      &graphql.Field{Type: graphql.Int},
+		},
+	})
+	queryFields["mulchInventoryReport"] = &graphql.Field{
+		Type: graphql.NewObject(graphql.ObjectConfig{
+			Name:        "MulchInventoryReportType",
+			Description: "Mulch inventory against the bags sold",
+			Fields: graphql.Fields{
+				"deliveries":              &graphql.Field{Type: graphql.NewList(mulchInventoryReportRowType)},
+				"totals":                  &graphql.Field{Type: mulchInventoryReportRowType},
+				"bagsSoldWithoutDelivery": &graphql.Field{Type: graphql.Int},
+			},
+		}),
+		Description: "Bags ordered, received, damaged and left over for each delivery against the bags sold (admin only)",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			report, err := GetMulchInventoryReport(p.Context)
+			if err != nil {
+				return nil, err
+			}
+			return report, nil
+		},
+	}
+	queryFields["supplierOrderSuggestion"] = &graphql.Field{
+		Type: graphql.NewObject(graphql.ObjectConfig{
+			Name:        "SupplierOrderSuggestionType",
+			Description: "Bags to order from the supplier for a delivery",
+			Fields: graphql.Fields{
+				"deliveryId":            &graphql.Field{Type: graphql.Int},
+				"bagsSold":              &graphql.Field{Type: graphql.Int},
+				"bufferPercentage":      &graphql.Field{Type: graphql.String},
+				"bagsNeeded":            &graphql.Field{Type: graphql.Int},
+				"bagsPerPallet":         &graphql.Field{Type: graphql.Int},
+				"palletsToOrder":        &graphql.Field{Type: graphql.Int},
+				"bagsToOrder":           &graphql.Field{Type: graphql.Int},
+				"bagsAlreadyOrdered":    &graphql.Field{Type: graphql.Int},
+				"additionalBagsToOrder": &graphql.Field{Type: graphql.Int},
+				"supplier":              &graphql.Field{Type: graphql.String},
+			},
+		}),
+		Description: "Suggests the bags to order from the supplier from the bags sold plus a buffer (admin only)",
+		Args: graphql.FieldConfigArgument{
+			"deliveryId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+			"bufferPercentage": &graphql.ArgumentConfig{
+				Description: "Defaults to the config's inventoryPolicy",
+				Type:        graphql.String,
+			},
+			"bagsPerPallet": &graphql.ArgumentConfig{
+				Description: "Defaults to the config's inventoryPolicy",
+				Type:        graphql.Int,
+			},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			var bufferPercentage *string
+			var bagsPerPallet *int
+			if val, ok := p.Args["bufferPercentage"].(string); ok {
+				bufferPercentage = &val
+			}
+			if val, ok := p.Args["bagsPerPallet"].(int); ok {
+				bagsPerPallet = &val
+			}
+			suggestion, err := GetSupplierOrderSuggestion(p.Context, p.Args["deliveryId"].(int), bufferPercentage, bagsPerPallet)
+			if err != nil {
+				return nil, err
+			}
+			return suggestion, nil
+		},
+	}
+	mulchInventoryInputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "MulchInventoryInputType",
+		Description: "Mulch inventory of a delivery.  Replaces the delivery's existing record",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"deliveryId":   &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Int)},
+			"supplier":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"bagsOrdered":  &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"pallets":      &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"bagsReceived": &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"bagsLeftover": &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"bagsDamaged":  &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"mulchCost": &graphql.InputObjectFieldConfig{
+				Type:        graphql.String,
+				Description: "What the supplier charged for the delivery's mulch.  Closeout uses the total of these",
+			},
+			"comments": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		},
+	})
+	mutationFields["setMulchInventory"] = &graphql.Field{
+		Type:        graphql.Boolean,
+		Description: "Adds or replaces the mulch inventory of a delivery (admin only)",
+		Args: graphql.FieldConfigArgument{
+			"inventory": &graphql.ArgumentConfig{Type: graphql.NewNonNull(mulchInventoryInputType)},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			jsonString, err := json.Marshal(p.Args["inventory"])
+			if err != nil {
+				return nil, err
+			}
+			inventory := MulchInventoryType{}
+			if err := json.Unmarshal(jsonString, &inventory); err != nil {
+				return nil, err
+			}
+			return SetMulchInventory(p.Context, inventory)
+		},
+	}
+	mutationFields["deleteMulchInventory"] = &graphql.Field{
+		Type:        graphql.Boolean,
+		Description: "Removes the mulch inventory of a delivery (admin only)",
+		Args: graphql.FieldConfigArgument{
+			"deliveryId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return DeleteMulchInventory(p.Context, p.Args["deliveryId"].(int))
+		},
+	}
+
+	//////////////////////////////////////////////////////////////////////////////
+	// Audit Log
+	auditLogEntryType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "AuditLogEntryType",
+		Description: "Change made by a mutation",
+		Fields: graphql.Fields{
+			"id":          &graphql.Field{Type: graphql.String},
+			"createdTime": &graphql.Field{Type: graphql.String},
+			"actorId":     &graphql.Field{Type: graphql.String},
+			"operation":   &graphql.Field{Type: graphql.String},
+			"targetKind":  &graphql.Field{Type: graphql.String},
+			"targetKey":   &graphql.Field{Type: graphql.String},
+			"diff": &graphql.Field{
+				Type:        graphql.String,
+				Description: "JSON object of {field: {before, after}} for the fields that changed",
+			},
+		},
+	})
+	queryFields["auditLog"] = &graphql.Field{
+		Type:        graphql.NewList(auditLogEntryType),
+		Description: "Retrieves the audit log newest first (admin only)",
+		Args: graphql.FieldConfigArgument{
+			"actorId": &graphql.ArgumentConfig{
+				Description: "Narrows the search for changes made by this user id",
+				Type:        graphql.String,
+			},
+			"operation": &graphql.ArgumentConfig{
+				Description: "Narrows the search for this mutation, i.e. updateMulchOrder",
+				Type:        graphql.String,
+			},
+			"targetKind": &graphql.ArgumentConfig{
+				Description: "Narrows the search for this kind of record, i.e. mulchOrder",
+				Type:        graphql.String,
+			},
+			"targetKey": &graphql.ArgumentConfig{
+				Description: "Narrows the search for this record, i.e. the orderId",
+				Type:        graphql.String,
+			},
+			"after": &graphql.ArgumentConfig{
+				Description: "RFC3339 time. Narrows the search for changes at or after this time",
+				Type:        graphql.String,
+			},
+			"before": &graphql.ArgumentConfig{
+				Description: "RFC3339 time. Narrows the search for changes before this time",
+				Type:        graphql.String,
+			},
+			"limit": &graphql.ArgumentConfig{
+				Description:  "Max number of entries to return",
+				Type:         graphql.Int,
+				DefaultValue: 100,
+			},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			params := GetAuditLogParams{}
+			if val, ok := p.Args["actorId"]; ok {
+				params.ActorId = val.(string)
+			}
+			if val, ok := p.Args["operation"]; ok {
+				params.Operation = val.(string)
+			}
+			if val, ok := p.Args["targetKind"]; ok {
+				params.TargetKind = val.(string)
+			}
+			if val, ok := p.Args["targetKey"]; ok {
+				params.TargetKey = val.(string)
+			}
+			if val, ok := p.Args["after"]; ok {
+				after, err := time.Parse(time.RFC3339, val.(string))
+				if err != nil {
+					return nil, newValidationError("after", "after is not a RFC3339 time: %s", err)
+				}
+				params.After = &after
+			}
+			if val, ok := p.Args["before"]; ok {
+				before, err := time.Parse(time.RFC3339, val.(string))
+				if err != nil {
+					return nil, newValidationError("before", "before is not a RFC3339 time: %s", err)
+				}
+				params.Before = &before
+			}
+			if val, ok := p.Args["limit"]; ok {
+				params.Limit = val.(int)
+			}
+			return GetAuditLog(p.Context, params)
+		},
+	}
+
+	//////////////////////////////////////////////////////////////////////////////
+	// Geolocation Address Type
+	addressType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "AddressType",
+		Description: "Address Information",
+		Fields: graphql.Fields{
+			"zipcode":     &graphql.Field{Type: graphql.Int},
+			"city":        &graphql.Field{Type: graphql.String},
+			"houseNumber": &graphql.Field{Type: graphql.String},
+			"street":      &graphql.Field{Type: graphql.String},
+		},
+	})
+	queryFields["getAddress"] = &graphql.Field{
+		Type:        addressType,
+		Description: "Retrieves Address from geo information",
+		Args: graphql.FieldConfigArgument{
+			"lat": &graphql.ArgumentConfig{
+				Description: "Latitude",
+				Type:        graphql.NewNonNull(graphql.Float),
+			},
+			"lng": &graphql.ArgumentConfig{
+				Description: "Longitude",
+				Type:        graphql.NewNonNull(graphql.Float),
+			},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			var lat, lng float64
+			if val, ok := p.Args["lat"]; ok {
+				lat = val.(float64)
+			}
+			if val, ok := p.Args["lng"]; ok {
+				lng = val.(float64)
+			}
+			return GetAddrFromLatLng(p.Context, lat, lng)
+		},
+	}
+
+	mutationFields["geocodeOrderAddrs"] = &graphql.Field{
+		Type: graphql.Int,
+		Description: "Geocodes the addresses of the orders not linked to a known address and links them. " +
+			"Returns the number of orders linked (admin only)",
+		Args: graphql.FieldConfigArgument{
+			"limit": &graphql.ArgumentConfig{
+				Description: "Most orders to geocode.  All of them when not given",
+				Type:        graphql.Int,
+			},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			limit := 0
+			if val, ok := p.Args["limit"]; ok {
+				limit = val.(int)
+			}
+			return GeocodeOrderAddrs(p.Context, limit)
+		},
+	}
+
+	schemaConfig := graphql.SchemaConfig{
+		Query:    graphql.NewObject(graphql.ObjectConfig{Name: "Query", Fields: graphql.Fields(queryFields)}),
+		Mutation: graphql.NewObject(graphql.ObjectConfig{Name: "Mutation", Fields: graphql.Fields(mutationFields)}),
+	}
+
+	var err error
+	FrSchema, err = graphql.NewSchema(schemaConfig)
+	if err != nil {
+		// Without this variables can't be resolved since the type map is left empty
+		log.Panic("Failed to build GraphQL schema: ", err)
+	}
 }
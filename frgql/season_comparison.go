@@ -0,0 +1,363 @@
+package frgql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// //////////////////////////////////////////////////////////////////////////
+// The numbers of one season for a scout, patrol, neighborhood or the troop.
+// The *Change fields are the percentage change from the season before it in
+// the comparison.  They are nil for the first season or when the season
+// before it had none.
+type SeasonStatsType struct {
+	Season                     int     `json:"season"`
+	NumOrders                  int     `json:"numOrders"`
+	BagsSold                   int     `json:"bagsSold"`
+	BagsToSpread               int     `json:"bagsToSpread"`
+	BagsSpread                 string  `json:"bagsSpread"`
+	DeliveryMinutes            int     `json:"deliveryMinutes"`
+	Donations                  string  `json:"donations"`
+	TotalAmountCollected       string  `json:"totalAmountCollected"`
+	BagsSoldChange             *string `json:"bagsSoldChange"`
+	BagsToSpreadChange         *string `json:"bagsToSpreadChange"`
+	BagsSpreadChange           *string `json:"bagsSpreadChange"`
+	DonationsChange            *string `json:"donationsChange"`
+	TotalAmountCollectedChange *string `json:"totalAmountCollectedChange"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Key is the scout id, patrol (group) or neighborhood.  Seasons has an entry
+// for every season in the comparison oldest first.
+type SeasonComparisonRowType struct {
+	Key     string            `json:"key"`
+	Name    string            `json:"name"`
+	Seasons []SeasonStatsType `json:"seasons"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+type SeasonComparisonType struct {
+	Seasons       []int                     `json:"seasons"`
+	Troop         []SeasonStatsType         `json:"troop"`
+	Scouts        []SeasonComparisonRowType `json:"scouts"`
+	Patrols       []SeasonComparisonRowType `json:"patrols"`
+	Neighborhoods []SeasonComparisonRowType `json:"neighborhoods"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// The records of one season a comparison is made from
+type seasonComparisonSource struct {
+	season    int
+	orders    []MulchOrderType
+	timecards []MulchTimecardType
+	users     []UserInfo
+}
+
+// //////////////////////////////////////////////////////////////////////////
+type seasonStatsTotals struct {
+	numOrders       int
+	bagsSold        int
+	bagsToSpread    int
+	bagsSpread      decimal.Decimal
+	deliveryTime    time.Duration
+	donations       decimal.Decimal
+	amountCollected decimal.Decimal
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (t *seasonStatsTotals) toStats(season int) SeasonStatsType {
+	return SeasonStatsType{
+		Season:               season,
+		NumOrders:            t.numOrders,
+		BagsSold:             t.bagsSold,
+		BagsToSpread:         t.bagsToSpread,
+		BagsSpread:           t.bagsSpread.RoundBank(2).String(),
+		DeliveryMinutes:      int(math.Floor(t.deliveryTime.Minutes())),
+		Donations:            t.donations.StringFixedBank(4),
+		TotalAmountCollected: t.amountCollected.StringFixedBank(4),
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Totals of one season keyed by scout, patrol and neighborhood
+type seasonComparisonTotals struct {
+	troop         seasonStatsTotals
+	scouts        map[string]*seasonStatsTotals
+	patrols       map[string]*seasonStatsTotals
+	neighborhoods map[string]*seasonStatsTotals
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func getSeasonStatsTotals(totals map[string]*seasonStatsTotals, key string) *seasonStatsTotals {
+	t, ok := totals[key]
+	if !ok {
+		t = &seasonStatsTotals{}
+		totals[key] = t
+	}
+	return t
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the percentage change from prev to cur or nil if prev is 0
+func calcPercentChange(prev decimal.Decimal, cur decimal.Decimal) *string {
+	if prev.IsZero() {
+		return nil
+	}
+	change := cur.Sub(prev).Div(prev).Mul(decimal.NewFromInt(100)).StringFixedBank(2)
+	return &change
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Fills in the *Change fields of stats which are oldest first
+func setSeasonStatsChanges(stats []SeasonStatsType) {
+	for idx := 1; idx < len(stats); idx++ {
+		prev, cur := stats[idx-1], &stats[idx]
+		prevBagsSpread, _ := decimal.NewFromString(prev.BagsSpread)
+		curBagsSpread, _ := decimal.NewFromString(cur.BagsSpread)
+		prevDonations, _ := decimal.NewFromString(prev.Donations)
+		curDonations, _ := decimal.NewFromString(cur.Donations)
+		prevTotal, _ := decimal.NewFromString(prev.TotalAmountCollected)
+		curTotal, _ := decimal.NewFromString(cur.TotalAmountCollected)
+
+		cur.BagsSoldChange = calcPercentChange(decimal.NewFromInt(int64(prev.BagsSold)), decimal.NewFromInt(int64(cur.BagsSold)))
+		cur.BagsToSpreadChange = calcPercentChange(
+			decimal.NewFromInt(int64(prev.BagsToSpread)), decimal.NewFromInt(int64(cur.BagsToSpread)))
+		cur.BagsSpreadChange = calcPercentChange(prevBagsSpread, curBagsSpread)
+		cur.DonationsChange = calcPercentChange(prevDonations, curDonations)
+		cur.TotalAmountCollectedChange = calcPercentChange(prevTotal, curTotal)
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Adds up a season.  Orders count towards the troop, their owner and the
+// customer's neighborhood.  Spreading is credited evenly to the spreaders
+// of an order.  Patrols come from the users of that season so orders of
+// owners who aren't users aren't in any patrol.
+func calcSeasonComparisonTotals(source seasonComparisonSource) (seasonComparisonTotals, error) {
+	totals := seasonComparisonTotals{
+		scouts:        make(map[string]*seasonStatsTotals),
+		patrols:       make(map[string]*seasonStatsTotals),
+		neighborhoods: make(map[string]*seasonStatsTotals),
+	}
+	patrolByUid := make(map[string]string)
+	for _, user := range source.users {
+		patrolByUid[user.Id] = user.Group
+	}
+
+	// Every total the records of a scout count towards
+	getScoutTotals := func(uid string) []*seasonStatsTotals {
+		scoutTotals := []*seasonStatsTotals{&totals.troop, getSeasonStatsTotals(totals.scouts, uid)}
+		if patrol, ok := patrolByUid[uid]; ok {
+			scoutTotals = append(scoutTotals, getSeasonStatsTotals(totals.patrols, patrol))
+		}
+		return scoutTotals
+	}
+
+	for _, order := range source.orders {
+		donations, err := parseAmount(order.AmountFromDonations)
+		if err != nil {
+			return totals, fmt.Errorf("season: %d order: %s amountFromDonations: %w", source.season, order.OrderId, err)
+		}
+		amountCollected, err := parseAmount(order.AmountTotalCollected)
+		if err != nil {
+			return totals, fmt.Errorf("season: %d order: %s amountTotalCollected: %w", source.season, order.OrderId, err)
+		}
+		bagsSold, bagsToSpread := 0, 0
+		for _, item := range order.Purchases {
+			switch item.ProductId {
+			case "bags":
+				bagsSold += item.NumSold
+			case "spreading":
+				bagsToSpread += item.NumSold
+			}
+		}
+
+		orderTotals := append(getScoutTotals(order.OwnerId),
+			getSeasonStatsTotals(totals.neighborhoods, order.Customer.Neighborhood))
+		for _, t := range orderTotals {
+			t.numOrders++
+			t.bagsSold += bagsSold
+			t.bagsToSpread += bagsToSpread
+			t.donations = t.donations.Add(donations)
+			t.amountCollected = t.amountCollected.Add(amountCollected)
+		}
+
+		if len(order.Spreaders) == 0 || bagsToSpread == 0 {
+			continue
+		}
+		bagsSpread := decimal.NewFromInt(int64(bagsToSpread))
+		totals.troop.bagsSpread = totals.troop.bagsSpread.Add(bagsSpread)
+		hoodTotals := getSeasonStatsTotals(totals.neighborhoods, order.Customer.Neighborhood)
+		hoodTotals.bagsSpread = hoodTotals.bagsSpread.Add(bagsSpread)
+
+		perSpreader := bagsSpread.Div(decimal.NewFromInt(int64(len(order.Spreaders))))
+		for _, spreader := range order.Spreaders {
+			// The troop already has the whole order
+			for _, t := range getScoutTotals(spreader)[1:] {
+				t.bagsSpread = t.bagsSpread.Add(perSpreader)
+			}
+		}
+	}
+
+	for _, timecard := range source.timecards {
+		duration, err := parseTimecardDuration(timecard.TimeTotal)
+		if err != nil {
+			return totals, fmt.Errorf("season: %d timecard of: %s: %w", source.season, timecard.Id, err)
+		}
+		for _, t := range getScoutTotals(timecard.Id) {
+			t.deliveryTime += duration
+		}
+	}
+	return totals, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Lines up the per key totals of every season into rows sorted by key
+func makeSeasonComparisonRows(
+	seasons []int, seasonTotals []seasonComparisonTotals,
+	getTotals func(totals seasonComparisonTotals) map[string]*seasonStatsTotals,
+	getName func(key string) string,
+) []SeasonComparisonRowType {
+	keys := []string{}
+	for _, totals := range seasonTotals {
+		for key := range getTotals(totals) {
+			if !slices.Contains(keys, key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+	slices.Sort(keys)
+
+	rows := []SeasonComparisonRowType{}
+	for _, key := range keys {
+		row := SeasonComparisonRowType{Key: key, Name: getName(key)}
+		for idx, totals := range seasonTotals {
+			t, ok := getTotals(totals)[key]
+			if !ok {
+				t = &seasonStatsTotals{}
+			}
+			row.Seasons = append(row.Seasons, t.toStats(seasons[idx]))
+		}
+		setSeasonStatsChanges(row.Seasons)
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Reads the records of an archived season or, if it's currentSeason, the
+// live ones
+func getSeasonComparisonSource(ctx context.Context, season int, currentSeason int) (seasonComparisonSource, error) {
+	source := seasonComparisonSource{season: season}
+	var err error
+
+	if season == currentSeason {
+		source.orders, err = frStore.GetMulchOrders(ctx, GetMulchOrdersParams{
+			GqlFields: []string{"orderId", "ownerId", "purchases", "amountFromDonations", "amountTotalCollected",
+				"customer", "spreaders"},
+		})
+		if err != nil {
+			return source, err
+		}
+		source.timecards, err = frStore.GetMulchTimecards(ctx, "", -1, []string{"id", "timeTotal"})
+		if err != nil {
+			return source, err
+		}
+		source.users, err = frStore.GetUsers(ctx, GetUsersParams{GqlFields: []string{"id", "firstName", "lastName", "group"}})
+		return source, err
+	}
+
+	if _, err := frStore.GetSeasonArchive(ctx, season); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return source, &NotFoundError{Message: fmt.Sprintf("season: %d has not been closed or imported", season)}
+		}
+		return source, err
+	}
+	source.orders, err = frStore.GetArchivedMulchOrders(ctx, season, "")
+	if err != nil {
+		return source, err
+	}
+	source.timecards, err = frStore.GetArchivedMulchTimecards(ctx, season, "")
+	if err != nil {
+		return source, err
+	}
+	source.users, err = frStore.GetArchivedUsers(ctx, season)
+	return source, err
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Compares seasons per scout, patrol, neighborhood and for the troop.  With
+// no seasons given every archived season is compared.  currentSeason (if
+// not 0) is the year the live, not yet closed, data is reported as.
+// Admin only
+func GetSeasonComparison(ctx context.Context, seasons []int, currentSeason int) (SeasonComparisonType, error) {
+	log.Printf("Comparing seasons: %v current season: %d", seasons, currentSeason)
+
+	if err := VerifyAdminTokenFromCtx(ctx); err != nil {
+		return SeasonComparisonType{}, err
+	}
+
+	seasons = slices.Clone(seasons)
+	if len(seasons) == 0 {
+		archives, err := frStore.GetSeasonArchives(ctx)
+		if err != nil {
+			return SeasonComparisonType{}, err
+		}
+		for _, archive := range archives {
+			seasons = append(seasons, archive.Season)
+		}
+	}
+	if currentSeason != 0 && !slices.Contains(seasons, currentSeason) {
+		seasons = append(seasons, currentSeason)
+	}
+	slices.Sort(seasons)
+	seasons = slices.Compact(seasons)
+
+	// Names from the newest season a scout is in
+	scoutNames := make(map[string]string)
+	seasonTotals := []seasonComparisonTotals{}
+	for _, season := range seasons {
+		source, err := getSeasonComparisonSource(ctx, season, currentSeason)
+		if err != nil {
+			log.Println("Reading season for comparison failed: ", err)
+			return SeasonComparisonType{}, err
+		}
+		for _, user := range source.users {
+			scoutNames[user.Id] = strings.TrimSpace(user.FirstName + " " + user.LastName)
+		}
+		totals, err := calcSeasonComparisonTotals(source)
+		if err != nil {
+			return SeasonComparisonType{}, err
+		}
+		seasonTotals = append(seasonTotals, totals)
+	}
+
+	comparison := SeasonComparisonType{Seasons: seasons, Troop: []SeasonStatsType{}}
+	for idx, totals := range seasonTotals {
+		comparison.Troop = append(comparison.Troop, totals.troop.toStats(seasons[idx]))
+	}
+	setSeasonStatsChanges(comparison.Troop)
+
+	keyAsName := func(key string) string { return key }
+	comparison.Scouts = makeSeasonComparisonRows(seasons, seasonTotals,
+		func(totals seasonComparisonTotals) map[string]*seasonStatsTotals { return totals.scouts },
+		func(key string) string {
+			if name, ok := scoutNames[key]; ok && len(name) != 0 {
+				return name
+			}
+			return key
+		})
+	comparison.Patrols = makeSeasonComparisonRows(seasons, seasonTotals,
+		func(totals seasonComparisonTotals) map[string]*seasonStatsTotals { return totals.patrols }, keyAsName)
+	comparison.Neighborhoods = makeSeasonComparisonRows(seasons, seasonTotals,
+		func(totals seasonComparisonTotals) map[string]*seasonStatsTotals { return totals.neighborhoods }, keyAsName)
+	return comparison, nil
+}
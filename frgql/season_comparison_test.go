@@ -0,0 +1,82 @@
+package frgql
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// //////////////////////////////////////////////////////////////////////////
+// A closed season compared with the live one for the troop, scouts and
+// patrols
+func TestGetSeasonComparison(t *testing.T) {
+	store := newTestMemStore(t)
+	ctx := context.Background()
+	adminCtx := newTestCtx(t, "admin1", true)
+
+	mustNotFail(t, store.InsertUser(ctx, UserInfo{Id: "scout1", FirstName: "Al", LastName: "Bee", Group: "Eagles"}))
+	mustNotFail(t, store.InsertUser(ctx, UserInfo{Id: "scout2", FirstName: "Cy", LastName: "Dee", Group: "Hawks"}))
+
+	donations := "10"
+	spreadOrder := makeTestOrder(testOrderId1, "scout1")
+	spreadOrder.AmountFromDonations = &donations
+	spreadOrder.Purchases = append(spreadOrder.Purchases, ProductsType{ProductId: "spreading", NumSold: 2, AmountCharged: "0"})
+	mustNotFail(t, store.InsertMulchOrder(ctx, spreadOrder))
+	mustNotFail(t, store.SetSpreaders(ctx, testOrderId1, []string{"scout1", "scout2"}))
+	otherOrder := makeTestOrder(testOrderId2, "scout2")
+	otherOrder.Purchases[0].NumSold = 6
+	mustNotFail(t, store.InsertMulchOrder(ctx, otherOrder))
+	mustNotFail(t, store.InsertMulchTimecard(ctx, MulchTimecardType{Id: "scout1", DeliveryId: 1, TimeTotal: "01:30:00"}))
+	_, err := CloseSeason(adminCtx, 2024)
+	mustNotFail(t, err)
+
+	liveOrder := makeTestOrder(testOrderId3, "scout1")
+	liveOrder.Purchases[0].NumSold = 8
+	mustNotFail(t, store.InsertMulchOrder(ctx, liveOrder))
+
+	comparison, err := GetSeasonComparison(adminCtx, nil, 2025)
+	mustNotFail(t, err)
+	if len(comparison.Seasons) != 2 || comparison.Seasons[0] != 2024 || comparison.Seasons[1] != 2025 {
+		t.Fatalf("seasons: %v", comparison.Seasons)
+	}
+
+	prev, cur := comparison.Troop[0], comparison.Troop[1]
+	if prev.NumOrders != 2 || prev.BagsSold != 10 || prev.BagsToSpread != 2 || prev.BagsSpread != "2" ||
+		prev.DeliveryMinutes != 90 || prev.Donations != "10.0000" || prev.TotalAmountCollected != "48.0000" ||
+		prev.BagsSoldChange != nil {
+		t.Errorf("troop 2024: %+v", prev)
+	}
+	if cur.NumOrders != 1 || cur.BagsSold != 8 || *cur.BagsSoldChange != "-20.00" ||
+		*cur.TotalAmountCollectedChange != "-50.00" || *cur.DonationsChange != "-100.00" || cur.BagsSpreadChange == nil {
+		t.Errorf("troop 2025: %+v", cur)
+	}
+
+	if len(comparison.Scouts) != 2 {
+		t.Fatalf("scouts: %+v", comparison.Scouts)
+	}
+	scout1, scout2 := comparison.Scouts[0], comparison.Scouts[1]
+	// The spreading is split between the spreaders
+	if scout1.Name != "Al Bee" || scout1.Seasons[0].BagsSpread != "1" || scout1.Seasons[0].DeliveryMinutes != 90 ||
+		scout1.Seasons[1].BagsSold != 8 || *scout1.Seasons[1].BagsSoldChange != "100.00" {
+		t.Errorf("scout1: %+v", scout1)
+	}
+	if scout2.Seasons[0].BagsSpread != "1" || scout2.Seasons[0].BagsSold != 6 || scout2.Seasons[1].NumOrders != 0 {
+		t.Errorf("scout2: %+v", scout2)
+	}
+	if len(comparison.Patrols) != 2 || comparison.Patrols[0].Key != "Eagles" || comparison.Patrols[1].Key != "Hawks" ||
+		comparison.Patrols[1].Seasons[0].BagsSold != 6 {
+		t.Errorf("patrols: %+v", comparison.Patrols)
+	}
+	if len(comparison.Neighborhoods) != 1 || comparison.Neighborhoods[0].Seasons[0].BagsSpread != "2" {
+		t.Errorf("neighborhoods: %+v", comparison.Neighborhoods)
+	}
+
+	var forbiddenErr *ForbiddenError
+	if _, err := GetSeasonComparison(newTestCtx(t, "scout1", false), nil, 2025); !errors.As(err, &forbiddenErr) {
+		t.Errorf("a scout comparing seasons returned: %v not a ForbiddenError", err)
+	}
+	var notFoundErr *NotFoundError
+	if _, err := GetSeasonComparison(adminCtx, []int{2019}, 2025); !errors.As(err, &notFoundErr) {
+		t.Errorf("comparing a season that wasn't closed returned: %v not a NotFoundError", err)
+	}
+}
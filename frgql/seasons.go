@@ -0,0 +1,388 @@
+package frgql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+)
+
+// //////////////////////////////////////////////////////////////////////////
+// A season that was closed by closeSeason or loaded by importSeason
+// (IsImported).  Config is the fundraiser config as it was when the season
+// was closed.
+type SeasonArchiveType struct {
+	Season     int          `json:"season"`
+	ClosedTime string       `json:"closedTime"`
+	ClosedBy   string       `json:"closedBy"`
+	IsImported bool         `json:"isImported"`
+	Config     FrConfigType `json:"config"`
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// The records copied into the archive when a season is closed.  Orders
+// include their spreaders and deposits their items.
+type SeasonArchiveContentsType struct {
+	MulchOrders      []MulchOrderType
+	MulchTimecards   []MulchTimecardType
+	Allocations      []AllocationItemType
+	Users            []UserInfo
+	BankDeposits     []BankDepositType
+	MulchInventories []MulchInventoryType
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// A table with data of the season.  ArchivedAs is the
+// SeasonArchiveContentsType field its rows are copied into when the season is
+// closed.  Tables that aren't archived say why in DiscardedBecause.  IsKept
+// tables are archived but not reset.
+type seasonTableType struct {
+	Name             string
+	ArchivedAs       string
+	DiscardedBecause string
+	IsKept           bool
+}
+
+// Every table with data of the season.  Resetting the order data truncates
+// the ones that aren't kept so a table can't be reset without deciding here
+// what closeSeason does with its rows.
+var seasonTables = []seasonTableType{
+	{Name: "mulch_orders", ArchivedAs: "MulchOrders"},
+	{Name: "mulch_spreaders", ArchivedAs: "MulchOrders"},
+	{Name: "mulch_delivery_timecards", ArchivedAs: "MulchTimecards"},
+	{Name: "allocation_summary", ArchivedAs: "Allocations"},
+	{Name: "bank_deposits", ArchivedAs: "BankDeposits"},
+	{Name: "bank_deposit_items", ArchivedAs: "BankDeposits"},
+	{Name: "mulch_inventory", ArchivedAs: "MulchInventories"},
+	{Name: "users", ArchivedAs: "Users", IsKept: true},
+	{Name: "mulch_order_history", DiscardedBecause: "the orders are archived as they are at the close"},
+	{Name: "notifications", DiscardedBecause: "they are only about things to do that season"},
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the season tables that resetting the order data clears
+func getResetSeasonTableNames() []string {
+	names := []string{}
+	for _, table := range seasonTables {
+		if !table.IsKept {
+			names = append(names, table.Name)
+		}
+	}
+	return names
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Clears the order data and the per season parts of the config in tx.
+// isAfterWrites deletes the rows rather than truncating the tables for when
+// tx has already written something (see DeleteOrderData).
+//
+// Notifications are cleared along with the orders without being archived on
+// purpose.  They only tell a scout something needs doing about an order this
+// season (i.e. a check bounced) and mean nothing once it is closed.  See
+// seasonTables for what happens to each table.
+func resetOrderDataWithTrxn(ctx context.Context, tx FrStore, isAfterWrites bool) error {
+	log.Println("Resetting orders data")
+	resetOrderData := tx.ResetOrderData
+	if isAfterWrites {
+		resetOrderData = tx.DeleteOrderData
+	}
+	if err := resetOrderData(ctx); err != nil {
+		return err
+	}
+
+	frConfig := FrConfigType{FinalizationData: &FinalizationDataType{}, MulchDeliveryConfigs: &[]MulchDeliveryConfigType{}}
+	return updateFundraiserConfigWithTrxn(ctx, tx, frConfig)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Reads everything that closing the season will reset from tx
+func getSeasonArchiveContents(ctx context.Context, tx FrStore) (SeasonArchiveContentsType, error) {
+	contents := SeasonArchiveContentsType{}
+
+	orders, err := tx.GetMulchOrders(ctx, GetMulchOrdersParams{
+		GqlFields: append([]string{"spreaders"}, allMulchOrderGqlFields...),
+	})
+	if err != nil {
+		// Archiving only some of the orders before they are reset would lose the rest
+		return contents, err
+	}
+	contents.MulchOrders = orders
+
+	timecards, err := tx.GetMulchTimecards(ctx, "", -1, allMulchTimecardGqlFields)
+	if err != nil {
+		return contents, err
+	}
+	contents.MulchTimecards = timecards
+
+	allocations, err := tx.GetAllocations(ctx)
+	if err != nil {
+		return contents, err
+	}
+	contents.Allocations = allocations
+
+	users, err := tx.GetUsers(ctx, GetUsersParams{GqlFields: allUserGqlFields})
+	if err != nil {
+		return contents, err
+	}
+	contents.Users = users
+
+	deposits, err := tx.GetBankDeposits(ctx)
+	if err != nil {
+		return contents, err
+	}
+	contents.BankDeposits = deposits
+
+	inventories, err := tx.GetMulchInventories(ctx)
+	if err != nil {
+		return contents, err
+	}
+	contents.MulchInventories = inventories
+	return contents, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Copies the orders, spreaders, timecards, allocations, bank deposits, mulch
+// inventory and the fundraiser config into the archive for season and then
+// resets them the way
+// resetFundraisingData(doResetOrders: true) does.  The users are archived
+// too (for their patrols) but are kept.  A season can only be closed once.
+// Admin only
+func CloseSeason(ctx context.Context, season int) (bool, error) {
+	log.Println("Closing season: ", season)
+
+	if err := VerifyAdminTokenFromCtx(ctx); err != nil {
+		return false, err
+	}
+	if season < 1000 || season > 9999 {
+		return false, newValidationError("season", "season must be a year not: %d", season)
+	}
+	claims, err := parseTokenClaimsFromCtx(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	err = frStore.InTx(ctx, func(tx FrStore) error {
+		if _, err := tx.GetSeasonArchive(ctx, season); err == nil {
+			return newValidationError("season", "season: %d has already been closed", season)
+		} else if !errors.Is(err, ErrNotFound) {
+			return err
+		}
+
+		frConfig, err := tx.GetFundraiserConfig(ctx, allFrConfigGqlFields)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		contents, err := getSeasonArchiveContents(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		archive := SeasonArchiveType{
+			Season:     season,
+			ClosedTime: time.Now().UTC().Format(time.RFC3339),
+			ClosedBy:   claims.userId(),
+			Config:     frConfig,
+		}
+		log.Printf("Archiving season: %d orders: %d timecards: %d allocations: %d users: %d deposits: %d inventories: %d",
+			season, len(contents.MulchOrders), len(contents.MulchTimecards), len(contents.Allocations),
+			len(contents.Users), len(contents.BankDeposits), len(contents.MulchInventories))
+		if err := tx.InsertSeasonArchive(ctx, archive, contents); err != nil {
+			return err
+		}
+
+		if err := resetOrderDataWithTrxn(ctx, tx, true); err != nil {
+			return err
+		}
+		return recordAuditEntry(ctx, tx, "closeSeason", "season", strconv.Itoa(season), nil,
+			map[string]int{
+				"mulchOrders":      len(contents.MulchOrders),
+				"mulchTimecards":   len(contents.MulchTimecards),
+				"allocations":      len(contents.Allocations),
+				"users":            len(contents.Users),
+				"bankDeposits":     len(contents.BankDeposits),
+				"mulchInventories": len(contents.MulchInventories),
+			})
+	})
+	if err != nil {
+		log.Println("Closing season failed: ", err)
+		return false, err
+	}
+	return true, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Checks the records of a season being imported.  Amounts are checked so the
+// reports don't fail on them later.
+func validateSeasonImport(contents SeasonArchiveContentsType) error {
+	orderIds := make(map[string]bool)
+	for idx, order := range contents.MulchOrders {
+		if len(order.OrderId) == 0 || len(order.OwnerId) == 0 {
+			return newValidationError(fmt.Sprintf("mulchOrders.%d", idx), "mulchOrders[%d] needs an orderId and ownerId", idx)
+		}
+		if orderIds[order.OrderId] {
+			return newValidationError(fmt.Sprintf("mulchOrders.%d.orderId", idx), "orderId: %s is in the import more than once",
+				order.OrderId)
+		}
+		orderIds[order.OrderId] = true
+
+		amounts := map[string]*string{
+			"amountFromDonations":  order.AmountFromDonations,
+			"amountTotalCollected": order.AmountTotalCollected,
+		}
+		for _, item := range order.Purchases {
+			amounts["purchases."+item.ProductId+".amountCharged"] = &item.AmountCharged
+		}
+		for field, amount := range amounts {
+			if _, err := parseAmount(amount); err != nil {
+				return newValidationError(fmt.Sprintf("mulchOrders.%d.%s", idx, field),
+					"order: %s %s is not an amount: %s", order.OrderId, field, *amount)
+			}
+		}
+	}
+
+	for idx, timecard := range contents.MulchTimecards {
+		if len(timecard.Id) == 0 {
+			return newValidationError(fmt.Sprintf("mulchTimecards.%d.id", idx), "mulchTimecards[%d] needs an id", idx)
+		}
+		if _, err := parseTimecardDuration(timecard.TimeTotal); err != nil {
+			return newValidationError(fmt.Sprintf("mulchTimecards.%d.timeTotal", idx),
+				"mulchTimecards[%d] timeTotal: %s is not hh:mm:ss", idx, timecard.TimeTotal)
+		}
+	}
+
+	for idx, item := range contents.Allocations {
+		if len(item.Uid) == 0 {
+			return newValidationError(fmt.Sprintf("allocations.%d.uid", idx), "allocations[%d] needs a uid", idx)
+		}
+	}
+
+	for idx, user := range contents.Users {
+		if len(user.Id) == 0 {
+			return newValidationError(fmt.Sprintf("users.%d.id", idx), "users[%d] needs an id", idx)
+		}
+	}
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Loads a prior season exported from elsewhere into the archive so it can be
+// read and compared like a closed one.  Nothing live is changed.  The season
+// can't already be archived.  Admin only
+func ImportSeason(ctx context.Context, season int, frConfig *FrConfigType, contents SeasonArchiveContentsType) (bool, error) {
+	log.Printf("Importing season: %d orders: %d timecards: %d allocations: %d users: %d", season,
+		len(contents.MulchOrders), len(contents.MulchTimecards), len(contents.Allocations), len(contents.Users))
+
+	if err := VerifyAdminTokenFromCtx(ctx); err != nil {
+		return false, err
+	}
+	if season < 1000 || season > 9999 {
+		return false, newValidationError("season", "season must be a year not: %d", season)
+	}
+	if err := validateSeasonImport(contents); err != nil {
+		return false, err
+	}
+	claims, err := parseTokenClaimsFromCtx(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	archive := SeasonArchiveType{
+		Season:     season,
+		ClosedTime: time.Now().UTC().Format(time.RFC3339),
+		ClosedBy:   claims.userId(),
+		IsImported: true,
+	}
+	if frConfig != nil {
+		archive.Config = *frConfig
+	}
+
+	err = frStore.InTx(ctx, func(tx FrStore) error {
+		if _, err := tx.GetSeasonArchive(ctx, season); err == nil {
+			return newValidationError("season", "season: %d has already been archived", season)
+		} else if !errors.Is(err, ErrNotFound) {
+			return err
+		}
+
+		if err := tx.InsertSeasonArchive(ctx, archive, contents); err != nil {
+			return err
+		}
+		return recordAuditEntry(ctx, tx, "importSeason", "season", strconv.Itoa(season), nil,
+			map[string]int{
+				"mulchOrders":      len(contents.MulchOrders),
+				"mulchTimecards":   len(contents.MulchTimecards),
+				"allocations":      len(contents.Allocations),
+				"users":            len(contents.Users),
+				"bankDeposits":     len(contents.BankDeposits),
+				"mulchInventories": len(contents.MulchInventories),
+			})
+	})
+	if err != nil {
+		log.Println("Importing season failed: ", err)
+		return false, err
+	}
+	return true, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the closed seasons newest first
+func GetSeasonArchives(ctx context.Context) ([]SeasonArchiveType, error) {
+	log.Println("Retrieving season archives")
+
+	archives, err := frStore.GetSeasonArchives(ctx)
+	if err != nil {
+		log.Println("Season archives query failed: ", err)
+		return nil, err
+	}
+	return archives, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func GetSeasonArchive(ctx context.Context, season int) (SeasonArchiveType, error) {
+	log.Println("Retrieving season archive: ", season)
+
+	archive, err := frStore.GetSeasonArchive(ctx, season)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return archive, &NotFoundError{Message: fmt.Sprintf("season: %d has not been closed", season)}
+		}
+		log.Println("Season archive query failed: ", err)
+		return archive, err
+	}
+	return archive, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the archived orders of ownerId.  Everyone's orders (ownerId of "")
+// can only be seen by an admin.
+func GetArchivedMulchOrders(ctx context.Context, season int, ownerId string) ([]MulchOrderType, error) {
+	log.Printf("Retrieving archived orders for season: %d ownerId: %s", season, ownerId)
+
+	if err := verifyUidAllowedFromCtx(ctx, ownerId); err != nil {
+		return nil, err
+	}
+	return frStore.GetArchivedMulchOrders(ctx, season, ownerId)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Same as GetArchivedMulchOrders but for timecards
+func GetArchivedMulchTimecards(ctx context.Context, season int, uid string) ([]MulchTimecardType, error) {
+	log.Printf("Retrieving archived timecards for season: %d uid: %s", season, uid)
+
+	if err := verifyUidAllowedFromCtx(ctx, uid); err != nil {
+		return nil, err
+	}
+	return frStore.GetArchivedMulchTimecards(ctx, season, uid)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Same as GetArchivedMulchOrders but for allocations
+func GetArchivedAllocations(ctx context.Context, season int, uid string) ([]AllocationItemType, error) {
+	log.Printf("Retrieving archived allocations for season: %d uid: %s", season, uid)
+
+	if err := verifyUidAllowedFromCtx(ctx, uid); err != nil {
+		return nil, err
+	}
+	return frStore.GetArchivedAllocations(ctx, season, uid)
+}
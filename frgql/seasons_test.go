@@ -0,0 +1,210 @@
+package frgql
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Tables that outlive a season so they are never reset or archived
+var nonSeasonTables = []string{"fundraiser_config", "neighborhoods", "known_addrs", "audit_log", "season_archives"}
+
+// //////////////////////////////////////////////////////////////////////////
+func TestSeasonTablesMatchArchiveContents(t *testing.T) {
+	archivedAs := map[string]bool{}
+	for _, table := range seasonTables {
+		if len(table.ArchivedAs) == 0 && len(table.DiscardedBecause) == 0 {
+			t.Errorf("season table: %s is neither archived nor says why it is discarded", table.Name)
+		}
+		if len(table.ArchivedAs) != 0 && len(table.DiscardedBecause) != 0 {
+			t.Errorf("season table: %s is archived and discarded", table.Name)
+		}
+		if table.IsKept && len(table.ArchivedAs) == 0 {
+			t.Errorf("season table: %s is kept but not archived", table.Name)
+		}
+		archivedAs[table.ArchivedAs] = true
+	}
+
+	contentsType := reflect.TypeOf(SeasonArchiveContentsType{})
+	fields := map[string]bool{}
+	for idx := 0; idx < contentsType.NumField(); idx++ {
+		field := contentsType.Field(idx).Name
+		fields[field] = true
+		if !archivedAs[field] {
+			t.Errorf("SeasonArchiveContentsType.%s isn't the ArchivedAs of any season table", field)
+		}
+	}
+	for _, table := range seasonTables {
+		if len(table.ArchivedAs) != 0 && !fields[table.ArchivedAs] {
+			t.Errorf("season table: %s is archived as: %s which isn't a SeasonArchiveContentsType field",
+				table.Name, table.ArchivedAs)
+		}
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// A table added by a migration has to be a season table (and so be reset and
+// archived or discarded) or be listed as outliving the season
+func TestSeasonTablesCoverMigratedTables(t *testing.T) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	createTableRe := regexp.MustCompile(`(?i)CREATE TABLE (?:IF NOT EXISTS )?([a-z_]+)`)
+	for _, migration := range migrations {
+		for _, match := range createTableRe.FindAllStringSubmatch(migration.UpSql, -1) {
+			name := match[1]
+			isSeasonTable := slices.ContainsFunc(seasonTables, func(table seasonTableType) bool { return table.Name == name })
+			if !isSeasonTable && !slices.Contains(nonSeasonTables, name) && !strings.HasPrefix(name, "archived_") {
+				t.Errorf("table: %s from migration: %d isn't in seasonTables or nonSeasonTables", name, migration.Version)
+			}
+		}
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func TestResetOrderDataSqlTruncatesSeasonTables(t *testing.T) {
+	truncated := strings.Split(strings.TrimPrefix(RESET_ORDER_DATA_SQL, "TRUNCATE "), ", ")
+	for _, table := range seasonTables {
+		if table.IsKept == slices.Contains(truncated, table.Name) {
+			t.Errorf("season table: %s (kept: %t) truncated: %t", table.Name, table.IsKept, !table.IsKept)
+		}
+	}
+	if len(truncated) != len(getResetSeasonTableNames()) {
+		t.Errorf("truncates: %v not: %v", truncated, getResetSeasonTableNames())
+	}
+
+	deleted := []string{}
+	for _, sqlCmd := range getDeleteOrderDataSqls() {
+		deleted = append(deleted, strings.TrimPrefix(sqlCmd, "DELETE FROM "))
+	}
+	if !slices.Equal(deleted, truncated) {
+		t.Errorf("deletes from: %v but truncates: %v", deleted, truncated)
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Closing the season archives and clears it in one transaction and the
+// next season can still be reset on its own
+func TestCloseSeasonThenReset(t *testing.T) {
+	newTestMemStore(t)
+	ctx := context.Background()
+	adminCtx := newTestCtx(t, "admin1", true)
+
+	for name, newStore := range getTestStores(t) {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+			SetStore(store)
+
+			mustNotFail(t, store.InsertMulchOrder(ctx, makeTestOrder(testOrderId1, "scout1")))
+			mustNotFail(t, store.InsertMulchTimecard(ctx, MulchTimecardType{Id: "scout1", DeliveryId: 1, TimeTotal: "01:00:00"}))
+			_, err := CloseSeason(adminCtx, 2024)
+			mustNotFail(t, err)
+
+			orders, err := store.GetMulchOrders(ctx, GetMulchOrdersParams{GqlFields: allMulchOrderGqlFields})
+			mustNotFail(t, err)
+			archived, err := store.GetArchivedMulchOrders(ctx, 2024, "")
+			mustNotFail(t, err)
+			if len(orders) != 0 || len(archived) != 1 {
+				t.Errorf("after the close orders: %d archived: %d", len(orders), len(archived))
+			}
+			if _, err := CloseSeason(adminCtx, 2024); getValidationFields(err) == nil {
+				t.Errorf("closed the season twice: %v", err)
+			}
+
+			mustNotFail(t, store.InsertMulchOrder(ctx, makeTestOrder(testOrderId2, "scout1")))
+			_, err = ResetFundraisingData(adminCtx, false, true)
+			mustNotFail(t, err)
+			orders, err = store.GetMulchOrders(ctx, GetMulchOrdersParams{GqlFields: allMulchOrderGqlFields})
+			mustNotFail(t, err)
+			archived, err = store.GetArchivedMulchOrders(ctx, 2024, "")
+			mustNotFail(t, err)
+			if len(orders) != 0 || len(archived) != 1 {
+				t.Errorf("after the reset orders: %d archived: %d", len(orders), len(archived))
+			}
+		})
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Every kind of season data is either in the archive or gone on purpose and
+// nothing but the users is left after the close
+func TestCloseSeasonArchivesSeasonData(t *testing.T) {
+	store := newTestMemStore(t)
+	ctx := context.Background()
+	orderId := "6b1ae1d8-5bbf-4cb1-b8c8-2f5fb12a1f01"
+	amount := "60.0000"
+
+	order := MulchOrderType{
+		OrderId:              orderId,
+		OwnerId:              "scout1",
+		AmountTotalCollected: &amount,
+		Customer:             CustomerType{Name: "Pat", Neighborhood: "Oak Hills"},
+	}
+	mustDo := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustDo(store.InsertMulchOrder(ctx, order))
+	mustDo(store.SetSpreaders(ctx, orderId, []string{"scout2"}))
+	mustDo(store.InsertMulchTimecard(ctx, MulchTimecardType{Id: "scout2", DeliveryId: 1, TimeTotal: "01:00:00"}))
+	mustDo(store.SetAllocations(ctx, []AllocationItemType{{Uid: "scout1"}}))
+	mustDo(store.InsertUser(ctx, UserInfo{Id: "scout1", FirstName: "A", LastName: "B"}))
+	_, err := store.InsertBankDeposit(ctx, BankDepositType{
+		DepositDate: "2024-04-01", AmountFromCash: amount,
+		Items: []BankDepositItemType{{OrderId: orderId, AmountFromCash: amount}},
+	})
+	mustDo(err)
+	mustDo(store.UpsertMulchInventory(ctx, MulchInventoryType{DeliveryId: 1, BagsOrdered: 100}))
+	_, err = store.InsertMulchOrderRevision(ctx, MulchOrderRevisionType{OrderId: orderId, Operation: "updateMulchOrder", Order: order})
+	mustDo(err)
+	mustDo(store.InsertNotification(ctx, NotificationType{Uid: "scout1", Kind: "checkBounced", Message: "bounced"}))
+
+	if _, err := CloseSeason(newTestCtx(t, "admin1", true), 2024); err != nil {
+		t.Fatal(err)
+	}
+
+	contents := reflect.ValueOf(store.data.seasons[2024].contents)
+	for idx := 0; idx < contents.NumField(); idx++ {
+		if contents.Field(idx).Len() == 0 {
+			t.Errorf("nothing was archived in: %s", contents.Type().Field(idx).Name)
+		}
+	}
+
+	orders, err := store.GetMulchOrders(ctx, GetMulchOrdersParams{GqlFields: allMulchOrderGqlFields})
+	mustDo(err)
+	timecards, err := store.GetMulchTimecards(ctx, "", -1, allMulchTimecardGqlFields)
+	mustDo(err)
+	allocations, err := store.GetAllocations(ctx)
+	mustDo(err)
+	deposits, err := store.GetBankDeposits(ctx)
+	mustDo(err)
+	inventories, err := store.GetMulchInventories(ctx)
+	mustDo(err)
+	history, err := store.GetMulchOrderHistory(ctx, orderId)
+	mustDo(err)
+	notifications, err := store.GetNotifications(ctx, "scout1", false)
+	mustDo(err)
+	left := map[string]int{
+		"orders": len(orders), "timecards": len(timecards), "allocations": len(allocations),
+		"deposits": len(deposits), "inventories": len(inventories), "history": len(history),
+		"notifications": len(notifications), "spreaders": len(store.data.spreaders),
+	}
+	for kind, num := range left {
+		if num != 0 {
+			t.Errorf("%d %s are left after the season was closed", num, kind)
+		}
+	}
+
+	users, err := store.GetUsers(ctx, GetUsersParams{GqlFields: allUserGqlFields})
+	mustDo(err)
+	if len(users) != 1 {
+		t.Errorf("users are kept but: %d are left", len(users))
+	}
+}
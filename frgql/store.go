@@ -0,0 +1,230 @@
+package frgql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// //////////////////////////////////////////////////////////////////////////
+// Returned by stores when the requested record doesn't exist
+var ErrNotFound = errors.New("record not found")
+
+// //////////////////////////////////////////////////////////////////////////
+// Returned by store updates given an expectedLastModifiedTime when the record
+// isn't at that version (or doesn't exist) so nothing was changed
+var ErrStaleVersion = errors.New("record is not at the expected version")
+
+// //////////////////////////////////////////////////////////////////////////
+// Returned by stores along with the rows that could be read when some of the
+// rows of a query couldn't be.  The rows that are returned are good.
+type RowScanError struct {
+	Errs []error
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (e *RowScanError) Error() string {
+	return fmt.Sprintf("%d row(s) could not be read: %s", len(e.Errs), errors.Join(e.Errs...))
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (e *RowScanError) Unwrap() []error {
+	return e.Errs
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns nil if no rows failed
+func newRowScanError(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return &RowScanError{Errs: errs}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+type OrderStore interface {
+	GetMulchOrders(ctx context.Context, params GetMulchOrdersParams) ([]MulchOrderType, error)
+	CountMulchOrders(ctx context.Context, params GetMulchOrdersParams) (int, error)
+	GetMulchOrdersMoneyCollected(ctx context.Context, params GetMulchOrdersParams) ([]MulchOrderMoneyCollectedType, error)
+	GetMulchOrder(ctx context.Context, params GetMulchOrderParams) (MulchOrderType, error)
+	InsertMulchOrder(ctx context.Context, order MulchOrderType) error
+	// Sets only the given gql fields (customer fields as customer.<field>) of
+	// the order along with lastModifiedTime.  ErrNotFound if it doesn't exist.
+	// If expectedLastModifiedTime is given it is only changed at that version.
+	UpdateMulchOrder(ctx context.Context, order MulchOrderType, gqlFields []string, expectedLastModifiedTime string) error
+	DeleteMulchOrder(ctx context.Context, orderId string) error
+	// Clears the season tables that aren't kept (seasonTables)
+	ResetOrderData(ctx context.Context) error
+	// Clears the same tables as ResetOrderData row by row so it can follow
+	// writes in the same transaction
+	DeleteOrderData(ctx context.Context) error
+}
+
+// //////////////////////////////////////////////////////////////////////////
+type SpreaderStore interface {
+	SetSpreaders(ctx context.Context, orderId string, spreaders []string) error
+}
+
+// //////////////////////////////////////////////////////////////////////////
+type TimecardStore interface {
+	GetMulchTimecards(ctx context.Context, id string, deliveryId int, gqlFields []string) ([]MulchTimecardType, error)
+	DeleteMulchTimecard(ctx context.Context, id string, deliveryId int) error
+	InsertMulchTimecard(ctx context.Context, timecard MulchTimecardType) error
+}
+
+// //////////////////////////////////////////////////////////////////////////
+type ConfigStore interface {
+	GetFundraiserConfig(ctx context.Context, gqlFields []string) (FrConfigType, error)
+	// Replaces the entire config record
+	SetFundraiserConfig(ctx context.Context, frConfig FrConfigType) error
+	// Updates only the fields provided.  LastModifiedTime is always updated.
+	// If expectedLastModifiedTime is given it is only changed at that version.
+	UpdateFundraiserConfig(ctx context.Context, frConfig FrConfigType, expectedLastModifiedTime string) error
+}
+
+// //////////////////////////////////////////////////////////////////////////
+type NeighborhoodStore interface {
+	GetNeighborhoods(ctx context.Context, gqlFields []string) ([]NeighborhoodInfo, error)
+	InsertNeighborhood(ctx context.Context, hood NeighborhoodInfo) error
+	// If expectedLastModifiedTime is given it is only changed at that version
+	UpdateNeighborhood(ctx context.Context, hood NeighborhoodInfo, expectedLastModifiedTime string) error
+}
+
+// //////////////////////////////////////////////////////////////////////////
+type UserStore interface {
+	GetUsers(ctx context.Context, params GetUsersParams) ([]UserInfo, error)
+	InsertUser(ctx context.Context, user UserInfo) error
+	// If expectedLastModifiedTime is given it is only changed at that version
+	UpdateUser(ctx context.Context, user UserInfo, expectedLastModifiedTime string) error
+	ResetUsers(ctx context.Context) error
+}
+
+// //////////////////////////////////////////////////////////////////////////
+type AllocationStore interface {
+	GetAllocation(ctx context.Context, uid string) (AllocationItemType, error)
+	// Ordered by uid
+	GetAllocations(ctx context.Context) ([]AllocationItemType, error)
+	// Replaces all existing allocations with the given ones
+	SetAllocations(ctx context.Context, allocations []AllocationItemType) error
+}
+
+// //////////////////////////////////////////////////////////////////////////
+type OrderHistoryStore interface {
+	// Saves the revision as the next revision number of the order which is
+	// returned.  Transactions saving revisions of the same order get their
+	// numbers one after the other.
+	InsertMulchOrderRevision(ctx context.Context, revision MulchOrderRevisionType) (int, error)
+	// Oldest revision first
+	GetMulchOrderHistory(ctx context.Context, orderId string) ([]MulchOrderRevisionType, error)
+	GetMulchOrderRevision(ctx context.Context, orderId string, revision int) (MulchOrderRevisionType, error)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+type AuditStore interface {
+	InsertAuditLogEntry(ctx context.Context, entry AuditLogEntryType) error
+	// Newest entries first
+	GetAuditLog(ctx context.Context, params GetAuditLogParams) ([]AuditLogEntryType, error)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Read only copies of the order data of closed seasons
+type SeasonArchiveStore interface {
+	// Saves the archive along with the records of the season.  Fails if the
+	// season is already archived.
+	InsertSeasonArchive(ctx context.Context, archive SeasonArchiveType, contents SeasonArchiveContentsType) error
+	// Newest season first
+	GetSeasonArchives(ctx context.Context) ([]SeasonArchiveType, error)
+	// ErrNotFound if the season hasn't been closed
+	GetSeasonArchive(ctx context.Context, season int) (SeasonArchiveType, error)
+	// ownerId/uid of "" returns them for everyone
+	GetArchivedMulchOrders(ctx context.Context, season int, ownerId string) ([]MulchOrderType, error)
+	GetArchivedMulchTimecards(ctx context.Context, season int, uid string) ([]MulchTimecardType, error)
+	GetArchivedAllocations(ctx context.Context, season int, uid string) ([]AllocationItemType, error)
+	GetArchivedUsers(ctx context.Context, season int) ([]UserInfo, error)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Ledger of the money taken to the bank
+type BankDepositStore interface {
+	// Saves the deposit and its items and returns its id
+	InsertBankDeposit(ctx context.Context, deposit BankDepositType) (string, error)
+	// Oldest deposit first with their items
+	GetBankDeposits(ctx context.Context) ([]BankDepositType, error)
+	// ErrNotFound if it doesn't exist
+	GetBankDeposit(ctx context.Context, id string) (BankDepositType, error)
+	DeleteBankDeposit(ctx context.Context, id string) error
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Mulch inventory records.  There is at most one per delivery.
+type MulchInventoryStore interface {
+	// Ordered by delivery id
+	GetMulchInventories(ctx context.Context) ([]MulchInventoryType, error)
+	// ErrNotFound if the delivery doesn't have one
+	GetMulchInventory(ctx context.Context, deliveryId int) (MulchInventoryType, error)
+	// Adds the record or replaces the delivery's existing one
+	UpsertMulchInventory(ctx context.Context, inventory MulchInventoryType) error
+	DeleteMulchInventory(ctx context.Context, deliveryId int) error
+}
+
+// //////////////////////////////////////////////////////////////////////////
+type NotificationStore interface {
+	// Id is assigned by the store
+	InsertNotification(ctx context.Context, notification NotificationType) error
+	// Newest notification first
+	GetNotifications(ctx context.Context, uid string, isUnreadOnly bool) ([]NotificationType, error)
+	// ErrNotFound if it doesn't exist
+	GetNotification(ctx context.Context, id string) (NotificationType, error)
+	MarkNotificationRead(ctx context.Context, id string) error
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Addresses with known coordinates (known_addrs).  Addresses the geocoder
+// couldn't find are stored without coordinates so they aren't looked up
+// again.
+type KnownAddrStore interface {
+	// Only the addresses with coordinates
+	GetKnownAddrs(ctx context.Context) ([]KnownAddrType, error)
+	// The addresses with coordinates inside the box
+	GetKnownAddrsInBox(ctx context.Context, box GeoBoxType) ([]KnownAddrType, error)
+	// The addresses (with or without coordinates) that normalize to
+	// normalizedAddr
+	GetKnownAddrsByNormalizedAddr(ctx context.Context, normalizedAddr string) ([]KnownAddrType, error)
+	// Returns the id of the new address
+	InsertKnownAddr(ctx context.Context, addr KnownAddrType) (string, error)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Storage used by the fundraiser api.  There is a Postgres/Cockroach
+// implementation (NewPgStore) and an in-memory one (NewMemStore).
+type FrStore interface {
+	OrderStore
+	SpreaderStore
+	TimecardStore
+	ConfigStore
+	NeighborhoodStore
+	UserStore
+	AllocationStore
+	OrderHistoryStore
+	AuditStore
+	SeasonArchiveStore
+	BankDepositStore
+	MulchInventoryStore
+	NotificationStore
+	KnownAddrStore
+
+	// Runs fn with a store where every operation is part of one transaction.
+	// If fn returns an error none of its changes are kept.
+	InTx(ctx context.Context, fn func(tx FrStore) error) error
+}
+
+var frStore FrStore
+
+// //////////////////////////////////////////////////////////////////////////
+// Sets the store used by the api.  When a store has been set OpenDb will not
+// create a database connection.
+func SetStore(store FrStore) {
+	dbMutex.Lock()
+	defer dbMutex.Unlock()
+	frStore = store
+}
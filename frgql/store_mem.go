@@ -0,0 +1,1249 @@
+package frgql
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// //////////////////////////////////////////////////////////////////////////
+type memTimecardKey struct {
+	uid        string
+	deliveryId int
+}
+
+// //////////////////////////////////////////////////////////////////////////
+type memSeasonArchive struct {
+	archive  SeasonArchiveType
+	contents SeasonArchiveContentsType
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Everything the in-memory store holds.  Records are never modified in place
+// (they are replaced) so a shallow copy of the maps is enough for a snapshot.
+type memData struct {
+	orders      map[string]MulchOrderType
+	spreaders   map[string][]string
+	timecards   map[memTimecardKey]MulchTimecardType
+	config      *FrConfigType
+	hoods       map[string]NeighborhoodInfo
+	users       map[string]UserInfo
+	allocations map[string]AllocationItemType
+	history     map[string][]MulchOrderRevisionType
+	seasons     map[int]memSeasonArchive
+	deposits    map[string]BankDepositType
+	inventory   map[int]MulchInventoryType
+	notices     map[string]NotificationType
+	knownAddrs  map[string]KnownAddrType
+	auditLog    []AuditLogEntryType
+	nextAuditId int
+	// Deposit, notification and known address ids are numbered like the
+	// audit ids
+	nextDepositId      int
+	nextNotificationId int
+	nextKnownAddrId    int
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func newMemData() *memData {
+	return &memData{
+		orders:      make(map[string]MulchOrderType),
+		spreaders:   make(map[string][]string),
+		timecards:   make(map[memTimecardKey]MulchTimecardType),
+		hoods:       make(map[string]NeighborhoodInfo),
+		users:       make(map[string]UserInfo),
+		allocations: make(map[string]AllocationItemType),
+		history:     make(map[string][]MulchOrderRevisionType),
+		seasons:     make(map[int]memSeasonArchive),
+		deposits:    make(map[string]BankDepositType),
+		inventory:   make(map[int]MulchInventoryType),
+		notices:     make(map[string]NotificationType),
+		knownAddrs:  make(map[string]KnownAddrType),
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (d *memData) clone() *memData {
+	return &memData{
+		orders:      maps.Clone(d.orders),
+		spreaders:   maps.Clone(d.spreaders),
+		timecards:   maps.Clone(d.timecards),
+		config:      d.config,
+		hoods:       maps.Clone(d.hoods),
+		users:       maps.Clone(d.users),
+		allocations: maps.Clone(d.allocations),
+		history:     maps.Clone(d.history),
+		seasons:     maps.Clone(d.seasons),
+		deposits:    maps.Clone(d.deposits),
+		inventory:   maps.Clone(d.inventory),
+		notices:     maps.Clone(d.notices),
+		knownAddrs:  maps.Clone(d.knownAddrs),
+		// Clipped so appends in a transaction don't touch the original
+		auditLog:           slices.Clip(d.auditLog),
+		nextAuditId:        d.nextAuditId,
+		nextDepositId:      d.nextDepositId,
+		nextNotificationId: d.nextNotificationId,
+		nextKnownAddrId:    d.nextKnownAddrId,
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// In-memory FrStore.  Useful for local development and for exercising the
+// api without a database.  Nothing is persisted.
+type MemStore struct {
+	mutex *sync.Mutex // nil when this store is bound to a transaction
+	data  *memData
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func NewMemStore() *MemStore {
+	return &MemStore{mutex: &sync.Mutex{}, data: newMemData()}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) lock() func() {
+	if s.mutex == nil {
+		return func() {}
+	}
+	s.mutex.Lock()
+	return s.mutex.Unlock
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) InTx(ctx context.Context, fn func(tx FrStore) error) error {
+	if s.mutex == nil {
+		// Already in a transaction
+		return fn(s)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	txData := s.data.clone()
+	if err := fn(&MemStore{data: txData}); err != nil {
+		return err
+	}
+	s.data = txData
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Stores amounts the way the database would.  "0" is stored as NULL and
+// everything else as a fixed 4 digit decimal.
+func memNormalizeAmount(amount *string) (*string, error) {
+	if amount == nil || *amount == "0" {
+		return nil, nil
+	}
+	d, err := decimal.NewFromString(*amount)
+	if err != nil {
+		return nil, err
+	}
+	v := d.StringFixed(4)
+	return &v, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func memSumAmount(total *decimal.Decimal, amount *string) *decimal.Decimal {
+	if amount == nil {
+		return total
+	}
+	d, err := decimal.NewFromString(*amount)
+	if err != nil {
+		return total
+	}
+	if total == nil {
+		return &d
+	}
+	sum := total.Add(d)
+	return &sum
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Same as the database's conditional update where a record that doesn't
+// exist is never at the expected version
+func memIsAtLastModifiedTime(isFound bool, current string, expected string) bool {
+	if len(expected) == 0 {
+		return true
+	}
+	return isFound && isSameLastModifiedTime(expected, current)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) GetMulchOrdersMoneyCollected(ctx context.Context, params GetMulchOrdersParams) ([]MulchOrderMoneyCollectedType, error) {
+	defer s.lock()()
+
+	type groupKey struct {
+		ownerId    string
+		deliveryId int
+		hasId      bool
+	}
+	type groupSums struct {
+		total, cash, checks            *decimal.Decimal
+		cashDeposited, checksDeposited *decimal.Decimal
+		deliveryId                     *int
+	}
+	groups := make(map[groupKey]*groupSums)
+	keys := []groupKey{}
+
+	cashDeposited := make(map[string]decimal.Decimal)
+	checksDeposited := make(map[string]decimal.Decimal)
+	for _, deposit := range s.data.deposits {
+		for _, item := range deposit.Items {
+			cash, _ := parseAmount(&item.AmountFromCash)
+			checks, _ := parseAmount(&item.AmountFromChecks)
+			cashDeposited[item.OrderId] = cashDeposited[item.OrderId].Add(cash)
+			checksDeposited[item.OrderId] = checksDeposited[item.OrderId].Add(checks)
+		}
+	}
+
+	for _, order := range s.data.orders {
+		if len(params.OwnerId) != 0 && order.OwnerId != params.OwnerId {
+			continue
+		}
+		key := groupKey{ownerId: order.OwnerId}
+		if order.DeliveryId != nil {
+			key.deliveryId = *order.DeliveryId
+			key.hasId = true
+		}
+		sums, ok := groups[key]
+		if !ok {
+			sums = &groupSums{deliveryId: order.DeliveryId}
+			groups[key] = sums
+			keys = append(keys, key)
+		}
+		sums.total = memSumAmount(sums.total, order.AmountTotalCollected)
+		sums.cash = memSumAmount(sums.cash, order.AmountFromCashCollected)
+		sums.checks = memSumAmount(sums.checks, order.AmountFromChecksCollected)
+		orderCashDeposited := cashDeposited[order.OrderId].String()
+		orderChecksDeposited := checksDeposited[order.OrderId].String()
+		sums.cashDeposited = memSumAmount(sums.cashDeposited, &orderCashDeposited)
+		sums.checksDeposited = memSumAmount(sums.checksDeposited, &orderChecksDeposited)
+	}
+
+	toStr := func(d *decimal.Decimal) *string {
+		if d == nil {
+			return nil
+		}
+		v := d.StringFixed(4)
+		return &v
+	}
+
+	orders := []MulchOrderMoneyCollectedType{}
+	for _, key := range keys {
+		sums := groups[key]
+		orders = append(orders, MulchOrderMoneyCollectedType{
+			OwnerId:                        key.ownerId,
+			DeliveryId:                     sums.deliveryId,
+			AmountTotalCollected:           toStr(sums.total),
+			AmountTotalFromCashCollected:   toStr(sums.cash),
+			AmountTotalFromChecksCollected: toStr(sums.checks),
+			AmountTotalFromCashDeposited:   toStr(sums.cashDeposited),
+			AmountTotalFromChecksDeposited: toStr(sums.checksDeposited),
+		})
+	}
+	return orders, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) getMulchOrder(orderId string) (MulchOrderType, bool) {
+	order, ok := s.data.orders[orderId]
+	if !ok {
+		return MulchOrderType{}, false
+	}
+	order.Purchases = slices.Clone(order.Purchases)
+	order.Checks = slices.Clone(order.Checks)
+	order.Spreaders = slices.Clone(s.data.spreaders[orderId])
+	return order, true
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func memIsMulchOrderMatch(order MulchOrderType, params GetMulchOrdersParams) bool {
+	isTrue := func(v *bool) bool { return v != nil && *v }
+
+	if len(params.OwnerId) != 0 && order.OwnerId != params.OwnerId {
+		return false
+	}
+	if len(params.ExcludeOwnerId) != 0 && order.OwnerId == params.ExcludeOwnerId {
+		return false
+	}
+	if params.DoGetSpreadOrdersOnly && !slices.ContainsFunc(order.Purchases, func(p ProductsType) bool {
+		return p.ProductId == "spreading"
+	}) {
+		return false
+	}
+	if len(params.SpreaderId) != 0 && !slices.Contains(order.Spreaders, params.SpreaderId) {
+		return false
+	}
+	if len(params.Neighborhood) != 0 && order.Customer.Neighborhood != params.Neighborhood {
+		return false
+	}
+	if params.DeliveryId != nil && (order.DeliveryId == nil || *order.DeliveryId != *params.DeliveryId) {
+		return false
+	}
+	if params.IsVerified != nil && isTrue(order.IsVerified) != *params.IsVerified {
+		return false
+	}
+	if params.WillCollectMoneyLater != nil && isTrue(order.WillCollectMoneyLater) != *params.WillCollectMoneyLater {
+		return false
+	}
+	if params.ModifiedAfter != nil || params.ModifiedBefore != nil {
+		modified, err := time.Parse(time.RFC3339, order.LastModifiedTime)
+		if err != nil {
+			return false
+		}
+		if params.ModifiedAfter != nil && modified.Before(*params.ModifiedAfter) {
+			return false
+		}
+		if params.ModifiedBefore != nil && !modified.Before(*params.ModifiedBefore) {
+			return false
+		}
+	}
+	if len(params.Search) != 0 {
+		search := strings.ToLower(params.Search)
+		addr2 := ""
+		if order.Customer.Addr2 != nil {
+			addr2 = *order.Customer.Addr2
+		}
+		if !strings.Contains(strings.ToLower(order.Customer.Name), search) &&
+			!strings.Contains(strings.ToLower(order.Customer.Addr1), search) &&
+			!strings.Contains(strings.ToLower(addr2), search) {
+			return false
+		}
+	}
+	return true
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Compares orders in the same order as the pg store sorts them.  nil values
+// sort first ascending/last descending
+func memMulchOrderCmp(sortBy string, isDescending bool) (func(a, b MulchOrderType) int, error) {
+	var cmpField func(a, b MulchOrderType) int
+	cmpStr := func(a, b string) int { return strings.Compare(a, b) }
+	switch sortBy {
+	case "orderId":
+		cmpField = func(a, b MulchOrderType) int { return 0 }
+	case "ownerId":
+		cmpField = func(a, b MulchOrderType) int { return cmpStr(a.OwnerId, b.OwnerId) }
+	case "lastModifiedTime":
+		cmpField = func(a, b MulchOrderType) int { return cmpStr(a.LastModifiedTime, b.LastModifiedTime) }
+	case "customerName":
+		cmpField = func(a, b MulchOrderType) int { return cmpStr(a.Customer.Name, b.Customer.Name) }
+	case "neighborhood":
+		cmpField = func(a, b MulchOrderType) int { return cmpStr(a.Customer.Neighborhood, b.Customer.Neighborhood) }
+	case "deliveryId":
+		cmpField = func(a, b MulchOrderType) int {
+			if a.DeliveryId == nil || b.DeliveryId == nil {
+				return cmpNil(a.DeliveryId == nil, b.DeliveryId == nil)
+			}
+			return *a.DeliveryId - *b.DeliveryId
+		}
+	case "amountTotalCollected":
+		cmpField = func(a, b MulchOrderType) int {
+			if a.AmountTotalCollected == nil || b.AmountTotalCollected == nil {
+				return cmpNil(a.AmountTotalCollected == nil, b.AmountTotalCollected == nil)
+			}
+			return decimal.RequireFromString(*a.AmountTotalCollected).Cmp(decimal.RequireFromString(*b.AmountTotalCollected))
+		}
+	default:
+		return nil, fmt.Errorf("unknown mulch order sort field: %s", sortBy)
+	}
+
+	return func(a, b MulchOrderType) int {
+		v := cmpField(a, b)
+		if v == 0 {
+			v = cmpStr(a.OrderId, b.OrderId)
+		}
+		if isDescending {
+			return -v
+		}
+		return v
+	}, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Order with just the fields of the sort key set so it can be compared
+// against the others
+func memMulchOrderFromSortKey(key MulchOrderSortKey) MulchOrderType {
+	order := MulchOrderType{OrderId: key.OrderId}
+	if key.Value == nil {
+		return order
+	}
+	switch key.SortBy {
+	case "ownerId":
+		order.OwnerId = *key.Value
+	case "lastModifiedTime":
+		order.LastModifiedTime = *key.Value
+	case "customerName":
+		order.Customer.Name = *key.Value
+	case "neighborhood":
+		order.Customer.Neighborhood = *key.Value
+	case "deliveryId":
+		if deliveryId, err := strconv.Atoi(*key.Value); err == nil {
+			order.DeliveryId = &deliveryId
+		}
+	case "amountTotalCollected":
+		order.AmountTotalCollected = key.Value
+	}
+	return order
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns if the order is between the AfterKey and BeforeKey of params
+func memIsInMulchOrderKeyset(order MulchOrderType, params GetMulchOrdersParams, cmp func(a, b MulchOrderType) int) bool {
+	if params.AfterKey != nil && cmp(order, memMulchOrderFromSortKey(*params.AfterKey)) <= 0 {
+		return false
+	}
+	if params.BeforeKey != nil && cmp(order, memMulchOrderFromSortKey(*params.BeforeKey)) >= 0 {
+		return false
+	}
+	return true
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the orders matching params in the sort order (by orderId if there
+// isn't one)
+func (s *MemStore) getMatchingMulchOrders(params GetMulchOrdersParams) ([]MulchOrderType, error) {
+	sortBy := params.SortBy
+	if len(sortBy) == 0 {
+		sortBy = "orderId"
+	}
+	cmp, err := memMulchOrderCmp(sortBy, params.IsSortDescending)
+	if err != nil {
+		return nil, err
+	}
+
+	orders := []MulchOrderType{}
+	for orderId := range s.data.orders {
+		order, _ := s.getMulchOrder(orderId)
+		if memIsMulchOrderMatch(order, params) && memIsInMulchOrderKeyset(order, params, cmp) {
+			orders = append(orders, order)
+		}
+	}
+	slices.SortFunc(orders, cmp)
+	return orders, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func cmpNil(isANil, isBNil bool) int {
+	switch {
+	case isANil && isBNil:
+		return 0
+	case isANil:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) GetMulchOrders(ctx context.Context, params GetMulchOrdersParams) ([]MulchOrderType, error) {
+	defer s.lock()()
+
+	orders, err := s.getMatchingMulchOrders(params)
+	if err != nil {
+		return nil, err
+	}
+	if params.Limit > 0 && params.Limit < len(orders) {
+		orders = orders[:params.Limit]
+	}
+	return orders, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) CountMulchOrders(ctx context.Context, params GetMulchOrdersParams) (int, error) {
+	defer s.lock()()
+
+	orders, err := s.getMatchingMulchOrders(params)
+	if err != nil {
+		return 0, err
+	}
+	return len(orders), nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) GetMulchOrder(ctx context.Context, params GetMulchOrderParams) (MulchOrderType, error) {
+	defer s.lock()()
+
+	order, ok := s.getMulchOrder(params.OrderId)
+	if !ok {
+		return order, ErrNotFound
+	}
+	return order, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func memNormalizeOrder(order MulchOrderType) (MulchOrderType, error) {
+	var err error
+	amounts := []**string{
+		&order.AmountFromDonations,
+		&order.AmountFromPurchases,
+		&order.AmountFromCashCollected,
+		&order.AmountFromChecksCollected,
+		&order.AmountTotalCollected,
+	}
+	for _, amount := range amounts {
+		if *amount, err = memNormalizeAmount(*amount); err != nil {
+			return order, err
+		}
+	}
+	order.Purchases = slices.Clone(order.Purchases)
+	order.Checks = storedOrderChecks(order.Checks)
+	// Spreaders are kept separately
+	order.Spreaders = nil
+	return order, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) InsertMulchOrder(ctx context.Context, order MulchOrderType) error {
+	defer s.lock()()
+
+	if _, ok := s.data.orders[order.OrderId]; ok {
+		return fmt.Errorf("order already exists: %s", order.OrderId)
+	}
+	order, err := memNormalizeOrder(order)
+	if err != nil {
+		return err
+	}
+	s.data.orders[order.OrderId] = order
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) UpdateMulchOrder(
+	ctx context.Context, order MulchOrderType, gqlFields []string, expectedLastModifiedTime string,
+) error {
+	defer s.lock()()
+
+	updated, ok := s.data.orders[order.OrderId]
+	if !memIsAtLastModifiedTime(ok, updated.LastModifiedTime, expectedLastModifiedTime) {
+		return ErrStaleVersion
+	}
+	if !ok {
+		return ErrNotFound
+	}
+	if err := applyMulchOrderFields(&updated, order, gqlFields); err != nil {
+		return err
+	}
+	updated.LastModifiedTime = order.LastModifiedTime
+
+	updated, err := memNormalizeOrder(updated)
+	if err != nil {
+		return err
+	}
+	s.data.orders[order.OrderId] = updated
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) DeleteMulchOrder(ctx context.Context, orderId string) error {
+	defer s.lock()()
+
+	delete(s.data.orders, orderId)
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) ResetOrderData(ctx context.Context) error {
+	defer s.lock()()
+
+	s.clearOrderData()
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) DeleteOrderData(ctx context.Context) error {
+	defer s.lock()()
+
+	s.clearOrderData()
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) clearOrderData() {
+	s.data.orders = make(map[string]MulchOrderType)
+	s.data.spreaders = make(map[string][]string)
+	s.data.timecards = make(map[memTimecardKey]MulchTimecardType)
+	s.data.allocations = make(map[string]AllocationItemType)
+	s.data.history = make(map[string][]MulchOrderRevisionType)
+	s.data.deposits = make(map[string]BankDepositType)
+	s.data.inventory = make(map[int]MulchInventoryType)
+	s.data.notices = make(map[string]NotificationType)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) SetSpreaders(ctx context.Context, orderId string, spreaders []string) error {
+	defer s.lock()()
+
+	if len(spreaders) == 0 {
+		delete(s.data.spreaders, orderId)
+	} else {
+		s.data.spreaders[orderId] = slices.Clone(spreaders)
+	}
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) GetMulchTimecards(ctx context.Context, id string, deliveryId int, gqlFields []string) ([]MulchTimecardType, error) {
+	defer s.lock()()
+
+	timecards := []MulchTimecardType{}
+	for key, tc := range s.data.timecards {
+		if len(id) != 0 && key.uid != id {
+			continue
+		}
+		if deliveryId != -1 && key.deliveryId != deliveryId {
+			continue
+		}
+		timecards = append(timecards, tc)
+	}
+	sort.SliceStable(timecards, func(i, j int) bool {
+		if timecards[i].Id != timecards[j].Id {
+			return timecards[i].Id < timecards[j].Id
+		}
+		return timecards[i].DeliveryId < timecards[j].DeliveryId
+	})
+	return timecards, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) DeleteMulchTimecard(ctx context.Context, id string, deliveryId int) error {
+	defer s.lock()()
+
+	delete(s.data.timecards, memTimecardKey{uid: id, deliveryId: deliveryId})
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) InsertMulchTimecard(ctx context.Context, timecard MulchTimecardType) error {
+	defer s.lock()()
+
+	s.data.timecards[memTimecardKey{uid: timecard.Id, deliveryId: timecard.DeliveryId}] = timecard
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) GetFundraiserConfig(ctx context.Context, gqlFields []string) (FrConfigType, error) {
+	defer s.lock()()
+
+	for _, gqlField := range gqlFields {
+		switch gqlField {
+		case "kind", "description", "lastModifiedTime", "mulchDeliveryConfigs",
+			"products", "finalizationData", "allocationPolicy", "inventoryPolicy", "isLocked", "users", "neighborhoods":
+		default:
+			return FrConfigType{}, fmt.Errorf("unknown fundraiser config field: %s", gqlField)
+		}
+	}
+
+	if s.data.config == nil {
+		return FrConfigType{}, ErrNotFound
+	}
+	return *s.data.config, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) SetFundraiserConfig(ctx context.Context, frConfig FrConfigType) error {
+	defer s.lock()()
+
+	s.data.config = &frConfig
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) UpdateFundraiserConfig(ctx context.Context, frConfig FrConfigType, expectedLastModifiedTime string) error {
+	defer s.lock()()
+
+	isFound := s.data.config != nil
+	currentLastModifiedTime := ""
+	if isFound {
+		currentLastModifiedTime = s.data.config.LastModifiedTime
+	}
+	if !memIsAtLastModifiedTime(isFound, currentLastModifiedTime, expectedLastModifiedTime) {
+		return ErrStaleVersion
+	}
+	if !isFound {
+		// Same as the database where there is no row to update
+		return nil
+	}
+
+	updated := *s.data.config
+	if len(frConfig.Kind) != 0 {
+		updated.Kind = frConfig.Kind
+	}
+	if len(frConfig.Description) != 0 {
+		updated.Description = frConfig.Description
+	}
+	if len(frConfig.Products) != 0 {
+		updated.Products = frConfig.Products
+	}
+	if nil != frConfig.MulchDeliveryConfigs {
+		updated.MulchDeliveryConfigs = frConfig.MulchDeliveryConfigs
+	}
+	if nil != frConfig.FinalizationData {
+		updated.FinalizationData = frConfig.FinalizationData
+	}
+	if nil != frConfig.AllocationPolicy {
+		updated.AllocationPolicy = frConfig.AllocationPolicy
+	}
+	if nil != frConfig.InventoryPolicy {
+		updated.InventoryPolicy = frConfig.InventoryPolicy
+	}
+	if nil != frConfig.IsLocked {
+		updated.IsLocked = frConfig.IsLocked
+	}
+	updated.LastModifiedTime = frConfig.LastModifiedTime
+	s.data.config = &updated
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) GetNeighborhoods(ctx context.Context, gqlFields []string) ([]NeighborhoodInfo, error) {
+	defer s.lock()()
+
+	neighborhoods := []NeighborhoodInfo{}
+	for _, gqlField := range gqlFields {
+		switch gqlField {
+		case "name", "zipcode", "city", "isVisible", "distributionPoint", "lastModifiedTime":
+		default:
+			return neighborhoods, fmt.Errorf("unknown fundraiser neighborhood field: %s", gqlField)
+		}
+	}
+
+	for _, hood := range s.data.hoods {
+		neighborhoods = append(neighborhoods, hood)
+	}
+	sort.SliceStable(neighborhoods, func(i, j int) bool {
+		return neighborhoods[i].Name < neighborhoods[j].Name
+	})
+	return neighborhoods, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) InsertNeighborhood(ctx context.Context, hood NeighborhoodInfo) error {
+	defer s.lock()()
+
+	if _, ok := s.data.hoods[hood.Name]; ok {
+		return fmt.Errorf("neighborhood already exists: %s", hood.Name)
+	}
+	s.data.hoods[hood.Name] = hood
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) UpdateNeighborhood(ctx context.Context, hood NeighborhoodInfo, expectedLastModifiedTime string) error {
+	defer s.lock()()
+
+	updated, ok := s.data.hoods[hood.Name]
+	if !memIsAtLastModifiedTime(ok, updated.LastModifiedTime, expectedLastModifiedTime) {
+		return ErrStaleVersion
+	}
+	if !ok {
+		return nil
+	}
+	if nil != hood.Zipcode {
+		updated.Zipcode = hood.Zipcode
+	}
+	if nil != hood.City {
+		updated.City = hood.City
+	}
+	if nil != hood.DistributionPoint {
+		updated.DistributionPoint = hood.DistributionPoint
+	}
+	if nil != hood.IsVisible {
+		updated.IsVisible = hood.IsVisible
+	}
+	updated.LastModifiedTime = hood.LastModifiedTime
+	s.data.hoods[hood.Name] = updated
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) GetUsers(ctx context.Context, params GetUsersParams) ([]UserInfo, error) {
+	defer s.lock()()
+
+	users := []UserInfo{}
+	doWantFullNames := false
+	for _, gqlField := range params.GqlFields {
+		switch gqlField {
+		case "name":
+			doWantFullNames = true
+		case "firstName", "lastName", "id", "group", "hasAuthCreds", "lastModifiedTime":
+		default:
+			return users, fmt.Errorf("unknown fundraiser user field: %s", gqlField)
+		}
+	}
+
+	for _, user := range s.data.users {
+		if params.ShowUsersWithoutAuthCreds && (user.HasAuthCreds == nil || *user.HasAuthCreds) {
+			continue
+		}
+		if doWantFullNames {
+			user.Name = fmt.Sprintf("%s %s", user.FirstName, user.LastName)
+		}
+		users = append(users, user)
+	}
+	sort.SliceStable(users, func(i, j int) bool {
+		return strings.Compare(users[i].Id, users[j].Id) < 0
+	})
+	return users, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) InsertUser(ctx context.Context, user UserInfo) error {
+	defer s.lock()()
+
+	if _, ok := s.data.users[user.Id]; ok {
+		return fmt.Errorf("user already exists: %s", user.Id)
+	}
+	hasAuthCreds := false
+	user.HasAuthCreds = &hasAuthCreds
+	user.Name = ""
+	s.data.users[user.Id] = user
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) UpdateUser(ctx context.Context, user UserInfo, expectedLastModifiedTime string) error {
+	defer s.lock()()
+
+	updated, ok := s.data.users[user.Id]
+	if !memIsAtLastModifiedTime(ok, updated.LastModifiedTime, expectedLastModifiedTime) {
+		return ErrStaleVersion
+	}
+	if !ok {
+		return nil
+	}
+	if nil != user.HasAuthCreds {
+		updated.HasAuthCreds = user.HasAuthCreds
+	}
+	if len(user.Group) != 0 {
+		updated.Group = user.Group
+	}
+	updated.LastModifiedTime = user.LastModifiedTime
+	s.data.users[user.Id] = updated
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) ResetUsers(ctx context.Context) error {
+	defer s.lock()()
+
+	s.data.users = make(map[string]UserInfo)
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) GetAllocation(ctx context.Context, uid string) (AllocationItemType, error) {
+	defer s.lock()()
+
+	item, ok := s.data.allocations[uid]
+	if !ok {
+		return AllocationItemType{Uid: uid}, ErrNotFound
+	}
+	return item, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) GetAllocations(ctx context.Context) ([]AllocationItemType, error) {
+	defer s.lock()()
+
+	allocations := slices.Collect(maps.Values(s.data.allocations))
+	slices.SortFunc(allocations, func(a, b AllocationItemType) int { return strings.Compare(a.Uid, b.Uid) })
+	return allocations, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) SetAllocations(ctx context.Context, allocations []AllocationItemType) error {
+	defer s.lock()()
+
+	s.data.allocations = make(map[string]AllocationItemType)
+	for _, item := range allocations {
+		s.data.allocations[item.Uid] = item
+	}
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) InsertAuditLogEntry(ctx context.Context, entry AuditLogEntryType) error {
+	defer s.lock()()
+
+	s.data.nextAuditId++
+	entry.Id = strconv.Itoa(s.data.nextAuditId)
+	s.data.auditLog = append(s.data.auditLog, entry)
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) GetAuditLog(ctx context.Context, params GetAuditLogParams) ([]AuditLogEntryType, error) {
+	defer s.lock()()
+
+	entries := []AuditLogEntryType{}
+	// Entries are appended in time order so walk backwards for newest first
+	for idx := len(s.data.auditLog) - 1; idx >= 0; idx-- {
+		entry := s.data.auditLog[idx]
+		if len(params.ActorId) != 0 && entry.ActorId != params.ActorId {
+			continue
+		}
+		if len(params.Operation) != 0 && entry.Operation != params.Operation {
+			continue
+		}
+		if len(params.TargetKind) != 0 && entry.TargetKind != params.TargetKind {
+			continue
+		}
+		if len(params.TargetKey) != 0 && entry.TargetKey != params.TargetKey {
+			continue
+		}
+		if params.After != nil || params.Before != nil {
+			createdTime, err := time.Parse(time.RFC3339, entry.CreatedTime)
+			if err != nil {
+				return nil, err
+			}
+			if params.After != nil && createdTime.Before(*params.After) {
+				continue
+			}
+			if params.Before != nil && !createdTime.Before(*params.Before) {
+				continue
+			}
+		}
+		entries = append(entries, entry)
+		if params.Limit > 0 && len(entries) == params.Limit {
+			break
+		}
+	}
+	return entries, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) InsertMulchOrderRevision(ctx context.Context, revision MulchOrderRevisionType) (int, error) {
+	defer s.lock()()
+
+	history := s.data.history[revision.OrderId]
+	revision.Revision = len(history) + 1
+	revision.Order.Purchases = slices.Clone(revision.Order.Purchases)
+	revision.Order.Checks = slices.Clone(revision.Order.Checks)
+	revision.Order.Spreaders = slices.Clone(revision.Order.Spreaders)
+	// Clipped so a transaction never appends into the original's array
+	s.data.history[revision.OrderId] = append(slices.Clip(history), revision)
+	return revision.Revision, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) GetMulchOrderHistory(ctx context.Context, orderId string) ([]MulchOrderRevisionType, error) {
+	defer s.lock()()
+
+	history := []MulchOrderRevisionType{}
+	for _, revision := range s.data.history[orderId] {
+		revision.Order.Purchases = slices.Clone(revision.Order.Purchases)
+		revision.Order.Checks = slices.Clone(revision.Order.Checks)
+		revision.Order.Spreaders = slices.Clone(revision.Order.Spreaders)
+		history = append(history, revision)
+	}
+	return history, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) GetMulchOrderRevision(ctx context.Context, orderId string, revision int) (MulchOrderRevisionType, error) {
+	defer s.lock()()
+
+	history := s.data.history[orderId]
+	if revision < 1 || revision > len(history) {
+		return MulchOrderRevisionType{}, ErrNotFound
+	}
+	orderRevision := history[revision-1]
+	orderRevision.Order.Purchases = slices.Clone(orderRevision.Order.Purchases)
+	orderRevision.Order.Checks = slices.Clone(orderRevision.Order.Checks)
+	orderRevision.Order.Spreaders = slices.Clone(orderRevision.Order.Spreaders)
+	return orderRevision, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) InsertSeasonArchive(ctx context.Context, archive SeasonArchiveType, contents SeasonArchiveContentsType) error {
+	defer s.lock()()
+
+	if _, ok := s.data.seasons[archive.Season]; ok {
+		return fmt.Errorf("season: %d is already archived", archive.Season)
+	}
+	s.data.seasons[archive.Season] = memSeasonArchive{
+		archive: archive,
+		contents: SeasonArchiveContentsType{
+			MulchOrders:      slices.Clone(contents.MulchOrders),
+			MulchTimecards:   slices.Clone(contents.MulchTimecards),
+			Allocations:      slices.Clone(contents.Allocations),
+			Users:            slices.Clone(contents.Users),
+			BankDeposits:     slices.Clone(contents.BankDeposits),
+			MulchInventories: slices.Clone(contents.MulchInventories),
+		},
+	}
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) GetSeasonArchives(ctx context.Context) ([]SeasonArchiveType, error) {
+	defer s.lock()()
+
+	archives := []SeasonArchiveType{}
+	for _, season := range s.data.seasons {
+		archives = append(archives, season.archive)
+	}
+	slices.SortFunc(archives, func(a, b SeasonArchiveType) int { return b.Season - a.Season })
+	return archives, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) GetSeasonArchive(ctx context.Context, season int) (SeasonArchiveType, error) {
+	defer s.lock()()
+
+	archived, ok := s.data.seasons[season]
+	if !ok {
+		return SeasonArchiveType{}, ErrNotFound
+	}
+	return archived.archive, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) GetArchivedMulchOrders(ctx context.Context, season int, ownerId string) ([]MulchOrderType, error) {
+	defer s.lock()()
+
+	orders := []MulchOrderType{}
+	for _, order := range s.data.seasons[season].contents.MulchOrders {
+		if len(ownerId) == 0 || order.OwnerId == ownerId {
+			orders = append(orders, order)
+		}
+	}
+	slices.SortFunc(orders, func(a, b MulchOrderType) int { return strings.Compare(a.OrderId, b.OrderId) })
+	return orders, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) GetArchivedMulchTimecards(ctx context.Context, season int, uid string) ([]MulchTimecardType, error) {
+	defer s.lock()()
+
+	timecards := []MulchTimecardType{}
+	for _, timecard := range s.data.seasons[season].contents.MulchTimecards {
+		if len(uid) == 0 || timecard.Id == uid {
+			timecards = append(timecards, timecard)
+		}
+	}
+	return timecards, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) GetArchivedAllocations(ctx context.Context, season int, uid string) ([]AllocationItemType, error) {
+	defer s.lock()()
+
+	allocations := []AllocationItemType{}
+	for _, item := range s.data.seasons[season].contents.Allocations {
+		if len(uid) == 0 || item.Uid == uid {
+			allocations = append(allocations, item)
+		}
+	}
+	return allocations, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) GetArchivedUsers(ctx context.Context, season int) ([]UserInfo, error) {
+	defer s.lock()()
+
+	users := slices.Clone(s.data.seasons[season].contents.Users)
+	slices.SortFunc(users, func(a, b UserInfo) int { return strings.Compare(a.Id, b.Id) })
+	return users, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) InsertBankDeposit(ctx context.Context, deposit BankDepositType) (string, error) {
+	defer s.lock()()
+
+	s.data.nextDepositId++
+	deposit.Id = strconv.Itoa(s.data.nextDepositId)
+	deposit.Items = slices.Clone(deposit.Items)
+	s.data.deposits[deposit.Id] = deposit
+	return deposit.Id, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) GetBankDeposits(ctx context.Context) ([]BankDepositType, error) {
+	defer s.lock()()
+
+	deposits := []BankDepositType{}
+	for _, deposit := range s.data.deposits {
+		deposit.Items = slices.Clone(deposit.Items)
+		deposits = append(deposits, deposit)
+	}
+	slices.SortFunc(deposits, func(a, b BankDepositType) int {
+		if cmp := strings.Compare(a.DepositDate, b.DepositDate); cmp != 0 {
+			return cmp
+		}
+		return strings.Compare(a.CreatedTime, b.CreatedTime)
+	})
+	return deposits, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) GetBankDeposit(ctx context.Context, id string) (BankDepositType, error) {
+	defer s.lock()()
+
+	deposit, ok := s.data.deposits[id]
+	if !ok {
+		return BankDepositType{}, ErrNotFound
+	}
+	deposit.Items = slices.Clone(deposit.Items)
+	return deposit, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) DeleteBankDeposit(ctx context.Context, id string) error {
+	defer s.lock()()
+
+	delete(s.data.deposits, id)
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) GetMulchInventories(ctx context.Context) ([]MulchInventoryType, error) {
+	defer s.lock()()
+
+	inventories := slices.Collect(maps.Values(s.data.inventory))
+	slices.SortFunc(inventories, func(a, b MulchInventoryType) int { return a.DeliveryId - b.DeliveryId })
+	if inventories == nil {
+		inventories = []MulchInventoryType{}
+	}
+	return inventories, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) GetMulchInventory(ctx context.Context, deliveryId int) (MulchInventoryType, error) {
+	defer s.lock()()
+
+	inventory, ok := s.data.inventory[deliveryId]
+	if !ok {
+		return MulchInventoryType{}, ErrNotFound
+	}
+	return inventory, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) UpsertMulchInventory(ctx context.Context, inventory MulchInventoryType) error {
+	defer s.lock()()
+
+	s.data.inventory[inventory.DeliveryId] = inventory
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) DeleteMulchInventory(ctx context.Context, deliveryId int) error {
+	defer s.lock()()
+
+	delete(s.data.inventory, deliveryId)
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) InsertNotification(ctx context.Context, notification NotificationType) error {
+	defer s.lock()()
+
+	s.data.nextNotificationId++
+	notification.Id = strconv.Itoa(s.data.nextNotificationId)
+	s.data.notices[notification.Id] = notification
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) GetNotifications(ctx context.Context, uid string, isUnreadOnly bool) ([]NotificationType, error) {
+	defer s.lock()()
+
+	notifications := []NotificationType{}
+	for _, notification := range s.data.notices {
+		if notification.Uid == uid && !(isUnreadOnly && notification.IsRead) {
+			notifications = append(notifications, notification)
+		}
+	}
+	slices.SortFunc(notifications, func(a, b NotificationType) int {
+		if cmp := strings.Compare(b.CreatedTime, a.CreatedTime); cmp != 0 {
+			return cmp
+		}
+		idA, _ := strconv.Atoi(a.Id)
+		idB, _ := strconv.Atoi(b.Id)
+		return idB - idA
+	})
+	return notifications, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) GetNotification(ctx context.Context, id string) (NotificationType, error) {
+	defer s.lock()()
+
+	notification, ok := s.data.notices[id]
+	if !ok {
+		return NotificationType{}, ErrNotFound
+	}
+	return notification, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) MarkNotificationRead(ctx context.Context, id string) error {
+	defer s.lock()()
+
+	notification, ok := s.data.notices[id]
+	if !ok {
+		return ErrNotFound
+	}
+	notification.IsRead = true
+	s.data.notices[id] = notification
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) GetKnownAddrs(ctx context.Context) ([]KnownAddrType, error) {
+	defer s.lock()()
+
+	addrs := slices.DeleteFunc(slices.Collect(maps.Values(s.data.knownAddrs)), func(addr KnownAddrType) bool {
+		return len(addr.Lat) == 0 || len(addr.Lng) == 0
+	})
+	slices.SortFunc(addrs, func(a, b KnownAddrType) int { return strings.Compare(a.Id, b.Id) })
+	return addrs, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) GetKnownAddrsInBox(ctx context.Context, box GeoBoxType) ([]KnownAddrType, error) {
+	defer s.lock()()
+
+	addrs := []KnownAddrType{}
+	for _, addr := range s.data.knownAddrs {
+		if lat, lng, ok := parseKnownAddrLocation(addr); ok && box.contains(lat, lng) {
+			addrs = append(addrs, addr)
+		}
+	}
+	slices.SortFunc(addrs, func(a, b KnownAddrType) int { return strings.Compare(a.Id, b.Id) })
+	return addrs, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) GetKnownAddrsByNormalizedAddr(ctx context.Context, normalizedAddr string) ([]KnownAddrType, error) {
+	defer s.lock()()
+
+	addrs := []KnownAddrType{}
+	for _, addr := range s.data.knownAddrs {
+		if normalizeAddr(addr.Addr) == normalizedAddr {
+			addrs = append(addrs, addr)
+		}
+	}
+	slices.SortFunc(addrs, func(a, b KnownAddrType) int { return strings.Compare(a.Id, b.Id) })
+	return addrs, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *MemStore) InsertKnownAddr(ctx context.Context, addr KnownAddrType) (string, error) {
+	defer s.lock()()
+
+	s.data.nextKnownAddrId++
+	addr.Id = strconv.Itoa(s.data.nextKnownAddrId)
+	s.data.knownAddrs[addr.Id] = addr
+	return addr.Id, nil
+}
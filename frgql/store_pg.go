@@ -0,0 +1,2239 @@
+package frgql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/doug-martin/goqu/v9"
+	_ "github.com/doug-martin/goqu/v9/dialect/postgres"
+	"github.com/doug-martin/goqu/v9/exp"
+)
+
+// //////////////////////////////////////////////////////////////////////////
+// Common subset of pgxpool.Pool and pgx.Tx so the same store code can run
+// either directly against the pool or inside of a transaction
+type pgQuerier interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Postgres/Cockroach backed FrStore
+type pgStore struct {
+	pool *pgxpool.Pool // nil when this store is bound to a transaction
+	db   pgQuerier
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func NewPgStore(pool *pgxpool.Pool) FrStore {
+	return &pgStore{pool: pool, db: pool}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) InTx(ctx context.Context, fn func(tx FrStore) error) error {
+	if s.pool == nil {
+		// Already in a transaction
+		return fn(s)
+	}
+
+	trxn, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(&pgStore{db: trxn}); err != nil {
+		trxn.Rollback(context.Background())
+		return err
+	}
+
+	log.Println("About to make a commitment")
+	return trxn.Commit(ctx)
+}
+
+// What has been deposited of each order
+const BANK_DEPOSITED_JOIN_SQL = "LEFT JOIN (SELECT order_id, SUM(cash_amount) AS cash_amount, " +
+	"SUM(check_amount) AS check_amount FROM bank_deposit_items GROUP BY order_id) AS deposited " +
+	"ON deposited.order_id = mulch_orders.order_id"
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) GetMulchOrdersMoneyCollected(ctx context.Context, params GetMulchOrdersParams) ([]MulchOrderMoneyCollectedType, error) {
+	////////////////////////////////////////////////////////////////////////////
+	//
+	gql2sql := func(orderOutput *MulchOrderMoneyCollectedType) ([]string, []interface{}, string) {
+		sqlFields := []string{}
+		inputs := []interface{}{}
+		joinSql := ""
+		for _, gqlField := range params.GqlFields {
+			// log.Println(gqlField)
+			switch gqlField {
+			case "ownerId":
+				inputs = append(inputs, &orderOutput.OwnerId)
+				sqlFields = append(sqlFields, "order_owner_id")
+			case "deliveryId":
+				inputs = append(inputs, &orderOutput.DeliveryId)
+				sqlFields = append(sqlFields, "delivery_id")
+			case "amountTotalCollected":
+				inputs = append(inputs, &orderOutput.AmountTotalCollected)
+				sqlFields = append(sqlFields, "SUM(total_amount_collected)::string")
+			case "amountTotalFromCashCollected":
+				inputs = append(inputs, &orderOutput.AmountTotalFromCashCollected)
+				sqlFields = append(sqlFields, "SUM(cash_amount_collected)::string")
+			case "amountTotalFromChecksCollected":
+				inputs = append(inputs, &orderOutput.AmountTotalFromChecksCollected)
+				sqlFields = append(sqlFields, "SUM(check_amount_collected)::string")
+			case "amountTotalFromCashDeposited":
+				inputs = append(inputs, &orderOutput.AmountTotalFromCashDeposited)
+				sqlFields = append(sqlFields, "SUM(COALESCE(deposited.cash_amount, 0))::string")
+				joinSql = BANK_DEPOSITED_JOIN_SQL
+			case "amountTotalFromChecksDeposited":
+				inputs = append(inputs, &orderOutput.AmountTotalFromChecksDeposited)
+				sqlFields = append(sqlFields, "SUM(COALESCE(deposited.check_amount, 0))::string")
+				joinSql = BANK_DEPOSITED_JOIN_SQL
+			default:
+				// log.Println("Do not know how to handle mulch orders money collected GraphQL Field: ", gqlField)
+			}
+		}
+		return sqlFields, inputs, joinSql
+	}
+
+	order := MulchOrderMoneyCollectedType{}
+	sqlFields, _, joinSql := gql2sql(&order)
+
+	doQuery := func() (pgx.Rows, error) {
+		sqlCmd := fmt.Sprintf("select %s from mulch_orders %s", strings.Join(sqlFields, ","), joinSql)
+		if len(params.OwnerId) != 0 {
+			sqlCmd = sqlCmd + " where order_owner_id=$1 group by order_owner_id, delivery_id"
+			log.Println("SqlCmd: ", sqlCmd)
+			return s.db.Query(ctx, sqlCmd, params.OwnerId)
+		} else {
+			sqlCmd = sqlCmd + " group by order_owner_id, delivery_id"
+			log.Println("SqlCmd: ", sqlCmd)
+			return s.db.Query(ctx, sqlCmd)
+		}
+	}
+
+	orders := []MulchOrderMoneyCollectedType{}
+	rows, err := doQuery()
+	if err != nil {
+		return orders, err
+	}
+	defer rows.Close()
+
+	scanErrs := []error{}
+	for rows.Next() {
+		order := MulchOrderMoneyCollectedType{}
+		_, inputs, _ := gql2sql(&order)
+		err = rows.Scan(inputs...)
+		if err != nil {
+			log.Println("Reading mulch order money collection row failed: ", err)
+			scanErrs = append(scanErrs, err)
+			continue
+		}
+		orders = append(orders, order)
+	}
+
+	if err := rows.Err(); err != nil {
+		return []MulchOrderMoneyCollectedType{}, err
+	}
+	return orders, newRowScanError(scanErrs)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func mulchOrderGql2SqlMap(gqlFields []string, orderOutput *MulchOrderType, queryBuilder *goqu.SelectDataset) (*goqu.SelectDataset, []interface{}) {
+	sqlFields := []interface{}{}
+	inputs := []interface{}{}
+	for _, gqlField := range gqlFields {
+		// log.Println(gqlField)
+		switch gqlField {
+		case "orderId":
+			inputs = append(inputs, &orderOutput.OrderId)
+			sqlFields = append(sqlFields, goqu.L("mulch_orders.order_id"))
+		case "ownerId":
+			inputs = append(inputs, &orderOutput.OwnerId)
+			sqlFields = append(sqlFields, "order_owner_id")
+		case "amountTotalCollected":
+			inputs = append(inputs, &orderOutput.AmountTotalCollected)
+			sqlFields = append(sqlFields, goqu.L("total_amount_collected::string"))
+		case "purchases":
+			inputs = append(inputs, &orderOutput.Purchases)
+			sqlFields = append(sqlFields, goqu.L("purchases::jsonb"))
+		case "lastModifiedTime":
+			inputs = append(inputs, &orderOutput.LastModifiedTime)
+			sqlFields = append(sqlFields, goqu.L("last_modified_time::string"))
+		case "comments":
+			inputs = append(inputs, &orderOutput.Comments)
+			sqlFields = append(sqlFields, "comments")
+		case "specialInstructions":
+			inputs = append(inputs, &orderOutput.SpecialInstructions)
+			sqlFields = append(sqlFields, "special_instructions")
+		case "amountFromDonations":
+			inputs = append(inputs, &orderOutput.AmountFromDonations)
+			sqlFields = append(sqlFields, goqu.L("amount_from_donations::string"))
+		case "amountFromPurchases":
+			inputs = append(inputs, &orderOutput.AmountFromPurchases)
+			sqlFields = append(sqlFields, goqu.L("amount_from_purchases::string"))
+		case "amountFromCashCollected":
+			inputs = append(inputs, &orderOutput.AmountFromCashCollected)
+			sqlFields = append(sqlFields, goqu.L("cash_amount_collected::string"))
+		case "amountFromChecksCollected":
+			inputs = append(inputs, &orderOutput.AmountFromChecksCollected)
+			sqlFields = append(sqlFields, goqu.L("check_amount_collected::string"))
+		case "checkNumbers":
+			inputs = append(inputs, &orderOutput.CheckNumbers)
+			sqlFields = append(sqlFields, goqu.L("check_numbers::string"))
+		case "checks":
+			inputs = append(inputs, &orderOutput.Checks)
+			sqlFields = append(sqlFields, goqu.L("checks::jsonb"))
+		case "knownAddrId":
+			inputs = append(inputs, &orderOutput.KnownAddrId)
+			sqlFields = append(sqlFields, goqu.L("known_addr_id::string"))
+		case "deliveryId":
+			inputs = append(inputs, &orderOutput.DeliveryId)
+			sqlFields = append(sqlFields, "delivery_id")
+		case "willCollectMoneyLater":
+			inputs = append(inputs, &orderOutput.WillCollectMoneyLater)
+			sqlFields = append(sqlFields, "will_collect_money_later")
+		case "isVerified":
+			inputs = append(inputs, &orderOutput.IsVerified)
+			sqlFields = append(sqlFields, "is_verified")
+		case "spreaders":
+			inputs = append(inputs, &orderOutput.Spreaders)
+			sqlFields = append(sqlFields, "spreaders")
+			if queryBuilder != nil {
+				queryBuilder = queryBuilder.LeftJoin(goqu.T("mulch_spreaders"),
+					goqu.On(goqu.Ex{"mulch_orders.order_id": goqu.I("mulch_spreaders.order_id")}),
+				)
+			}
+		case "customer":
+			inputs = append(inputs, &orderOutput.Customer.Name)
+			sqlFields = append(sqlFields, "customer_name")
+			inputs = append(inputs, &orderOutput.Customer.Addr1)
+			sqlFields = append(sqlFields, "customer_addr1")
+			inputs = append(inputs, &orderOutput.Customer.Addr2)
+			sqlFields = append(sqlFields, "customer_addr2")
+			inputs = append(inputs, &orderOutput.Customer.City)
+			sqlFields = append(sqlFields, "customer_city")
+			inputs = append(inputs, &orderOutput.Customer.Zipcode)
+			sqlFields = append(sqlFields, "customer_zipcode")
+			inputs = append(inputs, &orderOutput.Customer.Phone)
+			sqlFields = append(sqlFields, "customer_phone")
+			inputs = append(inputs, &orderOutput.Customer.Email)
+			sqlFields = append(sqlFields, "customer_email")
+			inputs = append(inputs, &orderOutput.Customer.Neighborhood)
+			sqlFields = append(sqlFields, "customer_neighborhood")
+		default:
+			log.Println("Do not know how to handle mulch order GraphQL Field: ", gqlField)
+		}
+	}
+	if queryBuilder != nil {
+		queryBuilder = queryBuilder.Select(sqlFields...)
+	}
+	return queryBuilder, inputs
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func applyMulchOrderFilters(queryBuilder *goqu.SelectDataset, params GetMulchOrdersParams, isSpreadersJoined bool) *goqu.SelectDataset {
+	if len(params.OwnerId) != 0 {
+		log.Println("Retrieving mulch orders. OwnerId: ", params.OwnerId)
+		queryBuilder = queryBuilder.Where(goqu.Ex{"order_owner_id": params.OwnerId})
+	}
+
+	if len(params.ExcludeOwnerId) != 0 {
+		log.Println("Retrieving mulch orders that exclude OwnerId: ", params.ExcludeOwnerId)
+		queryBuilder = queryBuilder.Where(goqu.Ex{"order_owner_id": goqu.Op{"neq": params.ExcludeOwnerId}})
+	}
+
+	if params.DoGetSpreadOrdersOnly {
+		queryBuilder = queryBuilder.Where(goqu.L(`purchases @> '[{"productId": "spreading"}]'`))
+	}
+
+	if len(params.SpreaderId) != 0 {
+		// if spreaders aren't in the GQL query then we need to make the join here.
+		if !isSpreadersJoined {
+			queryBuilder = queryBuilder.LeftJoin(goqu.T("mulch_spreaders"),
+				goqu.On(goqu.Ex{"mulch_orders.order_id": goqu.I("mulch_spreaders.order_id")}),
+			)
+		}
+
+		queryBuilder = queryBuilder.Where(goqu.V(params.SpreaderId).Eq(goqu.Any(goqu.L("spreaders"))))
+	}
+
+	if len(params.Neighborhood) != 0 {
+		queryBuilder = queryBuilder.Where(goqu.Ex{"customer_neighborhood": params.Neighborhood})
+	}
+
+	if params.DeliveryId != nil {
+		queryBuilder = queryBuilder.Where(goqu.Ex{"delivery_id": *params.DeliveryId})
+	}
+
+	// NULL is treated as false for the flags
+	if params.IsVerified != nil {
+		if *params.IsVerified {
+			queryBuilder = queryBuilder.Where(goqu.C("is_verified").IsTrue())
+		} else {
+			queryBuilder = queryBuilder.Where(goqu.C("is_verified").IsNotTrue())
+		}
+	}
+
+	if params.WillCollectMoneyLater != nil {
+		if *params.WillCollectMoneyLater {
+			queryBuilder = queryBuilder.Where(goqu.C("will_collect_money_later").IsTrue())
+		} else {
+			queryBuilder = queryBuilder.Where(goqu.C("will_collect_money_later").IsNotTrue())
+		}
+	}
+
+	if params.ModifiedAfter != nil {
+		queryBuilder = queryBuilder.Where(goqu.C("last_modified_time").Gte(params.ModifiedAfter.UTC()))
+	}
+
+	if params.ModifiedBefore != nil {
+		queryBuilder = queryBuilder.Where(goqu.C("last_modified_time").Lt(params.ModifiedBefore.UTC()))
+	}
+
+	if params.AfterKey != nil {
+		queryBuilder = queryBuilder.Where(mulchOrderKeysetExpression(*params.AfterKey, params.IsSortDescending))
+	}
+	if params.BeforeKey != nil {
+		// Before in one direction is after in the other
+		queryBuilder = queryBuilder.Where(mulchOrderKeysetExpression(*params.BeforeKey, !params.IsSortDescending))
+	}
+
+	if len(params.Search) != 0 {
+		// Escape LIKE wildcards so the search is just plain text
+		pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(params.Search) + "%"
+		queryBuilder = queryBuilder.Where(goqu.Or(
+			goqu.C("customer_name").ILike(pattern),
+			goqu.C("customer_addr1").ILike(pattern),
+			goqu.C("customer_addr2").ILike(pattern),
+		))
+	}
+	return queryBuilder
+}
+
+// //////////////////////////////////////////////////////////////////////////
+var mulchOrderSortColumns = map[string]string{
+	"orderId":              "mulch_orders.order_id",
+	"ownerId":              "order_owner_id",
+	"lastModifiedTime":     "last_modified_time",
+	"customerName":         "customer_name",
+	"neighborhood":         "customer_neighborhood",
+	"deliveryId":           "delivery_id",
+	"amountTotalCollected": "total_amount_collected",
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Matches the orders that come after key when sorted by its field the way
+// GetMulchOrders sorts (NULLs first ascending and last descending, ties by
+// order_id).  Unknown sort fields only compare the order_id since
+// GetMulchOrders rejects them anyway.
+func mulchOrderKeysetExpression(key MulchOrderSortKey, isDescending bool) exp.Expression {
+	idColumn := goqu.I("mulch_orders.order_id")
+	var isIdAfter exp.Expression = idColumn.Gt(key.OrderId)
+	if isDescending {
+		isIdAfter = idColumn.Lt(key.OrderId)
+	}
+
+	sortColumn, ok := mulchOrderSortColumns[key.SortBy]
+	if !ok || key.SortBy == "orderId" {
+		return isIdAfter
+	}
+	column := goqu.I(sortColumn)
+	if key.Value == nil {
+		if isDescending {
+			return goqu.And(column.IsNull(), isIdAfter)
+		}
+		return goqu.Or(column.IsNotNull(), goqu.And(column.IsNull(), isIdAfter))
+	}
+	var isValueAfter exp.Expression = column.Gt(*key.Value)
+	if isDescending {
+		isValueAfter = goqu.Or(column.Lt(*key.Value), column.IsNull())
+	}
+	return goqu.Or(isValueAfter, goqu.And(column.Eq(*key.Value), isIdAfter))
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) GetMulchOrders(ctx context.Context, params GetMulchOrdersParams) ([]MulchOrderType, error) {
+	order := MulchOrderType{}
+
+	doQuery := func() (pgx.Rows, error) {
+		queryBuilder := goqu.Dialect("postgres").From("mulch_orders")
+
+		queryBuilder, _ = mulchOrderGql2SqlMap(params.GqlFields, &order, queryBuilder)
+		queryBuilder = applyMulchOrderFilters(queryBuilder, params, slices.Contains(params.GqlFields, "spreaders"))
+
+		if len(params.SortBy) != 0 {
+			sortColumn, ok := mulchOrderSortColumns[params.SortBy]
+			if !ok {
+				return nil, fmt.Errorf("unknown mulch order sort field: %s", params.SortBy)
+			}
+			if params.IsSortDescending {
+				queryBuilder = queryBuilder.Order(goqu.I(sortColumn).Desc().NullsLast(), goqu.I("mulch_orders.order_id").Desc())
+			} else {
+				queryBuilder = queryBuilder.Order(goqu.I(sortColumn).Asc().NullsFirst(), goqu.I("mulch_orders.order_id").Asc())
+			}
+		}
+
+		if params.Limit > 0 {
+			queryBuilder = queryBuilder.Limit(uint(params.Limit))
+		}
+
+		sqlCmd, args, err := queryBuilder.ToSQL()
+		if err != nil {
+			return nil, err
+		}
+		log.Println("SqlCmd: ", sqlCmd)
+		return s.db.Query(ctx, sqlCmd, args...)
+	}
+
+	orders := []MulchOrderType{}
+	rows, err := doQuery()
+	if err != nil {
+		return orders, err
+	}
+	defer rows.Close()
+
+	// Process query results
+	scanErrs := []error{}
+	for rows.Next() {
+		order := MulchOrderType{}
+		_, inputs := mulchOrderGql2SqlMap(params.GqlFields, &order, nil)
+		err = rows.Scan(inputs...)
+		if err != nil {
+			log.Println("Reading mulch order row failed: ", err)
+			scanErrs = append(scanErrs, err)
+			continue
+		}
+		orders = append(orders, order)
+	}
+
+	if err := rows.Err(); err != nil {
+		return []MulchOrderType{}, err
+	}
+	return orders, newRowScanError(scanErrs)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) CountMulchOrders(ctx context.Context, params GetMulchOrdersParams) (int, error) {
+	queryBuilder := goqu.Dialect("postgres").From("mulch_orders").Select(goqu.COUNT(goqu.Star()))
+	queryBuilder = applyMulchOrderFilters(queryBuilder, params, false)
+
+	sqlCmd, args, err := queryBuilder.ToSQL()
+	if err != nil {
+		return 0, err
+	}
+	log.Println("SqlCmd: ", sqlCmd)
+
+	numOrders := 0
+	err = s.db.QueryRow(ctx, sqlCmd, args...).Scan(&numOrders)
+	return numOrders, err
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) GetMulchOrder(ctx context.Context, params GetMulchOrderParams) (MulchOrderType, error) {
+	order := MulchOrderType{}
+	queryBuilder := goqu.Dialect("postgres").From("mulch_orders").Where(goqu.Ex{"mulch_orders.order_id": params.OrderId})
+	queryBuilder, inputs := mulchOrderGql2SqlMap(params.GqlFields, &order, queryBuilder)
+
+	sqlCmd, args, err := queryBuilder.ToSQL()
+	if err != nil {
+		return order, err
+	}
+	log.Println("SqlCmd: ", sqlCmd)
+	if err = s.db.QueryRow(ctx, sqlCmd, args...).Scan(inputs...); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return order, ErrNotFound
+		}
+		return order, err
+	}
+	// log.Println("Purchases: ", order.Purchases)
+	return order, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func OrderType2Sql(order MulchOrderType) ([]string, []string, []interface{}) {
+	values := []interface{}{}
+	valIdxs := []string{}
+	valIdx := 1
+	sqlFields := []string{}
+
+	// Sometimes orders come in with "0" for amount fields and we do
+	// not want to put those in the database so this strips them out
+	strip_0_from_str := func(amount *string) {
+		if *amount == "0" {
+			values = append(values, nil)
+			valIdxs = append(valIdxs, fmt.Sprintf("$%d", valIdx))
+		} else {
+			values = append(values, *amount)
+			valIdxs = append(valIdxs, fmt.Sprintf("$%d::decimal", valIdx))
+		}
+		valIdx++
+	}
+
+	// Do OrderID first because it is always there
+	sqlFields = append(sqlFields, "order_id")
+	values = append(values, order.OrderId)
+	valIdxs = append(valIdxs, fmt.Sprintf("$%d::uuid", valIdx))
+	valIdx++
+
+	sqlFields = append(sqlFields, "last_modified_time")
+	values = append(values, order.LastModifiedTime)
+	valIdxs = append(valIdxs, fmt.Sprintf("$%d::timestamp", valIdx))
+	valIdx++
+
+	if len(order.OwnerId) != 0 {
+		sqlFields = append(sqlFields, "order_owner_id")
+		values = append(values, order.OwnerId)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
+		valIdx++
+	}
+	if len(order.Purchases) != 0 {
+		sqlFields = append(sqlFields, "purchases")
+		values = append(values, order.Purchases)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::jsonb", valIdx))
+		valIdx++
+	}
+	if len(order.Checks) != 0 {
+		sqlFields = append(sqlFields, "checks")
+		values = append(values, storedOrderChecks(order.Checks))
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::jsonb", valIdx))
+		valIdx++
+	}
+	if nil != order.KnownAddrId {
+		sqlFields = append(sqlFields, "known_addr_id")
+		values = append(values, *order.KnownAddrId)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::uuid", valIdx))
+		valIdx++
+	}
+	if nil != order.Comments {
+		sqlFields = append(sqlFields, "comments")
+		values = append(values, *order.Comments)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
+		valIdx++
+	}
+	if nil != order.SpecialInstructions {
+		sqlFields = append(sqlFields, "special_instructions")
+		values = append(values, *order.SpecialInstructions)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
+		valIdx++
+	}
+	if nil != order.AmountFromDonations {
+		sqlFields = append(sqlFields, "amount_from_donations")
+		strip_0_from_str(order.AmountFromDonations)
+	}
+	if nil != order.AmountFromPurchases {
+		sqlFields = append(sqlFields, "amount_from_purchases")
+		strip_0_from_str(order.AmountFromPurchases)
+	}
+	if nil != order.AmountFromCashCollected {
+		sqlFields = append(sqlFields, "cash_amount_collected")
+		strip_0_from_str(order.AmountFromCashCollected)
+	}
+	if nil != order.AmountFromChecksCollected {
+		sqlFields = append(sqlFields, "check_amount_collected")
+		strip_0_from_str(order.AmountFromChecksCollected)
+	}
+	if nil != order.AmountTotalCollected {
+		sqlFields = append(sqlFields, "total_amount_collected")
+		strip_0_from_str(order.AmountTotalCollected)
+	}
+	if nil != order.CheckNumbers {
+		sqlFields = append(sqlFields, "check_numbers")
+		values = append(values, *order.CheckNumbers)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
+		valIdx++
+	}
+	if nil != order.DeliveryId {
+		sqlFields = append(sqlFields, "delivery_id")
+		values = append(values, *order.DeliveryId)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::int", valIdx))
+		valIdx++
+	}
+	if nil != order.WillCollectMoneyLater {
+		sqlFields = append(sqlFields, "will_collect_money_later")
+		values = append(values, *order.WillCollectMoneyLater)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::bool", valIdx))
+		valIdx++
+	}
+	if nil != order.IsVerified {
+		sqlFields = append(sqlFields, "is_verified")
+		values = append(values, *order.IsVerified)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::bool", valIdx))
+		valIdx++
+	}
+	if len(order.Customer.Name) != 0 {
+		sqlFields = append(sqlFields, "customer_name")
+		values = append(values, order.Customer.Name)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
+		valIdx++
+	}
+	if len(order.Customer.Addr1) != 0 {
+		sqlFields = append(sqlFields, "customer_addr1")
+		values = append(values, order.Customer.Addr1)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
+		valIdx++
+	}
+	if nil != order.Customer.Addr2 {
+		sqlFields = append(sqlFields, "customer_addr2")
+		values = append(values, *order.Customer.Addr2)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
+		valIdx++
+	}
+	if nil != order.Customer.City {
+		sqlFields = append(sqlFields, "customer_city")
+		values = append(values, *order.Customer.City)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
+		valIdx++
+	}
+	if nil != order.Customer.Zipcode {
+		sqlFields = append(sqlFields, "customer_zipcode")
+		values = append(values, *order.Customer.Zipcode)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::int", valIdx))
+		valIdx++
+	}
+	if len(order.Customer.Phone) != 0 {
+		sqlFields = append(sqlFields, "customer_phone")
+		values = append(values, order.Customer.Phone)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
+		valIdx++
+	}
+	if nil != order.Customer.Email {
+		sqlFields = append(sqlFields, "customer_email")
+		values = append(values, *order.Customer.Email)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
+		valIdx++
+	}
+	if len(order.Customer.Neighborhood) != 0 {
+		sqlFields = append(sqlFields, "customer_neighborhood")
+		values = append(values, order.Customer.Neighborhood)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
+		valIdx++
+	}
+
+	return sqlFields, valIdxs, values
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) InsertMulchOrder(ctx context.Context, order MulchOrderType) error {
+	sqlFields, valIdxs, values := OrderType2Sql(order)
+
+	sqlCmd := fmt.Sprintf("insert into mulch_orders(%s) values (%s)",
+		strings.Join(sqlFields, ","), strings.Join(valIdxs, ","))
+
+	log.Println("Creating Order sqlCmd: ", sqlCmd)
+	_, err := s.db.Exec(ctx, sqlCmd, values...)
+	return err
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the condition that only matches rows still at
+// expectedLastModifiedTime along with values with its value added.  A version
+// that can't be parsed matches nothing.
+func lastModifiedTimeCondition(expectedLastModifiedTime string, values []interface{}) (string, []interface{}) {
+	expected, ok := parseLastModifiedTime(expectedLastModifiedTime)
+	if !ok {
+		return "false", values
+	}
+	values = append(values, expected.UTC().Format(lastModifiedTimeFormat))
+	return fmt.Sprintf("last_modified_time = $%d::timestamp", len(values)), values
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) UpdateMulchOrder(
+	ctx context.Context, order MulchOrderType, gqlFields []string, expectedLastModifiedTime string,
+) error {
+	values := []interface{}{order.OrderId, order.LastModifiedTime}
+	setCmds := []string{"last_modified_time = $2::timestamp"}
+
+	// Same as inserting, "0" amounts are stored as NULL
+	amount := func(amount *string) interface{} {
+		if amount == nil || *amount == "0" {
+			return nil
+		}
+		return *amount
+	}
+
+	for _, field := range gqlFields {
+		var column, sqlType string
+		var value interface{}
+		switch field {
+		case "ownerId":
+			column, sqlType, value = "order_owner_id", "string", order.OwnerId
+		case "comments":
+			column, sqlType, value = "comments", "string", order.Comments
+		case "specialInstructions":
+			column, sqlType, value = "special_instructions", "string", order.SpecialInstructions
+		case "amountFromDonations":
+			column, sqlType, value = "amount_from_donations", "decimal", amount(order.AmountFromDonations)
+		case "amountFromPurchases":
+			column, sqlType, value = "amount_from_purchases", "decimal", amount(order.AmountFromPurchases)
+		case "amountFromCashCollected":
+			column, sqlType, value = "cash_amount_collected", "decimal", amount(order.AmountFromCashCollected)
+		case "amountFromChecksCollected":
+			column, sqlType, value = "check_amount_collected", "decimal", amount(order.AmountFromChecksCollected)
+		case "amountTotalCollected":
+			column, sqlType, value = "total_amount_collected", "decimal", amount(order.AmountTotalCollected)
+		case "checkNumbers":
+			column, sqlType, value = "check_numbers", "string", order.CheckNumbers
+		case "deliveryId":
+			column, sqlType, value = "delivery_id", "int", order.DeliveryId
+		case "willCollectMoneyLater":
+			column, sqlType, value = "will_collect_money_later", "bool", order.WillCollectMoneyLater
+		case "isVerified":
+			column, sqlType, value = "is_verified", "bool", order.IsVerified
+		case "purchases":
+			column, sqlType, value = "purchases", "jsonb", order.Purchases
+			if order.Purchases == nil {
+				value = nil
+			}
+		case "checks":
+			column, sqlType, value = "checks", "jsonb", storedOrderChecks(order.Checks)
+			if order.Checks == nil {
+				value = nil
+			}
+		case "knownAddrId":
+			column, sqlType, value = "known_addr_id", "uuid", order.KnownAddrId
+		case "customer.name":
+			column, sqlType, value = "customer_name", "string", order.Customer.Name
+		case "customer.addr1":
+			column, sqlType, value = "customer_addr1", "string", order.Customer.Addr1
+		case "customer.addr2":
+			column, sqlType, value = "customer_addr2", "string", order.Customer.Addr2
+		case "customer.city":
+			column, sqlType, value = "customer_city", "string", order.Customer.City
+		case "customer.zipcode":
+			column, sqlType, value = "customer_zipcode", "int", order.Customer.Zipcode
+		case "customer.phone":
+			column, sqlType, value = "customer_phone", "string", order.Customer.Phone
+		case "customer.email":
+			column, sqlType, value = "customer_email", "string", order.Customer.Email
+		case "customer.neighborhood":
+			column, sqlType, value = "customer_neighborhood", "string", order.Customer.Neighborhood
+		default:
+			return fmt.Errorf("unknown mulch order field: %s", field)
+		}
+		values = append(values, value)
+		setCmds = append(setCmds, fmt.Sprintf("%s = $%d::%s", column, len(values), sqlType))
+	}
+
+	whereCmd := "order_id = $1::uuid"
+	if len(expectedLastModifiedTime) != 0 {
+		var condition string
+		condition, values = lastModifiedTimeCondition(expectedLastModifiedTime, values)
+		whereCmd += " AND " + condition
+	}
+
+	sqlCmd := fmt.Sprintf("UPDATE mulch_orders SET %s WHERE %s", strings.Join(setCmds, ", "), whereCmd)
+	log.Println("Updating Order sqlCmd: ", sqlCmd)
+	result, err := s.db.Exec(ctx, sqlCmd, values...)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		if len(expectedLastModifiedTime) != 0 {
+			return ErrStaleVersion
+		}
+		return ErrNotFound
+	}
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) DeleteMulchOrder(ctx context.Context, orderId string) error {
+	_, err := s.db.Exec(ctx, "delete from mulch_orders where order_id=$1", orderId)
+	return err
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) SetSpreaders(ctx context.Context, orderId string, spreaders []string) error {
+	return s.InTx(ctx, func(tx FrStore) error {
+		db := tx.(*pgStore).db
+		log.Println("Deleting existing record")
+		_, err := db.Exec(ctx, "delete from mulch_spreaders where order_id = $1", orderId)
+		if err != nil {
+			return err
+		}
+
+		if len(spreaders) > 0 {
+			sqlCmd := "insert into mulch_spreaders(order_id, spreaders) values ($1, $2)"
+			_, err = db.Exec(ctx, sqlCmd, orderId, spreaders)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) GetFundraiserConfig(ctx context.Context, gqlFields []string) (FrConfigType, error) {
+	frConfig := FrConfigType{}
+	params := []interface{}{}
+	sqlFields := []string{}
+
+	for _, gqlField := range gqlFields {
+		switch gqlField {
+		case "kind":
+			params = append(params, &frConfig.Kind)
+			sqlFields = append(sqlFields, "kind")
+		case "description":
+			params = append(params, &frConfig.Description)
+			sqlFields = append(sqlFields, "description")
+		case "lastModifiedTime":
+			params = append(params, &frConfig.LastModifiedTime)
+			sqlFields = append(sqlFields, "last_modified_time::string")
+		case "mulchDeliveryConfigs":
+			params = append(params, &frConfig.MulchDeliveryConfigs)
+			sqlFields = append(sqlFields, "mulch_delivery_configs::jsonb")
+		case "products":
+			params = append(params, &frConfig.Products)
+			sqlFields = append(sqlFields, "products::jsonb")
+		case "finalizationData":
+			params = append(params, &frConfig.FinalizationData)
+			sqlFields = append(sqlFields, "finalization_data::jsonb")
+		case "allocationPolicy":
+			params = append(params, &frConfig.AllocationPolicy)
+			sqlFields = append(sqlFields, "allocation_policy::jsonb")
+		case "inventoryPolicy":
+			params = append(params, &frConfig.InventoryPolicy)
+			sqlFields = append(sqlFields, "inventory_policy::jsonb")
+		case "isLocked":
+			params = append(params, &frConfig.IsLocked)
+			sqlFields = append(sqlFields, "is_locked")
+		case "users":
+		case "neighborhoods":
+			// Skipping because it is handled seperately
+		default:
+			return frConfig, fmt.Errorf("unknown fundraiser config field: %s", gqlField)
+		}
+	}
+
+	sqlCmd := fmt.Sprintf("select %s from fundraiser_config", strings.Join(sqlFields, ","))
+	log.Println("SqlCmd: ", sqlCmd)
+	err := s.db.QueryRow(ctx, sqlCmd).Scan(params...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return FrConfigType{}, ErrNotFound
+		}
+		return FrConfigType{}, err
+	}
+	return frConfig, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func FrConfigType2Sql(frConfig FrConfigType) ([]string, []string, []interface{}) {
+	values := []interface{}{}
+	sqlFields := []string{}
+	valIdxs := []string{}
+	valIdx := 1
+	if len(frConfig.Kind) != 0 {
+		sqlFields = append(sqlFields, "kind")
+		values = append(values, frConfig.Kind)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
+		valIdx++
+	}
+	if len(frConfig.Description) != 0 {
+		sqlFields = append(sqlFields, "description")
+		values = append(values, frConfig.Description)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
+		valIdx++
+	}
+	if len(frConfig.Products) != 0 {
+		sqlFields = append(sqlFields, "products")
+		values = append(values, frConfig.Products)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::jsonb", valIdx))
+		valIdx++
+	}
+	if nil != frConfig.MulchDeliveryConfigs {
+		sqlFields = append(sqlFields, "mulch_delivery_configs")
+		values = append(values, *frConfig.MulchDeliveryConfigs)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::jsonb", valIdx))
+		valIdx++
+	}
+	if nil != frConfig.FinalizationData {
+		sqlFields = append(sqlFields, "finalization_data")
+		values = append(values, *frConfig.FinalizationData)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::jsonb", valIdx))
+		valIdx++
+	}
+	if nil != frConfig.AllocationPolicy {
+		sqlFields = append(sqlFields, "allocation_policy")
+		values = append(values, *frConfig.AllocationPolicy)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::jsonb", valIdx))
+		valIdx++
+	}
+	if nil != frConfig.InventoryPolicy {
+		sqlFields = append(sqlFields, "inventory_policy")
+		values = append(values, *frConfig.InventoryPolicy)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::jsonb", valIdx))
+		valIdx++
+	}
+	if nil != frConfig.IsLocked {
+		// Unfortunately hard to detect if this is set or not
+		sqlFields = append(sqlFields, "is_locked")
+		values = append(values, *frConfig.IsLocked)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::bool", valIdx))
+		valIdx++
+	}
+
+	// Always do timestamp
+	sqlFields = append(sqlFields, "last_modified_time")
+	values = append(values, frConfig.LastModifiedTime)
+	valIdxs = append(valIdxs, fmt.Sprintf("$%d::timestamp", valIdx))
+	return sqlFields, valIdxs, values
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) SetFundraiserConfig(ctx context.Context, frConfig FrConfigType) error {
+	return s.InTx(ctx, func(tx FrStore) error {
+		db := tx.(*pgStore).db
+		log.Println("Deleting existing record")
+		_, err := db.Exec(ctx, "delete from fundraiser_config")
+		if err != nil {
+			return err
+		}
+
+		sqlFields, valIdxs, values := FrConfigType2Sql(frConfig)
+
+		sqlCmd := fmt.Sprintf("insert into fundraiser_config(%s) values (%s)",
+			strings.Join(sqlFields, ","), strings.Join(valIdxs, ","))
+
+		log.Println("Setting Config SqlCmd: ", sqlCmd)
+		_, err = db.Exec(ctx, sqlCmd, values...)
+		return err
+	})
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) UpdateFundraiserConfig(ctx context.Context, frConfig FrConfigType, expectedLastModifiedTime string) error {
+	sqlFields, valIdxs, values := FrConfigType2Sql(frConfig)
+
+	updateSqlFlds := []string{}
+	for i, f := range sqlFields {
+		updateSqlFlds = append(updateSqlFlds, fmt.Sprintf("%s=%s", f, valIdxs[i]))
+	}
+
+	// There is only the one config row
+	sqlCmd := fmt.Sprintf("UPDATE fundraiser_config SET %s", strings.Join(updateSqlFlds, ","))
+	if len(expectedLastModifiedTime) != 0 {
+		var condition string
+		condition, values = lastModifiedTimeCondition(expectedLastModifiedTime, values)
+		sqlCmd += " WHERE " + condition
+	}
+
+	log.Println("Update Config SqlCmd: ", sqlCmd)
+	result, err := s.db.Exec(ctx, sqlCmd, values...)
+	if err != nil {
+		return err
+	}
+	if len(expectedLastModifiedTime) != 0 && result.RowsAffected() == 0 {
+		return ErrStaleVersion
+	}
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) GetNeighborhoods(ctx context.Context, gqlFields []string) ([]NeighborhoodInfo, error) {
+	neighborhoods := []NeighborhoodInfo{}
+	sqlFields := []string{}
+
+	for _, gqlField := range gqlFields {
+		switch gqlField {
+		case "name":
+			sqlFields = append(sqlFields, "name")
+		case "zipcode":
+			sqlFields = append(sqlFields, "zipcode")
+		case "city":
+			sqlFields = append(sqlFields, "city")
+		case "isVisible":
+			sqlFields = append(sqlFields, "is_visible")
+		case "distributionPoint":
+			sqlFields = append(sqlFields, "dist_pt")
+		case "lastModifiedTime":
+			sqlFields = append(sqlFields, "COALESCE(last_modified_time::string, '')")
+		default:
+			return neighborhoods, fmt.Errorf("unknown fundraiser neighborhood field: %s", gqlField)
+		}
+	}
+
+	sqlCmd := fmt.Sprintf("select %s from neighborhoods", strings.Join(sqlFields, ","))
+	rows, err := s.db.Query(ctx, sqlCmd)
+	if err != nil {
+		return neighborhoods, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		hood := NeighborhoodInfo{}
+		inputs := []interface{}{}
+		for _, gqlField := range gqlFields {
+			switch gqlField {
+			case "name":
+				inputs = append(inputs, &hood.Name)
+			case "zipcode":
+				inputs = append(inputs, &hood.Zipcode)
+			case "city":
+				inputs = append(inputs, &hood.City)
+			case "isVisible":
+				inputs = append(inputs, &hood.IsVisible)
+			case "distributionPoint":
+				inputs = append(inputs, &hood.DistributionPoint)
+			case "lastModifiedTime":
+				inputs = append(inputs, &hood.LastModifiedTime)
+			default:
+				return neighborhoods, fmt.Errorf("unknown fundraiser neighborhood field: %s", gqlField)
+			}
+		}
+		err = rows.Scan(inputs...)
+		if err != nil {
+			log.Println("Reading Neighborhood row failed: ", err)
+			continue
+		}
+		neighborhoods = append(neighborhoods, hood)
+	}
+
+	if err := rows.Err(); err != nil {
+		return []NeighborhoodInfo{}, err
+	}
+	return neighborhoods, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func FrHoodType2Sql(hood NeighborhoodInfo, isUpdate bool) ([]string, []string, []interface{}) {
+	values := []interface{}{}
+	sqlFields := []string{}
+	valIdxs := []string{}
+	valIdx := 1
+
+	// We don't use Name when we are updating
+	if !isUpdate && len(hood.Name) != 0 {
+		sqlFields = append(sqlFields, "name")
+		values = append(values, hood.Name)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
+		valIdx++
+	}
+	if nil != hood.Zipcode {
+		sqlFields = append(sqlFields, "zipcode")
+		values = append(values, *hood.Zipcode)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::int", valIdx))
+		valIdx++
+	}
+	if nil != hood.City {
+		sqlFields = append(sqlFields, "city")
+		values = append(values, *hood.City)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
+		valIdx++
+	}
+	if nil != hood.DistributionPoint {
+		sqlFields = append(sqlFields, "dist_pt")
+		values = append(values, *hood.DistributionPoint)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
+		valIdx++
+	}
+	if nil != hood.IsVisible {
+		// Unfortunately hard to detect if this is set or not
+		sqlFields = append(sqlFields, "is_visible")
+		values = append(values, *hood.IsVisible)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::bool", valIdx))
+		valIdx++
+	}
+
+	// Always do timestamp
+	sqlFields = append(sqlFields, "last_modified_time")
+	values = append(values, hood.LastModifiedTime)
+	valIdxs = append(valIdxs, fmt.Sprintf("$%d::timestamp", valIdx))
+	return sqlFields, valIdxs, values
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) InsertNeighborhood(ctx context.Context, hood NeighborhoodInfo) error {
+	sqlFields, valIdxs, values := FrHoodType2Sql(hood, false)
+	sqlCmd := fmt.Sprintf("insert into neighborhoods(%s) values (%s)",
+		strings.Join(sqlFields, ","), strings.Join(valIdxs, ","))
+
+	log.Println("Neighborhood SqlCmd: ", sqlCmd)
+	_, err := s.db.Exec(ctx, sqlCmd, values...)
+	return err
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) UpdateNeighborhood(ctx context.Context, hood NeighborhoodInfo, expectedLastModifiedTime string) error {
+	sqlFields, valIdxs, values := FrHoodType2Sql(hood, true)
+	updateSqlFlds := []string{}
+	for i, f := range sqlFields {
+		updateSqlFlds = append(updateSqlFlds, fmt.Sprintf("%s=%s", f, valIdxs[i]))
+	}
+
+	values = append(values, hood.Name)
+	sqlCmd := fmt.Sprintf(
+		"UPDATE neighborhoods SET %s WHERE name = $%d",
+		strings.Join(updateSqlFlds, ","),
+		len(values),
+	)
+	if len(expectedLastModifiedTime) != 0 {
+		var condition string
+		condition, values = lastModifiedTimeCondition(expectedLastModifiedTime, values)
+		sqlCmd += " AND " + condition
+	}
+
+	log.Println("Neighborhood SqlCmd: ", sqlCmd)
+	result, err := s.db.Exec(ctx, sqlCmd, values...)
+	if err != nil {
+		return err
+	}
+	if len(expectedLastModifiedTime) != 0 && result.RowsAffected() == 0 {
+		return ErrStaleVersion
+	}
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func mulchTimecardGql2SqlMap(gqlFields []string, tc *MulchTimecardType) ([]string, []interface{}) {
+	sqlFields := []string{}
+	inputs := []interface{}{}
+
+	for _, gqlField := range gqlFields {
+		switch gqlField {
+		case "id":
+			inputs = append(inputs, &tc.Id)
+			sqlFields = append(sqlFields, "uid")
+		case "deliveryId":
+			inputs = append(inputs, &tc.DeliveryId)
+			sqlFields = append(sqlFields, "delivery_id")
+		case "lastModifiedTime":
+			inputs = append(inputs, &tc.LastModifiedTime)
+			sqlFields = append(sqlFields, "last_modified_time::string")
+		case "timeIn":
+			inputs = append(inputs, &tc.TimeIn)
+			sqlFields = append(sqlFields, "time_in::string")
+		case "timeOut":
+			inputs = append(inputs, &tc.TimeOut)
+			sqlFields = append(sqlFields, "time_out::string")
+		case "timeTotal":
+			inputs = append(inputs, &tc.TimeTotal)
+			sqlFields = append(sqlFields, "time_total::string")
+		default:
+			log.Println("Do not know how to handle GraphQL Field: ", gqlField)
+		}
+	}
+	return sqlFields, inputs
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) GetMulchTimecards(ctx context.Context, id string, deliveryId int, gqlFields []string) ([]MulchTimecardType, error) {
+	timecards := []MulchTimecardType{}
+
+	tc := MulchTimecardType{} // This is pretty much throw away probably should change to nil
+	sqlFields, _ := mulchTimecardGql2SqlMap(gqlFields, &tc)
+
+	sqlCmd := fmt.Sprintf("SELECT %s FROM mulch_delivery_timecards", strings.Join(sqlFields, ","))
+	log.Println("Retrieving Timecards: ", sqlCmd)
+
+	values := []interface{}{}
+	valIdx := 1
+	sqlFields = []string{} // Reset for WHERE entries
+	if len(id) != 0 || deliveryId != -1 {
+		sqlCmd = sqlCmd + " WHERE"
+	}
+
+	if len(id) != 0 {
+		log.Println("Timecards User Id: ", id)
+		values = append(values, id)
+		sqlFields = append(sqlFields, fmt.Sprintf("uid=$%d", valIdx))
+		valIdx++
+	}
+	if deliveryId != -1 {
+		log.Println("Timecards DeliveryId: ", deliveryId)
+		values = append(values, deliveryId)
+		sqlFields = append(sqlFields, fmt.Sprintf("delivery_id=$%d", valIdx))
+		valIdx++
+	}
+
+	sqlCmd = fmt.Sprintf("%s %s", sqlCmd, strings.Join(sqlFields, " AND "))
+	rows, err := s.db.Query(ctx, sqlCmd, values...)
+	if err != nil {
+		return timecards, err
+	}
+
+	defer rows.Close()
+
+	scanErrs := []error{}
+	for rows.Next() {
+		tc := MulchTimecardType{}
+		_, inputs := mulchTimecardGql2SqlMap(gqlFields, &tc)
+		err = rows.Scan(inputs...)
+		if err != nil {
+			log.Println("Reading timecard row failed: ", err)
+			scanErrs = append(scanErrs, err)
+			continue
+		}
+		timecards = append(timecards, tc)
+	}
+
+	if err := rows.Err(); err != nil {
+		return []MulchTimecardType{}, err
+	}
+	return timecards, newRowScanError(scanErrs)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) DeleteMulchTimecard(ctx context.Context, id string, deliveryId int) error {
+	_, err := s.db.Exec(ctx,
+		"delete from mulch_delivery_timecards where uid = $1 and delivery_id = $2",
+		id, deliveryId)
+	return err
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) InsertMulchTimecard(ctx context.Context, timecard MulchTimecardType) error {
+	sqlCmd := "insert into mulch_delivery_timecards(uid, delivery_id, last_modified_time, time_in, time_out, time_total) " +
+		"values ($1, $2, $3::timestamp, $4::time, $5::time, $6::time)"
+	log.Println("Setting Timecard SqlCmd: ", sqlCmd)
+	_, err := s.db.Exec(ctx, sqlCmd,
+		timecard.Id, timecard.DeliveryId, timecard.LastModifiedTime, timecard.TimeIn, timecard.TimeOut, timecard.TimeTotal)
+	return err
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) GetUsers(ctx context.Context, params GetUsersParams) ([]UserInfo, error) {
+	users := []UserInfo{}
+
+	getSqlFields := func() ([]string, bool, error) {
+		sqlFieldSet := make(map[string]struct{})
+		sqlFields := []string{}
+		doWantFullNames := false
+		exists := struct{}{}
+		for _, gqlField := range params.GqlFields {
+			switch gqlField {
+			case "firstName":
+				sqlFieldSet["first_name"] = exists
+			case "lastName":
+				sqlFieldSet["last_name"] = exists
+			case "name":
+				doWantFullNames = true
+				sqlFieldSet["first_name"] = exists
+				sqlFieldSet["last_name"] = exists
+			case "id":
+				sqlFieldSet["id"] = exists
+			case "group":
+				sqlFieldSet["group_id"] = exists
+			case "hasAuthCreds":
+				sqlFieldSet["has_auth_creds"] = exists
+			case "lastModifiedTime":
+				sqlFieldSet["COALESCE(last_modified_time::string, '')"] = exists
+			default:
+				return sqlFields, false, fmt.Errorf("unknown fundraiser user field: %s", gqlField)
+			}
+		}
+		for k := range sqlFieldSet {
+			sqlFields = append(sqlFields, k)
+		}
+		return sqlFields, doWantFullNames, nil
+	}
+
+	sqlFields, doWantFullNames, err := getSqlFields()
+	if err != nil {
+		return users, err
+	}
+
+	sqlCmd := fmt.Sprintf("select %s from users", strings.Join(sqlFields, ","))
+	if params.ShowUsersWithoutAuthCreds {
+		sqlCmd = sqlCmd + " where not has_auth_creds"
+	}
+
+	rows, err := s.db.Query(ctx, sqlCmd)
+	if err != nil {
+		return users, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user := UserInfo{}
+		inputs := []interface{}{}
+		for _, fld := range sqlFields {
+			switch fld {
+			case "first_name":
+				inputs = append(inputs, &user.FirstName)
+			case "last_name":
+				inputs = append(inputs, &user.LastName)
+			case "id":
+				inputs = append(inputs, &user.Id)
+			case "group_id":
+				inputs = append(inputs, &user.Group)
+			case "has_auth_creds":
+				inputs = append(inputs, &user.HasAuthCreds)
+			case "COALESCE(last_modified_time::string, '')":
+				inputs = append(inputs, &user.LastModifiedTime)
+			default:
+				return users, fmt.Errorf("unknown fundraiser user db field: %s", fld)
+			}
+		}
+		err = rows.Scan(inputs...)
+		if err != nil {
+			log.Println("Reading User row failed: ", err)
+			continue
+		}
+		if doWantFullNames {
+			user.Name = fmt.Sprintf("%s %s", user.FirstName, user.LastName)
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return []UserInfo{}, err
+	}
+	return users, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func FrUsers2Sql(user UserInfo, isUpdate bool) ([]string, []string, []interface{}) {
+	values := []interface{}{}
+	sqlFields := []string{}
+	valIdxs := []string{}
+	valIdx := 1
+
+	if !isUpdate {
+		// These are fields we don't change when updating
+
+		if len(user.Id) != 0 {
+			sqlFields = append(sqlFields, "id")
+			values = append(values, user.Id)
+			valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
+			valIdx++
+		}
+
+		if len(user.FirstName) != 0 {
+			sqlFields = append(sqlFields, "first_name")
+			values = append(values, user.FirstName)
+			valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
+			valIdx++
+		}
+
+		if len(user.LastName) != 0 {
+			sqlFields = append(sqlFields, "last_name")
+			values = append(values, user.LastName)
+			valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
+			valIdx++
+		}
+
+		// If we aren't updating then we are creating and so we always set these
+		sqlFields = append(sqlFields, "has_auth_creds")
+		values = append(values, false)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::bool", valIdx))
+		valIdx++
+
+		sqlFields = append(sqlFields, "created_time")
+		values = append(values, user.CreatedTime)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::timestamp", valIdx))
+		valIdx++
+	} else {
+		if nil != user.HasAuthCreds {
+			// Unfortunately hard to detect if this is set or not
+			sqlFields = append(sqlFields, "has_auth_creds")
+			values = append(values, *user.HasAuthCreds)
+			valIdxs = append(valIdxs, fmt.Sprintf("$%d::bool", valIdx))
+			valIdx++
+		}
+	}
+
+	if len(user.Group) != 0 {
+		sqlFields = append(sqlFields, "group_id")
+		values = append(values, user.Group)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
+		valIdx++
+	}
+
+	// Always do timestamp
+	sqlFields = append(sqlFields, "last_modified_time")
+	values = append(values, user.LastModifiedTime)
+	valIdxs = append(valIdxs, fmt.Sprintf("$%d::timestamp", valIdx))
+	return sqlFields, valIdxs, values
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) InsertUser(ctx context.Context, user UserInfo) error {
+	sqlFields, valIdxs, values := FrUsers2Sql(user, false)
+
+	sqlCmd := fmt.Sprintf("insert into users(%s) values (%s)",
+		strings.Join(sqlFields, ","), strings.Join(valIdxs, ","))
+
+	log.Println("Adding user SqlCmd: ", sqlCmd)
+	_, err := s.db.Exec(ctx, sqlCmd, values...)
+	return err
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) UpdateUser(ctx context.Context, user UserInfo, expectedLastModifiedTime string) error {
+	sqlFields, valIdxs, values := FrUsers2Sql(user, true)
+
+	updateSqlFlds := []string{}
+	for i, f := range sqlFields {
+		updateSqlFlds = append(updateSqlFlds, fmt.Sprintf("%s=%s", f, valIdxs[i]))
+	}
+
+	values = append(values, user.Id)
+	sqlCmd := fmt.Sprintf(
+		"UPDATE users SET %s WHERE id = $%d",
+		strings.Join(updateSqlFlds, ","),
+		len(values),
+	)
+	if len(expectedLastModifiedTime) != 0 {
+		var condition string
+		condition, values = lastModifiedTimeCondition(expectedLastModifiedTime, values)
+		sqlCmd += " AND " + condition
+	}
+
+	log.Println("Updating user SqlCmd: ", sqlCmd)
+	result, err := s.db.Exec(ctx, sqlCmd, values...)
+	if err != nil {
+		return err
+	}
+	if len(expectedLastModifiedTime) != 0 && result.RowsAffected() == 0 {
+		return ErrStaleVersion
+	}
+	return nil
+}
+
+const allocationSelectSql = "select uid, bags_sold, bags_spread::string, delivery_minutes::string, total_donations::string, " +
+	"allocation_from_bags_sold::string, allocation_from_bags_spread::string, " +
+	"allocation_from_delivery::string, allocation_total::string from allocation_summary"
+
+// //////////////////////////////////////////////////////////////////////////
+func scanAllocation(row pgx.Row) (AllocationItemType, error) {
+	item := AllocationItemType{}
+	var allocTotalStr *string
+
+	err := row.Scan(&item.Uid,
+		&item.BagsSold, &item.BagsSpread, &item.DeliveryMinutes, &item.TotalDonations,
+		&item.AllocationsFromBagsSold, &item.AllocationsFromBagsSpread, &item.AllocationsFromDelivery, &allocTotalStr)
+	if err != nil {
+		return item, err
+	}
+	if allocTotalStr != nil {
+		item.AllocationsTotal = *allocTotalStr
+	}
+	return item, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) GetAllocation(ctx context.Context, uid string) (AllocationItemType, error) {
+	sqlCmd := allocationSelectSql + " where allocation_summary.uid=$1"
+	log.Println("SqlCmd: ", sqlCmd)
+	item, err := scanAllocation(s.db.QueryRow(ctx, sqlCmd, uid))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return AllocationItemType{Uid: uid}, ErrNotFound
+		}
+		return AllocationItemType{Uid: uid}, err
+	}
+	return item, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) GetAllocations(ctx context.Context) ([]AllocationItemType, error) {
+	sqlCmd := allocationSelectSql + " order by uid"
+	log.Println("SqlCmd: ", sqlCmd)
+	rows, err := s.db.Query(ctx, sqlCmd)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	allocations := []AllocationItemType{}
+	for rows.Next() {
+		item, err := scanAllocation(rows)
+		if err != nil {
+			log.Println("Reading allocation row failed: ", err)
+			return nil, err
+		}
+		allocations = append(allocations, item)
+	}
+	return allocations, rows.Err()
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func AllocItemType2Sql(item AllocationItemType) ([]string, []string, []interface{}) {
+	values := []interface{}{}
+	sqlFields := []string{}
+	valIdxs := []string{}
+	valIdx := 1
+
+	sqlFields = append(sqlFields, "uid")
+	values = append(values, item.Uid)
+	valIdxs = append(valIdxs, fmt.Sprintf("$%d::string", valIdx))
+	valIdx++
+
+	sqlFields = append(sqlFields, "allocation_total")
+	values = append(values, item.AllocationsTotal)
+	valIdxs = append(valIdxs, fmt.Sprintf("$%d::decimal", valIdx))
+	valIdx++
+
+	if nil != item.BagsSold {
+		sqlFields = append(sqlFields, "bags_sold")
+		values = append(values, *item.BagsSold)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::int", valIdx))
+		valIdx++
+	}
+
+	if nil != item.BagsSpread {
+		sqlFields = append(sqlFields, "bags_spread")
+		values = append(values, *item.BagsSpread)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::decimal", valIdx))
+		valIdx++
+	}
+
+	if nil != item.DeliveryMinutes {
+		sqlFields = append(sqlFields, "delivery_minutes")
+		values = append(values, *item.DeliveryMinutes)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::decimal", valIdx))
+		valIdx++
+	}
+
+	if nil != item.TotalDonations {
+		sqlFields = append(sqlFields, "total_donations")
+		values = append(values, *item.TotalDonations)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::decimal", valIdx))
+		valIdx++
+	}
+
+	if nil != item.AllocationsFromBagsSold {
+		sqlFields = append(sqlFields, "allocation_from_bags_sold")
+		values = append(values, *item.AllocationsFromBagsSold)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::decimal", valIdx))
+		valIdx++
+	}
+
+	if nil != item.AllocationsFromBagsSpread {
+		sqlFields = append(sqlFields, "allocation_from_bags_spread")
+		values = append(values, *item.AllocationsFromBagsSpread)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::decimal", valIdx))
+		valIdx++
+	}
+
+	if nil != item.AllocationsFromDelivery {
+		sqlFields = append(sqlFields, "allocation_from_delivery")
+		values = append(values, *item.AllocationsFromDelivery)
+		valIdxs = append(valIdxs, fmt.Sprintf("$%d::decimal", valIdx))
+		valIdx++
+	}
+
+	return sqlFields, valIdxs, values
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) SetAllocations(ctx context.Context, allocations []AllocationItemType) error {
+	return s.InTx(ctx, func(tx FrStore) error {
+		db := tx.(*pgStore).db
+		log.Println("Deleting existing records")
+		_, err := db.Exec(ctx, "delete from allocation_summary")
+		if err != nil {
+			return err
+		}
+
+		for _, item := range allocations {
+			sqlFields, valIdxs, values := AllocItemType2Sql(item)
+			sqlCmd := fmt.Sprintf("insert into allocation_summary(%s) values (%s)",
+				strings.Join(sqlFields, ","), strings.Join(valIdxs, ","))
+			log.Println("Adding Allocation for ", item.Uid, " SqlCmd: ", sqlCmd)
+			_, err = db.Exec(ctx, sqlCmd, values...)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Tables cleared by ResetOrderData.  The tables themselves come from the
+// migrations.
+// Tables come from seasonTables so closeSeason archives what is reset
+var RESET_ORDER_DATA_SQL = "TRUNCATE " + strings.Join(getResetSeasonTableNames(), ", ")
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) ResetOrderData(ctx context.Context) error {
+	_, err := s.db.Exec(ctx, RESET_ORDER_DATA_SQL)
+	return err
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// CockroachDB runs TRUNCATE as a schema change which it rejects after writes
+// in the same transaction (i.e. closeSeason's archive) so the rows are
+// deleted instead
+func getDeleteOrderDataSqls() []string {
+	sqlCmds := []string{}
+	for _, table := range getResetSeasonTableNames() {
+		sqlCmds = append(sqlCmds, "DELETE FROM "+table)
+	}
+	return sqlCmds
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) DeleteOrderData(ctx context.Context) error {
+	for _, sqlCmd := range getDeleteOrderDataSqls() {
+		if _, err := s.db.Exec(ctx, sqlCmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) ResetUsers(ctx context.Context) error {
+	_, err := s.db.Exec(ctx, "TRUNCATE users")
+	return err
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) InsertMulchOrderRevision(ctx context.Context, revision MulchOrderRevisionType) (int, error) {
+	snapshot, err := json.Marshal(revision.Order)
+	if err != nil {
+		return 0, err
+	}
+
+	// The order's row is locked until the transaction ends so concurrent
+	// transactions number their revisions of it one after the other instead
+	// of both taking the same MAX(revision) + 1
+	lockSqlCmd := "SELECT order_id FROM mulch_orders WHERE order_id = $1 FOR UPDATE"
+	if _, err = s.db.Exec(ctx, lockSqlCmd, revision.OrderId); err != nil {
+		return 0, err
+	}
+
+	sqlCmd := "INSERT INTO mulch_order_history(order_id, revision, operation, actor_id, created_time, order_snapshot) " +
+		"SELECT $1, COALESCE(MAX(revision), 0) + 1, $2, $3, $4::timestamp, $5::jsonb " +
+		"FROM mulch_order_history WHERE order_id = $1 RETURNING revision"
+	err = s.db.QueryRow(ctx, sqlCmd, revision.OrderId, revision.Operation, revision.ActorId,
+		revision.CreatedTime, string(snapshot)).Scan(&revision.Revision)
+	return revision.Revision, err
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) queryMulchOrderRevisions(ctx context.Context, sqlCmd string, args ...any) ([]MulchOrderRevisionType, error) {
+	log.Println("SqlCmd: ", sqlCmd)
+	rows, err := s.db.Query(ctx, sqlCmd, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []MulchOrderRevisionType{}
+	for rows.Next() {
+		revision := MulchOrderRevisionType{}
+		snapshot := ""
+		err = rows.Scan(&revision.OrderId, &revision.Revision, &revision.Operation, &revision.ActorId,
+			&revision.CreatedTime, &snapshot)
+		if err != nil {
+			log.Println("Reading order history row failed: ", err)
+			return nil, err
+		}
+		if err = json.Unmarshal([]byte(snapshot), &revision.Order); err != nil {
+			return nil, err
+		}
+		history = append(history, revision)
+	}
+	return history, rows.Err()
+}
+
+const mulchOrderHistorySelectSql = "SELECT order_id::string, revision, operation, actor_id, created_time::string, " +
+	"order_snapshot::string FROM mulch_order_history"
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) GetMulchOrderHistory(ctx context.Context, orderId string) ([]MulchOrderRevisionType, error) {
+	return s.queryMulchOrderRevisions(ctx, mulchOrderHistorySelectSql+" WHERE order_id = $1 ORDER BY revision", orderId)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) GetMulchOrderRevision(ctx context.Context, orderId string, revision int) (MulchOrderRevisionType, error) {
+	history, err := s.queryMulchOrderRevisions(ctx,
+		mulchOrderHistorySelectSql+" WHERE order_id = $1 AND revision = $2", orderId, revision)
+	if err != nil {
+		return MulchOrderRevisionType{}, err
+	}
+	if len(history) == 0 {
+		return MulchOrderRevisionType{}, ErrNotFound
+	}
+	return history[0], nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) InsertAuditLogEntry(ctx context.Context, entry AuditLogEntryType) error {
+	sqlCmd := "INSERT INTO audit_log(created_time, actor_id, operation, target_kind, target_key, diff) " +
+		"VALUES ($1::timestamp, $2, $3, $4, $5, $6::jsonb)"
+	_, err := s.db.Exec(ctx, sqlCmd,
+		entry.CreatedTime, entry.ActorId, entry.Operation, entry.TargetKind, entry.TargetKey, entry.Diff)
+	return err
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) GetAuditLog(ctx context.Context, params GetAuditLogParams) ([]AuditLogEntryType, error) {
+	queryBuilder := goqu.Dialect("postgres").From("audit_log").Select(
+		goqu.L("id::string"), goqu.L("created_time::string"), "actor_id", "operation",
+		"target_kind", "target_key", goqu.L("diff::string"),
+	)
+
+	if len(params.ActorId) != 0 {
+		queryBuilder = queryBuilder.Where(goqu.Ex{"actor_id": params.ActorId})
+	}
+	if len(params.Operation) != 0 {
+		queryBuilder = queryBuilder.Where(goqu.Ex{"operation": params.Operation})
+	}
+	if len(params.TargetKind) != 0 {
+		queryBuilder = queryBuilder.Where(goqu.Ex{"target_kind": params.TargetKind})
+	}
+	if len(params.TargetKey) != 0 {
+		queryBuilder = queryBuilder.Where(goqu.Ex{"target_key": params.TargetKey})
+	}
+	if params.After != nil {
+		queryBuilder = queryBuilder.Where(goqu.C("created_time").Gte(params.After.UTC()))
+	}
+	if params.Before != nil {
+		queryBuilder = queryBuilder.Where(goqu.C("created_time").Lt(params.Before.UTC()))
+	}
+	queryBuilder = queryBuilder.Order(goqu.C("created_time").Desc(), goqu.C("id").Desc())
+	if params.Limit > 0 {
+		queryBuilder = queryBuilder.Limit(uint(params.Limit))
+	}
+
+	sqlCmd, args, err := queryBuilder.ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	log.Println("SqlCmd: ", sqlCmd)
+
+	rows, err := s.db.Query(ctx, sqlCmd, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []AuditLogEntryType{}
+	for rows.Next() {
+		entry := AuditLogEntryType{}
+		err = rows.Scan(&entry.Id, &entry.CreatedTime, &entry.ActorId, &entry.Operation,
+			&entry.TargetKind, &entry.TargetKey, &entry.Diff)
+		if err != nil {
+			log.Println("Reading audit log row failed: ", err)
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) InsertSeasonArchive(ctx context.Context, archive SeasonArchiveType, contents SeasonArchiveContentsType) error {
+	return s.InTx(ctx, func(tx FrStore) error {
+		db := tx.(*pgStore).db
+
+		frConfig, err := json.Marshal(archive.Config)
+		if err != nil {
+			return err
+		}
+		_, err = db.Exec(ctx, "INSERT INTO season_archives(season, closed_time, closed_by, is_imported, fundraiser_config) "+
+			"VALUES ($1, $2::timestamp, $3, $4, $5::jsonb)",
+			archive.Season, archive.ClosedTime, archive.ClosedBy, archive.IsImported, string(frConfig))
+		if err != nil {
+			return err
+		}
+
+		for _, order := range contents.MulchOrders {
+			snapshot, err := json.Marshal(order)
+			if err != nil {
+				return err
+			}
+			_, err = db.Exec(ctx, "INSERT INTO archived_mulch_orders(season, order_id, order_owner_id, order_snapshot) "+
+				"VALUES ($1, $2, $3, $4::jsonb)", archive.Season, order.OrderId, order.OwnerId, string(snapshot))
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, timecard := range contents.MulchTimecards {
+			snapshot, err := json.Marshal(timecard)
+			if err != nil {
+				return err
+			}
+			_, err = db.Exec(ctx, "INSERT INTO archived_mulch_timecards(season, uid, timecard) VALUES ($1, $2, $3::jsonb)",
+				archive.Season, timecard.Id, string(snapshot))
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, item := range contents.Allocations {
+			snapshot, err := json.Marshal(item)
+			if err != nil {
+				return err
+			}
+			_, err = db.Exec(ctx, "INSERT INTO archived_allocation_summary(season, uid, allocation) VALUES ($1, $2, $3::jsonb)",
+				archive.Season, item.Uid, string(snapshot))
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, user := range contents.Users {
+			snapshot, err := json.Marshal(user)
+			if err != nil {
+				return err
+			}
+			_, err = db.Exec(ctx, "INSERT INTO archived_users(season, uid, user_snapshot) VALUES ($1, $2, $3::jsonb)",
+				archive.Season, user.Id, string(snapshot))
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, deposit := range contents.BankDeposits {
+			snapshot, err := json.Marshal(deposit)
+			if err != nil {
+				return err
+			}
+			_, err = db.Exec(ctx, "INSERT INTO archived_bank_deposits(season, deposit_id, deposit) VALUES ($1, $2, $3::jsonb)",
+				archive.Season, deposit.Id, string(snapshot))
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, inventory := range contents.MulchInventories {
+			snapshot, err := json.Marshal(inventory)
+			if err != nil {
+				return err
+			}
+			_, err = db.Exec(ctx, "INSERT INTO archived_mulch_inventory(season, delivery_id, inventory) VALUES ($1, $2, $3::jsonb)",
+				archive.Season, inventory.DeliveryId, string(snapshot))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) querySeasonArchives(ctx context.Context, sqlCmd string, args ...any) ([]SeasonArchiveType, error) {
+	log.Println("SqlCmd: ", sqlCmd)
+	rows, err := s.db.Query(ctx, sqlCmd, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	archives := []SeasonArchiveType{}
+	for rows.Next() {
+		archive := SeasonArchiveType{}
+		frConfig := ""
+		err = rows.Scan(&archive.Season, &archive.ClosedTime, &archive.ClosedBy, &archive.IsImported, &frConfig)
+		if err != nil {
+			log.Println("Reading season archive row failed: ", err)
+			return nil, err
+		}
+		if err = json.Unmarshal([]byte(frConfig), &archive.Config); err != nil {
+			return nil, err
+		}
+		archives = append(archives, archive)
+	}
+	return archives, rows.Err()
+}
+
+const seasonArchiveSelectSql = "SELECT season, closed_time::string, closed_by, COALESCE(is_imported, false), " +
+	"fundraiser_config::string FROM season_archives"
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) GetSeasonArchives(ctx context.Context) ([]SeasonArchiveType, error) {
+	return s.querySeasonArchives(ctx, seasonArchiveSelectSql+" ORDER BY season DESC")
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) GetSeasonArchive(ctx context.Context, season int) (SeasonArchiveType, error) {
+	archives, err := s.querySeasonArchives(ctx, seasonArchiveSelectSql+" WHERE season = $1", season)
+	if err != nil {
+		return SeasonArchiveType{}, err
+	}
+	if len(archives) == 0 {
+		return SeasonArchiveType{}, ErrNotFound
+	}
+	return archives[0], nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Reads the archived records stored as JSON in the first column of each row
+func queryArchivedRecords[T any](ctx context.Context, db pgQuerier, sqlCmd string, args ...any) ([]T, error) {
+	log.Println("SqlCmd: ", sqlCmd)
+	rows, err := db.Query(ctx, sqlCmd, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []T{}
+	for rows.Next() {
+		snapshot := ""
+		if err = rows.Scan(&snapshot); err != nil {
+			log.Println("Reading archived row failed: ", err)
+			return nil, err
+		}
+		var record T
+		if err = json.Unmarshal([]byte(snapshot), &record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) GetArchivedMulchOrders(ctx context.Context, season int, ownerId string) ([]MulchOrderType, error) {
+	sqlCmd := "SELECT order_snapshot::string FROM archived_mulch_orders WHERE season = $1"
+	if len(ownerId) == 0 {
+		return queryArchivedRecords[MulchOrderType](ctx, s.db, sqlCmd+" ORDER BY order_id", season)
+	}
+	return queryArchivedRecords[MulchOrderType](ctx, s.db, sqlCmd+" AND order_owner_id = $2 ORDER BY order_id",
+		season, ownerId)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) GetArchivedMulchTimecards(ctx context.Context, season int, uid string) ([]MulchTimecardType, error) {
+	sqlCmd := "SELECT timecard::string FROM archived_mulch_timecards WHERE season = $1"
+	if len(uid) == 0 {
+		return queryArchivedRecords[MulchTimecardType](ctx, s.db, sqlCmd+" ORDER BY uid", season)
+	}
+	return queryArchivedRecords[MulchTimecardType](ctx, s.db, sqlCmd+" AND uid = $2", season, uid)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) GetArchivedAllocations(ctx context.Context, season int, uid string) ([]AllocationItemType, error) {
+	sqlCmd := "SELECT allocation::string FROM archived_allocation_summary WHERE season = $1"
+	if len(uid) == 0 {
+		return queryArchivedRecords[AllocationItemType](ctx, s.db, sqlCmd+" ORDER BY uid", season)
+	}
+	return queryArchivedRecords[AllocationItemType](ctx, s.db, sqlCmd+" AND uid = $2", season, uid)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) GetArchivedUsers(ctx context.Context, season int) ([]UserInfo, error) {
+	return queryArchivedRecords[UserInfo](ctx, s.db,
+		"SELECT user_snapshot::string FROM archived_users WHERE season = $1 ORDER BY uid", season)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) InsertBankDeposit(ctx context.Context, deposit BankDepositType) (string, error) {
+	depositId := ""
+	err := s.InTx(ctx, func(tx FrStore) error {
+		db := tx.(*pgStore).db
+
+		err := db.QueryRow(ctx, "INSERT INTO bank_deposits(deposit_date, cash_amount, check_amount, comments, "+
+			"created_by, created_time) VALUES ($1::date, $2::decimal, $3::decimal, $4, $5, $6::timestamp) RETURNING id::string",
+			deposit.DepositDate, deposit.AmountFromCash, deposit.AmountFromChecks, deposit.Comments,
+			deposit.CreatedBy, deposit.CreatedTime).Scan(&depositId)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range deposit.Items {
+			_, err = db.Exec(ctx, "INSERT INTO bank_deposit_items(deposit_id, order_id, check_number, cash_amount, check_amount) "+
+				"VALUES ($1::uuid, $2::uuid, $3, $4::decimal, $5::decimal)",
+				depositId, item.OrderId, item.CheckNumber, item.AmountFromCash, item.AmountFromChecks)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return depositId, err
+}
+
+const bankDepositSelectSql = "SELECT id::string, deposit_date::string, cash_amount::string, check_amount::string, " +
+	"comments, created_by, created_time::string FROM bank_deposits"
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the deposits that match the where clause along with their items
+func (s *pgStore) queryBankDeposits(ctx context.Context, whereSql string, args ...any) ([]BankDepositType, error) {
+	sqlCmd := bankDepositSelectSql + whereSql + " ORDER BY deposit_date, created_time"
+	log.Println("SqlCmd: ", sqlCmd)
+	rows, err := s.db.Query(ctx, sqlCmd, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deposits := []BankDepositType{}
+	depositIdxs := make(map[string]int)
+	for rows.Next() {
+		deposit := BankDepositType{Items: []BankDepositItemType{}}
+		err = rows.Scan(&deposit.Id, &deposit.DepositDate, &deposit.AmountFromCash, &deposit.AmountFromChecks,
+			&deposit.Comments, &deposit.CreatedBy, &deposit.CreatedTime)
+		if err != nil {
+			log.Println("Reading bank deposit row failed: ", err)
+			return nil, err
+		}
+		depositIdxs[deposit.Id] = len(deposits)
+		deposits = append(deposits, deposit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(deposits) == 0 {
+		return deposits, nil
+	}
+
+	itemRows, err := s.db.Query(ctx, "SELECT deposit_id::string, order_id::string, check_number, cash_amount::string, "+
+		"check_amount::string FROM bank_deposit_items WHERE deposit_id = ANY($1::uuid[]) ORDER BY order_id, check_number",
+		slices.Collect(maps.Keys(depositIdxs)))
+	if err != nil {
+		return nil, err
+	}
+	defer itemRows.Close()
+
+	for itemRows.Next() {
+		depositId, item := "", BankDepositItemType{}
+		err = itemRows.Scan(&depositId, &item.OrderId, &item.CheckNumber, &item.AmountFromCash, &item.AmountFromChecks)
+		if err != nil {
+			log.Println("Reading bank deposit item row failed: ", err)
+			return nil, err
+		}
+		idx := depositIdxs[depositId]
+		deposits[idx].Items = append(deposits[idx].Items, item)
+	}
+	return deposits, itemRows.Err()
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) GetBankDeposits(ctx context.Context) ([]BankDepositType, error) {
+	return s.queryBankDeposits(ctx, "")
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) GetBankDeposit(ctx context.Context, id string) (BankDepositType, error) {
+	deposits, err := s.queryBankDeposits(ctx, " WHERE id = $1::uuid", id)
+	if err != nil {
+		return BankDepositType{}, err
+	}
+	if len(deposits) == 0 {
+		return BankDepositType{}, ErrNotFound
+	}
+	return deposits[0], nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) DeleteBankDeposit(ctx context.Context, id string) error {
+	return s.InTx(ctx, func(tx FrStore) error {
+		db := tx.(*pgStore).db
+		if _, err := db.Exec(ctx, "DELETE FROM bank_deposit_items WHERE deposit_id = $1::uuid", id); err != nil {
+			return err
+		}
+		_, err := db.Exec(ctx, "DELETE FROM bank_deposits WHERE id = $1::uuid", id)
+		return err
+	})
+}
+
+const mulchInventorySelectSql = "SELECT delivery_id, supplier, bags_ordered, pallets, bags_received, bags_leftover, " +
+	"bags_damaged, mulch_cost::string, comments, last_modified_by, last_modified_time::string FROM mulch_inventory"
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) queryMulchInventories(ctx context.Context, whereSql string, args ...any) ([]MulchInventoryType, error) {
+	sqlCmd := mulchInventorySelectSql + whereSql + " ORDER BY delivery_id"
+	log.Println("SqlCmd: ", sqlCmd)
+	rows, err := s.db.Query(ctx, sqlCmd, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	inventories := []MulchInventoryType{}
+	for rows.Next() {
+		inventory := MulchInventoryType{}
+		err = rows.Scan(&inventory.DeliveryId, &inventory.Supplier, &inventory.BagsOrdered, &inventory.Pallets,
+			&inventory.BagsReceived, &inventory.BagsLeftover, &inventory.BagsDamaged, &inventory.MulchCost,
+			&inventory.Comments, &inventory.LastModifiedBy, &inventory.LastModifiedTime)
+		if err != nil {
+			log.Println("Reading mulch inventory row failed: ", err)
+			return nil, err
+		}
+		inventories = append(inventories, inventory)
+	}
+	return inventories, rows.Err()
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) GetMulchInventories(ctx context.Context) ([]MulchInventoryType, error) {
+	return s.queryMulchInventories(ctx, "")
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) GetMulchInventory(ctx context.Context, deliveryId int) (MulchInventoryType, error) {
+	inventories, err := s.queryMulchInventories(ctx, " WHERE delivery_id = $1", deliveryId)
+	if err != nil {
+		return MulchInventoryType{}, err
+	}
+	if len(inventories) == 0 {
+		return MulchInventoryType{}, ErrNotFound
+	}
+	return inventories[0], nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) UpsertMulchInventory(ctx context.Context, inventory MulchInventoryType) error {
+	_, err := s.db.Exec(ctx, "INSERT INTO mulch_inventory(delivery_id, supplier, bags_ordered, pallets, bags_received, "+
+		"bags_leftover, bags_damaged, mulch_cost, comments, last_modified_by, last_modified_time) "+
+		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8::decimal, $9, $10, $11::timestamp) "+
+		"ON CONFLICT (delivery_id) DO UPDATE SET supplier = excluded.supplier, bags_ordered = excluded.bags_ordered, "+
+		"pallets = excluded.pallets, bags_received = excluded.bags_received, bags_leftover = excluded.bags_leftover, "+
+		"bags_damaged = excluded.bags_damaged, mulch_cost = excluded.mulch_cost, comments = excluded.comments, "+
+		"last_modified_by = excluded.last_modified_by, last_modified_time = excluded.last_modified_time",
+		inventory.DeliveryId, inventory.Supplier, inventory.BagsOrdered, inventory.Pallets, inventory.BagsReceived,
+		inventory.BagsLeftover, inventory.BagsDamaged, inventory.MulchCost, inventory.Comments,
+		inventory.LastModifiedBy, inventory.LastModifiedTime)
+	return err
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) DeleteMulchInventory(ctx context.Context, deliveryId int) error {
+	_, err := s.db.Exec(ctx, "DELETE FROM mulch_inventory WHERE delivery_id = $1", deliveryId)
+	return err
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) InsertNotification(ctx context.Context, notification NotificationType) error {
+	_, err := s.db.Exec(ctx, "INSERT INTO notifications(uid, kind, message, order_id, created_time) "+
+		"VALUES ($1, $2, $3, $4::uuid, $5::timestamp)",
+		notification.Uid, notification.Kind, notification.Message, notification.OrderId, notification.CreatedTime)
+	return err
+}
+
+const notificationSelectSql = "SELECT id::string, uid, kind, message, order_id::string, created_time::string, " +
+	"is_read FROM notifications"
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) queryNotifications(ctx context.Context, whereSql string, args ...any) ([]NotificationType, error) {
+	sqlCmd := notificationSelectSql + whereSql + " ORDER BY created_time DESC"
+	log.Println("SqlCmd: ", sqlCmd)
+	rows, err := s.db.Query(ctx, sqlCmd, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []NotificationType{}
+	for rows.Next() {
+		notification := NotificationType{}
+		err = rows.Scan(&notification.Id, &notification.Uid, &notification.Kind, &notification.Message,
+			&notification.OrderId, &notification.CreatedTime, &notification.IsRead)
+		if err != nil {
+			log.Println("Reading notification row failed: ", err)
+			return nil, err
+		}
+		notifications = append(notifications, notification)
+	}
+	return notifications, rows.Err()
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) GetNotifications(ctx context.Context, uid string, isUnreadOnly bool) ([]NotificationType, error) {
+	if isUnreadOnly {
+		return s.queryNotifications(ctx, " WHERE uid = $1 AND NOT is_read", uid)
+	}
+	return s.queryNotifications(ctx, " WHERE uid = $1", uid)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) GetNotification(ctx context.Context, id string) (NotificationType, error) {
+	notifications, err := s.queryNotifications(ctx, " WHERE id = $1::uuid", id)
+	if err != nil {
+		return NotificationType{}, err
+	}
+	if len(notifications) == 0 {
+		return NotificationType{}, ErrNotFound
+	}
+	return notifications[0], nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) MarkNotificationRead(ctx context.Context, id string) error {
+	result, err := s.db.Exec(ctx, "UPDATE notifications SET is_read = true WHERE id = $1::uuid", id)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) queryKnownAddrs(ctx context.Context, where string, args ...any) ([]KnownAddrType, error) {
+	sqlCmd := "SELECT id::string, addr, zipcode, city, COALESCE(lat, ''), COALESCE(lng, ''), " +
+		"COALESCE(last_modified_time::string, ''), COALESCE(created_time::string, '') FROM known_addrs " +
+		where + " ORDER BY id"
+	rows, err := s.db.Query(ctx, sqlCmd, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	addrs := []KnownAddrType{}
+	for rows.Next() {
+		addr := KnownAddrType{}
+		err = rows.Scan(&addr.Id, &addr.Addr, &addr.Zipcode, &addr.City, &addr.Lat, &addr.Lng,
+			&addr.LastModifiedTime, &addr.CreatedTime)
+		if err != nil {
+			log.Println("Reading known address row failed: ", err)
+			return nil, err
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs, rows.Err()
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) GetKnownAddrs(ctx context.Context) ([]KnownAddrType, error) {
+	// Addresses that were never located have no coordinates
+	return s.queryKnownAddrs(ctx, "WHERE lat IS NOT NULL AND lng IS NOT NULL AND lat != '' AND lng != ''")
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Uses the index on lat_deg and lng_deg (migration 14)
+func (s *pgStore) GetKnownAddrsInBox(ctx context.Context, box GeoBoxType) ([]KnownAddrType, error) {
+	return s.queryKnownAddrs(ctx, "WHERE lat_deg BETWEEN $1 AND $2 AND lng_deg BETWEEN $3 AND $4",
+		box.MinLat, box.MaxLat, box.MinLng, box.MaxLng)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Rows from before normalized_addr was added were filled in by migration 13
+func (s *pgStore) GetKnownAddrsByNormalizedAddr(ctx context.Context, normalizedAddr string) ([]KnownAddrType, error) {
+	return s.queryKnownAddrs(ctx, "WHERE normalized_addr = $1", normalizedAddr)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func (s *pgStore) InsertKnownAddr(ctx context.Context, addr KnownAddrType) (string, error) {
+	var lat, lng *string
+	var latDeg, lngDeg *float64
+	if len(addr.Lat) != 0 && len(addr.Lng) != 0 {
+		lat, lng = &addr.Lat, &addr.Lng
+		if latVal, lngVal, ok := parseKnownAddrLocation(addr); ok {
+			latDeg, lngDeg = &latVal, &lngVal
+		}
+	}
+	sqlCmd := "INSERT INTO known_addrs (addr, normalized_addr, zipcode, city, lat, lng, lat_deg, lng_deg, " +
+		"last_modified_time, created_time) " +
+		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9::timestamp, $10::timestamp) RETURNING id::string"
+	var id string
+	err := s.db.QueryRow(ctx, sqlCmd, addr.Addr, normalizeAddr(addr.Addr), addr.Zipcode, addr.City, lat, lng,
+		latDeg, lngDeg, addr.LastModifiedTime, addr.CreatedTime).Scan(&id)
+	if err != nil {
+		log.Println("Inserting known address failed: ", err)
+		return "", err
+	}
+	return id, nil
+}
@@ -0,0 +1,461 @@
+package frgql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// The pg store is only tested when this points at a scratch database.  All
+// of its tables are truncated.
+const testDatabaseUrlEnv = "FRGQL_TEST_DATABASE_URL"
+
+// //////////////////////////////////////////////////////////////////////////
+// Returns the stores the store tests are run against by name
+func getTestStores(t *testing.T) map[string]func(t *testing.T) FrStore {
+	stores := map[string]func(t *testing.T) FrStore{
+		"mem": func(t *testing.T) FrStore { return NewMemStore() },
+	}
+	if dbUrl := os.Getenv(testDatabaseUrlEnv); len(dbUrl) != 0 {
+		stores["pg"] = func(t *testing.T) FrStore { return newTestPgStore(t, dbUrl) }
+	} else {
+		t.Logf("%s isn't set so the pg store isn't tested", testDatabaseUrlEnv)
+	}
+	return stores
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Migrates the database to the latest version and empties every table
+func newTestPgStore(t *testing.T, dbUrl string) FrStore {
+	t.Helper()
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dbUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+
+	prevDb := Db
+	Db = pool
+	err = MigrateUp(ctx, 0)
+	Db = prevDb
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := pool.Query(ctx, "SELECT table_name FROM information_schema.tables "+
+		"WHERE table_schema = current_schema() AND table_type = 'BASE TABLE' AND table_name != 'schema_migrations'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables := []string{}
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			t.Fatal(err)
+		}
+		tables = append(tables, table)
+	}
+	rows.Close()
+	for _, table := range tables {
+		if _, err := pool.Exec(ctx, "TRUNCATE "+table); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewPgStore(pool)
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func makeTestOrder(orderId string, ownerId string) MulchOrderType {
+	amount := "24.0000"
+	deliveryId := 1
+	city := "Springfield"
+	return MulchOrderType{
+		OrderId:                 orderId,
+		OwnerId:                 ownerId,
+		DeliveryId:              &deliveryId,
+		AmountFromPurchases:     &amount,
+		AmountFromCashCollected: &amount,
+		AmountTotalCollected:    &amount,
+		Customer: CustomerType{
+			Name:         "Pat Doe",
+			Addr1:        "12 Main St",
+			City:         &city,
+			Phone:        "555-0100",
+			Neighborhood: "Oak Hills",
+		},
+		Purchases:        []ProductsType{{ProductId: "bags", NumSold: 4, AmountCharged: amount}},
+		LastModifiedTime: makeLastModifiedTime(),
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// A version of a record that is older than any it has
+func makeStaleLastModifiedTime() string {
+	return time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC).Format(lastModifiedTimeFormat)
+}
+
+const (
+	testOrderId1 = "0b5d3f3e-6a4f-4b39-9d2a-1e1d6b8f0001"
+	testOrderId2 = "0b5d3f3e-6a4f-4b39-9d2a-1e1d6b8f0002"
+	testOrderId3 = "0b5d3f3e-6a4f-4b39-9d2a-1e1d6b8f0003"
+)
+
+var storeTests = []struct {
+	name string
+	test func(t *testing.T, ctx context.Context, store FrStore)
+}{
+	{"order round trip", func(t *testing.T, ctx context.Context, store FrStore) {
+		mustNotFail(t, store.InsertMulchOrder(ctx, makeTestOrder(testOrderId1, "scout1")))
+
+		order, err := store.GetMulchOrder(ctx, GetMulchOrderParams{OrderId: testOrderId1, GqlFields: allMulchOrderGqlFields})
+		mustNotFail(t, err)
+		if order.OwnerId != "scout1" || order.Customer.Name != "Pat Doe" || *order.Customer.City != "Springfield" ||
+			len(order.Purchases) != 1 || order.Purchases[0].NumSold != 4 {
+			t.Errorf("read back: %+v", order)
+		}
+
+		mustNotFail(t, store.DeleteMulchOrder(ctx, testOrderId1))
+		_, err = store.GetMulchOrder(ctx, GetMulchOrderParams{OrderId: testOrderId1, GqlFields: allMulchOrderGqlFields})
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("deleted order returned: %v not ErrNotFound", err)
+		}
+	}},
+
+	{"order update only sets the given fields", func(t *testing.T, ctx context.Context, store FrStore) {
+		mustNotFail(t, store.InsertMulchOrder(ctx, makeTestOrder(testOrderId1, "scout1")))
+
+		comments := "leave at the side door"
+		update := MulchOrderType{OrderId: testOrderId1, Comments: &comments, LastModifiedTime: makeLastModifiedTime()}
+		update.Customer.Name = "Chris Doe"
+		mustNotFail(t, store.UpdateMulchOrder(ctx, update, []string{"comments", "customer.name"}, ""))
+
+		order, err := store.GetMulchOrder(ctx, GetMulchOrderParams{OrderId: testOrderId1, GqlFields: allMulchOrderGqlFields})
+		mustNotFail(t, err)
+		if order.Comments == nil || *order.Comments != comments || order.Customer.Name != "Chris Doe" {
+			t.Errorf("given fields weren't set: %+v", order)
+		}
+		if order.OwnerId != "scout1" || order.Customer.Addr1 != "12 Main St" || len(order.Purchases) != 1 {
+			t.Errorf("fields that weren't given changed: %+v", order)
+		}
+	}},
+
+	{"order update at a version", func(t *testing.T, ctx context.Context, store FrStore) {
+		mustNotFail(t, store.InsertMulchOrder(ctx, makeTestOrder(testOrderId1, "scout1")))
+		order, err := store.GetMulchOrder(ctx, GetMulchOrderParams{OrderId: testOrderId1, GqlFields: allMulchOrderGqlFields})
+		mustNotFail(t, err)
+
+		comments := "first"
+		update := MulchOrderType{OrderId: testOrderId1, Comments: &comments, LastModifiedTime: makeLastModifiedTime()}
+		mustNotFail(t, store.UpdateMulchOrder(ctx, update, []string{"comments"}, order.LastModifiedTime))
+
+		// The version it was read at is gone now
+		stale := "second"
+		update = MulchOrderType{OrderId: testOrderId1, Comments: &stale, LastModifiedTime: makeLastModifiedTime()}
+		if err := store.UpdateMulchOrder(ctx, update, []string{"comments"}, order.LastModifiedTime); !errors.Is(err, ErrStaleVersion) {
+			t.Errorf("update at an old version returned: %v not ErrStaleVersion", err)
+		}
+		order, err = store.GetMulchOrder(ctx, GetMulchOrderParams{OrderId: testOrderId1, GqlFields: allMulchOrderGqlFields})
+		mustNotFail(t, err)
+		if *order.Comments != comments {
+			t.Errorf("update at an old version was saved: %s", *order.Comments)
+		}
+
+		update.OrderId = testOrderId2
+		if err := store.UpdateMulchOrder(ctx, update, []string{"comments"}, order.LastModifiedTime); !errors.Is(err, ErrStaleVersion) {
+			t.Errorf("update of a missing order at a version returned: %v not ErrStaleVersion", err)
+		}
+		if err := store.UpdateMulchOrder(ctx, update, []string{"comments"}, ""); !errors.Is(err, ErrNotFound) {
+			t.Errorf("update of a missing order returned: %v not ErrNotFound", err)
+		}
+	}},
+
+	{"config update at a version", func(t *testing.T, ctx context.Context, store FrStore) {
+		mustNotFail(t, store.SetFundraiserConfig(ctx, FrConfigType{
+			Kind: "mulch", Description: "Spring", LastModifiedTime: makeLastModifiedTime(),
+		}))
+		frConfig, err := store.GetFundraiserConfig(ctx, allFrConfigGqlFields)
+		mustNotFail(t, err)
+
+		err = store.UpdateFundraiserConfig(ctx, FrConfigType{
+			Description: "Fall", LastModifiedTime: makeLastModifiedTime(),
+		}, makeStaleLastModifiedTime())
+		if !errors.Is(err, ErrStaleVersion) {
+			t.Errorf("update at an old version returned: %v not ErrStaleVersion", err)
+		}
+		mustNotFail(t, store.UpdateFundraiserConfig(ctx, FrConfigType{
+			Description: "Fall", LastModifiedTime: makeLastModifiedTime(),
+		}, frConfig.LastModifiedTime))
+
+		frConfig, err = store.GetFundraiserConfig(ctx, allFrConfigGqlFields)
+		mustNotFail(t, err)
+		if frConfig.Description != "Fall" || frConfig.Kind != "mulch" {
+			t.Errorf("config after the update: %+v", frConfig)
+		}
+	}},
+
+	{"neighborhood update at a version", func(t *testing.T, ctx context.Context, store FrStore) {
+		zipcode, city, isVisible := 12345, "Springfield", true
+		mustNotFail(t, store.InsertNeighborhood(ctx, NeighborhoodInfo{
+			Name: "Oak Hills", Zipcode: &zipcode, City: &city, IsVisible: &isVisible, LastModifiedTime: makeLastModifiedTime(),
+		}))
+		hoods, err := store.GetNeighborhoods(ctx, append([]string{"lastModifiedTime"}, allNeighborhoodGqlFields...))
+		mustNotFail(t, err)
+		if len(hoods) != 1 {
+			t.Fatalf("%d neighborhoods were read back", len(hoods))
+		}
+
+		isHidden := false
+		hood := hoods[0]
+		hood.IsVisible, hood.LastModifiedTime = &isHidden, makeLastModifiedTime()
+		if err := store.UpdateNeighborhood(ctx, hood, makeStaleLastModifiedTime()); !errors.Is(err, ErrStaleVersion) {
+			t.Errorf("update at an old version returned: %v not ErrStaleVersion", err)
+		}
+		mustNotFail(t, store.UpdateNeighborhood(ctx, hood, hoods[0].LastModifiedTime))
+
+		hood.Name = "Elm Park"
+		if err := store.UpdateNeighborhood(ctx, hood, hoods[0].LastModifiedTime); !errors.Is(err, ErrStaleVersion) {
+			t.Errorf("update of a missing neighborhood at a version returned: %v not ErrStaleVersion", err)
+		}
+	}},
+
+	{"user update at a version", func(t *testing.T, ctx context.Context, store FrStore) {
+		mustNotFail(t, store.InsertUser(ctx, UserInfo{
+			Id: "scout1", FirstName: "Sam", LastName: "Doe", Group: "Patrol 1", LastModifiedTime: makeLastModifiedTime(),
+		}))
+		users, err := store.GetUsers(ctx, GetUsersParams{
+			GqlFields: append([]string{"lastModifiedTime"}, allUserGqlFields...), ShowUsersWithoutAuthCreds: true,
+		})
+		mustNotFail(t, err)
+		if len(users) != 1 {
+			t.Fatalf("%d users were read back", len(users))
+		}
+
+		user := users[0]
+		user.Group, user.LastModifiedTime = "Patrol 2", makeLastModifiedTime()
+		if err := store.UpdateUser(ctx, user, makeStaleLastModifiedTime()); !errors.Is(err, ErrStaleVersion) {
+			t.Errorf("update at an old version returned: %v not ErrStaleVersion", err)
+		}
+		mustNotFail(t, store.UpdateUser(ctx, user, users[0].LastModifiedTime))
+
+		users, err = store.GetUsers(ctx, GetUsersParams{GqlFields: allUserGqlFields, ShowUsersWithoutAuthCreds: true})
+		mustNotFail(t, err)
+		if len(users) != 1 || users[0].Group != "Patrol 2" {
+			t.Errorf("users after the update: %+v", users)
+		}
+	}},
+
+	{"failed transaction keeps nothing", func(t *testing.T, ctx context.Context, store FrStore) {
+		errRollback := errors.New("roll back")
+		err := store.InTx(ctx, func(tx FrStore) error {
+			if err := tx.InsertMulchOrder(ctx, makeTestOrder(testOrderId1, "scout1")); err != nil {
+				return err
+			}
+			return errRollback
+		})
+		if !errors.Is(err, errRollback) {
+			t.Errorf("transaction returned: %v", err)
+		}
+		_, err = store.GetMulchOrder(ctx, GetMulchOrderParams{OrderId: testOrderId1, GqlFields: allMulchOrderGqlFields})
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("order of the failed transaction returned: %v not ErrNotFound", err)
+		}
+	}},
+
+	{"spreader filters", func(t *testing.T, ctx context.Context, store FrStore) {
+		mustNotFail(t, store.InsertMulchOrder(ctx, makeTestOrder(testOrderId1, "scout1")))
+		mustNotFail(t, store.InsertMulchOrder(ctx, makeTestOrder(testOrderId2, "scout2")))
+		mustNotFail(t, store.SetSpreaders(ctx, testOrderId1, []string{"scout1", "scout2"}))
+		mustNotFail(t, store.SetSpreaders(ctx, testOrderId2, []string{"scout2"}))
+
+		// What getAssistedSpreadingOrderCountByOwnerId asks for
+		orders, err := store.GetMulchOrders(ctx, GetMulchOrdersParams{
+			SpreaderId: "scout2", ExcludeOwnerId: "scout2", GqlFields: []string{"orderId", "spreaders"},
+		})
+		mustNotFail(t, err)
+		if len(orders) != 1 || orders[0].OrderId != testOrderId1 || len(orders[0].Spreaders) != 2 {
+			t.Errorf("orders scout2 helped spread: %+v", orders)
+		}
+	}},
+
+	{"keyset paging", func(t *testing.T, ctx context.Context, store FrStore) {
+		for _, orderId := range []string{testOrderId3, testOrderId1, testOrderId2} {
+			mustNotFail(t, store.InsertMulchOrder(ctx, makeTestOrder(orderId, "scout1")))
+		}
+
+		getPage := func(params GetMulchOrdersParams) []string {
+			params.SortBy, params.GqlFields = "orderId", []string{"orderId"}
+			orders, err := store.GetMulchOrders(ctx, params)
+			mustNotFail(t, err)
+			orderIds := []string{}
+			for _, order := range orders {
+				orderIds = append(orderIds, order.OrderId)
+			}
+			return orderIds
+		}
+		firstKey := getMulchOrderSortKey(MulchOrderType{OrderId: testOrderId1}, "orderId", false)
+		lastKey := getMulchOrderSortKey(MulchOrderType{OrderId: testOrderId3}, "orderId", false)
+
+		if page := getPage(GetMulchOrdersParams{Limit: 2}); !slices.Equal(page, []string{testOrderId1, testOrderId2}) {
+			t.Errorf("first page: %v", page)
+		}
+		if page := getPage(GetMulchOrdersParams{AfterKey: &firstKey, Limit: 1}); !slices.Equal(page, []string{testOrderId2}) {
+			t.Errorf("page after the first order: %v", page)
+		}
+		if page := getPage(GetMulchOrdersParams{AfterKey: &firstKey, BeforeKey: &lastKey}); !slices.Equal(page, []string{testOrderId2}) {
+			t.Errorf("page between the first and last orders: %v", page)
+		}
+	}},
+
+	{"bank deposit round trip", func(t *testing.T, ctx context.Context, store FrStore) {
+		id, err := store.InsertBankDeposit(ctx, BankDepositType{
+			DepositDate: "2024-04-01", AmountFromCash: "24.0000", AmountFromChecks: "10.0000",
+			CreatedBy: "admin1", CreatedTime: time.Now().UTC().Format(time.RFC3339),
+			Items: []BankDepositItemType{
+				{OrderId: testOrderId1, AmountFromCash: "24.0000", AmountFromChecks: "0.0000"},
+				{OrderId: testOrderId2, CheckNumber: "1001", AmountFromCash: "0.0000", AmountFromChecks: "10.0000"},
+			},
+		})
+		mustNotFail(t, err)
+
+		deposit, err := store.GetBankDeposit(ctx, id)
+		mustNotFail(t, err)
+		if deposit.DepositDate != "2024-04-01" || len(deposit.Items) != 2 {
+			t.Errorf("read back: %+v", deposit)
+		}
+
+		mustNotFail(t, store.DeleteBankDeposit(ctx, id))
+		if _, err := store.GetBankDeposit(ctx, id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("deleted deposit returned: %v not ErrNotFound", err)
+		}
+	}},
+
+	{"notifications", func(t *testing.T, ctx context.Context, store FrStore) {
+		for idx := range 2 {
+			mustNotFail(t, store.InsertNotification(ctx, NotificationType{
+				Uid: "scout1", Kind: "checkBounced", Message: fmt.Sprintf("bounced %d", idx),
+				CreatedTime: time.Now().UTC().Format(time.RFC3339),
+			}))
+		}
+		notifications, err := store.GetNotifications(ctx, "scout1", false)
+		mustNotFail(t, err)
+		if len(notifications) != 2 {
+			t.Fatalf("%d notifications were read back", len(notifications))
+		}
+
+		mustNotFail(t, store.MarkNotificationRead(ctx, notifications[0].Id))
+		unread, err := store.GetNotifications(ctx, "scout1", true)
+		mustNotFail(t, err)
+		if len(unread) != 1 || unread[0].Id != notifications[1].Id {
+			t.Errorf("unread after marking one read: %+v", unread)
+		}
+		if others, _ := store.GetNotifications(ctx, "scout2", false); len(others) != 0 {
+			t.Errorf("scout2 got scout1's notifications: %+v", others)
+		}
+	}},
+
+	{"known addresses", func(t *testing.T, ctx context.Context, store FrStore) {
+		zipcode := 64101
+		now := time.Now().UTC().Format(time.RFC3339)
+		located, err := store.InsertKnownAddr(ctx, KnownAddrType{Addr: "12 Main Street", Zipcode: &zipcode,
+			Lat: "39.1000000", Lng: "-94.5000000", LastModifiedTime: now, CreatedTime: now})
+		mustNotFail(t, err)
+		_, err = store.InsertKnownAddr(ctx, KnownAddrType{Addr: "12 Main St", LastModifiedTime: now, CreatedTime: now})
+		mustNotFail(t, err)
+		_, err = store.InsertKnownAddr(ctx, KnownAddrType{Addr: "40 Elm St", Lat: "39.2000000", Lng: "-94.5000000",
+			LastModifiedTime: now, CreatedTime: now})
+		mustNotFail(t, err)
+
+		sameAddr, err := store.GetKnownAddrsByNormalizedAddr(ctx, normalizeAddr("12 main st."))
+		mustNotFail(t, err)
+		if len(sameAddr) != 2 {
+			t.Errorf("by normalized address: %+v", sameAddr)
+		}
+		withCoordinates, err := store.GetKnownAddrs(ctx)
+		mustNotFail(t, err)
+		if len(withCoordinates) != 2 {
+			t.Errorf("with coordinates: %+v", withCoordinates)
+		}
+		inBox, err := store.GetKnownAddrsInBox(ctx, makeGeoBox(39.1001, -94.5001, 0.02))
+		mustNotFail(t, err)
+		if len(inBox) != 1 || inBox[0].Id != located || *inBox[0].Zipcode != zipcode {
+			t.Errorf("in the box: %+v", inBox)
+		}
+	}},
+
+	{"concurrent order revisions", func(t *testing.T, ctx context.Context, store FrStore) {
+		mustNotFail(t, store.InsertMulchOrder(ctx, makeTestOrder(testOrderId1, "scout1")))
+
+		// Some transactions may fail as conflicts but none that commit can
+		// share or skip a revision number
+		const numTxs = 8
+		var wg sync.WaitGroup
+		revisions := make([]int, numTxs)
+		for i := range numTxs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				revision := 0
+				err := store.InTx(ctx, func(tx FrStore) error {
+					order, err := tx.GetMulchOrder(ctx, GetMulchOrderParams{OrderId: testOrderId1, GqlFields: allMulchOrderGqlFields})
+					if err != nil {
+						return err
+					}
+					revision, err = tx.InsertMulchOrderRevision(ctx, MulchOrderRevisionType{OrderId: testOrderId1,
+						Operation: "updateMulchOrder", ActorId: "scout1", CreatedTime: time.Now().UTC().Format(time.RFC3339),
+						Order: order})
+					if err != nil {
+						return err
+					}
+					comments := fmt.Sprintf("change %d", i)
+					return tx.UpdateMulchOrder(ctx, MulchOrderType{OrderId: testOrderId1, Comments: &comments,
+						LastModifiedTime: makeLastModifiedTime()}, []string{"comments"}, "")
+				})
+				if err == nil {
+					revisions[i] = revision
+				}
+			}()
+		}
+		wg.Wait()
+
+		committed := slices.DeleteFunc(revisions, func(revision int) bool { return revision == 0 })
+		slices.Sort(committed)
+		history, err := store.GetMulchOrderHistory(ctx, testOrderId1)
+		mustNotFail(t, err)
+		if len(committed) == 0 || len(history) != len(committed) {
+			t.Fatalf("committed revisions: %v history: %+v", committed, history)
+		}
+		for i, revision := range committed {
+			if revision != i+1 || history[i].Revision != i+1 {
+				t.Errorf("revision: %d returned: %d saved: %d", i+1, revision, history[i].Revision)
+			}
+		}
+	}},
+}
+
+// //////////////////////////////////////////////////////////////////////////
+func mustNotFail(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////
+// Every store has to behave the same for the api
+func TestStores(t *testing.T) {
+	for storeName, newStore := range getTestStores(t) {
+		for _, storeTest := range storeTests {
+			t.Run(storeName+"/"+storeTest.name, func(t *testing.T) {
+				storeTest.test(t, context.Background(), newStore(t))
+			})
+		}
+	}
+}